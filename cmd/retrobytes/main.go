@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"io"
 	"log"
 	"os"
@@ -18,9 +19,11 @@ import (
 
 	"retrobytes/internal/config"
 	"retrobytes/internal/http/handlers"
+	"retrobytes/internal/imaging"
 	applog "retrobytes/internal/log"
 	"retrobytes/internal/repos"
 	"retrobytes/internal/services"
+	"retrobytes/internal/validate"
 )
 
 func main() {
@@ -54,6 +57,13 @@ func main() {
 	app := fiber.New(fiber.Config{
 		Views: engine,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			// Bodies over the cap below are refused before any handler runs
+			if errors.Is(err, fiber.ErrRequestEntityTooLarge) {
+				applog.Security(c, "request.too_large", map[string]any{"path": c.Path()})
+				return c.Status(fiber.StatusRequestEntityTooLarge).Render("notfound", fiber.Map{
+					"Message": "That upload is too large: a form can carry at most 1 MB. Go back and attach smaller files.",
+				})
+			}
 			// Log and show a friendly message
 			applog.Error(c, "server.error", err, nil)
 			// Avoid leaking internals; best-effort render
@@ -132,8 +142,26 @@ func main() {
 			applog.Security(c, "media.traversal.block", map[string]any{"path": path})
 			return c.SendStatus(fiber.StatusNotFound)
 		}
+		// return photos are private and served by the order and admin pages
+		if strings.HasPrefix(filepath.ToSlash(clean), "returns/") {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
 		full := filepath.Join(mediaDir, clean)
-		return c.SendFile(full, true)
+		// ?w= selects a resized variant when one was generated at upload time
+		if w := imaging.PickWidth(c.QueryInt("w")); w > 0 {
+			variant := filepath.Join(mediaDir, imaging.VariantPath(clean, w))
+			if _, err := os.Stat(variant); err == nil {
+				full = variant
+			}
+		}
+		if err := c.SendFile(full, true); err != nil {
+			return err
+		}
+		if c.Response().StatusCode() == fiber.StatusOK {
+			// Uploaded files are never rewritten in place, so they can be cached for a year
+			c.Set(fiber.HeaderCacheControl, "public, max-age=31536000, immutable")
+		}
+		return nil
 	})
 
 	// ---------- App handlers ----------
@@ -149,6 +177,30 @@ func main() {
 		return c.Status(404).Render("notfound", fiber.Map{"Message": "This item is no longer available"})
 	})
 	app.Get("/product/:id", deps.ProductHandler.Detail)
+	app.Post("/product/:id/reviews", handlers.RequireUser(authSvc), limiter.New(limiter.Config{
+		Max:        5,
+		Expiration: 10 * time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			applog.Security(c, "rate.review.hit", nil)
+			id, ok := validate.ID(c.Params("id"))
+			if !ok {
+				return c.SendStatus(fiber.StatusTooManyRequests)
+			}
+			return c.Redirect("/product/" + id + "?review=rate_limited#reviews")
+		},
+	}), deps.ReviewHandler.Submit)
+	app.Post("/product/:id/questions", handlers.RequireUser(authSvc), limiter.New(limiter.Config{
+		Max:        5,
+		Expiration: 10 * time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			applog.Security(c, "rate.question.hit", nil)
+			id, ok := validate.ID(c.Params("id"))
+			if !ok {
+				return c.SendStatus(fiber.StatusTooManyRequests)
+			}
+			return c.Redirect("/product/" + id + "?question=rate_limited#questions")
+		},
+	}), deps.QuestionHandler.Ask)
 
 	// API
 	api := app.Group("/api/v1")
@@ -168,9 +220,27 @@ func main() {
 	// Cart & Orders
 	app.Get("/cart", deps.CartHandler.View)
 	app.Post("/cart", deps.CartHandler.Add)
+	app.Post("/cart/promo", deps.CartHandler.ApplyPromo)
+	app.Post("/cart/promo/remove", deps.CartHandler.RemovePromo)
 	app.Get("/checkout", deps.OrderHandler.Checkout)
+	app.Post("/checkout/prices", deps.OrderHandler.ConfirmPrices)
 	app.Post("/orders", deps.OrderHandler.Place)
 	app.Get("/order/:id", deps.OrderHandler.View)
+	app.Get("/orders/lookup", deps.OrderHandler.LookupForm)
+	app.Post("/orders/lookup", limiter.New(limiter.Config{
+		Max:        5,
+		Expiration: 10 * time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			applog.Security(c, "rate.lookup.hit", nil)
+			return c.Status(fiber.StatusTooManyRequests).Render("orders_lookup", fiber.Map{"Err": "Too many requests. Please try again later."})
+		},
+	}), deps.OrderHandler.LookupSubmit)
+	app.Post("/order/:id/cancel", deps.OrderHandler.Cancel)
+	app.Get("/order/:id/invoice.pdf", deps.OrderHandler.Invoice)
+	app.Get("/order/:id/packing-slip.pdf", deps.OrderHandler.PackingSlip)
+	app.Get("/order/:id/pickup-qr.svg", deps.OrderHandler.PickupQR)
+	app.Post("/order/:id/returns", deps.OrderHandler.RequestReturn)
+	app.Get("/order/:id/returns/:rid/photos/:name", deps.OrderHandler.ReturnPhoto)
 	app.Get("/orders", handlers.RequireUser(authSvc), deps.OrderHandler.History)
 
 	// Wishlist
@@ -178,6 +248,17 @@ func main() {
 	app.Post("/wishlist", deps.WishlistHandler.Save)
 	app.Post("/wishlist/delete", deps.WishlistHandler.Unsave)
 
+	// Saved searches & notifications (logged-in users)
+	app.Get("/saved-searches", handlers.RequireUser(authSvc), deps.SavedSearchHandler.List)
+	app.Post("/saved-searches", handlers.RequireUser(authSvc), deps.SavedSearchHandler.Save)
+	app.Post("/saved-searches/:id/delete", handlers.RequireUser(authSvc), deps.SavedSearchHandler.Delete)
+	app.Get("/account/addresses", handlers.RequireUser(authSvc), deps.AddressHandler.List)
+	app.Post("/account/addresses", handlers.RequireUser(authSvc), deps.AddressHandler.Add)
+	app.Post("/account/addresses/:id/delete", handlers.RequireUser(authSvc), deps.AddressHandler.Delete)
+	app.Post("/account/addresses/:id/default", handlers.RequireUser(authSvc), deps.AddressHandler.SetDefault)
+	app.Get("/notifications", handlers.RequireUser(authSvc), deps.NotificationHandler.Notifications)
+	app.Post("/notifications/read", handlers.RequireUser(authSvc), deps.NotificationHandler.MarkRead)
+
 	// Auth routes (login throttled)
 	app.Get("/login", authH.LoginForm)
 	app.Post("/login", limiter.New(limiter.Config{
@@ -189,20 +270,196 @@ func main() {
 		},
 	}), authH.Login)
 	app.Post("/logout", authH.Logout)
+	app.Get("/password/forgot", authH.ForgotForm)
+	app.Post("/password/forgot", limiter.New(limiter.Config{
+		Max:        3,
+		Expiration: 10 * time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			applog.Security(c, "rate.reset.hit", nil)
+			return c.Status(fiber.StatusTooManyRequests).Render("forgot_password", fiber.Map{"Err": "Too many requests. Please try again later."})
+		},
+	}), authH.Forgot)
+	app.Get("/password/reset", authH.ResetForm)
+	app.Post("/password/reset", limiter.New(limiter.Config{
+		Max:        5,
+		Expiration: 10 * time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			applog.Security(c, "rate.reset.hit", nil)
+			return c.Status(fiber.StatusTooManyRequests).Render("reset_password", fiber.Map{"Invalid": true})
+		},
+	}), authH.Reset)
 
 	// Admin
 	ordRepo := repos.NewOrderRepo(db)
 	invRepo := repos.NewInventoryRepo(db)
-	adminH := &handlers.AdminHandler{OrderRepo: ordRepo, Inv: invRepo, Users: userRepo}
+	searchSvc := deps.SearchHandler.Analytics
+	prodRepo := repos.NewProductRepo(db)
+	adminH := &handlers.AdminHandler{
+		OrderRepo: ordRepo, Orders: deps.OrderHandler.Order, Inv: invRepo, Users: userRepo, Search: searchSvc,
+		Prods: prodRepo, Images: services.NewProductImageService(prodRepo, mediaDir),
+		Attrs: services.NewAttributeService(repos.NewAttributeRepo(db), prodRepo),
+		Cats:  repos.NewCategoryRepo(db),
+
+		ConditionPhotos: services.NewConditionPhotoService(prodRepo, mediaDir),
+		Reviews:         services.NewReviewService(repos.NewReviewRepo(db), prodRepo),
+		Questions:       deps.QuestionHandler.Questions,
+		Views:           deps.ProductHandler.Views,
+		Pricing:         deps.ProductHandler.Catalog.Pricing,
+		Promos:          deps.CartHandler.Cart.Promos,
+		Tax:             deps.OrderHandler.Tax,
+		Shipping:        deps.OrderHandler.Shipping,
+		Payments:        deps.OrderHandler.Payments,
+		Refunds:         deps.OrderHandler.Refunds,
+		Returns:         deps.OrderHandler.Returns,
+		Mail:            deps.OrderHandler.Order.Mail,
+		Documents:       deps.OrderHandler.Documents,
+		Shipments:       deps.OrderHandler.Shipments,
+		Pickup:          deps.OrderHandler.Pickup,
+	}
 
 	admin := app.Group("/admin", handlers.RequireAdmin(authSvc))
 	admin.Get("/", adminH.Dashboard)
 	admin.Get("/orders", adminH.OrdersPage)
+	admin.Get("/orders/:id", adminH.OrderPage)
 	admin.Post("/orders/:id/status", adminH.UpdateOrderStatus)
+	admin.Post("/orders/:id/capture", adminH.CapturePayment)
+	admin.Get("/orders/:id/invoice.pdf", adminH.OrderInvoice)
+	admin.Post("/orders/:id/invoice", adminH.IssueInvoice)
+	admin.Get("/orders/:id/packing-slip.pdf", adminH.OrderPackingSlip)
+	admin.Post("/orders/:id/refunds", adminH.RefundOrder)
+	admin.Post("/orders/:id/shipments", adminH.CreateShipment)
+	admin.Post("/orders/:id/pickup/ready", adminH.PickupReady)
+	admin.Post("/orders/:id/pickup/collected", adminH.PickupCollected)
+	admin.Get("/pickups", adminH.PickupsPage)
+	admin.Post("/pickups/hours", adminH.SavePickupHours)
+	admin.Post("/pickups/hours/delete", adminH.DeletePickupHours)
+	admin.Get("/returns", adminH.ReturnsPage)
+	admin.Get("/returns/:id", adminH.ReturnPage)
+	admin.Get("/returns/:id/photos/:name", adminH.ReturnPhoto)
+	admin.Post("/returns/:id/resolve", adminH.ResolveReturn)
+	admin.Post("/returns/:id/:action", adminH.AdvanceReturn)
+	admin.Get("/emails", adminH.EmailsPage)
+	admin.Post("/emails/:id/retry", adminH.RetryEmail)
 	admin.Get("/inventory", adminH.Inventory)
 	admin.Post("/inventory", adminH.UpdateInventory)
 	admin.Get("/users", adminH.UsersPage)
 	admin.Post("/users/:id/delete", adminH.DeleteUser)
+	admin.Get("/search-insights", adminH.SearchInsights)
+	admin.Get("/product-views", adminH.ProductViews)
+	admin.Get("/products", adminH.ProductsPage)
+	admin.Get("/products/:id/images", adminH.ImagesPage)
+	admin.Post("/products/:id/images", adminH.UploadImage)
+	admin.Post("/products/:id/images/edit", adminH.EditImage)
+	admin.Get("/products/:id/condition", adminH.ConditionPage)
+	admin.Post("/products/:id/condition", adminH.SaveCondition)
+	admin.Post("/products/:id/condition/photos", adminH.UploadConditionPhoto)
+	admin.Post("/products/:id/condition/photos/edit", adminH.EditConditionPhoto)
+	admin.Get("/products/:id/pricing", adminH.PricingPage)
+	admin.Post("/products/:id/pricing/base", adminH.SetBasePrice)
+	admin.Post("/products/:id/pricing/sales", adminH.ScheduleSale)
+	admin.Post("/products/:id/pricing/sales/:sid/end", adminH.EndSale)
+	admin.Get("/products/:id/shipping", adminH.ParcelPage)
+	admin.Post("/products/:id/shipping", adminH.SaveParcel)
+	admin.Get("/products/:id/attributes", adminH.AttributesPage)
+	admin.Post("/products/:id/attributes", adminH.SaveAttributes)
+	admin.Post("/products/:id/variants", adminH.AddVariant)
+	admin.Post("/products/:id/variants/:vid/active", adminH.SetVariantActive)
+	admin.Get("/categories/:id/attributes", adminH.CategoryAttributesPage)
+	admin.Post("/categories/:id/attributes", adminH.SaveCategoryAttribute)
+	admin.Post("/categories/:id/attributes/:key/delete", adminH.DeleteCategoryAttribute)
+	admin.Get("/promotions", adminH.PromotionsPage)
+	admin.Post("/promotions", adminH.SavePromotion)
+	admin.Get("/promotions/:id", adminH.PromotionPage)
+	admin.Post("/promotions/:id", adminH.SavePromotion)
+	admin.Post("/promotions/:id/active", adminH.SetPromotionActive)
+	admin.Post("/promotions/:id/delete", adminH.DeletePromotion)
+	admin.Get("/shipping", adminH.ShippingPage)
+	admin.Post("/shipping/methods", adminH.SaveShippingMethod)
+	admin.Post("/shipping/zones", adminH.SaveShippingZone)
+	admin.Post("/shipping/zones/delete", adminH.DeleteShippingZone)
+	admin.Post("/shipping/rates", adminH.SaveShippingRate)
+	admin.Post("/shipping/rates/delete", adminH.DeleteShippingRate)
+	admin.Get("/tax", adminH.TaxPage)
+	admin.Post("/tax", adminH.SaveTaxRate)
+	admin.Post("/tax/:prefix/delete", adminH.DeleteTaxRate)
+	admin.Get("/tax/report", adminH.TaxReport)
+	admin.Get("/reviews", adminH.ReviewsPage)
+	admin.Post("/reviews/:id/moderate", adminH.ModerateReview)
+	admin.Get("/questions", adminH.QuestionsPage)
+	admin.Post("/questions/:id/answer", adminH.AnswerQuestion)
+	admin.Post("/questions/:id/hide", adminH.HideQuestion)
+
+	// Search analytics retention (daily purge)
+	go func() {
+		for {
+			if n, err := searchSvc.Purge(cfg.SearchRetentionDays); err != nil {
+				log.Printf("[search] purge failed: %v", err)
+			} else if n > 0 {
+				log.Printf("[search] purged %d events older than %d days", n, cfg.SearchRetentionDays)
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+
+	// Saved-search new-arrival alerts
+	go func() {
+		for {
+			if n, err := deps.SavedSearchHandler.Saved.RunAlerts(time.Now()); err != nil {
+				log.Printf("[alerts] run failed: %v", err)
+			} else if n > 0 {
+				log.Printf("[alerts] sent %d new-arrival alerts", n)
+			}
+			time.Sleep(time.Duration(cfg.AlertIntervalMinutes) * time.Minute)
+		}
+	}()
+
+	// "Customers also bought" recomputation
+	go func() {
+		for {
+			if n, err := deps.ProductHandler.Recs.Rebuild(time.Now()); err != nil {
+				log.Printf("[recs] rebuild failed: %v", err)
+			} else {
+				log.Printf("[recs] stored %d co-purchase pairs", n)
+			}
+			time.Sleep(time.Duration(cfg.RecsIntervalMinutes) * time.Minute)
+		}
+	}()
+
+	// Ready pickups that were never collected
+	go func() {
+		for {
+			if n, err := deps.OrderHandler.Pickup.ExpireUnclaimed(time.Now()); err != nil {
+				log.Printf("[pickup] expiry run failed: %v", err)
+			} else if n > 0 {
+				log.Printf("[pickup] canceled %d uncollected orders", n)
+			}
+			time.Sleep(time.Hour)
+		}
+	}()
+
+	// Card holds left on canceled orders whose void failed
+	go func() {
+		for {
+			if n, failed, err := deps.OrderHandler.Order.Payments.ReleaseCanceled(); err != nil {
+				log.Printf("[payments] release run failed: %v", err)
+			} else if n > 0 || failed > 0 {
+				log.Printf("[payments] released %d holds, %d still failing", n, failed)
+			}
+			time.Sleep(time.Hour)
+		}
+	}()
+
+	// Transactional email delivery from the outbox
+	go func() {
+		for {
+			if sent, failed, err := deps.OrderHandler.Order.Mail.Deliver(time.Now()); err != nil {
+				log.Printf("[mail] delivery run failed: %v", err)
+			} else if sent > 0 || failed > 0 {
+				log.Printf("[mail] sent %d emails, %d failed for good", sent, failed)
+			}
+			time.Sleep(time.Duration(cfg.MailIntervalSeconds) * time.Second)
+		}
+	}()
 
 	// Health & 404
 	app.Get("/healthz", func(c *fiber.Ctx) error { return c.JSON(fiber.Map{"ok": true}) })
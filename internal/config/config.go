@@ -1,8 +1,12 @@
 package config
 
 import (
+	"crypto/rand"
 	"log"
 	"os"
+	"strconv"
+
+	"retrobytes/internal/mail"
 )
 
 type Config struct {
@@ -10,6 +14,23 @@ type Config struct {
 	DBDSN    string
 	MediaDir string
 	LogFile  string
+
+	SearchRetentionDays  int // search analytics are purged after this many days
+	AlertIntervalMinutes int // how often saved-search alerts are evaluated
+	RecsIntervalMinutes  int // how often "customers also bought" is recomputed
+
+	PaymentProvider  string // card gateway; "fake" approves test tokens in-process
+	ReturnWindowDays int    // how long after shipping customers may request a return
+
+	BaseURL             string      // absolute site URL used for links in emails
+	Mail                mail.Config // outbox delivery transport
+	MailIntervalSeconds int         // how often the outbox is drained
+
+	LinkSecret []byte // signs emailed order links; LINK_SECRET
+
+	PickupSlotMinutes int // length of a pickup slot offered at checkout
+	PickupDaysAhead   int // how many days ahead pickup slots are offered
+	PickupHoldDays    int // ready pickups not collected within this many days are canceled
 }
 
 func Load() Config {
@@ -32,7 +53,93 @@ func Load() Config {
 		logFile = "./retrobytes.log" // default log sink in project root
 	}
 
-	cfg := Config{Port: port, DBDSN: dsn, MediaDir: media, LogFile: logFile}
+	searchRetention := envInt("SEARCH_RETENTION_DAYS", 90)
+	alertInterval := envInt("ALERT_INTERVAL_MINUTES", 15)
+	if alertInterval < 1 {
+		alertInterval = 1
+	}
+	recsInterval := envInt("RECS_INTERVAL_MINUTES", 60)
+	if recsInterval < 1 {
+		recsInterval = 1
+	}
+
+	returnWindow := envInt("RETURN_WINDOW_DAYS", 30)
+
+	payProvider := os.Getenv("PAYMENT_PROVIDER")
+	if payProvider == "" {
+		payProvider = "fake"
+	}
+
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8081"
+	}
+	mailCfg := mail.Config{
+		Transport:    os.Getenv("MAIL_TRANSPORT"), // log, smtp or file
+		From:         os.Getenv("MAIL_FROM"),
+		SMTPAddr:     os.Getenv("SMTP_ADDR"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		Dir:          os.Getenv("MAIL_DIR"),
+	}
+	if mailCfg.Transport == "" {
+		mailCfg.Transport = "log"
+	}
+	if mailCfg.From == "" {
+		mailCfg.From = "RetroBytes <orders@retrobytes.test>"
+	}
+	if mailCfg.Dir == "" {
+		mailCfg.Dir = "./mail-out"
+	}
+	mailInterval := envInt("MAIL_INTERVAL_SECONDS", 30)
+	if mailInterval < 1 {
+		mailInterval = 1
+	}
+
+	// without a configured secret, links signed before a restart stop working
+	linkSecret := []byte(os.Getenv("LINK_SECRET"))
+	if len(linkSecret) == 0 {
+		linkSecret = make([]byte, 32)
+		if _, err := rand.Read(linkSecret); err != nil {
+			log.Fatalf("[config] generate link secret: %v", err)
+		}
+		log.Printf("[config] LINK_SECRET not set; using a random secret, order links expire on restart")
+	}
+
+	pickupSlot := envInt("PICKUP_SLOT_MINUTES", 60)
+	if pickupSlot < 15 {
+		pickupSlot = 15
+	}
+	pickupDays := envInt("PICKUP_DAYS_AHEAD", 7)
+	pickupHold := envInt("PICKUP_HOLD_DAYS", 7)
+
+	cfg := Config{Port: port, DBDSN: dsn, MediaDir: media, LogFile: logFile,
+		SearchRetentionDays: searchRetention, AlertIntervalMinutes: alertInterval, RecsIntervalMinutes: recsInterval,
+		PaymentProvider: payProvider, ReturnWindowDays: returnWindow,
+		BaseURL: baseURL, Mail: mailCfg, MailIntervalSeconds: mailInterval,
+		LinkSecret:        linkSecret,
+		PickupSlotMinutes: pickupSlot, PickupDaysAhead: pickupDays, PickupHoldDays: pickupHold}
 	log.Printf("[config] PORT=%s DB_DSN=%s MEDIA_DIR=%s LOG_FILE=%s", cfg.Port, cfg.DBDSN, cfg.MediaDir, cfg.LogFile)
+	log.Printf("[config] SEARCH_RETENTION_DAYS=%d ALERT_INTERVAL_MINUTES=%d RECS_INTERVAL_MINUTES=%d",
+		cfg.SearchRetentionDays, cfg.AlertIntervalMinutes, cfg.RecsIntervalMinutes)
+	log.Printf("[config] PAYMENT_PROVIDER=%s RETURN_WINDOW_DAYS=%d", cfg.PaymentProvider, cfg.ReturnWindowDays)
+	log.Printf("[config] BASE_URL=%s MAIL_TRANSPORT=%s MAIL_FROM=%q SMTP_ADDR=%s MAIL_DIR=%s MAIL_INTERVAL_SECONDS=%d",
+		cfg.BaseURL, cfg.Mail.Transport, cfg.Mail.From, cfg.Mail.SMTPAddr, cfg.Mail.Dir, cfg.MailIntervalSeconds)
+	log.Printf("[config] PICKUP_SLOT_MINUTES=%d PICKUP_DAYS_AHEAD=%d PICKUP_HOLD_DAYS=%d",
+		cfg.PickupSlotMinutes, cfg.PickupDaysAhead, cfg.PickupHoldDays)
 	return cfg
 }
+
+// envInt reads a non-negative integer env var, falling back to def when unset or invalid.
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Printf("[config] ignoring invalid %s=%q", key, v)
+		return def
+	}
+	return n
+}
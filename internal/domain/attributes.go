@@ -0,0 +1,38 @@
+package domain
+
+import "encoding/json"
+
+// AttributeDef is one entry of a category's attribute schema.
+type AttributeDef struct {
+	CategoryID  string `db:"category_id"`
+	Key         string `db:"key"`
+	Label       string `db:"label"`
+	Type        string `db:"type"` // text | number | bool | enum
+	OptionsJSON string `db:"options_json"`
+	Filterable  bool   `db:"filterable"`
+	SortOrder   int    `db:"sort_order"`
+}
+
+// Options returns the allowed values of an enum attribute.
+func (d AttributeDef) Options() []string {
+	var out []string
+	_ = json.Unmarshal([]byte(d.OptionsJSON), &out)
+	return out
+}
+
+// AttributeValue is a product's value for one schema attribute.
+type AttributeValue struct {
+	AttributeDef
+	Value string
+}
+
+// Display renders the value for shoppers (bools as Yes/No).
+func (v AttributeValue) Display() string {
+	if v.Type == "bool" {
+		if v.Value == "yes" {
+			return "Yes"
+		}
+		return "No"
+	}
+	return v.Value
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Carrier is a parcel service shipments can go out with.
+type Carrier struct {
+	Code  string
+	Label string
+	// TrackURL is a printf pattern for the public tracking page; "" when
+	// the carrier has none.
+	TrackURL string
+}
+
+// Carriers lists the accepted carriers in display order.
+var Carriers = []Carrier{
+	{"USPS", "USPS", "https://tools.usps.com/go/TrackConfirmAction?tLabels=%s"},
+	{"UPS", "UPS", "https://www.ups.com/track?tracknum=%s"},
+	{"FEDEX", "FedEx", "https://www.fedex.com/fedextrack/?trknbr=%s"},
+	{"DHL", "DHL", "https://www.dhl.com/us-en/home/tracking/tracking-parcel.html?tracking-id=%s"},
+	{"OTHER", "Other", ""},
+}
+
+// CarrierOf looks up a carrier by code.
+func CarrierOf(code string) (Carrier, bool) {
+	for _, c := range Carriers {
+		if c.Code == code {
+			return c, true
+		}
+	}
+	return Carrier{}, false
+}
+
+// CarrierLabel renders a carrier code for shoppers.
+func CarrierLabel(code string) string {
+	if c, ok := CarrierOf(code); ok {
+		return c.Label
+	}
+	return code
+}
+
+// TrackingURL links a tracking number to the carrier's tracking page, or
+// returns "" when there is none.
+func TrackingURL(carrier, trackingNo string) string {
+	c, ok := CarrierOf(carrier)
+	if !ok || c.TrackURL == "" || trackingNo == "" {
+		return ""
+	}
+	return fmt.Sprintf(c.TrackURL, url.QueryEscape(trackingNo))
+}
@@ -0,0 +1,75 @@
+package domain
+
+// Grade is one step of the condition scale, best first.
+type Grade struct {
+	Code        string
+	Label       string
+	Description string
+	Rank        int // higher is better
+}
+
+// Grades is the condition scale used for products.condition.
+var Grades = []Grade{
+	{"NEW_SEALED", "New / Sealed", "Factory sealed or unused, in original packaging.", 6},
+	{"MINT", "Mint", "Opened but indistinguishable from new; no visible wear.", 5},
+	{"EXCELLENT", "Excellent", "Light signs of use, fully working, cosmetically very clean.", 4},
+	{"GOOD", "Good", "Normal wear such as scuffs or yellowing; fully working.", 3},
+	{"FAIR", "Fair", "Heavy wear or minor faults; working, see condition notes.", 2},
+	{"FOR_PARTS", "For Parts", "Not working or incomplete; sold as-is for parts or repair.", 1},
+}
+
+// LegacyGrade maps the old FIRST_HAND/SECOND_HAND values onto the scale.
+var LegacyGrade = map[string]string{
+	"FIRST_HAND":  "NEW_SEALED",
+	"SECOND_HAND": "GOOD",
+}
+
+// GradeOf looks up a grade by code.
+func GradeOf(code string) (Grade, bool) {
+	for _, g := range Grades {
+		if g.Code == code {
+			return g, true
+		}
+	}
+	return Grade{}, false
+}
+
+// ConditionLabel renders a condition code for shoppers.
+func ConditionLabel(code string) string {
+	if g, ok := GradeOf(code); ok {
+		return g.Label
+	}
+	return code
+}
+
+// GradesAtLeast returns the codes ranked at or above min, best first.
+func GradesAtLeast(min string) []string {
+	m, ok := GradeOf(min)
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, g := range Grades {
+		if g.Rank >= m.Rank {
+			out = append(out, g.Code)
+		}
+	}
+	return out
+}
+
+// MeetsGrade reports whether code is at least as good as min ("" = any).
+func MeetsGrade(code, min string) bool {
+	if min == "" {
+		return true
+	}
+	g, ok1 := GradeOf(code)
+	m, ok2 := GradeOf(min)
+	return ok1 && ok2 && g.Rank >= m.Rank
+}
+
+// ConditionReport is the detail shown under a listing's condition.
+type ConditionReport struct {
+	Grade  Grade
+	Notes  string
+	Photos []ProductImage
+}
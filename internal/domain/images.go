@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ProductImage is one entry of products.images_json. Path is relative to
+// the media directory (served under /media/). Order in the list is display
+// order; the first image is the listing thumbnail.
+type ProductImage struct {
+	Path string `json:"path"`
+	Alt  string `json:"alt,omitempty"`
+}
+
+// URL is the public URL of the image.
+func (i ProductImage) URL() string { return "/media/" + strings.TrimPrefix(i.Path, "/") }
+
+// ParseImages decodes images_json. It accepts both the current object form
+// ([{"path":..,"alt":..}]) and the legacy list of bare paths; malformed
+// input yields an empty list.
+func ParseImages(raw string) []ProductImage {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	var objs []ProductImage
+	if err := json.Unmarshal([]byte(raw), &objs); err == nil {
+		return keepValidImages(objs)
+	}
+	var paths []string
+	if err := json.Unmarshal([]byte(raw), &paths); err != nil {
+		return nil
+	}
+	objs = make([]ProductImage, 0, len(paths))
+	for _, p := range paths {
+		objs = append(objs, ProductImage{Path: p})
+	}
+	return keepValidImages(objs)
+}
+
+// EncodeImages is the inverse of ParseImages (always the object form).
+func EncodeImages(imgs []ProductImage) string {
+	if len(imgs) == 0 {
+		return "[]"
+	}
+	b, _ := json.Marshal(imgs)
+	return string(b)
+}
+
+func keepValidImages(in []ProductImage) []ProductImage {
+	out := in[:0]
+	for _, im := range in {
+		im.Path = strings.TrimSpace(im.Path)
+		if im.Path == "" || strings.Contains(im.Path, "..") {
+			continue
+		}
+		out = append(out, im)
+	}
+	return out
+}
+
+// Images returns the product's gallery; alt text defaults to the title.
+func (p Product) Images() []ProductImage {
+	imgs := ParseImages(p.ImagesJSON)
+	for i := range imgs {
+		if imgs[i].Alt == "" {
+			imgs[i].Alt = p.Title
+		}
+	}
+	return imgs
+}
+
+// ThumbURL is the first gallery image, or "" when the product has none.
+func (p Product) ThumbURL() string { return ThumbURL(p.ImagesJSON) }
+
+// ThumbURL returns the URL of the first image in an images_json value.
+func ThumbURL(imagesJSON string) string {
+	if imgs := ParseImages(imagesJSON); len(imgs) > 0 {
+		return imgs[0].URL()
+	}
+	return ""
+}
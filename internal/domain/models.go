@@ -1,5 +1,7 @@
 package domain
 
+import "strings"
+
 type Category struct {
 	ID        string `db:"id"`
 	Name      string `db:"name"`
@@ -12,14 +14,42 @@ type Product struct {
 	CategoryID  string  `db:"category_id"`
 	Title       string  `db:"title"`
 	Description string  `db:"description"`
-	Condition   string  `db:"condition"` // FIRST_HAND | SECOND_HAND
+	Condition   string  `db:"condition"` // a Grades code, e.g. EXCELLENT
 	Price       float64 `db:"price"`
 	ImagesJSON  string  `db:"images_json"`
 	Active      bool    `db:"active"`
 	CreatedAt   string  `db:"created_at"`
 	UpdatedAt   string  `db:"updated_at"`
+
+	ParentID     string `db:"parent_id"`     // set on variants; "" for listings
+	VariantLabel string `db:"variant_label"` // e.g. "Atomic Purple"
+
+	Rating Rating `db:"-"` // approved reviews; filled for listings
+
+	// Set while a scheduled sale is running: Price is then the sale price,
+	// ListPrice the regular price and SaleEnds the UTC end of the sale.
+	ListPrice float64 `db:"-"`
+	SaleEnds  string  `db:"-"`
+}
+
+// OnSale reports whether a sale price is currently applied.
+func (p Product) OnSale() bool { return p.ListPrice > p.Price }
+
+// Rating summarizes a listing's approved reviews.
+type Rating struct {
+	Average float64 `db:"average"`
+	Count   int     `db:"count"`
 }
 
+// Stars renders the average as five filled/empty stars, e.g. "★★★★☆".
+func (r Rating) Stars() string {
+	n := int(r.Average + 0.5)
+	return strings.Repeat("★", n) + strings.Repeat("☆", 5-n)
+}
+
+// ConditionLabel renders the product's grade for shoppers.
+func (p Product) ConditionLabel() string { return ConditionLabel(p.Condition) }
+
 type Availability struct {
 	Status string `json:"status"` // IN_STOCK | LOW_STOCK | OUT_OF_STOCK
 	Qty    int    `json:"qty,omitempty"`
@@ -0,0 +1,160 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"retrobytes/internal/http/handlers"
+)
+
+// Only the session (or account) that placed an order may download its invoice
+// or cancel it, and canceling only works while it is PLACED.
+func TestOrderCancelAndInvoiceOwnership(t *testing.T) {
+	app, db, ordRepo, _ := newOrderTotalsApp(t)
+
+	sid := "sid-cancel"
+	_, _ = db.Exec(`INSERT INTO carts(id,session_id,updated_at) VALUES(?,?,CURRENT_TIMESTAMP)`, sid, sid)
+	_, _ = db.Exec(`INSERT INTO cart_items(cart_id, product_id, qty, price_at_add, created_at) VALUES(?,?,?,?,CURRENT_TIMESTAMP)`,
+		sid, "gbc-001", 1, 129.99)
+
+	loginResp, _ := app.Test(httptest.NewRequest("GET", "/login", nil))
+	csrfTok := extractCookieTotals(loginResp, "csrf_")
+	post := func(path, as, form string) *http.Response {
+		req := httptest.NewRequest("POST", path, strings.NewReader("csrf="+csrfTok+form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "csrf_", Value: csrfTok})
+		req.AddCookie(&http.Cookie{Name: "sid", Value: as})
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := post("/orders", sid, "&region=20742&email=alice@retrobytes.test&name=Alice&fulfillment=pickup&payment_token=tok_visa"+pickupSlotForm(t, db))
+	loc := resp.Header.Get("Location")
+	if resp.StatusCode != http.StatusFound || !strings.HasPrefix(loc, "/order/") {
+		t.Fatalf("place: %d %q", resp.StatusCode, loc)
+	}
+	oid := strings.TrimPrefix(loc, "/order/")
+
+	if resp := post("/order/"+oid+"/cancel", "sid-stranger", ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("stranger cancel: %d", resp.StatusCode)
+	}
+	if o, _, _ := ordRepo.Get(oid); o.Status != "PLACED" {
+		t.Fatalf("stranger canceled the order: %s", o.Status)
+	}
+
+	// the invoice follows the same ownership rule
+	get := func(path, as string) *http.Response {
+		req := httptest.NewRequest("GET", path, nil)
+		req.AddCookie(&http.Cookie{Name: "sid", Value: as})
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	if resp := get("/order/"+oid+"/invoice.pdf", "sid-stranger"); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("stranger invoice: %d", resp.StatusCode)
+	}
+	// nor does the owner's download: the number is issued when the order
+	// ships, is collected, or by an admin
+	if resp := get("/order/"+oid+"/invoice.pdf", sid); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("owner invoice before issue: %d", resp.StatusCode)
+	}
+	if no, _, _ := ordRepo.InvoiceNo(oid); no != 0 {
+		t.Fatal("a download issued an invoice number")
+	}
+	if _, _, err := ordRepo.AssignInvoiceNo(oid); err != nil {
+		t.Fatal(err)
+	}
+	if resp := get("/order/"+oid+"/invoice.pdf", sid); resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/pdf" {
+		t.Fatalf("owner invoice: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// so does the pickup QR code, which disappears with the order
+	if resp := get("/order/"+oid+"/pickup-qr.svg", "sid-stranger"); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("stranger pickup QR: %d", resp.StatusCode)
+	}
+	if resp := get("/order/"+oid+"/pickup-qr.svg", sid); resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/svg+xml" {
+		t.Fatalf("owner pickup QR: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	resp = post("/order/"+oid+"/cancel", sid, "")
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/order/"+oid+"?cancel=done" {
+		t.Fatalf("owner cancel: %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if o, _, _ := ordRepo.Get(oid); o.Status != "CANCELED" {
+		t.Fatalf("status = %s", o.Status)
+	}
+	if resp := get("/order/"+oid+"/pickup-qr.svg", sid); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("pickup QR of a canceled order: %d", resp.StatusCode)
+	}
+	// canceling again only finishes what is left (the card hold)
+	if resp := post("/order/"+oid+"/cancel", sid, ""); resp.Header.Get("Location") != "/order/"+oid+"?cancel=done" {
+		t.Fatalf("second cancel: %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	hist, _ := ordRepo.History(oid)
+	if len(hist) != 1 || hist[0].Actor != "CUSTOMER" {
+		t.Fatalf("history = %+v", hist)
+	}
+}
+
+// Staff can only move an order between PLACED and RESERVED by hand; a
+// canceled order cannot be brought back with its stock already returned.
+func TestAdminStatusChangeIsGuarded(t *testing.T) {
+	app, db, ordRepo, userRepo := newOrderTotalsApp(t)
+	adminH := &handlers.AdminHandler{OrderRepo: ordRepo}
+	app.Post("/admin/orders/:id/status", adminH.UpdateOrderStatus)
+	_ = userRepo.BindSession("sid-admin", "u-admin")
+
+	loginResp, _ := app.Test(httptest.NewRequest("GET", "/login", nil))
+	csrfTok := extractCookieTotals(loginResp, "csrf_")
+	post := func(path, as, form string) *http.Response {
+		req := httptest.NewRequest("POST", path, strings.NewReader("csrf="+csrfTok+form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "csrf_", Value: csrfTok})
+		req.AddCookie(&http.Cookie{Name: "sid", Value: as})
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	place := func(sid string) string {
+		t.Helper()
+		_, _ = db.Exec(`INSERT INTO carts(id,session_id,updated_at) VALUES(?,?,CURRENT_TIMESTAMP)`, sid, sid)
+		_, _ = db.Exec(`INSERT INTO cart_items(cart_id, product_id, qty, price_at_add, created_at) VALUES(?,?,?,?,CURRENT_TIMESTAMP)`,
+			sid, "gbc-001", 1, 129.99)
+		resp := post("/orders", sid, "&region=20742&email=alice@retrobytes.test&name=Alice&fulfillment=pickup&payment_token=tok_visa"+pickupSlotForm(t, db))
+		return strings.TrimPrefix(resp.Header.Get("Location"), "/order/")
+	}
+
+	oid := place("sid-status")
+	if resp := post("/admin/orders/"+oid+"/status", "sid-admin", "&status=RESERVED"); resp.StatusCode != http.StatusFound {
+		t.Fatalf("reserve: %d", resp.StatusCode)
+	}
+	hist, _ := ordRepo.History(oid)
+	if o, _, _ := ordRepo.Get(oid); o.Status != "RESERVED" || len(hist) != 1 || hist[0].Status != "RESERVED" || hist[0].Actor != "ADMIN" || hist[0].UserID != "u-admin" {
+		t.Fatalf("after reserve: %s, history %+v", o.Status, hist)
+	}
+	if resp := post("/admin/orders/"+oid+"/status", "sid-admin", "&status=BOGUS"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unknown status: %d", resp.StatusCode)
+	}
+
+	canceled := place("sid-status-2")
+	if resp := post("/order/"+canceled+"/cancel", "sid-status-2", ""); resp.StatusCode != http.StatusFound {
+		t.Fatalf("cancel: %d", resp.StatusCode)
+	}
+	for _, status := range []string{"PLACED", "RESERVED"} {
+		if resp := post("/admin/orders/"+canceled+"/status", "sid-admin", "&status="+status); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("canceled -> %s: %d", status, resp.StatusCode)
+		}
+	}
+	if o, _, _ := ordRepo.Get(canceled); o.Status != "CANCELED" {
+		t.Fatalf("canceled order moved to %s", o.Status)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/csrf"
@@ -23,7 +25,8 @@ import (
 // Helper: minimal app for order placement with recompute check
 func newOrderTotalsApp(t *testing.T) (*fiber.App, *sqlx.DB, *repos.OrderRepo, *repos.UserRepo) {
 	t.Helper()
-	cfg := config.Config{DBDSN: ":memory:", MediaDir: "../../web/media"}
+	cfg := config.Config{DBDSN: ":memory:", MediaDir: "../../web/media", LinkSecret: []byte("test-secret"),
+		PickupSlotMinutes: 60, PickupDaysAhead: 7, PickupHoldDays: 7}
 	db, err := repos.OpenDB(cfg.DBDSN)
 	if err != nil {
 		t.Fatalf("open db: %v", err)
@@ -49,12 +52,29 @@ func newOrderTotalsApp(t *testing.T) (*fiber.App, *sqlx.DB, *repos.OrderRepo, *r
 
 	deps := handlers.NewDeps(db, cfg, authSvc)
 	app.Post("/cart", deps.CartHandler.Add)
+	app.Post("/checkout/prices", deps.OrderHandler.ConfirmPrices)
 	app.Post("/orders", deps.OrderHandler.Place)
+	app.Get("/order/:id", deps.OrderHandler.View)
+	app.Post("/order/:id/cancel", deps.OrderHandler.Cancel)
+	app.Get("/order/:id/invoice.pdf", deps.OrderHandler.Invoice)
+	app.Get("/order/:id/pickup-qr.svg", deps.OrderHandler.PickupQR)
+	app.Post("/orders/lookup", deps.OrderHandler.LookupSubmit)
 	app.Get("/login", authH.LoginForm)
 
 	return app, db, repos.NewOrderRepo(db), userRepo
 }
 
+// pickupSlotForm is the pickup_slot form field for the first slot the
+// seeded 20742 store offers.
+func pickupSlotForm(t *testing.T, db *sqlx.DB) string {
+	t.Helper()
+	slots, err := services.NewPickupService(repos.NewPickupRepo(db), nil, nil, nil, 60, 7, 7).Slots("20742", time.Now())
+	if err != nil || len(slots) == 0 {
+		t.Fatalf("pickup slots: %v, %d", err, len(slots))
+	}
+	return "&pickup_slot=" + url.QueryEscape(slots[0].Key())
+}
+
 func extractCookieTotals(resp *http.Response, name string) string {
 	for _, c := range resp.Cookies() {
 		if c.Name == name {
@@ -81,15 +101,42 @@ func TestOrderTotalsRecomputed(t *testing.T) {
 		t.Fatal("csrf token missing")
 	}
 
-	formOrder := strings.NewReader("csrf=" + csrfTok + "&region=20742&email=alice@retrobytes.test&name=Alice&fulfillment=delivery")
-	reqOrder := httptest.NewRequest("POST", "/orders", formOrder)
-	reqOrder.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	reqOrder.AddCookie(&http.Cookie{Name: "csrf_", Value: csrfTok})
-	reqOrder.AddCookie(&http.Cookie{Name: "sid", Value: sid})
-	respOrder, err := app.Test(reqOrder)
+	post := func(path, form string) *http.Response {
+		req := httptest.NewRequest("POST", path, strings.NewReader("csrf="+csrfTok+form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "csrf_", Value: csrfTok})
+		req.AddCookie(&http.Cookie{Name: "sid", Value: sid})
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	const orderForm = "&region=20742&email=alice@retrobytes.test&name=Alice&fulfillment=delivery" +
+		"&ship_name=Alice&ship_line1=1+Campus+Dr&ship_city=College+Park&ship_state=MD&ship_zip=20742&bill_same=1&payment_token=tok_visa"
+
+	// The stale cart price is not charged silently: checkout asks to re-confirm
+	respOrder := post("/orders", orderForm)
+	if loc := respOrder.Header.Get("Location"); respOrder.StatusCode != http.StatusFound || loc != "/checkout?prices=changed" {
+		t.Fatalf("expected price-change redirect, got %d %q", respOrder.StatusCode, loc)
+	}
+	var placed int
+	if err := db.Get(&placed, `SELECT COUNT(*) FROM orders WHERE session_id = ?`, sid); err != nil || placed != 0 {
+		t.Fatalf("order placed before prices were confirmed (n=%d, err=%v)", placed, err)
+	}
+	// accepting prices other than the ones shown is refused
+	if resp := post("/checkout/prices", "&prices=stale"); resp.Header.Get("Location") != "/checkout?prices=moved" {
+		t.Fatalf("confirm stale prices: got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	cv, err := services.NewCartService(repos.NewCartRepo(db), repos.NewProductRepo(db)).View(sid)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if resp := post("/checkout/prices", "&prices="+cv.PricesDigest()); resp.Header.Get("Location") != "/checkout?prices=confirmed" {
+		t.Fatalf("confirm prices: got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	respOrder = post("/orders", orderForm)
 	if respOrder.StatusCode != http.StatusFound {
 		body, _ := io.ReadAll(respOrder.Body)
 		t.Fatalf("expected redirect on order, got %d body=%s", respOrder.StatusCode, body)
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"retrobytes/internal/domain"
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+	"retrobytes/internal/validate"
+)
+
+type AddressHandler struct {
+	Addresses *services.AddressService
+}
+
+// GET /account/addresses
+func (h *AddressHandler) List(c *fiber.Ctx) error {
+	u, _ := c.Locals("user").(*domain.User)
+	if u == nil {
+		return c.Redirect("/login")
+	}
+	list, err := h.Addresses.List(u.ID)
+	if err != nil {
+		applog.Error(c, "address.list.fail", err, nil)
+		return c.Status(fiber.StatusInternalServerError).Render("notfound", fiber.Map{"Message": "Could not load addresses"})
+	}
+	return render(c, "addresses", fiber.Map{
+		"Addresses": list, "Max": services.MaxAddresses,
+		"Fields": addressFields{States: validate.States()},
+	})
+}
+
+// POST /account/addresses
+func (h *AddressHandler) Add(c *fiber.Ctx) error {
+	u, _ := c.Locals("user").(*domain.User)
+	if u == nil {
+		return c.Redirect("/login")
+	}
+	a, msg := addressForm(c, "")
+	if msg != "" {
+		applog.Security(c, "validation.fail", map[string]any{"field": "address"})
+		return c.Status(fiber.StatusBadRequest).SendString(msg)
+	}
+	id, err := h.Addresses.Add(u.ID, a)
+	if err != nil {
+		if errors.Is(err, services.ErrTooManyAddresses) {
+			return c.Status(fiber.StatusBadRequest).SendString("You can keep up to 10 addresses. Remove one to add another.")
+		}
+		applog.Error(c, "address.add.fail", err, nil)
+		return c.Status(fiber.StatusInternalServerError).SendString("Could not save address")
+	}
+	if c.FormValue("default") == "1" {
+		_ = h.Addresses.SetDefault(u.ID, id)
+	}
+	applog.Audit(c, "address.add", map[string]any{"id": id})
+	return c.Redirect("/account/addresses")
+}
+
+// POST /account/addresses/:id/delete
+func (h *AddressHandler) Delete(c *fiber.Ctx) error {
+	u, _ := c.Locals("user").(*domain.User)
+	if u == nil {
+		return c.Redirect("/login")
+	}
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid address")
+	}
+	if err := h.Addresses.Delete(u.ID, id); err != nil {
+		applog.Error(c, "address.delete.fail", err, map[string]any{"id": id})
+		return c.Status(fiber.StatusInternalServerError).SendString("Could not delete address")
+	}
+	applog.Audit(c, "address.delete", map[string]any{"id": id})
+	return c.Redirect("/account/addresses")
+}
+
+// POST /account/addresses/:id/default
+func (h *AddressHandler) SetDefault(c *fiber.Ctx) error {
+	u, _ := c.Locals("user").(*domain.User)
+	if u == nil {
+		return c.Redirect("/login")
+	}
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid address")
+	}
+	if err := h.Addresses.SetDefault(u.ID, id); err != nil {
+		applog.Error(c, "address.default.fail", err, map[string]any{"id": id})
+		return c.Status(fiber.StatusInternalServerError).SendString("Could not update address")
+	}
+	return c.Redirect("/account/addresses")
+}
+
+// addressForm reads an address from fields named prefix+"name", "line1",
+// "line2", "city", "state", "zip" and "phone"; msg is non-empty on bad input.
+func addressForm(c *fiber.Ctx, prefix string) (a repos.Address, msg string) {
+	var ok bool
+	if a.Name, ok = validate.AddressLine(c.FormValue(prefix+"name"), true); !ok {
+		return a, "recipient name is required (up to 80 characters)"
+	}
+	if a.Line1, ok = validate.AddressLine(c.FormValue(prefix+"line1"), true); !ok {
+		return a, "street address is required (up to 80 characters)"
+	}
+	if a.Line2, ok = validate.AddressLine(c.FormValue(prefix+"line2"), false); !ok {
+		return a, "address line 2 is too long"
+	}
+	if a.City, ok = validate.AddressLine(c.FormValue(prefix+"city"), true); !ok {
+		return a, "city is required"
+	}
+	if a.State, ok = validate.State(c.FormValue(prefix + "state")); !ok {
+		return a, "choose a US state"
+	}
+	if a.ZIP, ok = validate.Region(c.FormValue(prefix + "zip")); !ok {
+		return a, "ZIP must be 5 digits"
+	}
+	if !validate.ZIPInState(a.ZIP, a.State) {
+		return a, "ZIP " + a.ZIP + " is not in " + a.State
+	}
+	if a.Phone, ok = validate.Phone(c.FormValue(prefix + "phone")); !ok {
+		return a, "invalid phone number"
+	}
+	return a, ""
+}
+
+// addressFields feeds the "address_fields" template partial.
+type addressFields struct {
+	Prefix  string
+	Address repos.Address
+	States  []string
+	// FixedZIP shows the ZIP as text (it was chosen for the shipping quote)
+	FixedZIP bool
+	// Optional drops the required markers (billing when it may be skipped)
+	Optional bool
+}
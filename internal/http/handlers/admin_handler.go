@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"strconv"
+	"strings"
+	"time"
 
+	"retrobytes/internal/domain"
 	applog "retrobytes/internal/log"
 	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
 	"retrobytes/internal/validate"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,8 +18,31 @@ import (
 
 type AdminHandler struct {
 	OrderRepo *repos.OrderRepo
-	Inv       *repos.InventoryRepo
-	Users     *repos.UserRepo
+	// Orders cancels orders the way the customer's cancel button does
+	Orders *services.OrderService
+	Inv    *repos.InventoryRepo
+	Users  *repos.UserRepo
+	Search *services.SearchAnalyticsService
+	Prods  *repos.ProductRepo
+	Images *services.ProductImageService
+	Attrs  *services.AttributeService
+	// ConditionPhotos manages close-up photos shown with a listing's grade
+	ConditionPhotos *services.ProductImageService
+	Cats            *repos.CategoryRepo
+	Reviews         *services.ReviewService
+	Questions       *services.QuestionService
+	Views           *services.ViewService
+	Pricing         *services.PricingService
+	Promos          *services.PromotionService
+	Tax             *services.TaxService
+	Shipping        *services.ShippingService
+	Payments        *services.PaymentService
+	Refunds         *services.RefundService
+	Returns         *services.ReturnService
+	Mail            *services.MailService
+	Documents       *services.DocumentService
+	Shipments       *services.ShipmentService
+	Pickup          *services.PickupService
 }
 
 // GET /admin
@@ -31,6 +60,10 @@ func (h *AdminHandler) OrdersPage(c *fiber.Ctx) error {
 	return render(c, "admin_orders", fiber.Map{"Orders": ords})
 }
 
+// adminStatuses are the statuses staff may set by hand, and only from one
+// another; everything else has its own action.
+var adminStatuses = map[string]bool{"PLACED": true, "RESERVED": true}
+
 // POST /admin/orders/:id/status
 func (h *AdminHandler) UpdateOrderStatus(c *fiber.Ctx) error {
 	id := c.Params("id")
@@ -38,14 +71,221 @@ func (h *AdminHandler) UpdateOrderStatus(c *fiber.Ctx) error {
 	if id == "" || status == "" {
 		return c.Status(400).SendString("missing id or status")
 	}
-	if err := h.OrderRepo.UpdateStatus(id, status); err != nil {
-		applog.Error(c, "admin.orders.update.fail", err, map[string]any{"order_id": id})
-		return c.Status(400).SendString("could not update status")
+	// these need the pickup code and ID check or a shipment with its
+	// tracking number, and send their own notices
+	switch status {
+	case "READY_FOR_PICKUP", "COLLECTED":
+		return c.Status(400).SendString("use the pickup actions on the order page")
+	case "SHIPPED", "PARTIALLY_SHIPPED":
+		return c.Status(400).SendString("record a shipment on the order page")
+	case "CANCELED":
+		return h.cancelOrder(c, id)
+	}
+	if !adminStatuses[status] {
+		return c.Status(400).SendString("unknown status")
+	}
+	o, _, err := h.OrderRepo.Get(id)
+	if err != nil {
+		return c.Status(404).SendString("order not found")
+	}
+	if !adminStatuses[o.Status] {
+		return c.Status(400).SendString("a " + o.Status + " order cannot be set back to " + status)
+	}
+	if o.Status != status {
+		// the customer's notice is queued with the status change itself
+		var mails []repos.OutboxEmail
+		if h.Mail != nil && o.Email != "" {
+			m, err := h.Mail.StatusEmail(o, status)
+			if err != nil {
+				applog.Error(c, "admin.orders.mail.fail", err, map[string]any{"order_id": id})
+				return c.Status(500).SendString("could not prepare status email")
+			}
+			mails = append(mails, m)
+		}
+		moved, err := h.OrderRepo.TransitionStatus(id, o.Status, status, repos.StatusChange{Actor: "ADMIN", UserID: adminUserID(c)}, mails...)
+		if err != nil {
+			applog.Error(c, "admin.orders.update.fail", err, map[string]any{"order_id": id})
+			return c.Status(500).SendString("could not update status")
+		}
+		if !moved {
+			return c.Status(400).SendString("the order changed in the meantime; reload and try again")
+		}
+		applog.Audit(c, "admin.orders.update", map[string]any{"order_id": id, "from": o.Status, "status": status})
+	}
+	if c.FormValue("back") == "order" {
+		return c.Redirect("/admin/orders/" + id)
 	}
-	applog.Audit(c, "admin.orders.update", map[string]any{"order_id": id, "status": status})
 	return c.Redirect("/admin/orders")
 }
 
+// cancelOrder cancels through the order service, so the units are
+// restocked and the hold released as on a customer cancel.
+func (h *AdminHandler) cancelOrder(c *fiber.Ctx, id string) error {
+	if h.Orders == nil {
+		return c.Status(400).SendString("canceling is not configured")
+	}
+	o, err := h.Orders.Cancel(id, repos.StatusChange{Actor: "ADMIN", UserID: adminUserID(c)})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(404).SendString("order not found")
+	case errors.Is(err, services.ErrNotCancelable):
+		return c.Status(400).SendString("this order can no longer be canceled; refund it instead")
+	case err != nil && o.Status == "CANCELED":
+		applog.Error(c, "admin.orders.payment.void.fail", err, map[string]any{"order_id": id})
+		return c.Status(500).SendString("order canceled, but the card hold could not be released; cancel again to retry")
+	case err != nil:
+		applog.Error(c, "admin.orders.cancel.fail", err, map[string]any{"order_id": id})
+		return c.Status(500).SendString("could not cancel the order")
+	}
+	applog.Audit(c, "admin.orders.cancel", map[string]any{"order_id": id, "admin_id": adminUserID(c)})
+	if c.FormValue("back") == "order" {
+		return c.Redirect("/admin/orders/" + id)
+	}
+	return c.Redirect("/admin/orders")
+}
+
+// GET /admin/orders/:id
+func (h *AdminHandler) OrderPage(c *fiber.Ctx) error {
+	id := c.Params("id")
+	o, items, err := h.OrderRepo.Get(id)
+	if err != nil {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Order not found"})
+	}
+	adj, err := h.OrderRepo.Adjustments(id)
+	if err != nil {
+		applog.Error(c, "admin.orders.adjustments.fail", err, map[string]any{"order_id": id})
+	}
+	ship, bill, err := h.OrderRepo.Addresses(id)
+	if err != nil {
+		applog.Error(c, "admin.orders.addresses.fail", err, map[string]any{"order_id": id})
+	}
+	data := fiber.Map{"Order": o, "Items": items, "Adjustments": adj, "ShipTo": ship, "BillTo": bill}
+	if no, at, err := h.OrderRepo.InvoiceNo(id); err != nil {
+		applog.Error(c, "admin.orders.invoice.fail", err, map[string]any{"order_id": id})
+	} else if no > 0 {
+		data["Invoice"], data["InvoicedAt"] = services.InvoiceNumber(no), at
+	}
+	if hist, err := h.OrderRepo.History(id); err != nil {
+		applog.Error(c, "admin.orders.history.fail", err, map[string]any{"order_id": id})
+	} else {
+		data["History"] = hist
+	}
+	if h.Payments != nil {
+		p, ok, err := h.Payments.ForOrder(id)
+		if err != nil {
+			applog.Error(c, "admin.orders.payment.fail", err, map[string]any{"order_id": id})
+		} else if ok {
+			data["Payment"] = p
+		}
+	}
+	if h.Refunds != nil {
+		sum, err := h.Refunds.Summary(id)
+		if err != nil {
+			applog.Error(c, "admin.orders.refunds.fail", err, map[string]any{"order_id": id})
+		} else {
+			data["Refund"], data["Reasons"] = sum, services.RefundReasons
+		}
+	}
+	if h.Returns != nil {
+		if rets, err := h.Returns.ForOrder(id); err != nil {
+			applog.Error(c, "admin.orders.returns.fail", err, map[string]any{"order_id": id})
+		} else {
+			data["Returns"] = rets
+		}
+	}
+	if h.Shipments != nil {
+		shipments, err := h.Shipments.ForOrder(id)
+		if err != nil {
+			applog.Error(c, "admin.orders.shipments.fail", err, map[string]any{"order_id": id})
+		}
+		data["Shipments"] = shipments
+		if services.Shippable(o) {
+			if lines, err := h.Shipments.Lines(id); err != nil {
+				applog.Error(c, "admin.orders.shipments.fail", err, map[string]any{"order_id": id})
+			} else {
+				data["ShipLines"], data["Carriers"], data["Today"] = lines, domain.Carriers, time.Now().Format("2006-01-02")
+			}
+		}
+	}
+	if h.Pickup != nil && o.Fulfillment == "pickup" {
+		if at, ok := h.Pickup.Deadline(o); ok {
+			data["PickupDeadline"] = at.Local().Format("2006-01-02 15:04")
+		}
+	}
+	return render(c, "admin_order", data)
+}
+
+// POST /admin/orders/:id/refunds refunds the quantities entered per line
+// (qty_<product id>), optionally shipping, or everything left with full=1.
+func (h *AdminHandler) RefundOrder(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if h.Refunds == nil {
+		return c.Status(404).SendString("refunds are not configured")
+	}
+	sum, err := h.Refunds.Summary(id)
+	if err != nil {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Order not found"})
+	}
+	req := services.RefundRequest{
+		Qty:      map[string]int{},
+		Full:     c.FormValue("full") == "1",
+		Shipping: c.FormValue("shipping") == "1",
+		Restock:  c.FormValue("restock") == "1",
+		Reason:   c.FormValue("reason"),
+	}
+	var ok bool
+	if req.Note, ok = validate.RefundNote(c.FormValue("note")); !ok {
+		return c.Status(400).SendString("note must be at most 500 characters")
+	}
+	for _, l := range sum.Lines {
+		v := strings.TrimSpace(c.FormValue("qty_" + l.ProductID))
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return c.Status(400).SendString("invalid quantity for " + l.Title)
+		}
+		if n > 0 {
+			req.Qty[l.ProductID] = n
+		}
+	}
+
+	f, err := h.Refunds.Refund(id, adminUserID(c), req)
+	if errors.Is(err, services.ErrRefund) || errors.Is(err, services.ErrPaymentState) {
+		return c.Status(400).SendString(err.Error())
+	}
+	if err != nil {
+		applog.Error(c, "admin.orders.refund.fail", err, map[string]any{"order_id": id})
+		return c.Status(500).SendString("could not refund the order")
+	}
+	lines := map[string]int{}
+	for _, it := range f.Items {
+		lines[it.ProductID] = it.Qty
+	}
+	applog.Audit(c, "admin.orders.refund", map[string]any{
+		"order_id": id, "refund_id": f.ID, "amount": f.Amount, "shipping": f.Shipping,
+		"reason": f.Reason, "lines": lines, "restock": f.Restocked, "admin_id": adminUserID(c),
+	})
+	return c.Redirect("/admin/orders/" + id)
+}
+
+// POST /admin/orders/:id/capture settles the card without changing the
+// order status, e.g. when a pickup order is collected.
+func (h *AdminHandler) CapturePayment(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if h.Payments == nil {
+		return c.Status(404).SendString("payments are not configured")
+	}
+	p, err := h.Payments.Capture(id)
+	if err != nil {
+		applog.Error(c, "admin.orders.payment.capture.fail", err, map[string]any{"order_id": id})
+		return c.Status(400).SendString("could not capture payment")
+	}
+	applog.Audit(c, "admin.orders.payment.capture", map[string]any{"order_id": id, "amount": p.Amount, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/orders/" + id)
+}
+
 // GET /admin/inventory
 func (h *AdminHandler) Inventory(c *fiber.Ctx) error {
 	rows, err := h.Inv.ListAll()
@@ -104,3 +344,27 @@ func (h *AdminHandler) DeleteUser(c *fiber.Ctx) error {
 	applog.Audit(c, "admin.users.delete", map[string]any{"user_id": id})
 	return c.Redirect("/admin/users")
 }
+
+// GET /admin/search-insights?days=30
+func (h *AdminHandler) SearchInsights(c *fiber.Ctx) error {
+	days, err := strconv.Atoi(c.Query("days", "30"))
+	if err != nil || days < 1 || days > 365 {
+		days = 30
+	}
+	ins, err := h.Search.Insights(days)
+	if err != nil {
+		applog.Error(c, "admin.search.insights.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load search insights"})
+	}
+	return render(c, "admin_search_insights", fiber.Map{"Insights": ins})
+}
+
+// GET /admin/product-views
+func (h *AdminHandler) ProductViews(c *fiber.Ctx) error {
+	stats, err := h.Views.Report(200)
+	if err != nil {
+		applog.Error(c, "admin.product.views.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load product views"})
+	}
+	return render(c, "admin_product_views", fiber.Map{"Stats": stats})
+}
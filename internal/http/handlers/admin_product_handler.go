@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"retrobytes/internal/domain"
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/services"
+	"retrobytes/internal/validate"
+)
+
+// GET /admin/products
+func (h *AdminHandler) ProductsPage(c *fiber.Ctx) error {
+	prods, err := h.Prods.ListAll()
+	if err != nil {
+		applog.Error(c, "admin.products.list.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load products"})
+	}
+	cats, _ := h.Cats.List()
+	return render(c, "admin_products", fiber.Map{"Products": prods, "Categories": cats})
+}
+
+// GET /admin/products/:id/images
+func (h *AdminHandler) ImagesPage(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Product not found"})
+	}
+	p, imgs, err := h.Images.List(id)
+	if err != nil {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Product not found"})
+	}
+	return render(c, "admin_product_images", fiber.Map{"P": p, "Images": imgs, "Err": c.Query("err")})
+}
+
+// POST /admin/products/:id/images (multipart: image, alt)
+func (h *AdminHandler) UploadImage(c *fiber.Ctx) error {
+	return h.uploadImage(c, h.Images, "images", "admin.products.image")
+}
+
+// POST /admin/products/:id/images/edit (action=up|down|delete|alt, path, alt)
+func (h *AdminHandler) EditImage(c *fiber.Ctx) error {
+	return h.editImage(c, h.Images, "images", "admin.products.image")
+}
+
+// uploadImage adds an upload to a gallery and redirects back to
+// /admin/products/:id/<page>.
+func (h *AdminHandler) uploadImage(c *fiber.Ctx, svc *services.ProductImageService, page, action string) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid product")
+	}
+	back := "/admin/products/" + id + "/" + page
+	alt, ok := validate.AltText(c.FormValue("alt"))
+	if !ok {
+		return c.Status(400).SendString("alt text must be a single line of at most 120 characters")
+	}
+	fh, err := c.FormFile("image")
+	if err != nil {
+		return c.Status(400).SendString("choose an image to upload")
+	}
+	if fh.Size > services.MaxImageBytes {
+		return c.Redirect(back + "?err=too_large")
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return c.Status(400).SendString("could not read upload")
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, services.MaxImageBytes+1))
+	if err != nil {
+		return c.Status(400).SendString("could not read upload")
+	}
+
+	img, err := svc.Add(id, data, alt)
+	if err != nil {
+		applog.Security(c, action+".reject", map[string]any{"product": id, "error": err.Error()})
+		return c.Redirect(back + "?err=" + imageErrCode(err))
+	}
+	applog.Audit(c, action+".add", map[string]any{"product": id, "path": img.Path, "bytes": len(data)})
+	return c.Redirect(back)
+}
+
+func (h *AdminHandler) editImage(c *fiber.Ctx, svc *services.ProductImageService, page, logAction string) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid product")
+	}
+	back := "/admin/products/" + id + "/" + page
+	path := c.FormValue("path")
+	action := c.FormValue("action")
+
+	var err error
+	switch action {
+	case "up":
+		err = svc.Move(id, path, -1)
+	case "down":
+		err = svc.Move(id, path, 1)
+	case "delete":
+		err = svc.Remove(id, path)
+	case "alt":
+		alt, ok := validate.AltText(c.FormValue("alt"))
+		if !ok {
+			return c.Status(400).SendString("alt text must be a single line of at most 120 characters")
+		}
+		err = svc.SetAlt(id, path, alt)
+	default:
+		return c.Status(400).SendString("unknown action")
+	}
+	if err != nil {
+		applog.Error(c, logAction+".edit.fail", err, map[string]any{"product": id, "action": action})
+		return c.Redirect(back + "?err=" + imageErrCode(err))
+	}
+	applog.Audit(c, logAction+"."+action, map[string]any{"product": id, "path": path})
+	return c.Redirect(back)
+}
+
+func imageErrCode(err error) string {
+	switch {
+	case errors.Is(err, services.ErrImageType):
+		return "type"
+	case errors.Is(err, services.ErrImageDimensions):
+		return "dimensions"
+	case errors.Is(err, services.ErrImageTooLarge):
+		return "too_large"
+	case errors.Is(err, services.ErrImageLimit):
+		return "limit"
+	case errors.Is(err, services.ErrImageNotFound):
+		return "not_found"
+	}
+	return "failed"
+}
+
+// GET /admin/products/:id/attributes
+func (h *AdminHandler) AttributesPage(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Product not found"})
+	}
+	p, err := h.Prods.Get(id)
+	if err != nil {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Product not found"})
+	}
+	attrs, err := h.Attrs.ForProduct(p, true)
+	if err != nil {
+		applog.Error(c, "admin.products.attributes.fail", err, map[string]any{"product": id})
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load attributes"})
+	}
+	variants, _ := h.Prods.Variants(id)
+	return render(c, "admin_product_attributes", fiber.Map{"P": p, "Attributes": attrs, "Variants": variants})
+}
+
+// POST /admin/products/:id/attributes (fields attr.<key>)
+func (h *AdminHandler) SaveAttributes(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid product")
+	}
+	p, err := h.Prods.Get(id)
+	if err != nil {
+		return c.Status(404).SendString("product not found")
+	}
+	defs, err := h.Attrs.Schema(p.CategoryID)
+	if err != nil {
+		return c.Status(500).SendString("could not load attributes")
+	}
+	raw := make(map[string]string, len(defs))
+	for _, d := range defs {
+		raw[d.Key] = c.FormValue("attr." + d.Key)
+	}
+	if err := h.Attrs.SaveValues(p, raw); err != nil {
+		applog.Security(c, "validation.fail", map[string]any{"field": "attr", "product": id})
+		return c.Status(400).SendString(err.Error())
+	}
+	applog.Audit(c, "admin.products.attributes.save", map[string]any{"product": id})
+	return c.Redirect("/admin/products/" + id + "/attributes")
+}
+
+// POST /admin/products/:id/variants (label, price)
+func (h *AdminHandler) AddVariant(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid product")
+	}
+	label, ok := validate.Label(c.FormValue("label"))
+	if !ok {
+		return c.Status(400).SendString("variant label must be 1-40 characters")
+	}
+	price, ok := validate.Price(c.FormValue("price"))
+	if !ok {
+		return c.Status(400).SendString("invalid price")
+	}
+	vid, err := h.Attrs.AddVariant(id, label, price)
+	if err != nil {
+		applog.Error(c, "admin.products.variant.add.fail", err, map[string]any{"product": id, "label": label})
+		return c.Status(400).SendString("could not add variant (is the label already used?)")
+	}
+	applog.Audit(c, "admin.products.variant.add", map[string]any{"product": id, "variant": vid, "price": price})
+	return c.Redirect("/admin/products/" + id + "/attributes")
+}
+
+// POST /admin/products/:id/variants/:vid/active (active=1|0)
+func (h *AdminHandler) SetVariantActive(c *fiber.Ctx) error {
+	id, ok1 := validate.ID(c.Params("id"))
+	vid, ok2 := validate.ID(c.Params("vid"))
+	if !ok1 || !ok2 {
+		return c.Status(400).SendString("invalid product")
+	}
+	active := c.FormValue("active") == "1"
+	if err := h.Attrs.SetVariantActive(id, vid, active); err != nil {
+		applog.Error(c, "admin.products.variant.active.fail", err, map[string]any{"product": id, "variant": vid})
+		return c.Status(400).SendString("could not update variant")
+	}
+	applog.Audit(c, "admin.products.variant.active", map[string]any{"product": id, "variant": vid, "active": active})
+	return c.Redirect("/admin/products/" + id + "/attributes")
+}
+
+// GET /admin/categories/:id/attributes
+func (h *AdminHandler) CategoryAttributesPage(c *fiber.Ctx) error {
+	cat, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Category not found"})
+	}
+	defs, err := h.Attrs.Schema(cat)
+	if err != nil {
+		applog.Error(c, "admin.categories.attributes.fail", err, map[string]any{"category": cat})
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load attributes"})
+	}
+	return render(c, "admin_category_attributes", fiber.Map{"CategoryID": cat, "Defs": defs})
+}
+
+// POST /admin/categories/:id/attributes (key, label, type, options, filterable, sort_order)
+func (h *AdminHandler) SaveCategoryAttribute(c *fiber.Ctx) error {
+	cat, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid category")
+	}
+	key, ok := validate.AttrKey(c.FormValue("key"))
+	if !ok {
+		return c.Status(400).SendString("key must be lowercase letters, digits or underscores")
+	}
+	label, ok := validate.Label(c.FormValue("label"))
+	if !ok {
+		return c.Status(400).SendString("label must be 1-40 characters")
+	}
+	order, _ := strconv.Atoi(c.FormValue("sort_order"))
+	def := domain.AttributeDef{
+		CategoryID: cat, Key: key, Label: label, Type: c.FormValue("type"),
+		Filterable: c.FormValue("filterable") == "1", SortOrder: order,
+	}
+	if err := h.Attrs.SaveDef(def, strings.Split(c.FormValue("options"), ",")); err != nil {
+		return c.Status(400).SendString(err.Error())
+	}
+	applog.Audit(c, "admin.categories.attributes.save", map[string]any{"category": cat, "key": key, "type": def.Type})
+	return c.Redirect("/admin/categories/" + cat + "/attributes")
+}
+
+// POST /admin/categories/:id/attributes/:key/delete
+func (h *AdminHandler) DeleteCategoryAttribute(c *fiber.Ctx) error {
+	cat, ok1 := validate.ID(c.Params("id"))
+	key, ok2 := validate.AttrKey(c.Params("key"))
+	if !ok1 || !ok2 {
+		return c.Status(400).SendString("invalid attribute")
+	}
+	if err := h.Attrs.DeleteDef(cat, key); err != nil {
+		applog.Error(c, "admin.categories.attributes.delete.fail", err, map[string]any{"category": cat, "key": key})
+		return c.Status(400).SendString("could not delete attribute")
+	}
+	applog.Audit(c, "admin.categories.attributes.delete", map[string]any{"category": cat, "key": key})
+	return c.Redirect("/admin/categories/" + cat + "/attributes")
+}
+
+// GET /admin/products/:id/condition
+func (h *AdminHandler) ConditionPage(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Product not found"})
+	}
+	p, photos, err := h.ConditionPhotos.List(id)
+	if err != nil {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Product not found"})
+	}
+	notes, _, _ := h.Prods.ConditionDetails(id)
+	return render(c, "admin_product_condition", fiber.Map{
+		"P": p, "Notes": notes, "Photos": photos, "Grades": domain.Grades, "Err": c.Query("err"),
+	})
+}
+
+// POST /admin/products/:id/condition (grade, notes)
+func (h *AdminHandler) SaveCondition(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid product")
+	}
+	grade, ok := validate.Condition(c.FormValue("grade"))
+	if !ok {
+		applog.Security(c, "validation.fail", map[string]any{"field": "grade", "product": id})
+		return c.Status(400).SendString("invalid condition grade")
+	}
+	notes, ok := validate.ConditionNotes(c.FormValue("notes"))
+	if !ok {
+		return c.Status(400).SendString("condition notes must be at most 1000 characters")
+	}
+	if err := h.Prods.SetCondition(id, grade, notes); err != nil {
+		applog.Error(c, "admin.products.condition.fail", err, map[string]any{"product": id})
+		return c.Status(500).SendString("could not save condition")
+	}
+	applog.Audit(c, "admin.products.condition.save", map[string]any{"product": id, "grade": grade})
+	return c.Redirect("/admin/products/" + id + "/condition")
+}
+
+// POST /admin/products/:id/condition/photos (multipart: image, alt)
+func (h *AdminHandler) UploadConditionPhoto(c *fiber.Ctx) error {
+	return h.uploadImage(c, h.ConditionPhotos, "condition", "admin.products.condition_photo")
+}
+
+// POST /admin/products/:id/condition/photos/edit (action=up|down|delete|alt, path, alt)
+func (h *AdminHandler) EditConditionPhoto(c *fiber.Ctx) error {
+	return h.editImage(c, h.ConditionPhotos, "condition", "admin.products.condition_photo")
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"time"
 
 	"retrobytes/internal/log"
@@ -82,3 +83,59 @@ func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 	log.Audit(c, "auth.logout", map[string]any{"sid": sid})
 	return c.Redirect("/")
 }
+
+// GET /password/forgot
+func (h *AuthHandler) ForgotForm(c *fiber.Ctx) error {
+	return render(c, "forgot_password", fiber.Map{})
+}
+
+// POST /password/forgot (email). The answer is the same whether or not
+// the address has an account.
+func (h *AuthHandler) Forgot(c *fiber.Ctx) error {
+	email, ok := validate.Email(c.FormValue("email"))
+	if !ok {
+		return c.Status(400).Render("forgot_password", fiber.Map{"Err": "Please enter a valid email address", "CSRFToken": c.Cookies("csrf_")})
+	}
+	if err := h.Auth.RequestPasswordReset(email, time.Now()); err != nil {
+		log.Error(c, "auth.reset.request.fail", err, nil)
+		return c.Status(500).Render("forgot_password", fiber.Map{"Err": "Could not send the reset email. Please try again.", "CSRFToken": c.Cookies("csrf_")})
+	}
+	log.Security(c, "auth.reset.request", map[string]any{"email": email})
+	return render(c, "forgot_password", fiber.Map{"Sent": true})
+}
+
+// GET /password/reset?token=
+func (h *AuthHandler) ResetForm(c *fiber.Ctx) error {
+	token := c.Query("token")
+	ok, err := h.Auth.ResetTokenValid(token, time.Now())
+	if err != nil {
+		log.Error(c, "auth.reset.lookup.fail", err, nil)
+	}
+	if !ok {
+		return c.Status(400).Render("reset_password", fiber.Map{"Invalid": true})
+	}
+	return render(c, "reset_password", fiber.Map{"Token": token})
+}
+
+// POST /password/reset (token, password, confirm)
+func (h *AuthHandler) Reset(c *fiber.Ctx) error {
+	token, pass := c.FormValue("token"), c.FormValue("password")
+	tok := c.Cookies("csrf_")
+	if pass != c.FormValue("confirm") {
+		return c.Status(400).Render("reset_password", fiber.Map{"Token": token, "Err": "The passwords do not match", "CSRFToken": tok})
+	}
+	if !validate.Password(pass) {
+		return c.Status(400).Render("reset_password", fiber.Map{"Token": token, "Err": "Use 8-20 characters with upper and lower case letters, a digit and a symbol", "CSRFToken": tok})
+	}
+	err := h.Auth.ResetPassword(token, pass, time.Now())
+	if errors.Is(err, services.ErrResetInvalid) {
+		log.Security(c, "auth.reset.invalid", nil)
+		return c.Status(400).Render("reset_password", fiber.Map{"Invalid": true, "CSRFToken": tok})
+	}
+	if err != nil {
+		log.Error(c, "auth.reset.fail", err, nil)
+		return c.Status(500).Render("reset_password", fiber.Map{"Token": token, "Err": "Could not reset the password. Please try again.", "CSRFToken": tok})
+	}
+	log.Audit(c, "auth.reset.success", nil)
+	return render(c, "login", fiber.Map{"Msg": "Your password was changed. Please sign in."})
+}
@@ -11,6 +11,7 @@ import (
 
 type CartHandler struct {
 	Cart *services.CartService
+	Recs *services.RecommendationService
 }
 
 func (h *CartHandler) ensureSID(c *fiber.Ctx) string {
@@ -55,6 +56,51 @@ func (h *CartHandler) View(c *fiber.Ctx) error {
 		applog.Error(c, "cart.view.fail", err, nil)
 		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load cart"})
 	}
-	return render(c, "cart", fiber.Map{"Cart": cv})
+	data := fiber.Map{"Cart": cv, "PromoMsg": c.Query("promo"), "PromoBack": "cart"}
+	if h.Recs != nil && len(cv.Items) > 0 {
+		if recs, err := h.Recs.ForCart(cv.Items, 4); err == nil {
+			data["Suggestions"] = recs
+		} else {
+			applog.Error(c, "cart.recommendations.fail", err, nil)
+		}
+	}
+	return render(c, "cart", data)
+
+}
+
+// POST /cart/promo (code, back=cart|checkout)
+func (h *CartHandler) ApplyPromo(c *fiber.Ctx) error {
+	sid := h.ensureSID(c)
+	back := "/cart"
+	if c.FormValue("back") == "checkout" {
+		back = "/checkout"
+	}
+	code, ok := validate.PromoCode(c.FormValue("code"))
+	if !ok {
+		return c.Redirect(back + "?promo=not_found")
+	}
+	if err := h.Cart.ApplyPromo(sid, code); err != nil {
+		if reason := services.PromoErrorCode(err); reason != "" {
+			applog.Info(c, "cart.promo.refused", map[string]any{"code": code, "reason": reason})
+			return c.Redirect(back + "?promo=" + reason)
+		}
+		applog.Error(c, "cart.promo.fail", err, map[string]any{"code": code})
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not apply the code"})
+	}
+	applog.Info(c, "cart.promo.apply", map[string]any{"code": code})
+	return c.Redirect(back + "?promo=applied")
+}
 
+// POST /cart/promo/remove (back=cart|checkout)
+func (h *CartHandler) RemovePromo(c *fiber.Ctx) error {
+	sid := h.ensureSID(c)
+	back := "/cart"
+	if c.FormValue("back") == "checkout" {
+		back = "/checkout"
+	}
+	if err := h.Cart.RemovePromo(sid); err != nil {
+		applog.Error(c, "cart.promo.remove.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not update your cart"})
+	}
+	return c.Redirect(back + "?promo=removed")
 }
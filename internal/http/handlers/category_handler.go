@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"retrobytes/internal/domain"
 	"retrobytes/internal/log"
 	"retrobytes/internal/services"
 	"retrobytes/internal/validate"
@@ -10,6 +11,7 @@ import (
 
 type CategoryHandler struct {
 	Catalog *services.CatalogService
+	Views   *services.ViewService
 }
 
 func (h *CategoryHandler) Home(c *fiber.Ctx) error {
@@ -18,7 +20,19 @@ func (h *CategoryHandler) Home(c *fiber.Ctx) error {
 		log.Error(c, "categories.list.fail", err, nil)
 		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load categories"})
 	}
-	return render(c, "home", fiber.Map{"Categories": cats})
+	data := fiber.Map{"Categories": cats}
+	if h.Views != nil {
+		uid := ""
+		if u, ok := c.Locals("user").(*domain.User); ok && u != nil {
+			uid = u.ID
+		}
+		if recent, err := h.Views.Recent(c.Cookies("sid"), uid, "", 6); err == nil {
+			data["RecentlyViewed"] = recent
+		} else {
+			log.Error(c, "home.recent.fail", err, nil)
+		}
+	}
+	return render(c, "home", data)
 
 }
 
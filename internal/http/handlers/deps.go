@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"log"
+
 	"retrobytes/internal/config"
+	"retrobytes/internal/mail"
+	"retrobytes/internal/payments"
 	"retrobytes/internal/repos"
 	"retrobytes/internal/services"
 
@@ -16,6 +20,12 @@ type Deps struct {
 	CartHandler      *CartHandler
 	OrderHandler     *OrderHandler
 	WishlistHandler  *WishlistHandler
+
+	SavedSearchHandler  *SavedSearchHandler
+	NotificationHandler *NotificationHandler
+	ReviewHandler       *ReviewHandler
+	QuestionHandler     *QuestionHandler
+	AddressHandler      *AddressHandler
 }
 
 func NewDeps(db *sqlx.DB, cfg config.Config, auth *services.AuthService) *Deps {
@@ -25,20 +35,78 @@ func NewDeps(db *sqlx.DB, cfg config.Config, auth *services.AuthService) *Deps {
 	cartRepo := repos.NewCartRepo(db)
 	orderRepo := repos.NewOrderRepo(db)
 	wishRepo := repos.NewWishlistRepo(db)
+	searchLogRepo := repos.NewSearchLogRepo(db)
+	savedRepo := repos.NewSavedSearchRepo(db)
+	notifRepo := repos.NewNotificationRepo(db)
+	jobRepo := repos.NewJobRepo(db)
+	attrRepo := repos.NewAttributeRepo(db)
+	reviewRepo := repos.NewReviewRepo(db)
 
 	catalogSvc := services.NewCatalogService(catRepo, prodRepo)
+	catalogSvc.Reviews = reviewRepo
+	pricingSvc := services.NewPricingService(repos.NewPriceRepo(db), prodRepo)
+	catalogSvc.Pricing = pricingSvc
 	invSvc := services.NewInventoryService(invRepo)
 	cartSvc := services.NewCartService(cartRepo, prodRepo)
+	cartSvc.Pricing = pricingSvc
 	orderSvc := services.NewOrderService(cartRepo, invRepo, orderRepo, prodRepo)
+	orderSvc.Pricing = pricingSvc
+	promoSvc := services.NewPromotionService(repos.NewPromotionRepo(db), prodRepo)
+	cartSvc.Promos = promoSvc
+	orderSvc.Promos = promoSvc
+	taxSvc := services.NewTaxService(repos.NewTaxRepo(db), prodRepo)
+	orderSvc.Tax = taxSvc
+	shipSvc := services.NewShippingService(repos.NewShippingRepo(db))
+	orderSvc.Shipping = shipSvc
+	payProvider, err := payments.New(cfg.PaymentProvider)
+	if err != nil {
+		log.Fatalf("[payments] %v", err)
+	}
+	paySvc := services.NewPaymentService(payProvider, repos.NewPaymentRepo(db))
+	orderSvc.Payments = paySvc
+	refundSvc := services.NewRefundService(orderRepo, repos.NewRefundRepo(db), paySvc)
+	shipmentRepo := repos.NewShipmentRepo(db)
+	returnSvc := services.NewReturnService(repos.NewReturnRepo(db), orderRepo, shipmentRepo, prodRepo, invRepo, refundSvc, cfg.MediaDir, cfg.ReturnWindowDays)
 	wishSvc := services.NewWishlistService(wishRepo)
+	searchSvc := services.NewSearchAnalyticsService(searchLogRepo)
+	attrSvc := services.NewAttributeService(attrRepo, prodRepo)
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		log.Fatalf("[mail] %v", err)
+	}
+	// everything below queues into the outbox; only the worker uses mailer
+	mailSvc := services.NewMailService(repos.NewOutboxRepo(db), mailer, cfg.BaseURL)
+	orderSvc.Mail = mailSvc
+	if auth != nil {
+		auth.Mail = mailSvc
+	}
+	notifySvc := services.NewNotificationService(notifRepo, mailSvc)
+	savedSvc := services.NewSavedSearchService(savedRepo, prodRepo, jobRepo, notifySvc)
+	reviewSvc := services.NewReviewService(reviewRepo, prodRepo)
+	questionSvc := services.NewQuestionService(repos.NewQuestionRepo(db), prodRepo, notifySvc)
+	recSvc := services.NewRecommendationService(repos.NewRecommendationRepo(db), prodRepo, jobRepo)
+	recSvc.Pricing = pricingSvc
+	viewSvc := services.NewViewService(repos.NewViewRepo(db))
+	viewSvc.Pricing = pricingSvc
+	addrSvc := services.NewAddressService(repos.NewAddressRepo(db))
+	shipmentSvc := services.NewShipmentService(shipmentRepo, orderRepo, paySvc, mailSvc)
+	pickupSvc := services.NewPickupService(repos.NewPickupRepo(db), orderRepo, paySvc, mailSvc, cfg.PickupSlotMinutes, cfg.PickupDaysAhead, cfg.PickupHoldDays)
+	orderSvc.Pickup = pickupSvc
 
 	return &Deps{
-		CategoryHandler:  &CategoryHandler{Catalog: catalogSvc},
-		ProductHandler:   &ProductHandler{Catalog: catalogSvc},
+		CategoryHandler:  &CategoryHandler{Catalog: catalogSvc, Views: viewSvc},
+		ProductHandler:   &ProductHandler{Catalog: catalogSvc, Analytics: searchSvc, Attrs: attrSvc, Reviews: reviewSvc, Questions: questionSvc, Recs: recSvc, Views: viewSvc},
 		InventoryHandler: &InventoryHandler{Inv: invSvc},
-		SearchHandler:    &SearchHandler{Catalog: catalogSvc},
-		CartHandler:      &CartHandler{Cart: cartSvc},
-		OrderHandler:     &OrderHandler{Cart: cartSvc, Order: orderSvc, Repo: orderRepo, Auth: auth},
-		WishlistHandler:  &WishlistHandler{Wish: wishSvc},
+		SearchHandler:    &SearchHandler{Catalog: catalogSvc, Analytics: searchSvc, Attrs: attrSvc},
+		CartHandler:      &CartHandler{Cart: cartSvc, Recs: recSvc},
+		OrderHandler: &OrderHandler{Cart: cartSvc, Order: orderSvc, Repo: orderRepo, Auth: auth, Tax: taxSvc, Shipping: shipSvc, Addresses: addrSvc, Payments: paySvc, Refunds: refundSvc, Returns: returnSvc, Documents: services.NewDocumentService(orderRepo),
+			Shipments: shipmentSvc, Pickup: pickupSvc, Lookup: services.NewOrderLookupService(orderRepo, mailSvc, cfg.LinkSecret)},
+		WishlistHandler: &WishlistHandler{Wish: wishSvc},
+
+		SavedSearchHandler:  &SavedSearchHandler{Saved: savedSvc},
+		NotificationHandler: &NotificationHandler{Notify: notifySvc},
+		ReviewHandler:       &ReviewHandler{Reviews: reviewSvc},
+		QuestionHandler:     &QuestionHandler{Questions: questionSvc},
+		AddressHandler:      &AddressHandler{Addresses: addrSvc},
 	}
 }
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GET /order/:id/invoice.pdf
+func (h *OrderHandler) Invoice(c *fiber.Ctx) error {
+	return h.sendDocument(c, "invoice")
+}
+
+// GET /order/:id/packing-slip.pdf
+func (h *OrderHandler) PackingSlip(c *fiber.Ctx) error {
+	return h.sendDocument(c, "packing_slip")
+}
+
+func (h *OrderHandler) sendDocument(c *fiber.Ctx, kind string) error {
+	oid := c.Params("id")
+	o, _, err := h.Repo.Get(oid)
+	if err != nil || h.Documents == nil {
+		return c.Status(fiber.StatusNotFound).Render("notfound", fiber.Map{"Message": "Order not found"})
+	}
+	if !h.canSeeOrder(c, o) {
+		applog.Security(c, "access.denied.order."+kind, map[string]any{"order_id": oid})
+		return c.Status(fiber.StatusNotFound).Render("notfound", fiber.Map{"Message": "Order not found"})
+	}
+	return sendDocument(c, h.Documents, kind, oid, "order."+kind)
+}
+
+// GET /admin/orders/:id/invoice.pdf
+func (h *AdminHandler) OrderInvoice(c *fiber.Ctx) error {
+	return sendDocument(c, h.Documents, "invoice", c.Params("id"), "admin.orders.invoice")
+}
+
+// POST /admin/orders/:id/invoice issues the invoice number ahead of
+// shipping or collection.
+func (h *AdminHandler) IssueInvoice(c *fiber.Ctx) error {
+	id := c.Params("id")
+	number, err := h.Documents.Issue(id)
+	if errors.Is(err, services.ErrNoDocument) {
+		return c.Status(400).SendString("the order was canceled; there is nothing to invoice")
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Order not found"})
+	}
+	if err != nil {
+		applog.Error(c, "admin.orders.invoice.issue.fail", err, map[string]any{"order_id": id})
+		return c.Status(500).SendString("could not issue the invoice")
+	}
+	applog.Audit(c, "admin.orders.invoice.issue", map[string]any{"order_id": id, "invoice": number, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/orders/" + id)
+}
+
+// GET /admin/orders/:id/packing-slip.pdf
+func (h *AdminHandler) OrderPackingSlip(c *fiber.Ctx) error {
+	return sendDocument(c, h.Documents, "packing_slip", c.Params("id"), "admin.orders.packing_slip")
+}
+
+func sendDocument(c *fiber.Ctx, svc *services.DocumentService, kind, orderID, action string) error {
+	var body []byte
+	var name string
+	var err error
+	label := "invoice"
+	if kind == "invoice" {
+		body, name, err = svc.Invoice(orderID)
+	} else {
+		label = "packing slip"
+		body, name, err = svc.PackingSlip(orderID)
+	}
+	if errors.Is(err, services.ErrNoDocument) {
+		return c.Status(fiber.StatusNotFound).Render("notfound", fiber.Map{"Message": "This order was canceled; there is no " + label + " for it."})
+	}
+	if errors.Is(err, services.ErrNoInvoice) {
+		return c.Status(fiber.StatusNotFound).Render("notfound", fiber.Map{"Message": "The invoice is issued once the order ships or is collected."})
+	}
+	if err != nil {
+		applog.Error(c, action+".fail", err, map[string]any{"order_id": orderID})
+		return c.Status(fiber.StatusNotFound).Render("notfound", fiber.Map{"Message": "Order not found"})
+	}
+	applog.Info(c, action, map[string]any{"order_id": orderID})
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+name+`"`)
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.Send(body)
+}
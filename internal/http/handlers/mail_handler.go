@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"strconv"
+
+	applog "retrobytes/internal/log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GET /admin/emails lists emails that ran out of delivery attempts.
+func (h *AdminHandler) EmailsPage(c *fiber.Ctx) error {
+	failed, err := h.Mail.Failed()
+	if err != nil {
+		applog.Error(c, "admin.emails.list.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load emails"})
+	}
+	counts, err := h.Mail.Counts()
+	if err != nil {
+		applog.Error(c, "admin.emails.counts.fail", err, nil)
+	}
+	return render(c, "admin_emails", fiber.Map{"Failed": failed, "Counts": counts, "Retried": c.Query("retried") == "1"})
+}
+
+// POST /admin/emails/:id/retry queues a failed email again.
+func (h *AdminHandler) RetryEmail(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return c.Status(400).SendString("invalid email id")
+	}
+	ok, err := h.Mail.Retry(id)
+	if err != nil {
+		applog.Error(c, "admin.emails.retry.fail", err, map[string]any{"email_id": id})
+		return c.Status(500).SendString("could not retry email")
+	}
+	if !ok {
+		return c.Status(400).SendString("email is not in the failed list")
+	}
+	applog.Audit(c, "admin.emails.retry", map[string]any{"email_id": id, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/emails?retried=1")
+}
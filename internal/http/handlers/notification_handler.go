@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"retrobytes/internal/domain"
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/services"
+)
+
+type NotificationHandler struct {
+	Notify *services.NotificationService
+}
+
+// GET /notifications
+func (h *NotificationHandler) Notifications(c *fiber.Ctx) error {
+	u, _ := c.Locals("user").(*domain.User)
+	if u == nil {
+		return c.Redirect("/login")
+	}
+	items, err := h.Notify.List(u.ID)
+	if err != nil {
+		applog.Error(c, "notifications.list.fail", err, nil)
+		return c.Status(fiber.StatusInternalServerError).Render("notfound", fiber.Map{"Message": "Could not load notifications"})
+	}
+	return render(c, "notifications", fiber.Map{"Notifications": items})
+}
+
+// POST /notifications/read
+func (h *NotificationHandler) MarkRead(c *fiber.Ctx) error {
+	u, _ := c.Locals("user").(*domain.User)
+	if u == nil {
+		return c.Redirect("/login")
+	}
+	if err := h.Notify.MarkAllRead(u.ID); err != nil {
+		applog.Error(c, "notifications.read.fail", err, nil)
+		return c.Status(fiber.StatusInternalServerError).SendString("Could not update notifications")
+	}
+	return c.Redirect("/notifications")
+}
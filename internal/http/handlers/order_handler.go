@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
 	"retrobytes/internal/domain"
 	applog "retrobytes/internal/log"
+	"retrobytes/internal/payments"
 	"retrobytes/internal/repos"
 	"retrobytes/internal/services"
 	"retrobytes/internal/validate"
@@ -18,6 +21,26 @@ type OrderHandler struct {
 	Order *services.OrderService
 	Repo  *repos.OrderRepo
 	Auth  *services.AuthService
+	// Tax estimates sales tax on /checkout once a ZIP is entered
+	Tax *services.TaxService
+	// Shipping lists delivery options and costs on /checkout
+	Shipping *services.ShippingService
+	// Addresses offers a signed-in shopper's saved addresses at checkout
+	Addresses *services.AddressService
+	// Payments decides whether checkout offers the fake gateway's test cards
+	Payments *services.PaymentService
+	// Refunds shows money already given back on the order page
+	Refunds *services.RefundService
+	// Returns lets customers send shipped items back from the order page
+	Returns *services.ReturnService
+	// Documents renders the invoice and packing slip PDFs
+	Documents *services.DocumentService
+	// Shipments lists the parcels sent with their tracking numbers
+	Shipments *services.ShipmentService
+	// Lookup emails guests a signed link to their order and checks it
+	Lookup *services.OrderLookupService
+	// Pickup offers pickup slots at checkout and draws the pickup QR code
+	Pickup *services.PickupService
 }
 
 type OrderDeps struct {
@@ -47,7 +70,105 @@ func (h *OrderHandler) Checkout(c *fiber.Ctx) error {
 		applog.Error(c, "checkout.load", err, nil)
 		return c.Status(fiber.StatusInternalServerError).Render("notfound", fiber.Map{"Message": "Could not load your cart"})
 	}
-	return render(c, "checkout", fiber.Map{"Cart": cv})
+	fulfillment := normalizeFulfillment(c.Query("fulfillment"))
+	data := fiber.Map{
+		"Cart": cv, "PricesMsg": c.Query("prices"), "PromoMsg": c.Query("promo"), "PromoBack": "checkout",
+		"ShipMsg": c.Query("ship"), "PayMsg": c.Query("pay"), "PickupMsg": c.Query("pickup"), "Fulfillment": fulfillment,
+	}
+	if h.Payments != nil {
+		_, data["TestCards"] = h.Payments.Provider.(*payments.Fake)
+	}
+	// a signed-in shopper may ship to a saved address (the default unless
+	// another address or ZIP was picked)
+	var addr repos.Address
+	if u, _ := c.Locals("user").(*domain.User); u != nil && h.Addresses != nil {
+		saved, err := h.Addresses.List(u.ID)
+		if err != nil {
+			applog.Error(c, "checkout.addresses", err, nil)
+		}
+		data["Saved"] = saved
+		pick, picked := c.Query("address"), c.Context().QueryArgs().Has("address")
+		for _, a := range saved {
+			if (picked && a.ID == pick) || (!picked && c.Query("ship_zip") == "" && a.IsDefault) {
+				addr = a
+			}
+		}
+	}
+	data["AddressID"] = addr.ID
+	// with a region entered, price shipping and tax for the chosen
+	// fulfillment; tax is due at the delivery ZIP, or the region for pickup
+	region, ok := validate.Region(c.Query("region"))
+	if !ok || len(cv.Items) == 0 {
+		return render(c, "checkout", data)
+	}
+	data["Region"] = region
+	discount := 0.0
+	if cv.Promo != nil {
+		discount = cv.Promo.Discount
+	}
+	total, taxZIP := cv.Due(), region
+	if fulfillment == "delivery" && h.Shipping != nil {
+		shipZIP := region
+		if addr.ZIP != "" {
+			shipZIP = addr.ZIP
+		} else if z, ok := validate.Region(c.Query("ship_zip")); ok {
+			shipZIP = z
+		}
+		addr.ZIP = shipZIP
+		data["ShipFields"] = addressFields{Prefix: "ship_", Address: addr, States: validate.States(), FixedZIP: true}
+		opts, err := h.Shipping.Options(region, shipZIP, cv.Lines(), cv.Due())
+		if err != nil {
+			applog.Error(c, "checkout.shipping", err, map[string]any{"region": region, "ship_zip": shipZIP})
+			return c.Status(fiber.StatusInternalServerError).Render("notfound", fiber.Map{"Message": "Could not calculate shipping"})
+		}
+		data["ShipZIP"], data["ShipOptions"] = shipZIP, opts
+		opt, ok := services.ChooseShipping(opts, c.Query("method"))
+		if !ok {
+			opt, ok = services.ChooseShipping(opts, "")
+		}
+		if ok {
+			data["Ship"] = opt
+			total += opt.Cost
+		}
+		taxZIP = shipZIP
+	}
+	if fulfillment == "pickup" && h.Pickup != nil {
+		slots, err := h.Pickup.Slots(region, time.Now())
+		if err != nil {
+			applog.Error(c, "checkout.pickup", err, map[string]any{"region": region})
+			return c.Status(fiber.StatusInternalServerError).Render("notfound", fiber.Map{"Message": "Could not load pickup times"})
+		}
+		data["PickupSlots"], data["PickupBooking"] = slots, true
+	}
+	if h.Tax != nil {
+		tax, err := h.Tax.Quote(taxZIP, cv.Lines(), discount)
+		if err != nil {
+			applog.Error(c, "checkout.tax", err, map[string]any{"zip": taxZIP})
+			return c.Status(fiber.StatusInternalServerError).Render("notfound", fiber.Map{"Message": "Could not calculate tax"})
+		}
+		data["Tax"], data["TaxZIP"] = tax, taxZIP
+		total += tax.Amount
+	}
+	data["Total"] = total
+	data["BillFields"] = addressFields{Prefix: "bill_", States: validate.States(), Optional: true}
+	return render(c, "checkout", data)
+}
+
+// POST /checkout/prices (prices) accepts the changed prices shown on
+// /checkout, as long as they are still the current ones.
+func (h *OrderHandler) ConfirmPrices(c *fiber.Ctx) error {
+	sid := h.ensureSID(c)
+	err := h.Cart.ConfirmPrices(sid, c.FormValue("prices"))
+	if errors.Is(err, services.ErrPricesChanged) {
+		applog.Info(c, "checkout.prices.moved", map[string]any{"sid": sid})
+		return c.Redirect("/checkout?prices=moved")
+	}
+	if err != nil {
+		applog.Error(c, "checkout.prices.confirm.fail", err, nil)
+		return c.Status(fiber.StatusInternalServerError).Render("notfound", fiber.Map{"Message": "Could not update your cart"})
+	}
+	applog.Info(c, "checkout.prices.confirm", map[string]any{"sid": sid})
+	return c.Redirect("/checkout?prices=confirmed")
 }
 
 func (h *OrderHandler) Place(c *fiber.Ctx) error {
@@ -72,20 +193,85 @@ func (h *OrderHandler) Place(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).SendString("name must be 1-20 characters")
 	}
 
-	// Normalize fulfillment
-	fulfillment := strings.ToLower(strings.TrimSpace(c.FormValue("fulfillment")))
-	if fulfillment != "delivery" && fulfillment != "pickup" {
-		fulfillment = "delivery"
-	}
+	fulfillment := normalizeFulfillment(c.FormValue("fulfillment"))
 
 	contact := services.Contact{Name: name, Email: email}
+	if fulfillment == "delivery" {
+		shipTo, msg := addressForm(c, "ship_")
+		if msg != "" {
+			applog.Security(c, "validation.fail", map[string]any{"field": "ship_address"})
+			return c.Status(fiber.StatusBadRequest).SendString("shipping address: " + msg)
+		}
+		contact.ShipTo, contact.ShipZIP = &shipTo, shipTo.ZIP
+		if c.FormValue("bill_same") == "1" {
+			contact.BillTo = &shipTo
+		}
+		contact.ShipMethod = strings.ToUpper(strings.TrimSpace(c.FormValue("ship_method")))
+		if len(contact.ShipMethod) > 20 {
+			applog.Security(c, "validation.fail", map[string]any{"field": "ship_method"})
+			return c.Status(fiber.StatusBadRequest).SendString("invalid shipping method")
+		}
+	}
+
+	if fulfillment == "pickup" {
+		contact.PickupSlot = strings.TrimSpace(c.FormValue("pickup_slot"))
+		if len(contact.PickupSlot) > 20 {
+			applog.Security(c, "validation.fail", map[string]any{"field": "pickup_slot"})
+			return c.Status(fiber.StatusBadRequest).SendString("invalid pickup time")
+		}
+	}
+
+	// billing address: required when it differs from the shipping address,
+	// optional for pickup
+	if contact.BillTo == nil && (fulfillment == "delivery" || c.FormValue("bill_line1") != "") {
+		billTo, msg := addressForm(c, "bill_")
+		if msg != "" {
+			applog.Security(c, "validation.fail", map[string]any{"field": "bill_address"})
+			return c.Status(fiber.StatusBadRequest).SendString("billing address: " + msg)
+		}
+		contact.BillTo = &billTo
+	}
+
+	if contact.PaymentToken, ok = validate.PaymentToken(c.FormValue("payment_token")); !ok {
+		applog.Security(c, "validation.fail", map[string]any{"field": "payment_token"})
+		return c.Status(fiber.StatusBadRequest).SendString("missing or invalid payment card")
+	}
 
 	orderID, serverTotal, clientTotal, err := h.Order.Place(sid, region, fulfillment, contact)
+	if errors.Is(err, services.ErrPricesChanged) {
+		applog.Info(c, "order.place.prices_changed", map[string]any{"sid": sid})
+		return c.Redirect("/checkout?prices=changed")
+	}
+	if errors.Is(err, services.ErrShippingUnavailable) {
+		applog.Info(c, "order.place.shipping_unavailable", map[string]any{"sid": sid, "method": contact.ShipMethod})
+		return c.Redirect("/checkout?ship=unavailable")
+	}
+	if errors.Is(err, services.ErrPickupSlot) {
+		applog.Info(c, "order.place.pickup_unavailable", map[string]any{"sid": sid, "slot": contact.PickupSlot})
+		return c.Redirect("/checkout?fulfillment=pickup&region=" + region + "&pickup=unavailable")
+	}
+	if errors.Is(err, payments.ErrDeclined) {
+		applog.Security(c, "order.place.payment_declined", map[string]any{"sid": sid})
+		return c.Redirect("/checkout?pay=declined")
+	}
+	if errors.Is(err, payments.ErrTimeout) {
+		applog.Error(c, "order.place.payment_timeout", err, map[string]any{"sid": sid})
+		return c.Redirect("/checkout?pay=timeout")
+	}
+	if reason := services.PromoErrorCode(err); reason != "" {
+		applog.Info(c, "order.place.promo_refused", map[string]any{"sid": sid, "reason": reason})
+		return c.Redirect("/checkout?promo=" + reason)
+	}
 	if err != nil {
 		// business rule errors (e.g., insufficient stock) surface as 400
 		applog.Security(c, "order.place.fail", map[string]any{"sid": sid, "error": err.Error()})
 		return c.Status(fiber.StatusBadRequest).SendString("Could not place order. Please review quantities and try again.")
 	}
+	if u, _ := c.Locals("user").(*domain.User); u != nil && contact.ShipTo != nil && c.FormValue("save_address") == "1" && h.Addresses != nil {
+		if _, err := h.Addresses.Add(u.ID, *contact.ShipTo); err != nil && !errors.Is(err, services.ErrTooManyAddresses) {
+			applog.Error(c, "address.add.fail", err, map[string]any{"order_id": orderID})
+		}
+	}
 	applog.Audit(c, "order.place", map[string]any{
 		"order_id":     orderID,
 		"server_total": serverTotal,
@@ -102,13 +288,112 @@ func (h *OrderHandler) View(c *fiber.Ctx) error {
 	if oid == "" {
 		return c.Status(fiber.StatusNotFound).Render("notfound", fiber.Map{"Message": "Order not found"})
 	}
+	if h.rememberOrderLink(c, oid) {
+		return c.Redirect("/order/" + oid)
+	}
 
 	o, items, err := h.Repo.Get(oid)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).Render("notfound", fiber.Map{"Message": "Order not found"})
 	}
+	adj, err := h.Repo.Adjustments(oid)
+	if err != nil {
+		applog.Error(c, "order.adjustments.fail", err, map[string]any{"order_id": oid})
+	}
+	ship, bill, err := h.Repo.Addresses(oid)
+	if err != nil {
+		applog.Error(c, "order.addresses.fail", err, map[string]any{"order_id": oid})
+	}
+	data := fiber.Map{"Order": o, "Items": items, "Adjustments": adj, "ShipTo": ship, "BillTo": bill, "CancelMsg": c.Query("cancel")}
+	if no, _, err := h.Repo.InvoiceNo(oid); err != nil {
+		applog.Error(c, "order.invoice.fail", err, map[string]any{"order_id": oid})
+	} else {
+		data["Invoiced"] = no > 0
+	}
+	if h.Refunds != nil {
+		if sum, err := h.Refunds.Summary(oid); err != nil {
+			applog.Error(c, "order.refunds.fail", err, map[string]any{"order_id": oid})
+		} else if sum.Refunded > 0 {
+			data["Refund"] = sum
+		}
+	}
+	if h.Shipments != nil {
+		if shipments, err := h.Shipments.ForOrder(oid); err != nil {
+			applog.Error(c, "order.shipments.fail", err, map[string]any{"order_id": oid})
+		} else {
+			data["Shipments"] = shipments
+		}
+	}
+
+	if !h.canSeeOrder(c, o) {
+		applog.Security(c, "access.denied.order", map[string]any{"order_id": oid})
+		return c.Status(fiber.StatusNotFound).Render("notfound", fiber.Map{"Message": "Order not found"})
+	}
+	data["CanManage"] = h.canManageOrder(c, o)
+	if h.Returns != nil {
+		h.returnData(c, o, data)
+	}
+	if h.Pickup != nil {
+		if at, ok := h.Pickup.Deadline(o); ok {
+			data["PickupDeadline"] = at.Local().Format("Mon 2 Jan 2006")
+		}
+	}
+
+	return render(c, "order", data)
+}
 
-	// Ownership check: session owner or same user via sessions.user_id; admins allowed
+// POST /order/:id/cancel lets the owner cancel an order that has not been
+// processed yet.
+func (h *OrderHandler) Cancel(c *fiber.Ctx) error {
+	oid := c.Params("id")
+	o, _, err := h.Repo.Get(oid)
+	if err != nil || !h.canManageOrder(c, o) {
+		if err == nil {
+			applog.Security(c, "access.denied.order", map[string]any{"order_id": oid})
+		}
+		return c.Status(fiber.StatusNotFound).Render("notfound", fiber.Map{"Message": "Order not found"})
+	}
+	// staff canceling someone else's order are recorded as such
+	by := repos.StatusChange{Actor: "CUSTOMER", Note: "Canceled by the customer"}
+	if u, _ := c.Locals("user").(*domain.User); u != nil {
+		by.UserID = u.ID
+		if u.Role == "ADMIN" && u.ID != o.UserID {
+			by.Actor, by.Note = "ADMIN", ""
+		}
+	}
+	if co, err := h.Order.Cancel(oid, by); errors.Is(err, services.ErrNotCancelable) {
+		applog.Info(c, "order.cancel.refused", map[string]any{"order_id": oid, "status": o.Status})
+		return c.Redirect("/order/" + oid + "?cancel=too_late")
+	} else if err != nil {
+		applog.Error(c, "order.cancel.fail", err, map[string]any{"order_id": oid, "status": co.Status})
+		return c.Status(fiber.StatusInternalServerError).Render("notfound", fiber.Map{"Message": "Could not cancel the order. Please contact us."})
+	}
+	applog.Audit(c, "order.cancel", map[string]any{"order_id": oid, "user_id": by.UserID, "actor": by.Actor})
+	return c.Redirect("/order/" + oid + "?cancel=done")
+}
+
+// canSeeOrder is the order page's ownership check: whoever may manage the
+// order, or anyone holding a signed order link. The link only shows the
+// order and its documents.
+func (h *OrderHandler) canSeeOrder(c *fiber.Ctx, o repos.OrderRow) bool {
+	if h.canManageOrder(c, o) {
+		return true
+	}
+	if h.Lookup != nil {
+		for _, t := range []string{c.Query("t"), c.Cookies(orderLinkCookie)} {
+			if _, ok := h.Lookup.Verify(o.ID, t, time.Now()); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// canManageOrder guards changes to an order (cancel, returns) and the
+// customer's return photos: the session that placed the order, the same
+// user via sessions.user_id, or an admin. A forwarded order link is not
+// enough.
+func (h *OrderHandler) canManageOrder(c *fiber.Ctx, o repos.OrderRow) bool {
 	sid := c.Cookies("sid")
 	var uID string
 	var uRole string
@@ -118,15 +403,7 @@ func (h *OrderHandler) View(c *fiber.Ctx) error {
 			uRole = u.Role
 		}
 	}
-	if !(sid != "" && sid == o.SessionID) && !(uID != "" && uID == o.UserID) {
-		if uRole == "ADMIN" {
-			return render(c, "order", fiber.Map{"Order": o, "Items": items})
-		}
-		applog.Security(c, "access.denied.order", map[string]any{"order_id": oid})
-		return c.Status(fiber.StatusNotFound).Render("notfound", fiber.Map{"Message": "Order not found"})
-	}
-
-	return render(c, "order", fiber.Map{"Order": o, "Items": items})
+	return (sid != "" && sid == o.SessionID) || (uID != "" && uID == o.UserID) || uRole == "ADMIN"
 }
 
 // History lists orders for the current logged-in user.
@@ -151,3 +428,11 @@ func (h *OrderHandler) History(c *fiber.Ctx) error {
 	}
 	return render(c, "order_history", fiber.Map{"Orders": orders})
 }
+
+// normalizeFulfillment maps form input to delivery|pickup (default delivery).
+func normalizeFulfillment(s string) string {
+	if strings.ToLower(strings.TrimSpace(s)) == "pickup" {
+		return "pickup"
+	}
+	return "delivery"
+}
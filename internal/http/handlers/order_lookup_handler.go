@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/validate"
+)
+
+// orderLinkCookie keeps a signed order link working after the token is
+// dropped from the URL; it is scoped to that order's pages.
+const orderLinkCookie = "order_link"
+
+// GET /orders/lookup
+func (h *OrderHandler) LookupForm(c *fiber.Ctx) error {
+	return render(c, "orders_lookup", fiber.Map{})
+}
+
+// POST /orders/lookup (order_no, email). The answer is the same whether or
+// not the order exists and matches the address.
+func (h *OrderHandler) LookupSubmit(c *fiber.Ctx) error {
+	orderNo, okNo := validate.OrderNo(c.FormValue("order_no"))
+	email, okEmail := validate.Email(c.FormValue("email"))
+	if !okNo || !okEmail {
+		return c.Status(400).Render("orders_lookup", fiber.Map{
+			"Err": "Please enter an order number like RB-2026-000123 and a valid email address", "OrderNo": c.FormValue("order_no"), "CSRFToken": c.Cookies("csrf_"),
+		})
+	}
+	if err := h.Lookup.Request(orderNo, email, time.Now()); err != nil {
+		applog.Error(c, "order.lookup.fail", err, map[string]any{"order_no": orderNo})
+		return c.Status(500).Render("orders_lookup", fiber.Map{"Err": "Could not send the email. Please try again.", "OrderNo": orderNo, "CSRFToken": c.Cookies("csrf_")})
+	}
+	applog.Security(c, "order.lookup.request", map[string]any{"order_no": orderNo})
+	return render(c, "orders_lookup", fiber.Map{"Sent": true})
+}
+
+// rememberOrderLink moves a valid ?t= order link token into a cookie so the
+// caller can redirect to the clean URL; the token stays out of history,
+// logs and Referer headers from then on.
+func (h *OrderHandler) rememberOrderLink(c *fiber.Ctx, orderID string) bool {
+	t := c.Query("t")
+	if t == "" || h.Lookup == nil {
+		return false
+	}
+	expires, ok := h.Lookup.Verify(orderID, t, time.Now())
+	if !ok {
+		applog.Security(c, "order.link.invalid", map[string]any{"order_id": orderID})
+		return false
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     orderLinkCookie,
+		Value:    t,
+		Path:     "/order/" + orderID,
+		Expires:  expires,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+		Secure:   false, // enable true behind TLS
+	})
+	applog.Info(c, "order.link.open", map[string]any{"order_id": orderID})
+	return true
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/qr"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+	"retrobytes/internal/validate"
+)
+
+// GET /order/:id/pickup-qr.svg draws the pickup code for the counter
+// scanner, while the order waits to be collected.
+func (h *OrderHandler) PickupQR(c *fiber.Ctx) error {
+	oid := c.Params("id")
+	o, _, err := h.Repo.Get(oid)
+	if err != nil || !o.PickupOpen() {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	if !h.canSeeOrder(c, o) {
+		applog.Security(c, "access.denied.order.pickup_qr", map[string]any{"order_id": oid})
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	code, err := qr.Encode(o.PickupCode)
+	if err != nil {
+		applog.Error(c, "order.pickup_qr.fail", err, map[string]any{"order_id": oid})
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	c.Set(fiber.HeaderContentType, "image/svg+xml")
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.Send(code.SVG(8))
+}
+
+// GET /admin/pickups lists the pickup queue and store hours; ?code= jumps
+// to the order with that pickup code.
+func (h *AdminHandler) PickupsPage(c *fiber.Ctx) error {
+	if h.Pickup == nil {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Pickup is not configured"})
+	}
+	data := fiber.Map{}
+	if raw := c.Query("code"); raw != "" {
+		code, ok := validate.PickupCode(raw)
+		if ok {
+			id, err := h.Pickup.Find(code)
+			if err == nil {
+				applog.Audit(c, "admin.pickups.lookup", map[string]any{"order_id": id, "admin_id": adminUserID(c)})
+				return c.Redirect("/admin/orders/" + id)
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				applog.Error(c, "admin.pickups.lookup.fail", err, nil)
+				return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not look up the pickup code"})
+			}
+		}
+		data["Code"], data["NotFound"] = raw, true
+	}
+	queue, err := h.Pickup.Queue()
+	if err != nil {
+		applog.Error(c, "admin.pickups.list.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load pickups"})
+	}
+	hours, err := h.Pickup.Hours()
+	if err != nil {
+		applog.Error(c, "admin.pickups.list.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load pickups"})
+	}
+	data["Queue"], data["Hours"], data["HoldDays"] = queue, hours, h.Pickup.HoldDays
+	return render(c, "admin_pickups", data)
+}
+
+// POST /admin/pickups/hours (region, weekday, opens, closes)
+func (h *AdminHandler) SavePickupHours(c *fiber.Ctx) error {
+	var hrs repos.PickupHours
+	var ok bool
+	if hrs.Region, ok = validate.Region(c.FormValue("region")); !ok {
+		return c.Status(400).SendString("invalid store region")
+	}
+	if hrs.Weekday, ok = validate.Weekday(c.FormValue("weekday")); !ok {
+		return c.Status(400).SendString("invalid weekday")
+	}
+	if hrs.Opens, ok = validate.Clock(c.FormValue("opens")); !ok {
+		return c.Status(400).SendString("opening time must be HH:MM")
+	}
+	if hrs.Closes, ok = validate.Clock(c.FormValue("closes")); !ok {
+		return c.Status(400).SendString("closing time must be HH:MM")
+	}
+	if err := h.Pickup.SaveHours(hrs); err != nil {
+		if errors.Is(err, services.ErrPickup) {
+			return c.Status(400).SendString(err.Error())
+		}
+		applog.Error(c, "admin.pickups.hours.save.fail", err, map[string]any{"region": hrs.Region, "weekday": hrs.Weekday})
+		return c.Status(500).SendString("could not save pickup hours")
+	}
+	applog.Audit(c, "admin.pickups.hours.save", map[string]any{
+		"region": hrs.Region, "weekday": hrs.Weekday, "opens": hrs.Opens, "closes": hrs.Closes, "admin_id": adminUserID(c),
+	})
+	return c.Redirect("/admin/pickups")
+}
+
+// POST /admin/pickups/hours/delete (region, weekday)
+func (h *AdminHandler) DeletePickupHours(c *fiber.Ctx) error {
+	region, ok := validate.Region(c.FormValue("region"))
+	if !ok {
+		return c.Status(400).SendString("invalid store region")
+	}
+	weekday, ok := validate.Weekday(c.FormValue("weekday"))
+	if !ok {
+		return c.Status(400).SendString("invalid weekday")
+	}
+	if err := h.Pickup.DeleteHours(region, weekday); err != nil {
+		applog.Error(c, "admin.pickups.hours.delete.fail", err, map[string]any{"region": region, "weekday": weekday})
+		return c.Status(500).SendString("could not delete pickup hours")
+	}
+	applog.Audit(c, "admin.pickups.hours.delete", map[string]any{"region": region, "weekday": weekday, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/pickups")
+}
+
+// POST /admin/orders/:id/pickup/ready
+func (h *AdminHandler) PickupReady(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if h.Pickup == nil {
+		return c.Status(404).SendString("pickup is not configured")
+	}
+	_, err := h.Pickup.Ready(id, adminUserID(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Order not found"})
+	}
+	if errors.Is(err, services.ErrPickup) {
+		return c.Status(400).SendString(err.Error())
+	}
+	if err != nil {
+		applog.Error(c, "admin.orders.pickup.ready.fail", err, map[string]any{"order_id": id})
+		return c.Status(500).SendString("could not mark the order ready")
+	}
+	applog.Audit(c, "admin.orders.pickup.ready", map[string]any{"order_id": id, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/orders/" + id)
+}
+
+// POST /admin/orders/:id/pickup/collected (code, note) hands the order
+// over once the customer's code and ID have been checked.
+func (h *AdminHandler) PickupCollected(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if h.Pickup == nil {
+		return c.Status(404).SendString("pickup is not configured")
+	}
+	code, ok := validate.PickupCode(c.FormValue("code"))
+	if !ok {
+		return c.Status(400).SendString("pickup code must look like ABCD-2345")
+	}
+	note, ok := validate.PickupNote(c.FormValue("note"))
+	if !ok {
+		return c.Status(400).SendString("note how the collector's ID was checked (3-200 characters)")
+	}
+	_, err := h.Pickup.Collect(id, adminUserID(c), code, note)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Order not found"})
+	}
+	if errors.Is(err, services.ErrPickup) {
+		applog.Security(c, "admin.orders.pickup.collect.refused", map[string]any{"order_id": id, "admin_id": adminUserID(c), "error": err.Error()})
+		return c.Status(400).SendString(err.Error())
+	}
+	if err != nil {
+		applog.Error(c, "admin.orders.pickup.collect.fail", err, map[string]any{"order_id": id})
+		return c.Status(500).SendString("could not record the pickup; status unchanged")
+	}
+	applog.Audit(c, "admin.orders.pickup.collect", map[string]any{"order_id": id, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/orders/" + id)
+}
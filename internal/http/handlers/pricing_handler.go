@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"retrobytes/internal/domain"
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/services"
+	"retrobytes/internal/validate"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GET /admin/products/:id/pricing
+func (h *AdminHandler) PricingPage(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Product not found"})
+	}
+	p, err := h.Prods.Get(id)
+	if err != nil {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Product not found"})
+	}
+	history, err := h.Pricing.History(id)
+	if err != nil {
+		applog.Error(c, "admin.products.pricing.fail", err, map[string]any{"product": id})
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load price history"})
+	}
+	return render(c, "admin_product_pricing", fiber.Map{
+		"P": p, "History": history, "Err": c.Query("err"),
+		"Now": time.Now().UTC().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// POST /admin/products/:id/pricing/base (price)
+func (h *AdminHandler) SetBasePrice(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid product")
+	}
+	price, ok := validate.Price(c.FormValue("price"))
+	if !ok {
+		return c.Redirect("/admin/products/" + id + "/pricing?err=price")
+	}
+	adminID := adminUserID(c)
+	if err := h.Pricing.SetBase(id, price, adminID, time.Now()); err != nil {
+		applog.Error(c, "admin.products.price.fail", err, map[string]any{"product": id})
+		return c.Status(500).SendString("could not update price")
+	}
+	applog.Audit(c, "admin.products.price.set", map[string]any{"product": id, "price": price, "admin_id": adminID})
+	return c.Redirect("/admin/products/" + id + "/pricing")
+}
+
+// POST /admin/products/:id/pricing/sales (price, starts, ends; UTC)
+func (h *AdminHandler) ScheduleSale(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid product")
+	}
+	back := "/admin/products/" + id + "/pricing"
+	price, ok := validate.Price(c.FormValue("price"))
+	if !ok {
+		return c.Redirect(back + "?err=price")
+	}
+	starts, ok1 := validate.DateTime(c.FormValue("starts"))
+	ends, ok2 := validate.DateTime(c.FormValue("ends"))
+	if !ok1 || !ok2 {
+		return c.Redirect(back + "?err=window")
+	}
+	adminID := adminUserID(c)
+	if err := h.Pricing.ScheduleSale(id, price, starts, ends, adminID, time.Now()); err != nil {
+		switch {
+		case errors.Is(err, services.ErrSaleWindow):
+			return c.Redirect(back + "?err=window")
+		case errors.Is(err, services.ErrSaleOverlap):
+			return c.Redirect(back + "?err=overlap")
+		case errors.Is(err, services.ErrSaleNotBelow):
+			return c.Redirect(back + "?err=not_below")
+		}
+		applog.Error(c, "admin.products.sale.fail", err, map[string]any{"product": id})
+		return c.Status(500).SendString("could not schedule sale")
+	}
+	applog.Audit(c, "admin.products.sale.schedule", map[string]any{
+		"product": id, "price": price, "starts": starts.Format(time.RFC3339), "ends": ends.Format(time.RFC3339), "admin_id": adminID,
+	})
+	return c.Redirect(back)
+}
+
+// POST /admin/products/:id/pricing/sales/:sid/end
+func (h *AdminHandler) EndSale(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid product")
+	}
+	saleID, err := strconv.ParseInt(c.Params("sid"), 10, 64)
+	if err != nil || saleID <= 0 {
+		return c.Status(400).SendString("invalid sale")
+	}
+	if err := h.Pricing.EndSale(id, saleID, time.Now()); err != nil {
+		if errors.Is(err, services.ErrSaleNotFound) {
+			return c.Redirect("/admin/products/" + id + "/pricing?err=not_found")
+		}
+		applog.Error(c, "admin.products.sale.end.fail", err, map[string]any{"product": id, "sale_id": saleID})
+		return c.Status(500).SendString("could not end sale")
+	}
+	applog.Audit(c, "admin.products.sale.end", map[string]any{"product": id, "sale_id": saleID, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/products/" + id + "/pricing")
+}
+
+func adminUserID(c *fiber.Ctx) string {
+	if u, ok := c.Locals("user").(*domain.User); ok && u != nil {
+		return u.ID
+	}
+	return ""
+}
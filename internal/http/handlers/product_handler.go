@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"strconv"
+
+	"retrobytes/internal/domain"
 	"retrobytes/internal/log"
 	"retrobytes/internal/services"
 	"retrobytes/internal/validate"
@@ -9,7 +12,13 @@ import (
 )
 
 type ProductHandler struct {
-	Catalog *services.CatalogService
+	Catalog   *services.CatalogService
+	Analytics *services.SearchAnalyticsService
+	Attrs     *services.AttributeService
+	Reviews   *services.ReviewService
+	Questions *services.QuestionService
+	Recs      *services.RecommendationService
+	Views     *services.ViewService
 }
 
 func (h *ProductHandler) Detail(c *fiber.Ctx) error {
@@ -22,5 +31,77 @@ func (h *ProductHandler) Detail(c *fiber.Ctx) error {
 	if err != nil || p.ID == "" {
 		return c.Status(404).Render("notfound", fiber.Map{"Message": "This item is no longer available"})
 	}
-	return render(c, "product", fiber.Map{"P": p})
+	// Variants are shown on their parent's page with the variant preselected
+	if parent, err := h.Catalog.ParentOf(p.ID); err == nil && parent != "" {
+		return c.Redirect("/product/" + parent + "?variant=" + p.ID)
+	}
+	// Click-through from /search results (?sref=<search id>)
+	if h.Analytics != nil {
+		if ref, err := strconv.ParseInt(c.Query("sref"), 10, 64); err == nil && ref > 0 {
+			if err := h.Analytics.RecordClick(ref, p.ID); err != nil {
+				log.Error(c, "search.click.fail", err, map[string]any{"product": p.ID})
+			}
+		}
+	}
+	data := fiber.Map{"P": p}
+	if h.Views != nil {
+		sid, uid := ensureSID(c), ""
+		if u, ok := c.Locals("user").(*domain.User); ok && u != nil {
+			uid = u.ID
+		}
+		if err := h.Views.Record(p.ID, sid, uid); err != nil {
+			log.Error(c, "product.view.record.fail", err, map[string]any{"product": p.ID})
+		}
+		if recent, err := h.Views.Recent(sid, uid, p.ID, 6); err == nil {
+			data["RecentlyViewed"] = recent
+		} else {
+			log.Error(c, "product.recent.fail", err, map[string]any{"product": p.ID})
+		}
+	}
+	if cond, err := h.Catalog.Condition(p); err == nil {
+		data["Condition"] = cond
+	} else {
+		log.Error(c, "product.condition.fail", err, map[string]any{"product": p.ID})
+	}
+	if h.Attrs != nil {
+		if attrs, err := h.Attrs.ForProduct(p, false); err == nil {
+			data["Attributes"] = attrs
+		} else {
+			log.Error(c, "product.attributes.fail", err, map[string]any{"product": p.ID})
+		}
+	}
+	if variants, err := h.Catalog.Variants(p.ID); err == nil && len(variants) > 0 {
+		data["Variants"] = variants
+		data["Selected"] = c.Query("variant")
+	}
+	if h.Reviews != nil {
+		if rating, list, err := h.Reviews.ForProduct(p.ID); err == nil {
+			data["Rating"], data["Reviews"] = rating, list
+		} else {
+			log.Error(c, "product.reviews.fail", err, map[string]any{"product": p.ID})
+		}
+		if u, ok := c.Locals("user").(*domain.User); ok && u != nil {
+			data["CanReview"], _ = h.Reviews.CanReview(u.ID, p.ID)
+		}
+		data["ReviewMsg"] = c.Query("review")
+	}
+	if h.Questions != nil {
+		if qs, err := h.Questions.ForProduct(p.ID); err == nil {
+			data["Questions"] = qs
+		} else {
+			log.Error(c, "product.questions.fail", err, map[string]any{"product": p.ID})
+		}
+		if u, ok := c.Locals("user").(*domain.User); ok && u != nil {
+			data["MyQuestions"], _ = h.Questions.Mine(u.ID, p.ID)
+		}
+		data["QuestionMsg"] = c.Query("question")
+	}
+	if h.Recs != nil {
+		if recs, err := h.Recs.AlsoBought(p.ID, 4); err == nil {
+			data["AlsoBought"] = recs
+		} else {
+			log.Error(c, "product.recommendations.fail", err, map[string]any{"product": p.ID})
+		}
+	}
+	return render(c, "product", data)
 }
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+	"retrobytes/internal/validate"
+)
+
+// GET /admin/promotions
+func (h *AdminHandler) PromotionsPage(c *fiber.Ctx) error {
+	list, err := h.Promos.List()
+	if err != nil {
+		applog.Error(c, "admin.promotions.list.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load promotions"})
+	}
+	cats, _ := h.Cats.List()
+	return render(c, "admin_promotions", fiber.Map{
+		"Promotions": list, "Categories": cats,
+		"Promo": repos.Promotion{Kind: "PERCENT", Scope: "ALL", Active: true},
+	})
+}
+
+// GET /admin/promotions/:id (edit form and redemptions report)
+func (h *AdminHandler) PromotionPage(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Promotion not found"})
+	}
+	p, err := h.Promos.Get(id)
+	if err != nil {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Promotion not found"})
+	}
+	reds, err := h.Promos.Redemptions(id)
+	if err != nil {
+		applog.Error(c, "admin.promotions.redemptions.fail", err, map[string]any{"promotion": id})
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load redemptions"})
+	}
+	total := 0.0
+	for _, r := range reds {
+		total += r.Amount
+	}
+	cats, _ := h.Cats.List()
+	return render(c, "admin_promotion", fiber.Map{
+		"Promo": p, "Redemptions": reds, "Discounted": total, "Categories": cats, "Err": c.Query("err"),
+	})
+}
+
+// POST /admin/promotions (create) and /admin/promotions/:id (update)
+func (h *AdminHandler) SavePromotion(c *fiber.Ctx) error {
+	p, msg := promotionForm(c)
+	if msg != "" {
+		return c.Status(400).SendString(msg)
+	}
+	if raw := c.Params("id"); raw != "" {
+		id, ok := validate.ID(raw)
+		if !ok {
+			return c.Status(400).SendString("invalid promotion")
+		}
+		if _, err := h.Promos.Get(id); err != nil {
+			return c.Status(404).SendString("promotion not found")
+		}
+		p.ID = id
+	}
+	id, err := h.Promos.Save(p)
+	if err != nil {
+		if errors.Is(err, services.ErrPromoConfig) {
+			return c.Status(400).SendString(err.Error())
+		}
+		applog.Error(c, "admin.promotions.save.fail", err, map[string]any{"code": p.Code})
+		return c.Status(500).SendString("could not save promotion")
+	}
+	applog.Audit(c, "admin.promotions.save", map[string]any{"promotion": id, "code": p.Code, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/promotions/" + id)
+}
+
+// POST /admin/promotions/:id/active (active=1|0)
+func (h *AdminHandler) SetPromotionActive(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid promotion")
+	}
+	active := c.FormValue("active") == "1"
+	if err := h.Promos.SetActive(id, active); err != nil {
+		applog.Error(c, "admin.promotions.active.fail", err, map[string]any{"promotion": id})
+		return c.Status(500).SendString("could not update promotion")
+	}
+	applog.Audit(c, "admin.promotions.active", map[string]any{"promotion": id, "active": active, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/promotions")
+}
+
+// POST /admin/promotions/:id/delete (only never-redeemed codes)
+func (h *AdminHandler) DeletePromotion(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid promotion")
+	}
+	if err := h.Promos.Delete(id); err != nil {
+		if errors.Is(err, services.ErrPromoInUse) {
+			return c.Redirect("/admin/promotions/" + id + "?err=in_use")
+		}
+		applog.Error(c, "admin.promotions.delete.fail", err, map[string]any{"promotion": id})
+		return c.Status(500).SendString("could not delete promotion")
+	}
+	applog.Audit(c, "admin.promotions.delete", map[string]any{"promotion": id, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/promotions")
+}
+
+// promotionForm reads the create/edit form; msg is non-empty on bad input.
+func promotionForm(c *fiber.Ctx) (p repos.Promotion, msg string) {
+	var ok bool
+	if p.Code, ok = validate.PromoCode(c.FormValue("code")); !ok {
+		return p, "code must be 3-32 letters, digits, - or _"
+	}
+	if d := strings.TrimSpace(c.FormValue("description")); d != "" {
+		if p.Description, ok = validate.Label(d); !ok {
+			return p, "description must be at most 40 characters"
+		}
+	}
+	p.Kind = c.FormValue("kind")
+	if p.Amount, ok = validate.Price(c.FormValue("amount")); !ok {
+		return p, "invalid amount"
+	}
+	if raw := c.FormValue("min_subtotal"); raw != "" {
+		if p.MinSubtotal, ok = validate.Price(raw); !ok {
+			return p, "invalid minimum subtotal"
+		}
+	}
+	p.Scope = c.FormValue("scope", "ALL")
+	switch p.Scope {
+	case "CATEGORY":
+		p.ScopeID, ok = validate.ID(c.FormValue("category_id"))
+	case "PRODUCT":
+		p.ScopeID, ok = validate.ID(c.FormValue("product_id"))
+	}
+	if !ok {
+		return p, "invalid category or product"
+	}
+	for field, dst := range map[string]*int{"max_uses": &p.MaxUses, "max_uses_per_customer": &p.MaxPerCustomer} {
+		if raw := strings.TrimSpace(c.FormValue(field)); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 || n > 1000000 {
+				return p, "usage limits must be whole numbers (empty for unlimited)"
+			}
+			*dst = n
+		}
+	}
+	for field, dst := range map[string]*string{"starts": &p.StartsAt, "ends": &p.EndsAt} {
+		if raw := c.FormValue(field); raw != "" {
+			t, ok := validate.DateTime(raw)
+			if !ok {
+				return p, "invalid start or end time"
+			}
+			*dst = t.Format("2006-01-02 15:04:05")
+		}
+	}
+	p.Active = c.FormValue("active") == "1"
+	return p, ""
+}
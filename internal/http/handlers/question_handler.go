@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"retrobytes/internal/domain"
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/services"
+	"retrobytes/internal/validate"
+)
+
+type QuestionHandler struct {
+	Questions *services.QuestionService
+}
+
+// POST /product/:id/questions (question)
+func (h *QuestionHandler) Ask(c *fiber.Ctx) error {
+	u, _ := c.Locals("user").(*domain.User)
+	if u == nil {
+		return c.Redirect("/login")
+	}
+	pid, ok := validate.ID(c.Params("id"))
+	if !ok {
+		applog.Security(c, "validation.fail", map[string]any{"field": "product"})
+		return c.Status(fiber.StatusNotFound).Render("notfound", fiber.Map{"Message": "This item is no longer available"})
+	}
+	back := "/product/" + pid
+	text, ok := validate.Question(c.FormValue("question"))
+	if !ok {
+		applog.Security(c, "validation.fail", map[string]any{"field": "question", "product": pid})
+		return c.Redirect(back + "?question=invalid#questions")
+	}
+	id, err := h.Questions.Ask(u.ID, pid, text)
+	if err != nil {
+		if errors.Is(err, services.ErrQuestionRateLimited) {
+			applog.Security(c, "question.reject", map[string]any{"product": pid, "reason": "rate_limited"})
+			return c.Redirect(back + "?question=rate_limited#questions")
+		}
+		applog.Error(c, "question.ask.fail", err, map[string]any{"product": pid})
+		return c.Redirect(back + "?question=failed#questions")
+	}
+	applog.Audit(c, "question.ask", map[string]any{"question_id": id, "product": pid})
+	return c.Redirect(back + "?question=sent#questions")
+}
+
+// GET /admin/questions?status=OPEN|ANSWERED|HIDDEN
+func (h *AdminHandler) QuestionsPage(c *fiber.Ctx) error {
+	status := c.Query("status")
+	if status != "ANSWERED" && status != "HIDDEN" {
+		status = "OPEN"
+	}
+	list, err := h.Questions.Queue(status)
+	if err != nil {
+		applog.Error(c, "admin.questions.list.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load questions"})
+	}
+	return render(c, "admin_questions", fiber.Map{"Questions": list, "Status": status})
+}
+
+// POST /admin/questions/:id/answer (answer)
+func (h *AdminHandler) AnswerQuestion(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid question")
+	}
+	answer, ok := validate.Answer(c.FormValue("answer"))
+	if !ok {
+		return c.Status(400).SendString("answer must be 2-2000 characters")
+	}
+	var adminID string
+	if u, ok := c.Locals("user").(*domain.User); ok && u != nil {
+		adminID = u.ID
+	}
+	if err := h.Questions.Answer(id, answer, adminID); err != nil {
+		if errors.Is(err, services.ErrQuestionNotFound) {
+			return c.Status(404).SendString("question not found")
+		}
+		applog.Error(c, "admin.questions.answer.fail", err, map[string]any{"question_id": id})
+		return c.Status(500).SendString("could not save answer")
+	}
+	applog.Audit(c, "admin.questions.answer", map[string]any{"question_id": id, "admin_id": adminID})
+	return c.Redirect("/admin/questions")
+}
+
+// POST /admin/questions/:id/hide
+func (h *AdminHandler) HideQuestion(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid question")
+	}
+	if err := h.Questions.Hide(id); err != nil {
+		if errors.Is(err, services.ErrQuestionNotFound) {
+			return c.Status(404).SendString("question not found")
+		}
+		applog.Error(c, "admin.questions.hide.fail", err, map[string]any{"question_id": id})
+		return c.Status(500).SendString("could not hide question")
+	}
+	applog.Audit(c, "admin.questions.hide", map[string]any{"question_id": id})
+	return c.Redirect("/admin/questions")
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+	"retrobytes/internal/validate"
+)
+
+// returnData adds the order page's returns section: past requests and, while
+// the window is open, the lines that can still be sent back.
+func (h *OrderHandler) returnData(c *fiber.Ctx, o repos.OrderRow, data fiber.Map) {
+	rets, err := h.Returns.ForOrder(o.ID)
+	if err != nil {
+		applog.Error(c, "order.returns.fail", err, map[string]any{"order_id": o.ID})
+		return
+	}
+	data["Returns"], data["ReturnMsg"] = rets, c.Query("return")
+	lines, deadline, err := h.Returns.Returnable(o.ID, time.Now().UTC())
+	if err != nil {
+		if !errors.Is(err, services.ErrReturn) {
+			applog.Error(c, "order.returns.fail", err, map[string]any{"order_id": o.ID})
+		}
+		if !deadline.IsZero() {
+			data["ReturnClosed"] = deadline.Format("January 2, 2006")
+		}
+		return
+	}
+	for _, l := range lines {
+		if l.Returnable > 0 {
+			data["ReturnLines"], data["ReturnReasons"] = lines, services.ReturnReasons
+			data["MaxReturnPhotos"], data["MaxReturnPhotoKB"] = services.MaxReturnPhotos, services.MaxReturnPhotoBytes>>10
+			break
+		}
+	}
+}
+
+// POST /order/:id/returns (multipart: qty_<product id>, reason, details, photos)
+func (h *OrderHandler) RequestReturn(c *fiber.Ctx) error {
+	oid := c.Params("id")
+	o, _, err := h.Repo.Get(oid)
+	if err != nil || h.Returns == nil {
+		return c.Status(fiber.StatusNotFound).Render("notfound", fiber.Map{"Message": "Order not found"})
+	}
+	if !h.canManageOrder(c, o) {
+		applog.Security(c, "access.denied.order", map[string]any{"order_id": oid})
+		return c.Status(fiber.StatusNotFound).Render("notfound", fiber.Map{"Message": "Order not found"})
+	}
+	details, ok := validate.ReturnDetails(c.FormValue("details"))
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).SendString("please describe the problem in at most 1000 characters")
+	}
+	lines, _, err := h.Returns.Returnable(oid, time.Now().UTC())
+	if err != nil && !errors.Is(err, services.ErrReturn) {
+		applog.Error(c, "order.return.fail", err, map[string]any{"order_id": oid})
+		return c.Status(fiber.StatusInternalServerError).SendString("could not request the return")
+	}
+	qty := map[string]int{}
+	for _, l := range lines {
+		if v := strings.TrimSpace(c.FormValue("qty_" + l.ProductID)); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString("invalid quantity for " + l.Title)
+			}
+			qty[l.ProductID] = n
+		}
+	}
+	var photos [][]byte
+	if form, err := c.MultipartForm(); err == nil {
+		var total int64
+		for _, fh := range form.File["photos"] {
+			total += fh.Size
+		}
+		if total > services.MaxReturnPhotoBytes {
+			return c.Status(fiber.StatusBadRequest).SendString("photos must be under " + strconv.Itoa(services.MaxReturnPhotoBytes>>10) + " KB in total")
+		}
+		for _, fh := range form.File["photos"] {
+			if fh.Size == 0 {
+				continue
+			}
+			f, err := fh.Open()
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString("could not read upload")
+			}
+			data, err := io.ReadAll(io.LimitReader(f, services.MaxReturnPhotoBytes+1))
+			f.Close()
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString("could not read upload")
+			}
+			photos = append(photos, data)
+		}
+	}
+
+	id, err := h.Returns.Request(oid, qty, c.FormValue("reason"), details, photos, time.Now().UTC())
+	if errors.Is(err, services.ErrReturn) {
+		return c.Status(fiber.StatusBadRequest).SendString(strings.TrimPrefix(err.Error(), services.ErrReturn.Error()+": "))
+	}
+	if errors.Is(err, services.ErrImageType) || errors.Is(err, services.ErrImageDimensions) || errors.Is(err, services.ErrImageTooLarge) {
+		applog.Security(c, "order.return.photo.reject", map[string]any{"order_id": oid, "error": err.Error()})
+		return c.Status(fiber.StatusBadRequest).SendString("photos must be JPEG or PNG images")
+	}
+	if err != nil {
+		applog.Error(c, "order.return.fail", err, map[string]any{"order_id": oid})
+		return c.Status(fiber.StatusInternalServerError).SendString("could not request the return")
+	}
+	applog.Audit(c, "order.return.request", map[string]any{"order_id": oid, "return_id": id, "lines": qty, "photos": len(photos)})
+	return c.Redirect("/order/" + oid + "?return=requested")
+}
+
+// GET /order/:id/returns/:rid/photos/:name
+func (h *OrderHandler) ReturnPhoto(c *fiber.Ctx) error {
+	o, _, err := h.Repo.Get(c.Params("id"))
+	if err != nil || h.Returns == nil || !h.canManageOrder(c, o) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	ret, err := h.Returns.Get(c.Params("rid"))
+	if err != nil || ret.OrderID != o.ID {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return sendReturnPhoto(c, h.Returns, ret)
+}
+
+func sendReturnPhoto(c *fiber.Ctx, svc *services.ReturnService, ret repos.Return) error {
+	path, ok := svc.PhotoPath(ret, c.Params("name"))
+	if !ok {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	if err := c.SendFile(path); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderCacheControl, "private, max-age=3600")
+	return nil
+}
+
+// GET /admin/returns?status=
+func (h *AdminHandler) ReturnsPage(c *fiber.Ctx) error {
+	status := strings.ToUpper(c.Query("status"))
+	rets, err := h.Returns.List(status)
+	if err != nil {
+		applog.Error(c, "admin.returns.list.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load returns"})
+	}
+	return render(c, "admin_returns", fiber.Map{"Returns": rets, "Status": status})
+}
+
+// GET /admin/returns/:id
+func (h *AdminHandler) ReturnPage(c *fiber.Ctx) error {
+	ret, err := h.Returns.Get(c.Params("id"))
+	if err != nil {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Return not found"})
+	}
+	o, _, err := h.OrderRepo.Get(ret.OrderID)
+	if err != nil {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Order not found"})
+	}
+	return render(c, "admin_return", fiber.Map{
+		"Return": ret, "Order": o, "ReasonLabel": services.ReturnReasonLabel(ret.Reason), "Err": c.Query("err"),
+	})
+}
+
+// POST /admin/returns/:id/:action (approve|reject|receive|inspect, note)
+func (h *AdminHandler) AdvanceReturn(c *fiber.Ctx) error {
+	id, action := c.Params("id"), c.Params("action")
+	note, ok := validate.ReturnDetails(c.FormValue("note"))
+	if !ok {
+		return c.Status(400).SendString("note must be at most 1000 characters")
+	}
+	ret, err := h.Returns.Advance(id, action, note)
+	if errors.Is(err, services.ErrReturn) {
+		return c.Status(400).SendString(err.Error())
+	}
+	if err != nil {
+		applog.Error(c, "admin.returns."+action+".fail", err, map[string]any{"return_id": id})
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Return not found"})
+	}
+	applog.Audit(c, "admin.returns."+action, map[string]any{"return_id": id, "order_id": ret.OrderID, "status": ret.Status, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/returns/" + id)
+}
+
+// POST /admin/returns/:id/resolve (disposition=RESTOCK|WRITE_OFF) refunds
+// the returned lines.
+func (h *AdminHandler) ResolveReturn(c *fiber.Ctx) error {
+	id := c.Params("id")
+	ret, f, err := h.Returns.Resolve(id, adminUserID(c), c.FormValue("disposition"))
+	if errors.Is(err, services.ErrReturn) || errors.Is(err, services.ErrRefund) || errors.Is(err, services.ErrPaymentState) {
+		return c.Status(400).SendString(err.Error())
+	}
+	if err != nil {
+		applog.Error(c, "admin.returns.resolve.fail", err, map[string]any{"return_id": id})
+		return c.Status(500).SendString("could not refund the return")
+	}
+	applog.Audit(c, "admin.returns.resolve", map[string]any{
+		"return_id": id, "order_id": ret.OrderID, "disposition": ret.Disposition,
+		"refund_id": f.ID, "amount": f.Amount, "admin_id": adminUserID(c),
+	})
+	return c.Redirect("/admin/returns/" + id)
+}
+
+// GET /admin/returns/:id/photos/:name
+func (h *AdminHandler) ReturnPhoto(c *fiber.Ctx) error {
+	ret, err := h.Returns.Get(c.Params("id"))
+	if err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return sendReturnPhoto(c, h.Returns, ret)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"retrobytes/internal/domain"
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/services"
+	"retrobytes/internal/validate"
+)
+
+type ReviewHandler struct {
+	Reviews *services.ReviewService
+}
+
+// POST /product/:id/reviews (rating, title, body)
+func (h *ReviewHandler) Submit(c *fiber.Ctx) error {
+	u, _ := c.Locals("user").(*domain.User)
+	if u == nil {
+		return c.Redirect("/login")
+	}
+	pid, ok := validate.ID(c.Params("id"))
+	if !ok {
+		applog.Security(c, "validation.fail", map[string]any{"field": "product"})
+		return c.Status(fiber.StatusNotFound).Render("notfound", fiber.Map{"Message": "This item is no longer available"})
+	}
+	back := "/product/" + pid
+
+	rating, ok1 := validate.Rating(c.FormValue("rating"))
+	title, ok2 := validate.ReviewTitle(c.FormValue("title"))
+	body, ok3 := validate.ReviewBody(c.FormValue("body"))
+	if !ok1 || !ok2 || !ok3 {
+		applog.Security(c, "validation.fail", map[string]any{"field": "review", "product": pid})
+		return c.Redirect(back + "?review=invalid#reviews")
+	}
+
+	id, err := h.Reviews.Submit(u.ID, pid, rating, title, body)
+	if err != nil {
+		code := "failed"
+		switch {
+		case errors.Is(err, services.ErrReviewNotPurchased):
+			code = "not_purchased"
+		case errors.Is(err, services.ErrReviewDuplicate):
+			code = "duplicate"
+		case errors.Is(err, services.ErrReviewRateLimited):
+			code = "rate_limited"
+		default:
+			applog.Error(c, "review.submit.fail", err, map[string]any{"product": pid})
+		}
+		if code != "failed" {
+			applog.Security(c, "review.reject", map[string]any{"product": pid, "reason": code})
+		}
+		return c.Redirect(back + "?review=" + code + "#reviews")
+	}
+	applog.Audit(c, "review.submit", map[string]any{"review_id": id, "product": pid, "rating": rating})
+	return c.Redirect(back + "?review=pending#reviews")
+}
+
+// GET /admin/reviews?status=PENDING|APPROVED|REJECTED
+func (h *AdminHandler) ReviewsPage(c *fiber.Ctx) error {
+	status := c.Query("status")
+	if status != "APPROVED" && status != "REJECTED" {
+		status = "PENDING"
+	}
+	list, err := h.Reviews.Queue(status)
+	if err != nil {
+		applog.Error(c, "admin.reviews.list.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load reviews"})
+	}
+	return render(c, "admin_reviews", fiber.Map{"Reviews": list, "Status": status})
+}
+
+// POST /admin/reviews/:id/moderate (decision=approve|reject)
+func (h *AdminHandler) ModerateReview(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid review")
+	}
+	decision := c.FormValue("decision")
+	if decision != "approve" && decision != "reject" {
+		return c.Status(400).SendString("unknown decision")
+	}
+	var adminID string
+	if u, ok := c.Locals("user").(*domain.User); ok && u != nil {
+		adminID = u.ID
+	}
+	if err := h.Reviews.Moderate(id, decision == "approve", adminID); err != nil {
+		if errors.Is(err, services.ErrReviewNotFound) {
+			return c.Status(404).SendString("review not found")
+		}
+		applog.Error(c, "admin.reviews.moderate.fail", err, map[string]any{"review_id": id})
+		return c.Status(500).SendString("could not update review")
+	}
+	applog.Audit(c, "admin.reviews."+decision, map[string]any{"review_id": id, "admin_id": adminID})
+	return c.Redirect("/admin/reviews")
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"retrobytes/internal/domain"
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/services"
+	"retrobytes/internal/validate"
+)
+
+type SavedSearchHandler struct {
+	Saved *services.SavedSearchService
+}
+
+// POST /saved-searches
+func (h *SavedSearchHandler) Save(c *fiber.Ctx) error {
+	u, _ := c.Locals("user").(*domain.User)
+	if u == nil {
+		return c.Redirect("/login")
+	}
+	q, ok := validate.Q(c.FormValue("q"))
+	if !ok {
+		applog.Security(c, "validation.fail", map[string]any{"field": "q"})
+		return c.Status(fiber.StatusBadRequest).SendString("invalid search")
+	}
+	category := strings.TrimSpace(c.FormValue("category"))
+	if category != "" {
+		if _, ok := validate.ID(category); !ok {
+			applog.Security(c, "validation.fail", map[string]any{"field": "category"})
+			return c.Status(fiber.StatusBadRequest).SendString("invalid category")
+		}
+	}
+	condition := strings.TrimSpace(c.FormValue("condition"))
+	if condition != "" {
+		if _, ok := validate.Condition(condition); !ok {
+			applog.Security(c, "validation.fail", map[string]any{"field": "condition"})
+			return c.Status(fiber.StatusBadRequest).SendString("invalid condition")
+		}
+	}
+	id, err := h.Saved.Save(u.ID, q, category, condition)
+	if err != nil {
+		if errors.Is(err, services.ErrTooManySavedSearches) {
+			return c.Status(fiber.StatusBadRequest).SendString("You can keep up to 20 saved searches. Remove one to add another.")
+		}
+		applog.Error(c, "saved_search.save.fail", err, nil)
+		return c.Status(fiber.StatusInternalServerError).SendString("Could not save search")
+	}
+	applog.Audit(c, "saved_search.save", map[string]any{"id": id, "q": q, "category": category, "condition": condition})
+	return c.Redirect("/saved-searches")
+}
+
+// GET /saved-searches
+func (h *SavedSearchHandler) List(c *fiber.Ctx) error {
+	u, _ := c.Locals("user").(*domain.User)
+	if u == nil {
+		return c.Redirect("/login")
+	}
+	rows, err := h.Saved.List(u.ID)
+	if err != nil {
+		applog.Error(c, "saved_search.list.fail", err, nil)
+		return c.Status(fiber.StatusInternalServerError).Render("notfound", fiber.Map{"Message": "Could not load saved searches"})
+	}
+	type view struct {
+		ID, Query, CategoryID, Condition, CreatedAt, Link string
+	}
+	out := make([]view, 0, len(rows))
+	for _, r := range rows {
+		v := url.Values{"q": {r.Query}}
+		if r.CategoryID != "" {
+			v.Set("category", r.CategoryID)
+		}
+		if r.Condition != "" {
+			v.Set("condition", r.Condition)
+		}
+		cond := ""
+		if r.Condition != "" {
+			cond = domain.ConditionLabel(r.Condition) + " or better"
+		}
+		out = append(out, view{r.ID, r.Query, r.CategoryID, cond, r.CreatedAt, "/search?" + v.Encode()})
+	}
+	return render(c, "saved_searches", fiber.Map{"Searches": out})
+}
+
+// POST /saved-searches/:id/delete
+func (h *SavedSearchHandler) Delete(c *fiber.Ctx) error {
+	u, _ := c.Locals("user").(*domain.User)
+	if u == nil {
+		return c.Redirect("/login")
+	}
+	id := c.Params("id")
+	if _, ok := validate.ID(id); !ok {
+		return c.Status(fiber.StatusBadRequest).SendString("missing id")
+	}
+	if err := h.Saved.Delete(u.ID, id); err != nil {
+		applog.Error(c, "saved_search.delete.fail", err, map[string]any{"id": id})
+		return c.Status(fiber.StatusInternalServerError).SendString("Could not delete saved search")
+	}
+	applog.Audit(c, "saved_search.delete", map[string]any{"id": id})
+	return c.Redirect("/saved-searches")
+}
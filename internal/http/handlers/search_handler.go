@@ -3,6 +3,7 @@ package handlers
 import (
 	"strings"
 
+	"retrobytes/internal/domain"
 	"retrobytes/internal/log"
 	"retrobytes/internal/services"
 	"retrobytes/internal/validate"
@@ -11,14 +12,16 @@ import (
 )
 
 type SearchHandler struct {
-	Catalog *services.CatalogService
+	Catalog   *services.CatalogService
+	Analytics *services.SearchAnalyticsService
+	Attrs     *services.AttributeService
 }
 
 func (h *SearchHandler) Search(c *fiber.Ctx) error {
 	rawQ := c.Query("q")
 	if strings.TrimSpace(rawQ) == "" {
 		// Initial page load: show empty search without errors
-		return render(c, "search", fiber.Map{"Q": "", "Products": []any{}, "Count": 0})
+		return render(c, "search", fiber.Map{"Q": "", "Products": []any{}, "Count": 0, "Grades": domain.Grades})
 	}
 	q, ok := validate.Q(rawQ)
 	if !ok {
@@ -28,6 +31,7 @@ func (h *SearchHandler) Search(c *fiber.Ctx) error {
 		})
 	}
 	q = strings.ToLower(q)
+	var err error
 	category := strings.TrimSpace(c.Query("category"))
 	if category != "" {
 		if _, ok := validate.ID(category); !ok {
@@ -37,7 +41,7 @@ func (h *SearchHandler) Search(c *fiber.Ctx) error {
 			})
 		}
 	}
-	condition := strings.TrimSpace(c.Query("condition")) // FIRST_HAND | SECOND_HAND
+	condition := strings.TrimSpace(c.Query("condition")) // minimum grade, e.g. GOOD
 	if condition != "" {
 		if _, ok := validate.Condition(condition); !ok {
 			log.Security(c, "validation.fail", map[string]any{"field": "condition"})
@@ -47,14 +51,53 @@ func (h *SearchHandler) Search(c *fiber.Ctx) error {
 		}
 	}
 
-	products, err := h.Catalog.Search(q, category, condition, 1, 20)
+	// Structured attribute filters (?attr.<key>=<value>) for the chosen category
+	var filterDefs []domain.AttributeDef
+	attrs := map[string]string{}
+	if h.Attrs != nil && category != "" {
+		raw := map[string]string{}
+		c.Context().QueryArgs().VisitAll(func(k, v []byte) {
+			if key, ok := strings.CutPrefix(string(k), "attr."); ok {
+				raw[key] = string(v)
+			}
+		})
+		if attrs, err = h.Attrs.ParseFilters(category, raw); err != nil {
+			log.Security(c, "validation.fail", map[string]any{"field": "attr"})
+			return c.Status(fiber.StatusBadRequest).Render("search", fiber.Map{
+				"Q": q, "Products": []any{}, "Count": 0, "Err": "Invalid filter",
+			})
+		}
+		filterDefs, _ = h.Attrs.Filterable(category)
+	}
+
+	sort := c.Query("sort")
+	switch sort {
+	case "", "rating", "price_asc", "price_desc":
+	default:
+		sort = ""
+	}
+
+	products, err := h.Catalog.Search(q, category, condition, attrs, sort, 1, 20)
 	if err != nil {
 		log.Error(c, "search.error", err, nil)
 		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load results. Please retry."})
 	}
 
+	// Record the search for /admin/search-insights; failures never block results
+	var searchRef int64
+	if h.Analytics != nil {
+		var uid string
+		if u, ok := c.Locals("user").(*domain.User); ok && u != nil {
+			uid = u.ID
+		}
+		if searchRef, err = h.Analytics.Record(q, category, condition, len(products), c.Cookies("sid"), uid); err != nil {
+			log.Error(c, "search.analytics.fail", err, nil)
+		}
+	}
+
 	return render(c, "search", fiber.Map{
 		"Q": q, "CategoryID": category, "Condition": condition,
-		"Products": products, "Count": len(products),
+		"Products": products, "Count": len(products), "SearchRef": searchRef,
+		"AttrFilters": filterDefs, "AttrValues": attrs, "Grades": domain.Grades, "Sort": sort,
 	})
 }
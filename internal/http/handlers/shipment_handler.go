@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/services"
+	"retrobytes/internal/validate"
+)
+
+// POST /admin/orders/:id/shipments (carrier, tracking_no, shipped_on,
+// qty_<product id>) records a parcel with the units it contains.
+func (h *AdminHandler) CreateShipment(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if h.Shipments == nil {
+		return c.Status(404).SendString("shipments are not configured")
+	}
+	lines, err := h.Shipments.Lines(id)
+	if err != nil {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Order not found"})
+	}
+	var req services.ShipmentRequest
+	var ok bool
+	if req.Carrier, ok = validate.Carrier(c.FormValue("carrier")); !ok {
+		return c.Status(400).SendString("choose a carrier")
+	}
+	if req.TrackingNo, ok = validate.TrackingNo(c.FormValue("tracking_no")); !ok {
+		return c.Status(400).SendString("tracking number must be 6-40 letters, digits or dashes")
+	}
+	if req.ShippedOn, ok = validate.Date(c.FormValue("shipped_on")); !ok {
+		return c.Status(400).SendString("invalid ship date")
+	}
+	if req.ShippedOn.After(time.Now().AddDate(0, 0, 1)) {
+		return c.Status(400).SendString("ship date is in the future")
+	}
+	req.Qty = map[string]int{}
+	for _, l := range lines {
+		v := strings.TrimSpace(c.FormValue("qty_" + l.ProductID))
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return c.Status(400).SendString("invalid quantity for " + l.Title)
+		}
+		if n > 0 {
+			req.Qty[l.ProductID] = n
+		}
+	}
+
+	sh, err := h.Shipments.Ship(id, adminUserID(c), req)
+	if errors.Is(err, services.ErrShipment) {
+		return c.Status(400).SendString(err.Error())
+	}
+	if err != nil && sh.ID == "" {
+		applog.Error(c, "admin.orders.shipment.fail", err, map[string]any{"order_id": id})
+		return c.Status(500).SendString("could not record the shipment; status unchanged")
+	}
+	applog.Audit(c, "admin.orders.shipment", map[string]any{
+		"order_id": id, "shipment_id": sh.ID, "carrier": sh.Carrier, "tracking_no": sh.TrackingNo,
+		"lines": req.Qty, "admin_id": adminUserID(c),
+	})
+	if err != nil {
+		applog.Error(c, "admin.orders.payment.capture.fail", err, map[string]any{"order_id": id})
+		return c.Status(500).SendString("shipment recorded, but the payment could not be captured; capture it on the order page")
+	}
+	return c.Redirect("/admin/orders/" + id)
+}
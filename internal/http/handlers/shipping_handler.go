@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+	"retrobytes/internal/validate"
+)
+
+// GET /admin/shipping
+func (h *AdminHandler) ShippingPage(c *fiber.Ctx) error {
+	methods, err := h.Shipping.Methods()
+	if err != nil {
+		applog.Error(c, "admin.shipping.list.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load shipping settings"})
+	}
+	zones, err := h.Shipping.Zones()
+	if err != nil {
+		applog.Error(c, "admin.shipping.list.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load shipping settings"})
+	}
+	rates, err := h.Shipping.Rates()
+	if err != nil {
+		applog.Error(c, "admin.shipping.list.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load shipping settings"})
+	}
+	return render(c, "admin_shipping", fiber.Map{"Methods": methods, "Zones": zones, "Rates": rates})
+}
+
+// POST /admin/shipping/methods (code, name, free_over, active, sort)
+func (h *AdminHandler) SaveShippingMethod(c *fiber.Ctx) error {
+	m := repos.ShippingMethod{Code: c.FormValue("code"), Name: c.FormValue("name"), Active: c.FormValue("active") == "1"}
+	if raw := strings.TrimSpace(c.FormValue("free_over")); raw != "" {
+		v, ok := validate.Price(raw)
+		if !ok {
+			return c.Status(400).SendString("invalid free-shipping threshold")
+		}
+		m.FreeOver = &v
+	}
+	if raw := strings.TrimSpace(c.FormValue("sort")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > 1000 {
+			return c.Status(400).SendString("invalid sort order")
+		}
+		m.Sort = n
+	}
+	return h.saveShipping(c, "method", m.Code, h.Shipping.SaveMethod(m))
+}
+
+// POST /admin/shipping/zones (origin, dest, zone)
+func (h *AdminHandler) SaveShippingZone(c *fiber.Ctx) error {
+	zone, err := strconv.Atoi(strings.TrimSpace(c.FormValue("zone")))
+	if err != nil {
+		return c.Status(400).SendString("invalid zone")
+	}
+	z := repos.ShippingZone{Origin: c.FormValue("origin"), DestPrefix: c.FormValue("dest"), Zone: zone}
+	return h.saveShipping(c, "zone", z.Origin+"→"+z.DestPrefix, h.Shipping.SaveZone(z))
+}
+
+// POST /admin/shipping/zones/delete (origin, dest)
+func (h *AdminHandler) DeleteShippingZone(c *fiber.Ctx) error {
+	origin, dest := c.FormValue("origin"), c.FormValue("dest")
+	if err := h.Shipping.DeleteZone(origin, dest); err != nil {
+		applog.Error(c, "admin.shipping.zone.delete.fail", err, map[string]any{"origin": origin, "dest": dest})
+		return c.Status(500).SendString("could not delete zone")
+	}
+	applog.Audit(c, "admin.shipping.zone.delete", map[string]any{"origin": origin, "dest": dest, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/shipping")
+}
+
+// POST /admin/shipping/rates (method, zone, max_weight, price)
+func (h *AdminHandler) SaveShippingRate(c *fiber.Ctx) error {
+	rt, msg := shippingRateForm(c)
+	if msg != "" {
+		return c.Status(400).SendString(msg)
+	}
+	price, ok := validate.Price(c.FormValue("price"))
+	if !ok {
+		return c.Status(400).SendString("invalid price")
+	}
+	rt.Price = price
+	return h.saveShipping(c, "rate", rt.Method, h.Shipping.SaveRate(rt))
+}
+
+// POST /admin/shipping/rates/delete (method, zone, max_weight)
+func (h *AdminHandler) DeleteShippingRate(c *fiber.Ctx) error {
+	rt, msg := shippingRateForm(c)
+	if msg != "" {
+		return c.Status(400).SendString(msg)
+	}
+	if err := h.Shipping.DeleteRate(rt.Method, rt.Zone, rt.MaxWeight); err != nil {
+		applog.Error(c, "admin.shipping.rate.delete.fail", err, map[string]any{"method": rt.Method, "zone": rt.Zone})
+		return c.Status(500).SendString("could not delete rate")
+	}
+	applog.Audit(c, "admin.shipping.rate.delete", map[string]any{"method": rt.Method, "zone": rt.Zone, "max_weight_kg": rt.MaxWeight, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/shipping")
+}
+
+// saveShipping turns the result of a settings write into a response.
+func (h *AdminHandler) saveShipping(c *fiber.Ctx, kind, key string, err error) error {
+	if err != nil {
+		if errors.Is(err, services.ErrShippingConfig) {
+			return c.Status(400).SendString(err.Error())
+		}
+		applog.Error(c, "admin.shipping."+kind+".save.fail", err, map[string]any{kind: key})
+		return c.Status(500).SendString("could not save shipping " + kind)
+	}
+	applog.Audit(c, "admin.shipping."+kind+".save", map[string]any{kind: key, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/shipping")
+}
+
+// shippingRateForm reads the key of a rate bracket; msg is non-empty on bad
+// input.
+func shippingRateForm(c *fiber.Ctx) (rt repos.ShippingRate, msg string) {
+	rt.Method = strings.ToUpper(strings.TrimSpace(c.FormValue("method")))
+	zone, err := strconv.Atoi(strings.TrimSpace(c.FormValue("zone")))
+	if err != nil {
+		return rt, "invalid zone"
+	}
+	rt.Zone = zone
+	w, err := strconv.ParseFloat(strings.TrimSpace(c.FormValue("max_weight")), 64)
+	if err != nil {
+		return rt, "invalid max weight"
+	}
+	rt.MaxWeight = w
+	return rt, ""
+}
+
+// GET /admin/products/:id/shipping
+func (h *AdminHandler) ParcelPage(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Product not found"})
+	}
+	p, err := h.Prods.Get(id)
+	if err != nil {
+		return c.Status(404).Render("notfound", fiber.Map{"Message": "Product not found"})
+	}
+	parcel, err := h.Shipping.Parcel(id)
+	if err != nil {
+		applog.Error(c, "admin.products.parcel.fail", err, map[string]any{"product": id})
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load parcel size"})
+	}
+	billable, _ := h.Shipping.Weight([]services.CartLine{{ProductID: id, Qty: 1}})
+	return render(c, "admin_product_shipping", fiber.Map{"P": p, "Parcel": parcel, "Billable": billable})
+}
+
+// POST /admin/products/:id/shipping (weight_kg, length_cm, width_cm, height_cm)
+func (h *AdminHandler) SaveParcel(c *fiber.Ctx) error {
+	id, ok := validate.ID(c.Params("id"))
+	if !ok {
+		return c.Status(400).SendString("invalid product")
+	}
+	if _, err := h.Prods.Get(id); err != nil {
+		return c.Status(404).SendString("product not found")
+	}
+	p := repos.Parcel{ProductID: id}
+	for field, dst := range map[string]*float64{"weight_kg": &p.WeightKg, "length_cm": &p.LengthCm, "width_cm": &p.WidthCm, "height_cm": &p.HeightCm} {
+		if raw := strings.TrimSpace(c.FormValue(field)); raw != "" {
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return c.Status(400).SendString("weight and dimensions must be numbers")
+			}
+			*dst = v
+		}
+	}
+	if err := h.Shipping.SetParcel(p); err != nil {
+		if errors.Is(err, services.ErrShippingConfig) {
+			return c.Status(400).SendString(err.Error())
+		}
+		applog.Error(c, "admin.products.parcel.save.fail", err, map[string]any{"product": id})
+		return c.Status(500).SendString("could not save parcel size")
+	}
+	applog.Audit(c, "admin.products.parcel.save", map[string]any{"product": id, "weight_kg": p.WeightKg, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/products/" + id + "/shipping")
+}
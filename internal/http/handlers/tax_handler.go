@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	applog "retrobytes/internal/log"
+	"retrobytes/internal/services"
+	"retrobytes/internal/validate"
+)
+
+// GET /admin/tax
+func (h *AdminHandler) TaxPage(c *fiber.Ctx) error {
+	rates, err := h.Tax.Rates()
+	if err != nil {
+		applog.Error(c, "admin.tax.list.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load tax rates"})
+	}
+	cats, _ := h.Cats.List()
+	return render(c, "admin_tax", fiber.Map{"Rates": rates, "Categories": cats})
+}
+
+// POST /admin/tax creates or updates a rate and its exempt categories.
+func (h *AdminHandler) SaveTaxRate(c *fiber.Ctx) error {
+	rate, ok := validate.Price(c.FormValue("rate"))
+	if !ok {
+		return c.Status(400).SendString("invalid rate")
+	}
+	var exempt []string
+	for _, raw := range c.Request().PostArgs().PeekMulti("exempt") {
+		id, ok := validate.ID(string(raw))
+		if !ok {
+			return c.Status(400).SendString("invalid category")
+		}
+		exempt = append(exempt, id)
+	}
+	prefix := c.FormValue("zip_prefix")
+	if err := h.Tax.Save(prefix, c.FormValue("name"), rate, exempt); err != nil {
+		if errors.Is(err, services.ErrTaxConfig) {
+			return c.Status(400).SendString(err.Error())
+		}
+		applog.Error(c, "admin.tax.save.fail", err, map[string]any{"zip_prefix": prefix})
+		return c.Status(500).SendString("could not save tax rate")
+	}
+	applog.Audit(c, "admin.tax.save", map[string]any{"zip_prefix": prefix, "rate": rate, "exempt": exempt, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/tax")
+}
+
+// POST /admin/tax/:prefix/delete
+func (h *AdminHandler) DeleteTaxRate(c *fiber.Ctx) error {
+	prefix := c.Params("prefix")
+	if err := h.Tax.Delete(prefix); err != nil {
+		applog.Error(c, "admin.tax.delete.fail", err, map[string]any{"zip_prefix": prefix})
+		return c.Status(500).SendString("could not delete tax rate")
+	}
+	applog.Audit(c, "admin.tax.delete", map[string]any{"zip_prefix": prefix, "admin_id": adminUserID(c)})
+	return c.Redirect("/admin/tax")
+}
+
+// GET /admin/tax/report?from=YYYY-MM-DD&to=YYYY-MM-DD (inclusive; defaults
+// to the current month)
+func (h *AdminHandler) TaxReport(c *fiber.Ctx) error {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, -1)
+	for field, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		if raw := c.Query(field); raw != "" {
+			t, err := time.Parse("2006-01-02", raw)
+			if err != nil {
+				return c.Status(400).SendString("dates must be YYYY-MM-DD")
+			}
+			*dst = t
+		}
+	}
+	rows, err := h.Tax.Report(from.Format("2006-01-02"), to.AddDate(0, 0, 1).Format("2006-01-02"))
+	if err != nil {
+		applog.Error(c, "admin.tax.report.fail", err, nil)
+		return c.Status(500).Render("notfound", fiber.Map{"Message": "Could not load tax report"})
+	}
+	var taxable, tax float64
+	for _, r := range rows {
+		taxable += r.Taxable
+		tax += r.Tax
+	}
+	return render(c, "admin_tax_report", fiber.Map{
+		"Rows": rows, "From": from.Format("2006-01-02"), "To": to.Format("2006-01-02"),
+		"Taxable": taxable, "Tax": tax,
+	})
+}
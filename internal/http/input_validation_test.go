@@ -132,7 +132,7 @@ func TestTemplateAutoEscape(t *testing.T) {
 	// Insert a product with XSS-y fields
 	_, _ = db.Exec(`
 		INSERT INTO products(id,category_id,title,description,condition,price,images_json,active)
-		VALUES('xss-1','retro-consoles','<script>alert(1)</script>','<b>desc</b>','SECOND_HAND',9.99,'[]',1)
+		VALUES('xss-1','retro-consoles','<script>alert(1)</script>','<b>desc</b>','GOOD',9.99,'[]',1)
 	`)
 
 	req := httptest.NewRequest("GET", "/product/xss-1", nil)
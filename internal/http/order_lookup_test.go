@@ -0,0 +1,121 @@
+package handlers_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// A guest who lost their session gets back to the order through the emailed
+// signed link, and only to that order.
+func TestGuestOrderLookupLink(t *testing.T) {
+	app, db, ordRepo, _ := newOrderTotalsApp(t)
+
+	sid := "sid-lookup"
+	_, _ = db.Exec(`INSERT INTO carts(id,session_id,updated_at) VALUES(?,?,CURRENT_TIMESTAMP)`, sid, sid)
+	_, _ = db.Exec(`INSERT INTO cart_items(cart_id, product_id, qty, price_at_add, created_at) VALUES(?,?,?,?,CURRENT_TIMESTAMP)`,
+		sid, "gbc-001", 1, 129.99)
+
+	loginResp, _ := app.Test(httptest.NewRequest("GET", "/login", nil))
+	csrfTok := extractCookieTotals(loginResp, "csrf_")
+	post := func(path, as, form string) *http.Response {
+		req := httptest.NewRequest("POST", path, strings.NewReader("csrf="+csrfTok+form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "csrf_", Value: csrfTok})
+		req.AddCookie(&http.Cookie{Name: "sid", Value: as})
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	get := func(path string, cookies ...*http.Cookie) *http.Response {
+		req := httptest.NewRequest("GET", path, nil)
+		req.AddCookie(&http.Cookie{Name: "sid", Value: "sid-new-device"})
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := post("/orders", sid, "&region=20742&email=guest@retrobytes.test&name=Gus&fulfillment=pickup&payment_token=tok_visa"+pickupSlotForm(t, db))
+	oid := strings.TrimPrefix(resp.Header.Get("Location"), "/order/")
+	o, _, err := ordRepo.Get(oid)
+	if err != nil || o.OrderNo == "" {
+		t.Fatalf("placed order: %+v, %v", o, err)
+	}
+	if resp := get("/order/" + oid); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("new device without link: %d", resp.StatusCode)
+	}
+
+	// a wrong address looks the same as a right one but sends nothing
+	if resp := post("/orders/lookup", "sid-new-device", "&order_no="+o.OrderNo+"&email=someone@retrobytes.test"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("lookup with wrong email: %d", resp.StatusCode)
+	}
+	if resp := post("/orders/lookup", "sid-new-device", "&order_no="+strings.ToLower(o.OrderNo)+"&email=Guest@RetroBytes.test"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("lookup: %d", resp.StatusCode)
+	}
+	var bodies []string
+	if err := db.Select(&bodies, `SELECT text_body FROM email_outbox WHERE kind='order_link'`); err != nil || len(bodies) != 1 {
+		t.Fatalf("lookup mails = %d, %v", len(bodies), err)
+	}
+	m := regexp.MustCompile(`/order/` + oid + `\?t=(\S+)`).FindStringSubmatch(bodies[0])
+	if m == nil {
+		t.Fatalf("no link in %q", bodies[0])
+	}
+
+	if resp := get("/order/" + oid + "?t=" + m[1] + "0"); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("tampered link: %d", resp.StatusCode)
+	}
+	resp = get("/order/" + oid + "?t=" + m[1])
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/order/"+oid {
+		t.Fatalf("link: %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	var link *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "order_link" {
+			link = c
+		}
+	}
+	if link == nil || link.Path != "/order/"+oid || !link.HttpOnly {
+		t.Fatalf("order_link cookie = %+v", link)
+	}
+	resp = get("/order/"+oid, &http.Cookie{Name: "order_link", Value: link.Value})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("order page with link cookie: %d", resp.StatusCode)
+	}
+	if body, _ := io.ReadAll(resp.Body); strings.Contains(string(body), "/order/"+oid+"/cancel") {
+		t.Fatal("link view offers to cancel the order")
+	}
+
+	// the link shows the order but does not let whoever holds it change it
+	req := httptest.NewRequest("POST", "/order/"+oid+"/cancel", strings.NewReader("csrf="+csrfTok))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "csrf_", Value: csrfTok})
+	req.AddCookie(&http.Cookie{Name: "sid", Value: "sid-new-device"})
+	req.AddCookie(&http.Cookie{Name: "order_link", Value: link.Value})
+	if resp, err := app.Test(req); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("cancel with link cookie: %v, %v", resp, err)
+	}
+	if o, _, _ := ordRepo.Get(oid); o.Status != "PLACED" {
+		t.Fatalf("link holder canceled the order: %s", o.Status)
+	}
+
+	// the link opens this order only
+	other := "sid-lookup-2"
+	_, _ = db.Exec(`INSERT INTO carts(id,session_id,updated_at) VALUES(?,?,CURRENT_TIMESTAMP)`, other, other)
+	_, _ = db.Exec(`INSERT INTO cart_items(cart_id, product_id, qty, price_at_add, created_at) VALUES(?,?,?,?,CURRENT_TIMESTAMP)`,
+		other, "gbc-001", 1, 129.99)
+	resp = post("/orders", other, "&region=20742&email=guest@retrobytes.test&name=Gus&fulfillment=pickup&payment_token=tok_visa"+pickupSlotForm(t, db))
+	oid2 := strings.TrimPrefix(resp.Header.Get("Location"), "/order/")
+	if resp := get("/order/"+oid2+"?t="+m[1], &http.Cookie{Name: "order_link", Value: link.Value}); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("link reused for another order: %d", resp.StatusCode)
+	}
+}
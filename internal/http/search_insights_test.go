@@ -0,0 +1,108 @@
+package handlers_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	html "github.com/gofiber/template/html/v2"
+
+	"retrobytes/internal/config"
+	"retrobytes/internal/http/handlers"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+// Searches are recorded, clicks are attributed and zero-result queries surface for admins
+func TestSearchInsightsRecordsQueriesAndClicks(t *testing.T) {
+	cfg := config.Config{DBDSN: ":memory:", MediaDir: "../../web/media"}
+	db, err := repos.OpenDB(cfg.DBDSN)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	userRepo := repos.NewUserRepo(db)
+	authSvc := &services.AuthService{Users: userRepo}
+
+	engine := html.New("../../web/templates", ".html")
+	app := fiber.New(fiber.Config{Views: engine})
+	app.Use(requestid.New())
+
+	deps := handlers.NewDeps(db, cfg, authSvc)
+	app.Get("/search", deps.SearchHandler.Search)
+	app.Get("/product/:id", deps.ProductHandler.Detail)
+	adminH := &handlers.AdminHandler{Users: userRepo, Search: services.NewSearchAnalyticsService(repos.NewSearchLogRepo(db))}
+	admin := app.Group("/admin", handlers.RequireAdmin(authSvc))
+	admin.Get("/search-insights", adminH.SearchInsights)
+
+	// Zero-result search
+	if _, err := app.Test(httptest.NewRequest("GET", "/search?q=Famicom++Disk", nil)); err != nil {
+		t.Fatal(err)
+	}
+	// Matching search; result links carry the search ref
+	resp, err := app.Test(httptest.NewRequest("GET", "/search?q=console", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "?sref=") {
+		t.Fatalf("search results should link with sref; body=%s", body)
+	}
+
+	var ref int64
+	if err := db.Get(&ref, `SELECT id FROM search_events WHERE query = 'console'`); err != nil {
+		t.Fatalf("search not recorded: %v", err)
+	}
+	var zero int
+	_ = db.Get(&zero, `SELECT result_count FROM search_events WHERE query = 'famicom disk'`)
+	if zero != 0 {
+		t.Fatalf("want zero results for famicom disk, got %d", zero)
+	}
+
+	// Click-through from the results page
+	if _, err := app.Test(httptest.NewRequest("GET", "/product/nes-001?sref="+strconv.FormatInt(ref, 10), nil)); err != nil {
+		t.Fatal(err)
+	}
+	var clicks int
+	_ = db.Get(&clicks, `SELECT COUNT(*) FROM search_clicks WHERE search_id = ? AND product_id = 'nes-001'`, ref)
+	if clicks != 1 {
+		t.Fatalf("want 1 click, got %d", clicks)
+	}
+	// a second product opened from the same results is one more click, but
+	// the click-through rate counts searches, not clicks
+	if _, err := app.Test(httptest.NewRequest("GET", "/product/snes-001?sref="+strconv.FormatInt(ref, 10), nil)); err != nil {
+		t.Fatal(err)
+	}
+	ins, err := adminH.Search.Insights(30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ins.Searches != 2 || ins.Clicks != 2 || ins.Clicked != 1 || ins.ClickRate != 50 {
+		t.Fatalf("insights = %d searches, %d clicks, %d clicked, %.1f%%", ins.Searches, ins.Clicks, ins.Clicked, ins.ClickRate)
+	}
+	for _, q := range ins.TopQueries {
+		if q.Query == "console" && q.ClickRate != 100 {
+			t.Fatalf("console click-through = %.1f%%, want 100%%", q.ClickRate)
+		}
+	}
+
+	// Admin report lists the zero-result query
+	_ = userRepo.BindSession("sid-admin", "u-admin")
+	req := httptest.NewRequest("GET", "/admin/search-insights", nil)
+	req.AddCookie(&http.Cookie{Name: "sid", Value: "sid-admin"})
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("admin insights expected 200, got %d", resp.StatusCode)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "famicom disk") {
+		t.Fatalf("zero-result query missing from report; body=%s", body)
+	}
+}
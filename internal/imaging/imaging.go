@@ -0,0 +1,278 @@
+// Package imaging sanitizes and resizes uploaded product photos using only
+// the standard library image packages.
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Limits applied to every upload.
+const (
+	MinDimension = 64
+	MaxDimension = 6000
+	MaxPixels    = 24_000_000
+	jpegQuality  = 85
+)
+
+// Widths are the generated variants, smallest first. The largest one is
+// also the cap for the stored original.
+var Widths = []int{320, 800, 1600}
+
+var (
+	ErrFormat     = errors.New("only JPEG or PNG images are allowed")
+	ErrDimensions = errors.New("image dimensions are out of range")
+)
+
+// Variant is one encoded rendition of an upload.
+type Variant struct {
+	Width int // 0 for the full-size original
+	Data  []byte
+}
+
+// Result is a sanitized upload ready to be written to disk.
+type Result struct {
+	Ext      string // ".jpg" or ".png"
+	Width    int
+	Height   int
+	Original []byte // re-encoded, metadata-free, capped at the largest width
+	Variants []Variant
+}
+
+// Process sniffs, validates, re-encodes (dropping EXIF/GPS and any other
+// metadata) and resizes an uploaded image.
+func Process(data []byte) (Result, error) {
+	var ext string
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		ext = ".jpg"
+	case "image/png":
+		ext = ".png"
+	default:
+		return Result{}, ErrFormat
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Result{}, ErrFormat
+	}
+	if cfg.Width < MinDimension || cfg.Height < MinDimension ||
+		cfg.Width > MaxDimension || cfg.Height > MaxDimension ||
+		cfg.Width*cfg.Height > MaxPixels {
+		return Result{}, fmt.Errorf("%w: %dx%d", ErrDimensions, cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Result{}, ErrFormat
+	}
+	img := toRGBA(src)
+	if ext == ".jpg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	largest := Widths[len(Widths)-1]
+	if img.Bounds().Dx() > largest {
+		img = resizeToWidth(img, largest)
+	}
+	out := Result{Ext: ext, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	if out.Original, err = encode(img, ext); err != nil {
+		return Result{}, err
+	}
+	for _, w := range Widths {
+		if w >= out.Width {
+			break // never upscale; the original already serves this size
+		}
+		b, err := encode(resizeToWidth(img, w), ext)
+		if err != nil {
+			return Result{}, err
+		}
+		out.Variants = append(out.Variants, Variant{Width: w, Data: b})
+	}
+	return out, nil
+}
+
+// VariantPath returns the media-relative path of the w-pixel rendition of
+// an image, e.g. products/x/abc.jpg -> products/x/abc_w320.jpg.
+func VariantPath(p string, w int) string {
+	ext := path.Ext(p)
+	return strings.TrimSuffix(p, ext) + fmt.Sprintf("_w%d", w) + ext
+}
+
+// PickWidth maps a requested width to the smallest generated width that is
+// at least as wide, or 0 (the original) when none is.
+func PickWidth(requested int) int {
+	if requested <= 0 {
+		return 0
+	}
+	for _, w := range Widths {
+		if w >= requested {
+			return w
+		}
+	}
+	return 0
+}
+
+func encode(img image.Image, ext string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if ext == ".png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	return buf.Bytes(), err
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	return dst
+}
+
+// resizeToWidth downscales with a box filter, preserving aspect ratio.
+func resizeToWidth(src *image.RGBA, w int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if w >= sw {
+		return src
+	}
+	h := sh * w / sw
+	if h < 1 {
+		h = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, (y+1)*sh/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, (x+1)*sw/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					b += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+			j := dst.PixOffset(x, y)
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(b / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag (1-8) from a JPEG, or 1.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 { // start of scan / end of image
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		seg := data[i+4 : i+2+size]
+		if marker == 0xE1 && len(seg) > 14 && string(seg[:6]) == "Exif\x00\x00" {
+			return exifOrientation(seg[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+	off := int(bo.Uint32(tiff[4:8]))
+	if off+2 > len(tiff) {
+		return 1
+	}
+	n := int(bo.Uint16(tiff[off:]))
+	for k := 0; k < n; k++ {
+		e := off + 2 + k*12
+		if e+12 > len(tiff) {
+			return 1
+		}
+		if bo.Uint16(tiff[e:]) == 0x0112 {
+			if v := int(bo.Uint16(tiff[e+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation bakes an EXIF orientation into the pixels, since the
+// re-encoded file no longer carries the tag.
+func applyOrientation(src *image.RGBA, o int) *image.RGBA {
+	if o <= 1 || o > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2: // mirror horizontal
+				dx, dy = w-1-x, y
+			case 3: // rotate 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirror vertical
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // rotate 90 CW
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotate 90 CCW
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
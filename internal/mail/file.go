@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileMailer drops each message as an .eml file into Dir, for development
+// and for environments where another process picks mail up from disk.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (f *FileMailer) Send(m Message) error {
+	now := time.Now()
+	body, err := encode(f.From, m, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405"), uuid.NewString()[:8])
+	// write under a temporary name so readers never see a partial file
+	tmp := filepath.Join(f.Dir, "."+name)
+	if err := os.WriteFile(tmp, body, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(f.Dir, name))
+}
@@ -0,0 +1,74 @@
+// Package mail renders and delivers transactional email. Messages are
+// normally queued in the outbox first and handed to a Mailer by the
+// delivery worker.
+package mail
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// Message is an email with a plain-text body and an optional HTML
+// alternative.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers messages. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(m Message) error
+}
+
+// LogMailer writes messages to the application log instead of delivering them.
+// It is the default when no real transport is configured.
+type LogMailer struct{}
+
+func (LogMailer) Send(m Message) error {
+	log.Printf("[mail] to=%s subject=%q body=%q", m.To, m.Subject, strings.TrimSpace(m.Text))
+	return nil
+}
+
+// Config selects and configures a transport.
+type Config struct {
+	Transport string // "log" (default), "smtp" or "file"
+	From      string // sender address, e.g. "RetroBytes <orders@retrobytes.test>"
+
+	SMTPAddr     string // host:port of the relay
+	SMTPUsername string // PLAIN auth is only used when set
+	SMTPPassword string
+
+	Dir string // where the file transport drops .eml files
+}
+
+// New returns the transport configured by c.
+func New(c Config) (Mailer, error) {
+	switch c.Transport {
+	case "", "log":
+		return LogMailer{}, nil
+	case "smtp":
+		if c.SMTPAddr == "" {
+			return nil, fmt.Errorf("smtp transport needs an address")
+		}
+		m := &SMTPMailer{Addr: c.SMTPAddr, From: c.From}
+		if c.SMTPUsername != "" {
+			host, _, err := net.SplitHostPort(c.SMTPAddr)
+			if err != nil {
+				return nil, fmt.Errorf("smtp address %q: %w", c.SMTPAddr, err)
+			}
+			m.Auth = smtp.PlainAuth("", c.SMTPUsername, c.SMTPPassword, host)
+		}
+		return m, nil
+	case "file":
+		if c.Dir == "" {
+			return nil, fmt.Errorf("file transport needs a directory")
+		}
+		return &FileMailer{Dir: c.Dir, From: c.From}, nil
+	}
+	return nil, fmt.Errorf("unknown mail transport %q", c.Transport)
+}
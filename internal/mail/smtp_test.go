@@ -0,0 +1,128 @@
+package mail_test
+
+import (
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"retrobytes/internal/mail"
+)
+
+// smtpStandIn accepts one SMTP session and hands back the envelope and data.
+type smtpStandIn struct {
+	addr string
+	got  chan [3]string // from, to, data
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &smtpStandIn{addr: ln.Addr().String(), got: make(chan [3]string, 1)}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		var from, to string
+		_ = tp.PrintfLine("220 localhost ESMTP stand-in")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				_ = tp.PrintfLine("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				from = line[len("MAIL FROM:"):]
+				_ = tp.PrintfLine("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				to = line[len("RCPT TO:"):]
+				_ = tp.PrintfLine("250 OK")
+			case cmd == "DATA":
+				_ = tp.PrintfLine("354 go ahead")
+				data, err := io.ReadAll(tp.DotReader())
+				if err != nil {
+					return
+				}
+				_ = tp.PrintfLine("250 queued")
+				s.got <- [3]string{from, to, string(data)}
+			case cmd == "QUIT":
+				_ = tp.PrintfLine("221 bye")
+				return
+			default:
+				_ = tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	return s
+}
+
+func TestSMTPMailerDelivers(t *testing.T) {
+	srv := newSMTPStandIn(t)
+	m, err := mail.New(mail.Config{Transport: "smtp", SMTPAddr: srv.addr, From: "RetroBytes <orders@retrobytes.test>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.OrderStatusMessage("ann@retrobytes.test", mail.OrderStatus{
+		Name: "Ann <script>", OrderNo: "RB-2026-000001", Status: "SHIPPED", Link: "http://localhost:8081/order/o-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+	got := <-srv.got
+	if got[0] != "<orders@retrobytes.test>" || got[1] != "<ann@retrobytes.test>" {
+		t.Fatalf("envelope = %q -> %q", got[0], got[1])
+	}
+	data := got[2]
+	for _, want := range []string{
+		"Subject: Your order RB-2026-000001 has shipped",
+		"multipart/alternative",
+		"text/plain; charset=utf-8",
+		"text/html; charset=utf-8",
+		"Ann &lt;script&gt;",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("message lacks %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestFileMailerAndHeaderInjection(t *testing.T) {
+	dir := t.TempDir()
+	m, err := mail.New(mail.Config{Transport: "file", Dir: dir, From: "orders@retrobytes.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(mail.Message{To: "ann@retrobytes.test\r\nBcc: all@example.com", Subject: "x", Text: "y"}); err == nil {
+		t.Fatal("recipient with a line break was accepted")
+	}
+	if err := m.Send(mail.Message{To: "ann@retrobytes.test", Subject: "Hello\r\nBcc: all@example.com", Text: "plain body"}); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("files = %v", files)
+	}
+	b, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(b), "Subject: Hello Bcc: all@example.com\r\n") || strings.Contains(string(b), "\r\nBcc:") {
+		t.Fatalf("subject not folded onto one line:\n%s", b)
+	}
+	if !strings.Contains(string(b), "text/plain") || strings.Contains(string(b), "multipart") {
+		t.Fatalf("text-only message should not be multipart:\n%s", b)
+	}
+}
@@ -0,0 +1,156 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Outbox kinds of the templated messages.
+const (
+	KindOrderConfirmation = "order_confirmation"
+	KindOrderStatus       = "order_status"
+	KindPasswordReset     = "password_reset"
+	KindOrderLink         = "order_link"
+	KindShipment          = "shipment"
+	KindPickupReady       = "pickup_ready"
+	KindPickupExpired     = "pickup_expired"
+)
+
+// Each message has a .txt file defining "<kind>.subject" and "<kind>.text"
+// and a .html file defining "<kind>.html".
+//
+//go:embed templates
+var templateFS embed.FS
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html"))
+)
+
+func render(kind, to string, data any) (Message, error) {
+	var subject, text, html bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&subject, kind+".subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := textTemplates.ExecuteTemplate(&text, kind+".text", data); err != nil {
+		return Message{}, err
+	}
+	if err := htmlTemplates.ExecuteTemplate(&html, kind+".html", data); err != nil {
+		return Message{}, err
+	}
+	return Message{To: to, Subject: strings.TrimSpace(subject.String()), Text: strings.TrimSpace(text.String()) + "\n", HTML: html.String()}, nil
+}
+
+// OrderLine is one item on an order confirmation.
+type OrderLine struct {
+	Title string
+	Qty   int
+	Price float64
+}
+
+func (l OrderLine) Amount() float64 { return l.Price * float64(l.Qty) }
+
+// OrderAdjustment is a discount, shipping or tax line; discounts are negative.
+type OrderAdjustment struct {
+	Label  string
+	Amount float64
+}
+
+// Signed formats the amount with a leading minus for discounts.
+func (a OrderAdjustment) Signed() string {
+	if a.Amount < 0 {
+		return fmt.Sprintf("-$%.2f", -a.Amount)
+	}
+	return fmt.Sprintf("$%.2f", a.Amount)
+}
+
+type OrderConfirmation struct {
+	Name        string
+	OrderNo     string
+	Fulfillment string // "pickup" or "delivery"
+	Lines       []OrderLine
+	Adjustments []OrderAdjustment
+	Total       float64
+	Link        string
+	LookupLink  string // where guests find the order again
+	PickupAt    string // chosen pickup slot, for pickup orders
+	PickupCode  string
+}
+
+func OrderConfirmationMessage(to string, d OrderConfirmation) (Message, error) {
+	return render(KindOrderConfirmation, to, d)
+}
+
+type OrderStatus struct {
+	Name    string
+	OrderNo string
+	Status  string // the new status, e.g. SHIPPED
+	Link    string
+}
+
+func OrderStatusMessage(to string, d OrderStatus) (Message, error) {
+	return render(KindOrderStatus, to, d)
+}
+
+// Shipment is the tracking notice sent for each parcel.
+type Shipment struct {
+	Name        string
+	OrderNo     string
+	Carrier     string
+	TrackingNo  string
+	TrackingURL string // "" when the carrier has no tracking page
+	ShippedOn   string
+	Lines       []OrderLine // Price is not shown
+	Partial     bool        // more of the order follows in a later shipment
+	Link        string
+}
+
+func ShipmentMessage(to string, d Shipment) (Message, error) {
+	return render(KindShipment, to, d)
+}
+
+// Pickup is the ready-for-pickup notice and, with HoldUntil passed, the
+// notice that an uncollected order was canceled.
+type Pickup struct {
+	Name       string
+	OrderNo    string
+	Store      string // region code of the store
+	PickupAt   string
+	PickupCode string
+	HoldUntil  string // last day the order is kept
+	HoldDays   int
+	Link       string
+}
+
+func PickupReadyMessage(to string, d Pickup) (Message, error) {
+	return render(KindPickupReady, to, d)
+}
+
+func PickupExpiredMessage(to string, d Pickup) (Message, error) {
+	return render(KindPickupExpired, to, d)
+}
+
+type PasswordReset struct {
+	Name         string
+	Link         string
+	ValidMinutes int
+}
+
+func PasswordResetMessage(to string, d PasswordReset) (Message, error) {
+	return render(KindPasswordReset, to, d)
+}
+
+type OrderLink struct {
+	Name       string
+	OrderNo    string
+	Link       string
+	ValidHours int
+}
+
+func OrderLinkMessage(to string, d OrderLink) (Message, error) {
+	return render(KindOrderLink, to, d)
+}
@@ -0,0 +1,65 @@
+package payments
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Test card tokens understood by the fake gateway; any other non-empty token
+// is approved.
+const (
+	TokenApproved = "tok_visa"
+	TokenDeclined = "tok_declined"
+	TokenTimeout  = "tok_timeout"
+)
+
+// Fake is an in-process gateway for local development and tests. Outcomes
+// depend only on the card token, authorization refs are derived from the
+// order id and refund refs from the caller's key, so the same request
+// always gets the same answer. It keeps no state; amounts are checked by
+// the caller's records.
+type Fake struct{}
+
+func NewFake() *Fake { return &Fake{} }
+
+func (f *Fake) Name() string { return "fake" }
+
+func (f *Fake) Authorize(r Request) (string, error) {
+	switch r.Token {
+	case "", TokenDeclined:
+		return "", ErrDeclined
+	case TokenTimeout:
+		return "", ErrTimeout
+	}
+	if r.Amount <= 0 {
+		return "", fmt.Errorf("fake: invalid amount %.2f", r.Amount)
+	}
+	return "fake_auth_" + r.OrderID, nil
+}
+
+func (f *Fake) Capture(ref string, amount float64) error {
+	if !strings.HasPrefix(ref, "fake_auth_") {
+		return fmt.Errorf("fake: unknown authorization %q", ref)
+	}
+	if amount <= 0 {
+		return fmt.Errorf("fake: invalid amount %.2f", amount)
+	}
+	return nil
+}
+
+func (f *Fake) Void(ref string) error {
+	if !strings.HasPrefix(ref, "fake_auth_") {
+		return fmt.Errorf("fake: unknown authorization %q", ref)
+	}
+	return nil
+}
+
+func (f *Fake) Refund(ref, key string, amount float64) (string, error) {
+	if !strings.HasPrefix(ref, "fake_auth_") {
+		return "", fmt.Errorf("fake: unknown authorization %q", ref)
+	}
+	if amount <= 0 {
+		return "", fmt.Errorf("fake: invalid amount %.2f", amount)
+	}
+	return "fake_rf_" + key, nil
+}
@@ -0,0 +1,52 @@
+// Package payments talks to card payment gateways. Providers only move money;
+// what was authorized, captured or refunded for an order is recorded by the
+// caller.
+package payments
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrDeclined means the gateway refused the card; the shopper can retry
+	// with another one.
+	ErrDeclined = errors.New("payment declined")
+	// ErrTimeout means the gateway did not answer in time. The outcome is
+	// unknown; a hold placed anyway is never captured and lapses at the
+	// gateway.
+	ErrTimeout = errors.New("payment gateway timed out")
+)
+
+// Request asks the gateway to hold Amount on the card behind Token.
+type Request struct {
+	OrderID string
+	Amount  float64
+	Token   string // card token collected at checkout
+	Email   string
+}
+
+// Provider is a payment gateway. Refs are the gateway's own identifiers.
+// Implementations must be safe for concurrent use.
+type Provider interface {
+	Name() string
+	// Authorize holds the amount and returns the authorization ref.
+	Authorize(r Request) (string, error)
+	// Capture settles up to the authorized amount.
+	Capture(ref string, amount float64) error
+	// Void releases an authorization that was not captured.
+	Void(ref string) error
+	// Refund returns part or all of a captured amount and returns the
+	// refund's ref. key identifies the refund on our side; retrying with
+	// the same key must not pay out twice.
+	Refund(ref, key string, amount float64) (string, error)
+}
+
+// New returns the provider configured by name.
+func New(name string) (Provider, error) {
+	switch name {
+	case "", "fake":
+		return NewFake(), nil
+	}
+	return nil, fmt.Errorf("unknown payment provider %q", name)
+}
@@ -0,0 +1,196 @@
+// Package pdf writes simple text documents (invoices, packing slips) as PDF
+// using only the standard library. It knows the two built-in Helvetica
+// faces, lines and rectangles; text outside Windows-1252 is replaced by "?".
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// US Letter in points.
+const (
+	PageWidth  = 612.0
+	PageHeight = 792.0
+)
+
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+// Doc is a document under construction. Coordinates are in points from the
+// top-left corner of the page; y is the text baseline.
+type Doc struct {
+	title string
+	pages []*bytes.Buffer
+	cur   *bytes.Buffer
+}
+
+func New(title string) *Doc { return &Doc{title: title} }
+
+// AddPage starts a new page; drawing goes to it from now on.
+func (d *Doc) AddPage() {
+	d.cur = &bytes.Buffer{}
+	d.pages = append(d.pages, d.cur)
+}
+
+// Pages returns the number of pages so far.
+func (d *Doc) Pages() int { return len(d.pages) }
+
+// Text draws s with its left edge at x.
+func (d *Doc) Text(x, y float64, f Font, size float64, s string) {
+	if d.cur == nil {
+		d.AddPage()
+	}
+	fmt.Fprintf(d.cur, "BT /F%d %.1f Tf %.2f %.2f Td (%s) Tj ET\n", int(f)+1, size, x, PageHeight-y, escape(encode(s)))
+}
+
+// TextRight draws s with its right edge at x.
+func (d *Doc) TextRight(x, y float64, f Font, size float64, s string) {
+	d.Text(x-Width(s, f, size), y, f, size, s)
+}
+
+// Line draws a thin line.
+func (d *Doc) Line(x1, y1, x2, y2 float64) {
+	if d.cur == nil {
+		d.AddPage()
+	}
+	fmt.Fprintf(d.cur, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// Rect draws the outline of a w×h box whose top-left corner is at x, y.
+func (d *Doc) Rect(x, y, w, h float64) {
+	if d.cur == nil {
+		d.AddPage()
+	}
+	fmt.Fprintf(d.cur, "0.5 w %.2f %.2f %.2f %.2f re S\n", x, PageHeight-y-h, w, h)
+}
+
+// Width is the advance width of s in points.
+func Width(s string, f Font, size float64) float64 {
+	widths := &helvetica
+	if f == Bold {
+		widths = &helveticaBold
+	}
+	total := 0
+	for _, b := range encode(s) {
+		if b >= 32 && b <= 126 {
+			total += widths[b-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// Fit shortens s with "..." until it is at most w points wide.
+func Fit(s string, f Font, size, w float64) string {
+	if Width(s, f, size) <= w {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && Width(string(r)+"...", f, size) > w {
+		r = r[:len(r)-1]
+	}
+	return strings.TrimRight(string(r), " ") + "..."
+}
+
+// Bytes renders the finished document.
+func (d *Doc) Bytes() []byte {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	var out bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// 1 catalog, 2 page tree, 3-4 fonts, 5 info, then a page and its
+	// content stream per page
+	const firstPage = 6
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	obj(fmt.Sprintf("<< /Title (%s) /Producer (RetroBytes) >>", escape(encode(d.title))))
+	for i, p := range d.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, firstPage+2*i+1))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", p.Len(), p.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// encode maps s to Windows-1252 (the fonts' WinAnsiEncoding).
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			out = append(out, ' ')
+		case r >= 32 && r <= 126, r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		default:
+			if b, ok := cp1252[r]; ok {
+				out = append(out, b)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+func escape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if c == '(' || c == ')' || c == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// The Windows-1252 characters outside Latin-1 that show up in listings.
+var cp1252 = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '•': 0x95, '–': 0x96, '—': 0x97,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '™': 0x99,
+}
+
+// Advance widths (1/1000 em) of ASCII 32-126 from the standard AFM files.
+var helvetica = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBold = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
@@ -0,0 +1,50 @@
+package pdf_test
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"retrobytes/internal/pdf"
+)
+
+// Readers locate objects through the xref table, so every offset has to
+// point at the start of its object.
+func TestXrefOffsets(t *testing.T) {
+	doc := pdf.New("Test (1)")
+	doc.Text(50, 60, pdf.Bold, 12, "Café ™ (x)")
+	doc.AddPage()
+	doc.TextRight(pdf.PageWidth-50, 60, pdf.Regular, 10, "$1.00")
+	out := doc.Bytes()
+
+	start, err := strconv.Atoi(string(regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(out)[1]))
+	if err != nil || !bytes.HasPrefix(out[start:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", start)
+	}
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(out[start:], -1)
+	if len(offsets) != 9 { // catalog, pages, 2 fonts, info, 2 × (page, content)
+		t.Fatalf("%d objects", len(offsets))
+	}
+	for i, m := range offsets {
+		off, _ := strconv.Atoi(string(m[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(out[off:], []byte(want)) {
+			t.Errorf("object %d: offset %d points at %q", i+1, off, out[off:off+10])
+		}
+	}
+	if !bytes.Contains(out, []byte("/Count 2")) || !bytes.Contains(out, []byte("(Caf\xe9 \x99 \\(x\\)) Tj")) {
+		t.Fatal("text not encoded as WinAnsi with escaped parentheses")
+	}
+}
+
+func TestFit(t *testing.T) {
+	long := "Nintendo Entertainment System Action Set with Zapper and two controllers"
+	fit := pdf.Fit(long, pdf.Regular, 10, 150)
+	if pdf.Width(fit, pdf.Regular, 10) > 150 || fit[len(fit)-3:] != "..." {
+		t.Fatalf("Fit = %q", fit)
+	}
+	if pdf.Fit("NES", pdf.Regular, 10, 150) != "NES" {
+		t.Fatal("short text was shortened")
+	}
+}
@@ -0,0 +1,382 @@
+// Package qr encodes short text (pickup codes, links) as a QR code using only
+// the standard library. It always uses byte mode and error correction level
+// M, which covers versions 1-5: up to 84 bytes.
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTooLong is returned for text that does not fit a version 5 symbol.
+var ErrTooLong = errors.New("qr: text too long")
+
+// Code is an encoded symbol: Size × Size modules, without the quiet zone.
+type Code struct {
+	Size    int
+	modules []bool
+}
+
+// Black reports whether the module in column x, row y is dark.
+func (c *Code) Black(x, y int) bool {
+	return x >= 0 && y >= 0 && x < c.Size && y < c.Size && c.modules[y*c.Size+x]
+}
+
+// version holds the level M layout of one version.
+type version struct {
+	blocks    int // error correction blocks, all the same size here
+	dataWords int // data codewords per block
+	ecWords   int // error correction codewords per block
+	align     int // centre of the bottom-right alignment pattern (0 = none)
+}
+
+var versions = []version{
+	1: {1, 16, 10, 0},
+	2: {1, 28, 16, 18},
+	3: {1, 44, 26, 22},
+	4: {2, 32, 18, 26},
+	5: {2, 43, 24, 30},
+}
+
+// Encode picks the smallest version that holds text and the mask with the
+// lowest penalty score.
+func Encode(text string) (*Code, error) {
+	for v := 1; v < len(versions); v++ {
+		if len(text) <= versions[v].blocks*versions[v].dataWords-2 {
+			var best *Code
+			bestScore := 0
+			for mask := 0; mask < 8; mask++ {
+				c := encode(v, mask, []byte(text))
+				if s := c.penalty(); best == nil || s < bestScore {
+					best, bestScore = c, s
+				}
+			}
+			return best, nil
+		}
+	}
+	return nil, ErrTooLong
+}
+
+// encode lays out text in version v with the given mask.
+func encode(v, mask int, text []byte) *Code {
+	ver := versions[v]
+	c := &builder{Code: Code{Size: 17 + 4*v}}
+	c.modules = make([]bool, c.Size*c.Size)
+	c.function = make([]bool, c.Size*c.Size)
+	c.drawFunctionPatterns(ver)
+	c.drawData(codewords(ver, text))
+	c.applyMask(mask)
+	c.drawFormat(mask)
+	return &c.Code
+}
+
+// codewords returns the interleaved data and error correction codewords.
+func codewords(ver version, text []byte) []byte {
+	capacity := ver.blocks * ver.dataWords
+	var bits bitBuffer
+	bits.append(0b0100, 4) // byte mode
+	bits.append(len(text), 8)
+	for _, b := range text {
+		bits.append(int(b), 8)
+	}
+	bits.append(0, min(4, capacity*8-len(bits))) // terminator
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity*8; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+	data := bits.bytes()
+
+	var blocks, ecc [][]byte
+	for i := 0; i < ver.blocks; i++ {
+		block := data[i*ver.dataWords : (i+1)*ver.dataWords]
+		blocks = append(blocks, block)
+		ecc = append(ecc, reedSolomon(block, ver.ecWords))
+	}
+	out := make([]byte, 0, ver.blocks*(ver.dataWords+ver.ecWords))
+	for i := 0; i < ver.dataWords; i++ {
+		for _, b := range blocks {
+			out = append(out, b[i])
+		}
+	}
+	for i := 0; i < ver.ecWords; i++ {
+		for _, e := range ecc {
+			out = append(out, e[i])
+		}
+	}
+	return out
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, v>>i&1 == 1)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	out := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// reedSolomon computes n error correction codewords over GF(256) with the
+// QR polynomial x^8 + x^4 + x^3 + x^2 + 1.
+func reedSolomon(data []byte, n int) []byte {
+	// generator (x - a^0)(x - a^1)...(x - a^(n-1)), highest term implied
+	gen := make([]byte, n)
+	gen[n-1] = 1
+	root := byte(1)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			gen[j] = gfMul(gen[j], root)
+			if j+1 < n {
+				gen[j] ^= gen[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+	rem := make([]byte, n)
+	for _, b := range data {
+		factor := b ^ rem[0]
+		copy(rem, rem[1:])
+		rem[n-1] = 0
+		for j := range rem {
+			rem[j] ^= gfMul(gen[j], factor)
+		}
+	}
+	return rem
+}
+
+func gfMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// builder tracks which modules belong to function patterns while a symbol
+// is drawn.
+type builder struct {
+	Code
+	function []bool
+}
+
+func (c *builder) set(x, y int, dark bool) {
+	c.modules[y*c.Size+x] = dark
+	c.function[y*c.Size+x] = true
+}
+
+func (c *builder) drawFunctionPatterns(ver version) {
+	n := c.Size
+	for i := 0; i < n; i++ { // timing patterns
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+	c.drawFinder(3, 3)
+	c.drawFinder(n-4, 3)
+	c.drawFinder(3, n-4)
+	if ver.align > 0 {
+		for dy := -2; dy <= 2; dy++ {
+			for dx := -2; dx <= 2; dx++ {
+				c.set(ver.align+dx, ver.align+dy, max(abs(dx), abs(dy)) != 1)
+			}
+		}
+	}
+	c.drawFormat(0) // reserve the format areas; redrawn after masking
+}
+
+// drawFinder draws a finder pattern centred on x, y with its separator.
+func (c *builder) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx >= 0 && xx < c.Size && yy >= 0 && yy < c.Size {
+				d := max(abs(dx), abs(dy))
+				c.set(xx, yy, d != 2 && d != 4)
+			}
+		}
+	}
+}
+
+// drawFormat writes both copies of the format information for level M and
+// the mask, plus the dark module.
+func (c *builder) drawFormat(mask int) {
+	data := mask // level M is 00
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	n := c.Size
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		c.set(n-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, n-15+i, bit(i))
+	}
+	c.set(8, n-8, true)
+}
+
+// drawData fills the non-function modules in the zigzag order of the spec,
+// two columns at a time from the bottom-right corner.
+func (c *builder) drawData(words []byte) {
+	n, i := c.Size, 0
+	for right := n - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < n; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = n - 1 - vert // upward
+				}
+				if !c.function[y*n+x] && i < len(words)*8 {
+					c.modules[y*n+x] = words[i/8]>>(7-i%8)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (c *builder) applyMask(mask int) {
+	n := c.Size
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip && !c.function[y*n+x] {
+				c.modules[y*n+x] = !c.modules[y*n+x]
+			}
+		}
+	}
+}
+
+// penalty scores a masked symbol by the spec's four rules; lower reads
+// more reliably.
+func (c *Code) penalty() int {
+	n, score := c.Size, 0
+	line := func(at func(i int) bool) {
+		run := 1
+		for i := 1; i <= n; i++ {
+			if i < n && at(i) == at(i-1) {
+				run++
+				continue
+			}
+			if run >= 5 {
+				score += 3 + run - 5
+			}
+			run = 1
+		}
+		// finder-like 1:1:3:1:1 with four light modules on one side;
+		// outside the symbol counts as light
+		for i := -4; i < n; i++ {
+			match1, match2 := true, true
+			for k, want := range []bool{true, false, true, true, true, false, true} {
+				p := i + k
+				if (p >= 0 && p < n && at(p)) != want {
+					match1, match2 = false, false
+					break
+				}
+			}
+			if !match1 {
+				continue
+			}
+			for k := 1; k <= 4; k++ {
+				if p := i - k; p >= 0 && p < n && at(p) {
+					match1 = false
+				}
+				if p := i + 6 + k; p >= 0 && p < n && at(p) {
+					match2 = false
+				}
+			}
+			if match1 || match2 {
+				score += 40
+			}
+		}
+	}
+	for y := 0; y < n; y++ {
+		line(func(x int) bool { return c.Black(x, y) })
+	}
+	for x := 0; x < n; x++ {
+		line(func(y int) bool { return c.Black(x, y) })
+	}
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if c.Black(x, y) {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				b := c.Black(x, y)
+				if c.Black(x+1, y) == b && c.Black(x, y+1) == b && c.Black(x+1, y+1) == b {
+					score += 3
+				}
+			}
+		}
+	}
+	return score + abs(dark*20-n*n*10)/(n*n)*10
+}
+
+// SVG draws the symbol with a four-module quiet zone, scale pixels per
+// module.
+func (c *Code) SVG(scale int) []byte {
+	size := (c.Size + 8) * scale
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, c.Size+8, c.Size+8)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, c.Size+8, c.Size+8)
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.Black(x, y) {
+				fmt.Fprintf(&b, "M%d %dh1v1h-1z", x+4, y+4)
+			}
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return []byte(b.String())
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,50 @@
+package qr_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"retrobytes/internal/qr"
+)
+
+func TestEncode(t *testing.T) {
+	for text, size := range map[string]int{
+		"7Q4M-2XAB":                21, // version 1 holds 14 bytes
+		"RB-2026-000123 7Q4M-2XAB": 25,
+		strings.Repeat("x", 84):    37, // the most version 5 holds
+	} {
+		c, err := qr.Encode(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Size != size {
+			t.Errorf("%d bytes: size %d, want %d", len(text), c.Size, size)
+		}
+		// the three finder patterns: dark ring, light ring, dark centre
+		for _, o := range [][2]int{{0, 0}, {c.Size - 7, 0}, {0, c.Size - 7}} {
+			if !c.Black(o[0], o[1]) || c.Black(o[0]+1, o[1]+1) || !c.Black(o[0]+3, o[1]+3) {
+				t.Errorf("%d bytes: no finder pattern at %v", len(text), o)
+			}
+		}
+		if !c.Black(8, c.Size-8) {
+			t.Errorf("%d bytes: dark module missing", len(text))
+		}
+	}
+	if _, err := qr.Encode(strings.Repeat("x", 85)); !errors.Is(err, qr.ErrTooLong) {
+		t.Fatalf("85 bytes: err = %v", err)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	c, _ := qr.Encode("7Q4M-2XAB")
+	svg := c.SVG(4)
+	if !bytes.HasPrefix(svg, []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="116" height="116" viewBox="0 0 29 29"`)) {
+		t.Fatalf("svg header: %.120s", svg)
+	}
+	// the top-left finder starts after the four-module quiet zone
+	if !bytes.Contains(svg, []byte(`d="M4 4h1v1h-1z`)) {
+		t.Fatal("first module not at the quiet zone offset")
+	}
+}
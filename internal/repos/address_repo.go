@@ -0,0 +1,97 @@
+package repos
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Address is a US postal address.
+type Address struct {
+	ID        string `db:"id"` // saved addresses only
+	Name      string `db:"name"`
+	Line1     string `db:"line1"`
+	Line2     string `db:"line2"`
+	City      string `db:"city"`
+	State     string `db:"state"`
+	ZIP       string `db:"zip"`
+	Phone     string `db:"phone"`
+	IsDefault bool   `db:"is_default"`
+}
+
+// OneLine renders the address for selects and lists.
+func (a Address) OneLine() string {
+	parts := []string{a.Name, a.Line1}
+	if a.Line2 != "" {
+		parts = append(parts, a.Line2)
+	}
+	return strings.Join(append(parts, a.City+", "+a.State+" "+a.ZIP), ", ")
+}
+
+// SameAs compares the postal fields (not the id or default flag).
+func (a Address) SameAs(b Address) bool {
+	return a.Name == b.Name && a.Line1 == b.Line1 && a.Line2 == b.Line2 &&
+		a.City == b.City && a.State == b.State && a.ZIP == b.ZIP && a.Phone == b.Phone
+}
+
+type AddressRepo struct{ db *sqlx.DB }
+
+func NewAddressRepo(db *sqlx.DB) *AddressRepo { return &AddressRepo{db: db} }
+
+const addressCols = `id, name, line1, COALESCE(line2,'') AS line2, city, state, zip, COALESCE(phone,'') AS phone, is_default`
+
+// List returns a user's saved addresses, default first.
+func (r *AddressRepo) List(userID string) ([]Address, error) {
+	var out []Address
+	err := r.db.Select(&out, `SELECT `+addressCols+` FROM user_addresses WHERE user_id = ? ORDER BY is_default DESC, created_at DESC, id`, userID)
+	return out, err
+}
+
+// Get returns one of the user's saved addresses.
+func (r *AddressRepo) Get(userID, id string) (Address, error) {
+	var a Address
+	err := r.db.Get(&a, `SELECT `+addressCols+` FROM user_addresses WHERE id = ? AND user_id = ?`, id, userID)
+	return a, err
+}
+
+// Add saves an address; the first one becomes the default.
+func (r *AddressRepo) Add(userID string, a Address) (string, error) {
+	id := uuid.NewString()
+	_, err := r.db.Exec(`
+	  INSERT INTO user_addresses(id, user_id, name, line1, line2, city, state, zip, phone, is_default)
+	  VALUES(?, ?, ?, ?, NULLIF(?,''), ?, ?, ?, NULLIF(?,''),
+	         NOT EXISTS (SELECT 1 FROM user_addresses WHERE user_id = ?))
+	`, id, userID, a.Name, a.Line1, a.Line2, a.City, a.State, a.ZIP, a.Phone, userID)
+	return id, err
+}
+
+// Delete removes an address; if it was the default, the newest remaining
+// one takes over.
+func (r *AddressRepo) Delete(userID, id string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`DELETE FROM user_addresses WHERE id = ? AND user_id = ?`, id, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+	  UPDATE user_addresses SET is_default = 1
+	  WHERE id = (SELECT id FROM user_addresses WHERE user_id = ? ORDER BY created_at DESC, id LIMIT 1)
+	    AND NOT EXISTS (SELECT 1 FROM user_addresses WHERE user_id = ? AND is_default = 1)
+	`, userID, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetDefault makes id the user's default address.
+func (r *AddressRepo) SetDefault(userID, id string) error {
+	_, err := r.db.Exec(`
+	  UPDATE user_addresses SET is_default = (id = ?)
+	  WHERE user_id = ? AND EXISTS (SELECT 1 FROM user_addresses WHERE id = ? AND user_id = ?)
+	`, id, userID, id, userID)
+	return err
+}
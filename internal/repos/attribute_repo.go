@@ -0,0 +1,90 @@
+package repos
+
+import (
+	"retrobytes/internal/domain"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AttributeRepo struct{ db *sqlx.DB }
+
+func NewAttributeRepo(db *sqlx.DB) *AttributeRepo { return &AttributeRepo{db: db} }
+
+// Defs returns a category's attribute schema in display order.
+func (r *AttributeRepo) Defs(categoryID string) ([]domain.AttributeDef, error) {
+	var out []domain.AttributeDef
+	err := r.db.Select(&out, `
+	  SELECT category_id, key, label, type, COALESCE(options_json,'') AS options_json, filterable, sort_order
+	  FROM category_attributes
+	  WHERE category_id = ?
+	  ORDER BY sort_order, label
+	`, categoryID)
+	return out, err
+}
+
+func (r *AttributeRepo) UpsertDef(d domain.AttributeDef) error {
+	_, err := r.db.Exec(`
+	  INSERT INTO category_attributes(category_id, key, label, type, options_json, filterable, sort_order)
+	  VALUES(?, ?, ?, ?, NULLIF(?,''), ?, ?)
+	  ON CONFLICT(category_id, key) DO UPDATE SET
+	    label = excluded.label, type = excluded.type, options_json = excluded.options_json,
+	    filterable = excluded.filterable, sort_order = excluded.sort_order
+	`, d.CategoryID, d.Key, d.Label, d.Type, d.OptionsJSON, d.Filterable, d.SortOrder)
+	return err
+}
+
+// DeleteDef removes an attribute from a category and clears its values.
+func (r *AttributeRepo) DeleteDef(categoryID, key string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`
+	  DELETE FROM product_attributes
+	  WHERE key = ? AND product_id IN (SELECT id FROM products WHERE category_id = ?)
+	`, key, categoryID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM category_attributes WHERE category_id = ? AND key = ?`, categoryID, key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Values returns a product's raw attribute values keyed by attribute key.
+func (r *AttributeRepo) Values(productID string) (map[string]string, error) {
+	var rows []struct {
+		Key   string `db:"key"`
+		Value string `db:"value"`
+	}
+	if err := r.db.Select(&rows, `SELECT key, value FROM product_attributes WHERE product_id = ?`, productID); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(rows))
+	for _, kv := range rows {
+		out[kv.Key] = kv.Value
+	}
+	return out, nil
+}
+
+// SetValues replaces all attribute values of a product; empty values are dropped.
+func (r *AttributeRepo) SetValues(productID string, values map[string]string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`DELETE FROM product_attributes WHERE product_id = ?`, productID); err != nil {
+		return err
+	}
+	for k, v := range values {
+		if v == "" {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT INTO product_attributes(product_id, key, value) VALUES(?, ?, ?)`, productID, k, v); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
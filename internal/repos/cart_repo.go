@@ -2,9 +2,12 @@ package repos
 
 import (
 	"database/sql"
+	"math"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+
+	"retrobytes/internal/domain"
 )
 
 type CartRepo struct{ db *sqlx.DB }
@@ -18,8 +21,15 @@ type CartItemRow struct {
 	Qty        int     `db:"qty"`
 	PriceAtAdd float64 `db:"price_at_add"`
 	Subtotal   float64 `db:"subtotal"`
+	// CurrentPrice is the catalog price now; CartService.View applies sales
+	CurrentPrice float64 `db:"current_price"`
 }
 
+func (r CartItemRow) ConditionLabel() string { return domain.ConditionLabel(r.Condition) }
+
+// PriceChanged reports whether the price moved since the item was added.
+func (r CartItemRow) PriceChanged() bool { return math.Abs(r.CurrentPrice-r.PriceAtAdd) >= 0.005 }
+
 func (r *CartRepo) EnsureCart(sessionID string) (string, error) {
 	var cartID string
 	if err := r.db.Get(&cartID, `SELECT id FROM carts WHERE session_id = ?`, sessionID); err == nil {
@@ -47,7 +57,7 @@ func (r *CartRepo) View(cartID string) ([]CartItemRow, float64, error) {
 	rows := []CartItemRow{}
 	if err := r.db.Select(&rows, `
 	  SELECT ci.product_id, p.title, p.condition, ci.qty, ci.price_at_add,
-	         (ci.qty*ci.price_at_add) AS subtotal
+	         (ci.qty*ci.price_at_add) AS subtotal, p.price AS current_price
 	  FROM cart_items ci JOIN products p ON p.id=ci.product_id
 	  WHERE ci.cart_id = ?
 	`, cartID); err != nil {
@@ -68,6 +78,8 @@ type CartItem struct {
 	Title     string  `db:"title"`
 }
 
+func (i CartItem) ConditionLabel() string { return domain.ConditionLabel(i.Condition) }
+
 func (r *CartRepo) Items(cartID string) ([]CartItem, error) {
 	var out []CartItem
 	err := r.db.Select(&out, `
@@ -78,11 +90,31 @@ func (r *CartRepo) Items(cartID string) ([]CartItem, error) {
 	return out, err
 }
 
+// SetPrice re-snapshots a line's price, e.g. after the shopper accepted a
+// price change at checkout.
+func (r *CartRepo) SetPrice(cartID, productID string, price float64) error {
+	_, err := r.db.Exec(`UPDATE cart_items SET price_at_add = ?, updated_at = CURRENT_TIMESTAMP WHERE cart_id = ? AND product_id = ?`, price, cartID, productID)
+	return err
+}
+
 func (r *CartRepo) Clear(cartID string) error {
 	_, err := r.db.Exec(`DELETE FROM cart_items WHERE cart_id = ?`, cartID)
 	return err
 }
 
+// PromoCode returns the promotion code entered on the cart ("" if none).
+func (r *CartRepo) PromoCode(cartID string) (string, error) {
+	var code string
+	err := r.db.Get(&code, `SELECT COALESCE(promo_code,'') FROM carts WHERE id = ?`, cartID)
+	return code, err
+}
+
+// SetPromoCode stores (or with "" clears) the cart's promotion code.
+func (r *CartRepo) SetPromoCode(cartID, code string) error {
+	_, err := r.db.Exec(`UPDATE carts SET promo_code = NULLIF(?,''), updated_at = CURRENT_TIMESTAMP WHERE id = ?`, code, cartID)
+	return err
+}
+
 func (r *CartRepo) MergeForLogin(userID, sid string) error {
 	tx, err := r.db.Beginx()
 	if err != nil {
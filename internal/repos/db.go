@@ -1,7 +1,10 @@
 package repos
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 	"golang.org/x/crypto/bcrypt"
@@ -20,6 +23,9 @@ func OpenDB(dsn string) (*sqlx.DB, error) {
 	if err := ensureSchema(db); err != nil {
 		return nil, err
 	}
+	if err := migrateSchema(db); err != nil {
+		return nil, err
+	}
 	// Seed baseline data if DB is empty (categories/products/inventory)
 	if err := seedIfEmpty(db); err != nil {
 		return nil, err
@@ -32,6 +38,22 @@ func OpenDB(dsn string) (*sqlx.DB, error) {
 	if err := seedUsers(db); err != nil {
 		return nil, err
 	}
+	// Default attribute schema per category (idempotent)
+	if err := seedAttributes(db); err != nil {
+		return nil, err
+	}
+	// Opening BASE price rows for products without history (idempotent)
+	if err := seedPriceHistory(db); err != nil {
+		return nil, err
+	}
+	// Default shipping tables and demo parcel sizes (only when unconfigured)
+	if err := seedShipping(db); err != nil {
+		return nil, err
+	}
+	// Store hours for pickup (only when unconfigured)
+	if err := seedPickupHours(db); err != nil {
+		return nil, err
+	}
 
 	return db, nil
 }
@@ -55,7 +77,7 @@ CREATE TABLE IF NOT EXISTS products(
   category_id TEXT NOT NULL REFERENCES categories(id) ON DELETE RESTRICT,
   title TEXT NOT NULL,
   description TEXT,
-  condition TEXT NOT NULL CHECK (condition IN ('FIRST_HAND','SECOND_HAND')),
+  condition TEXT NOT NULL CHECK (condition IN ('NEW_SEALED','MINT','EXCELLENT','GOOD','FAIR','FOR_PARTS')),
   price NUMERIC NOT NULL CHECK (price >= 0),
   images_json TEXT,
   active INTEGER NOT NULL DEFAULT 1,
@@ -151,11 +173,632 @@ CREATE TABLE IF NOT EXISTS sessions(
   last_seen  TEXT
 );
 CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+
+-- Search analytics
+CREATE TABLE IF NOT EXISTS search_events(
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  query TEXT NOT NULL,               -- normalized (lowercased, single-spaced)
+  category_id TEXT,
+  condition TEXT,
+  result_count INTEGER NOT NULL DEFAULT 0,
+  session_id TEXT,
+  user_id TEXT,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_search_events_created_at ON search_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_search_events_query      ON search_events(query);
+
+CREATE TABLE IF NOT EXISTS search_clicks(
+  search_id INTEGER NOT NULL REFERENCES search_events(id) ON DELETE CASCADE,
+  product_id TEXT NOT NULL,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (search_id, product_id)
+);
+
+-- Saved searches & on-site notifications
+CREATE TABLE IF NOT EXISTS saved_searches(
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  query TEXT NOT NULL,
+  category_id TEXT,
+  condition TEXT,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_saved_searches_user ON saved_searches(user_id);
+
+CREATE TABLE IF NOT EXISTS saved_search_matches(
+  saved_search_id TEXT NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+  product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (saved_search_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS notifications(
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  message TEXT NOT NULL,
+  link TEXT,
+  read_at TEXT,
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, read_at);
+
+-- Background job bookkeeping
+CREATE TABLE IF NOT EXISTS job_runs(
+  name TEXT PRIMARY KEY,
+  last_run_at TEXT NOT NULL
+);
+
+-- Structured attributes: schema per category, values per product
+CREATE TABLE IF NOT EXISTS category_attributes(
+  category_id TEXT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+  key TEXT NOT NULL,
+  label TEXT NOT NULL,
+  type TEXT NOT NULL CHECK (type IN ('text','number','bool','enum')),
+  options_json TEXT,                 -- enum choices, e.g. ["NTSC-U","NTSC-J","PAL"]
+  filterable INTEGER NOT NULL DEFAULT 0,
+  sort_order INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (category_id, key)
+);
+
+CREATE TABLE IF NOT EXISTS product_attributes(
+  product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  key TEXT NOT NULL,
+  value TEXT NOT NULL,
+  PRIMARY KEY (product_id, key)
+);
+CREATE INDEX IF NOT EXISTS idx_product_attributes_kv ON product_attributes(key, value);
+
+-- Customer reviews (one per user per listing), shown once approved
+CREATE TABLE IF NOT EXISTS reviews(
+  id TEXT PRIMARY KEY,
+  product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+  title TEXT NOT NULL,
+  body TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING','APPROVED','REJECTED')),
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  moderated_at TEXT,
+  moderated_by TEXT,
+  UNIQUE(product_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_reviews_product_status ON reviews(product_id, status);
+CREATE INDEX IF NOT EXISTS idx_reviews_status_created ON reviews(status, created_at);
+
+-- Product questions; answered ones are shown on the product page
+CREATE TABLE IF NOT EXISTS product_questions(
+  id TEXT PRIMARY KEY,
+  product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  question TEXT NOT NULL,
+  answer TEXT,
+  status TEXT NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN','ANSWERED','HIDDEN')),
+  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+  answered_at TEXT,
+  answered_by TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_questions_product_status ON product_questions(product_id, status);
+CREATE INDEX IF NOT EXISTS idx_questions_status_created ON product_questions(status, created_at);
+
+-- "Customers also bought": top co-purchased listings, rebuilt by a job
+CREATE TABLE IF NOT EXISTS product_recommendations(
+  product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  related_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  score INTEGER NOT NULL,          -- number of orders containing both
+  rank INTEGER NOT NULL,
+  computed_at TEXT NOT NULL,
+  PRIMARY KEY (product_id, related_id)
+);
+CREATE INDEX IF NOT EXISTS idx_recommendations_rank ON product_recommendations(product_id, rank);
+
+-- Recently viewed listings per owner ("s:<session id>" or "u:<user id>"),
+-- pruned to the newest few, plus lifetime view counters
+CREATE TABLE IF NOT EXISTS recent_views(
+  owner_key TEXT NOT NULL,
+  product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  viewed_at TEXT NOT NULL,
+  PRIMARY KEY (owner_key, product_id)
+);
+CREATE INDEX IF NOT EXISTS idx_recent_views_owner ON recent_views(owner_key, viewed_at);
+CREATE TABLE IF NOT EXISTS product_view_counts(
+  product_id TEXT PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+  views INTEGER NOT NULL DEFAULT 0,
+  last_viewed_at TEXT
+);
+
+-- Price history: BASE rows record every list-price change, SALE rows are
+-- scheduled sale prices active for starts_at <= now < ends_at (UTC)
+CREATE TABLE IF NOT EXISTS product_prices(
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL CHECK (kind IN ('BASE','SALE')),
+  price NUMERIC NOT NULL CHECK (price >= 0),
+  starts_at TEXT NOT NULL,
+  ends_at TEXT,
+  created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  created_by TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_product_prices_product ON product_prices(product_id, kind, starts_at);
+
+-- Promotions: coupon codes scoped to the whole cart, a category or a
+-- listing, with usage limits (NULL = unlimited) and a validity window
+CREATE TABLE IF NOT EXISTS promotions(
+  id TEXT PRIMARY KEY,
+  code TEXT NOT NULL UNIQUE COLLATE NOCASE,
+  description TEXT NOT NULL DEFAULT '',
+  kind TEXT NOT NULL CHECK (kind IN ('PERCENT','FIXED')),
+  amount NUMERIC NOT NULL CHECK (amount > 0),
+  min_subtotal NUMERIC NOT NULL DEFAULT 0,
+  scope TEXT NOT NULL DEFAULT 'ALL' CHECK (scope IN ('ALL','CATEGORY','PRODUCT')),
+  scope_id TEXT,
+  max_uses INTEGER,
+  max_uses_per_customer INTEGER,
+  starts_at TEXT,
+  ends_at TEXT,
+  active INTEGER NOT NULL DEFAULT 1,
+  created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS promotion_redemptions(
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  promotion_id TEXT NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+  order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  user_id TEXT,
+  customer_email TEXT NOT NULL,
+  amount NUMERIC NOT NULL,
+  created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_redemptions_promotion ON promotion_redemptions(promotion_id);
+
+-- Order adjustments: lines stored next to the items that change the order
+-- total (kind DISCOUNT or TAX, amounts negative for discounts)
+CREATE TABLE IF NOT EXISTS order_adjustments(
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  code TEXT,
+  label TEXT NOT NULL,
+  amount NUMERIC NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_order_adjustments_order ON order_adjustments(order_id);
+
+-- Sales tax rates by ZIP prefix (longest prefix wins), rate in percent,
+-- with per-rate category exemptions
+CREATE TABLE IF NOT EXISTS tax_rates(
+  zip_prefix TEXT PRIMARY KEY CHECK (length(zip_prefix) BETWEEN 1 AND 5),
+  name TEXT NOT NULL,
+  rate NUMERIC NOT NULL CHECK (rate >= 0 AND rate <= 25),
+  updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS tax_exempt_categories(
+  zip_prefix TEXT NOT NULL REFERENCES tax_rates(zip_prefix) ON DELETE CASCADE,
+  category_id TEXT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+  PRIMARY KEY (zip_prefix, category_id)
+);
+
+-- Shipping: methods with an optional free-shipping threshold, zones from an
+-- origin (stock) region to destination ZIP prefixes (origin '' = any, dest
+-- '' = everywhere else; most specific wins) and weight brackets per zone
+CREATE TABLE IF NOT EXISTS shipping_methods(
+  code TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  free_over NUMERIC CHECK (free_over IS NULL OR free_over >= 0),
+  active INTEGER NOT NULL DEFAULT 1,
+  sort INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS shipping_zones(
+  origin_region TEXT NOT NULL DEFAULT '',
+  dest_prefix TEXT NOT NULL DEFAULT '' CHECK (length(dest_prefix) <= 5),
+  zone INTEGER NOT NULL CHECK (zone > 0),
+  PRIMARY KEY (origin_region, dest_prefix)
+);
+CREATE TABLE IF NOT EXISTS shipping_rates(
+  method TEXT NOT NULL REFERENCES shipping_methods(code) ON DELETE CASCADE,
+  zone INTEGER NOT NULL,
+  max_weight_kg NUMERIC NOT NULL CHECK (max_weight_kg > 0),
+  price NUMERIC NOT NULL CHECK (price >= 0),
+  PRIMARY KEY (method, zone, max_weight_kg)
+);
+
+-- Postal addresses: copied onto orders when placed, and saved per user
+CREATE TABLE IF NOT EXISTS order_addresses(
+  order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL CHECK (kind IN ('SHIPPING','BILLING')),
+  name TEXT NOT NULL,
+  line1 TEXT NOT NULL,
+  line2 TEXT,
+  city TEXT NOT NULL,
+  state TEXT NOT NULL,
+  zip TEXT NOT NULL,
+  phone TEXT,
+  PRIMARY KEY (order_id, kind)
+);
+CREATE TABLE IF NOT EXISTS user_addresses(
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  line1 TEXT NOT NULL,
+  line2 TEXT,
+  city TEXT NOT NULL,
+  state TEXT NOT NULL,
+  zip TEXT NOT NULL,
+  phone TEXT,
+  is_default INTEGER NOT NULL DEFAULT 0,
+  created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_user_addresses_user ON user_addresses(user_id);
+
+-- Card payments: one authorization per order, captured when it ships
+CREATE TABLE IF NOT EXISTS payments(
+  id TEXT PRIMARY KEY,
+  order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  provider TEXT NOT NULL,
+  ref TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('AUTHORIZED','CAPTURED','VOIDED','REFUNDED')),
+  amount NUMERIC NOT NULL,
+  captured NUMERIC NOT NULL DEFAULT 0,
+  refunded NUMERIC NOT NULL DEFAULT 0,
+  created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_payments_order ON payments(order_id);
+
+-- Refunds given back on an order, optionally itemized by line
+CREATE TABLE IF NOT EXISTS refunds(
+  id TEXT PRIMARY KEY,
+  order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  payment_id TEXT REFERENCES payments(id),
+  ref TEXT,                       -- gateway refund id; NULL for offline refunds
+  amount NUMERIC NOT NULL,
+  shipping NUMERIC NOT NULL DEFAULT 0,
+  reason TEXT NOT NULL,
+  note TEXT,
+  restocked INTEGER NOT NULL DEFAULT 0,
+  admin_id TEXT,
+  created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_refunds_order ON refunds(order_id);
+CREATE TABLE IF NOT EXISTS refund_items(
+  refund_id TEXT NOT NULL REFERENCES refunds(id) ON DELETE CASCADE,
+  order_id TEXT NOT NULL,
+  product_id TEXT NOT NULL,
+  qty INTEGER NOT NULL CHECK (qty > 0),
+  amount NUMERIC NOT NULL,
+  PRIMARY KEY (refund_id, product_id),
+  FOREIGN KEY (order_id, product_id) REFERENCES order_items(order_id, product_id) ON DELETE CASCADE
+);
+
+-- Customer returns (RMA), walked through by staff from request to refund
+CREATE TABLE IF NOT EXISTS returns(
+  id TEXT PRIMARY KEY,
+  order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  status TEXT NOT NULL DEFAULT 'REQUESTED'
+    CHECK (status IN ('REQUESTED','APPROVED','REJECTED','RECEIVED','INSPECTED','REFUNDED')),
+  reason TEXT NOT NULL,
+  details TEXT,
+  photos_json TEXT,
+  admin_note TEXT,
+  disposition TEXT CHECK (disposition IN ('RESTOCK','WRITE_OFF')),
+  refund_id TEXT REFERENCES refunds(id),
+  created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_returns_order ON returns(order_id);
+
+-- Order status changes and who made them
+CREATE TABLE IF NOT EXISTS order_status_history(
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  status TEXT NOT NULL,
+  actor TEXT NOT NULL CHECK (actor IN ('CUSTOMER','ADMIN','SYSTEM')),
+  user_id TEXT,
+  note TEXT,
+  created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order ON order_status_history(order_id, id);
+CREATE INDEX IF NOT EXISTS idx_returns_status ON returns(status, created_at);
+CREATE TABLE IF NOT EXISTS return_items(
+  return_id TEXT NOT NULL REFERENCES returns(id) ON DELETE CASCADE,
+  order_id TEXT NOT NULL,
+  product_id TEXT NOT NULL,
+  qty INTEGER NOT NULL CHECK (qty > 0),
+  PRIMARY KEY (return_id, product_id),
+  FOREIGN KEY (order_id, product_id) REFERENCES order_items(order_id, product_id) ON DELETE CASCADE
+);
+
+-- Transactional email: rows are written together with the change that
+-- triggers them and delivered by a background worker
+CREATE TABLE IF NOT EXISTS email_outbox(
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  kind TEXT NOT NULL,
+  to_addr TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  text_body TEXT NOT NULL,
+  html_body TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING','SENT','FAILED')),
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_error TEXT,
+  created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  sent_at TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outbox(status, next_attempt_at);
+
+-- Last order number handed out per year (RB-<year>-<n>)
+CREATE TABLE IF NOT EXISTS order_number_seq(
+  year INTEGER PRIMARY KEY,
+  last INTEGER NOT NULL
+);
+
+-- Password reset tokens (only the SHA-256 of the token is stored)
+CREATE TABLE IF NOT EXISTS password_resets(
+  token_hash TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  expires_at TEXT NOT NULL,
+  used_at TEXT,
+  created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_password_resets_user ON password_resets(user_id);
+
+-- Parcels sent for an order; shipment_items holds the units in each, so an
+-- order can go out in several shipments
+CREATE TABLE IF NOT EXISTS shipments(
+  id TEXT PRIMARY KEY,
+  order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  carrier TEXT NOT NULL,
+  tracking_no TEXT NOT NULL,
+  shipped_on TEXT NOT NULL,      -- YYYY-MM-DD
+  admin_id TEXT,
+  created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_shipments_order ON shipments(order_id);
+CREATE TABLE IF NOT EXISTS shipment_items(
+  shipment_id TEXT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+  order_id TEXT NOT NULL,
+  product_id TEXT NOT NULL,
+  qty INTEGER NOT NULL CHECK (qty > 0),
+  PRIMARY KEY (shipment_id, product_id),
+  FOREIGN KEY (order_id, product_id) REFERENCES order_items(order_id, product_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_shipment_items_order ON shipment_items(order_id);
+
+-- Store opening hours for order pickup, per region; weekday 0 = Sunday and
+-- a missing day is closed
+CREATE TABLE IF NOT EXISTS pickup_hours(
+  region_code TEXT NOT NULL,
+  weekday INTEGER NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+  opens TEXT NOT NULL,           -- HH:MM
+  closes TEXT NOT NULL,
+  PRIMARY KEY (region_code, weekday)
+);
 `
 	_, err := db.Exec(schema)
 	return err
 }
 
+// migrateSchema applies column additions to databases created by older builds.
+func migrateSchema(db *sqlx.DB) error {
+	// Variants are child products: same listing, own price and inventory
+	if err := addColumnIfMissing(db, "products", "parent_id", "TEXT REFERENCES products(id)"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "products", "variant_label", "TEXT"); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_products_parent ON products(parent_id)`); err != nil {
+		return err
+	}
+	// Condition notes and close-up photos shown under the grade
+	if err := addColumnIfMissing(db, "products", "condition_notes", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "products", "condition_images_json", "TEXT"); err != nil {
+		return err
+	}
+	// Promotion code entered on the cart
+	if err := addColumnIfMissing(db, "carts", "promo_code", "TEXT"); err != nil {
+		return err
+	}
+	// Taxable amount behind a TAX adjustment, for filing
+	if err := addColumnIfMissing(db, "order_adjustments", "base", "NUMERIC"); err != nil {
+		return err
+	}
+	// Parcel size for shipping quotes; variants fall back to their parent
+	for _, col := range []string{"weight_kg", "length_cm", "width_cm", "height_cm"} {
+		if err := addColumnIfMissing(db, "products", col, "NUMERIC NOT NULL DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+	// Chosen shipping method and destination ZIP
+	if err := addColumnIfMissing(db, "orders", "shipping_method", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "orders", "ship_zip", "TEXT"); err != nil {
+		return err
+	}
+	// start of the return window
+	if err := addColumnIfMissing(db, "orders", "shipped_at", "TEXT"); err != nil {
+		return err
+	}
+	// Sequential invoice number, assigned when the invoice is first issued
+	if err := addColumnIfMissing(db, "orders", "invoice_no", "INTEGER"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "orders", "invoiced_at", "TEXT"); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_invoice_no ON orders(invoice_no)`); err != nil {
+		return err
+	}
+	// Customer-facing order number; the UUID stays the key and the URL
+	if err := addColumnIfMissing(db, "orders", "order_no", "TEXT"); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_order_no ON orders(order_no)`); err != nil {
+		return err
+	}
+	if err := backfillOrderNumbers(db); err != nil {
+		return err
+	}
+	// Pickup slot (store-local "2006-01-02 15:04"), the code shown at the
+	// counter and the ready/collected steps
+	for _, col := range []string{"pickup_from", "pickup_until", "pickup_code", "ready_at", "collected_at", "collect_note"} {
+		if err := addColumnIfMissing(db, "orders", col, "TEXT"); err != nil {
+			return err
+		}
+	}
+	if _, err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_pickup_code ON orders(pickup_code)`); err != nil {
+		return err
+	}
+	// Gateway call in flight on a payment (CAPTURE, VOID or REFUND)
+	if err := addColumnIfMissing(db, "payments", "pending", "TEXT"); err != nil {
+		return err
+	}
+	// Refunds are reserved before the gateway pays them out; each refunded
+	// line keeps the tax it gives back, for the tax report
+	if err := addColumnIfMissing(db, "refunds", "pending", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "refund_items", "tax", "NUMERIC NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	// When an unlisted product was put on sale; saved-search alerts key on
+	// it (products listed from the start fall back to created_at)
+	if err := addColumnIfMissing(db, "products", "listed_at", "TEXT"); err != nil {
+		return err
+	}
+	return migrateConditionGrades(db)
+}
+
+// backfillOrderNumbers numbers orders placed before order numbers existed,
+// in placement order within each year.
+func backfillOrderNumbers(db *sqlx.DB) error {
+	var orders []struct {
+		ID   string `db:"id"`
+		Year int    `db:"year"`
+	}
+	if err := db.Select(&orders, `
+	  SELECT id, CAST(COALESCE(strftime('%Y', created_at), strftime('%Y','now')) AS INTEGER) AS year
+	  FROM orders WHERE order_no IS NULL ORDER BY datetime(created_at), rowid
+	`); err != nil {
+		return err
+	}
+	if len(orders) == 0 {
+		return nil
+	}
+	log.Printf("[migrate] numbering %d existing orders", len(orders))
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, o := range orders {
+		no, err := nextOrderNo(tx, o.Year)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE orders SET order_no = ? WHERE id = ?`, no, o.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// migrateConditionGrades moves databases created with the old
+// FIRST_HAND/SECOND_HAND CHECK onto the graded scale. SQLite cannot alter a
+// CHECK constraint, so products is rebuilt (foreign keys off, as the SQLite
+// docs prescribe) and legacy values are mapped on copy.
+func migrateConditionGrades(db *sqlx.DB) error {
+	var ddl string
+	if err := db.Get(&ddl, `SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'products'`); err != nil {
+		return err
+	}
+	if !strings.Contains(ddl, "'FIRST_HAND'") {
+		return nil
+	}
+	log.Println("[migrate] rebuilding products for graded conditions")
+
+	var cols []string
+	if err := db.Select(&cols, `SELECT name FROM pragma_table_info('products') ORDER BY cid`); err != nil {
+		return err
+	}
+	sel := make([]string, len(cols))
+	for i, c := range cols {
+		sel[i] = c
+		if c == "condition" {
+			sel[i] = `CASE condition WHEN 'FIRST_HAND' THEN 'NEW_SEALED' WHEN 'SECOND_HAND' THEN 'GOOD' ELSE condition END`
+		}
+	}
+	var extras []string // indexes/triggers to recreate after the swap
+	if err := db.Select(&extras, `SELECT sql FROM sqlite_master WHERE tbl_name = 'products' AND type IN ('index','trigger') AND sql IS NOT NULL`); err != nil {
+		return err
+	}
+	newDDL := strings.Replace(ddl, "CREATE TABLE products", "CREATE TABLE products_new", 1)
+	newDDL = strings.Replace(newDDL, "CHECK (condition IN ('FIRST_HAND','SECOND_HAND'))",
+		"CHECK (condition IN ('NEW_SEALED','MINT','EXCELLENT','GOOD','FAIR','FOR_PARTS'))", 1)
+
+	ctx := context.Background()
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return err
+	}
+	defer func() { _, _ = conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`) }()
+
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	stmts := []string{
+		newDDL,
+		`INSERT INTO products_new(` + strings.Join(cols, ", ") + `) SELECT ` + strings.Join(sel, ", ") + ` FROM products`,
+		`DROP TABLE products`,
+		`ALTER TABLE products_new RENAME TO products`,
+	}
+	stmts = append(stmts, extras...)
+	stmts = append(stmts,
+		// Order lines and saved searches carry the grade too
+		`UPDATE order_items SET condition = CASE condition WHEN 'FIRST_HAND' THEN 'NEW_SEALED' WHEN 'SECOND_HAND' THEN 'GOOD' ELSE condition END`,
+		`UPDATE saved_searches SET condition = CASE condition WHEN 'FIRST_HAND' THEN 'NEW_SEALED' WHEN 'SECOND_HAND' THEN 'GOOD' ELSE condition END`,
+	)
+	for _, q := range stmts {
+		if _, err := tx.Exec(q); err != nil {
+			return err
+		}
+	}
+	var broken int
+	if err := tx.Get(&broken, `SELECT COUNT(*) FROM pragma_foreign_key_check`); err != nil {
+		return err
+	}
+	if broken > 0 {
+		return fmt.Errorf("migrate conditions: %d foreign key violations", broken)
+	}
+	return tx.Commit()
+}
+
+// addColumnIfMissing adds a column unless it already exists
+// (SQLite has no ADD COLUMN IF NOT EXISTS).
+func addColumnIfMissing(db *sqlx.DB, table, column, decl string) error {
+	var cols []struct {
+		Name string `db:"name"`
+	}
+	if err := db.Select(&cols, `SELECT name FROM pragma_table_info(?)`, table); err != nil {
+		return err
+	}
+	for _, c := range cols {
+		if c.Name == column {
+			return nil
+		}
+	}
+	_, err := db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + decl)
+	return err
+}
+
 func seedIfEmpty(db *sqlx.DB) error {
 	var n int
 	if err := db.Get(&n, `SELECT COUNT(*) FROM categories`); err != nil {
@@ -175,9 +818,9 @@ func seedIfEmpty(db *sqlx.DB) error {
 	  ('retro-electronics','Retro Electronics')`)
 
 	tx.MustExec(`INSERT INTO products(id,category_id,title,description,condition,price,images_json) VALUES
-	  ('gbc-001','retro-consoles','Game Boy Color','Handheld console','SECOND_HAND',129.99,'["products/gbc-001/main.jpg"]'),
-	  ('nes-001','retro-consoles','NES Console','Classic 8-bit console','FIRST_HAND',199.00,'["products/nes-001/main.jpg"]'),
-	  ('radio-001','vintage-radios','Philco 1939','Vintage vacuum tube radio','SECOND_HAND',349.50,'["products/radio-001/main.jpg"]')`)
+	  ('gbc-001','retro-consoles','Game Boy Color','Handheld console','GOOD',129.99,'["products/gbc-001/main.jpg"]'),
+	  ('nes-001','retro-consoles','NES Console','Classic 8-bit console','NEW_SEALED',199.00,'["products/nes-001/main.jpg"]'),
+	  ('radio-001','vintage-radios','Philco 1939','Vintage vacuum tube radio','GOOD',349.50,'["products/radio-001/main.jpg"]')`)
 
 	tx.MustExec(`INSERT INTO inventory(product_id,region_code,qty) VALUES
 	  ('gbc-001','20742',8),
@@ -186,6 +829,11 @@ func seedIfEmpty(db *sqlx.DB) error {
 	  ('nes-001','10001',5),
 	  ('radio-001','20742',2)`)
 
+	tx.MustExec(`INSERT INTO tax_rates(zip_prefix,name,rate) VALUES
+	  ('207','Maryland',6),
+	  ('100','New York City',8.875)`)
+	tx.MustExec(`INSERT INTO tax_exempt_categories(zip_prefix,category_id) VALUES ('100','retro-shoes')`)
+
 	return tx.Commit()
 }
 
@@ -216,7 +864,7 @@ func seedDefaultData(db *sqlx.DB) error {
 			'snes-001', 'retro-consoles',
 			'Super Nintendo (SNES) Console',
 			'Classic 16-bit SNES console with controller. Tested and cleaned.',
-			'SECOND_HAND', 199.00, '["products/snes-001/main.jpg"]', 1, CURRENT_TIMESTAMP, NULL
+			'EXCELLENT', 199.00, '["products/snes-001/main.jpg"]', 1, CURRENT_TIMESTAMP, NULL
 		WHERE NOT EXISTS (SELECT 1 FROM products WHERE id='snes-001')
 	`)
 
@@ -229,7 +877,7 @@ func seedDefaultData(db *sqlx.DB) error {
 			'radio-zenith-500', 'vintage-radios',
 			'Zenith Royal 500 (1960s) Transistor Radio',
 			'Iconic vintage pocket radio. Cosmetic wear; works with 9V battery.',
-			'SECOND_HAND', 89.00, '["products/radio-zenith-500/main.jpg"]', 1, CURRENT_TIMESTAMP, NULL
+			'FAIR', 89.00, '["products/radio-zenith-500/main.jpg"]', 1, CURRENT_TIMESTAMP, NULL
 		WHERE NOT EXISTS (SELECT 1 FROM products WHERE id='radio-zenith-500')
 	`)
 
@@ -280,3 +928,148 @@ func seedUsers(db *sqlx.DB) error {
 
 	return tx.Commit()
 }
+
+// seedAttributes installs the default collectible attributes for each
+// category without touching definitions an admin has already edited.
+func seedAttributes(db *sqlx.DB) error {
+	type def struct {
+		Key, Label, Type, Options string
+		Filterable                bool
+	}
+	common := []def{
+		{"manufacturer", "Manufacturer", "text", "", true},
+		{"model_number", "Model number", "text", "", false},
+		{"year", "Year", "number", "", false},
+		{"tested_working", "Tested / working", "bool", "", true},
+	}
+	consoles := []def{
+		{"platform", "Platform", "enum", `["NES","SNES","Famicom","Super Famicom","Game Boy","Game Boy Color","Genesis","Other"]`, true},
+		{"video_region", "Region", "enum", `["NTSC-U","NTSC-J","PAL"]`, true},
+		{"box_included", "Box included", "bool", "", true},
+		{"manual_included", "Manual included", "bool", "", true},
+	}
+	perCat := map[string][]def{
+		"retro-consoles":    append(append([]def{}, consoles...), common...),
+		"vintage-radios":    common,
+		"retro-electronics": common,
+		"retro-shoes":       common[:1],
+	}
+
+	tx := db.MustBegin()
+	defer func() { _ = tx.Rollback() }()
+	for cat, defs := range perCat {
+		for i, d := range defs {
+			if _, err := tx.Exec(`
+				INSERT INTO category_attributes(category_id, key, label, type, options_json, filterable, sort_order)
+				SELECT ?, ?, ?, ?, NULLIF(?,''), ?, ?
+				WHERE EXISTS (SELECT 1 FROM categories WHERE id = ?)
+				ON CONFLICT(category_id, key) DO NOTHING
+			`, cat, d.Key, d.Label, d.Type, d.Options, d.Filterable, i, cat); err != nil {
+				return err
+			}
+		}
+	}
+	// Sample values for the demo catalog (first run only)
+	var n int
+	if err := tx.Get(&n, `SELECT COUNT(*) FROM product_attributes`); err != nil {
+		return err
+	}
+	if n > 0 {
+		return tx.Commit()
+	}
+	_, _ = tx.Exec(`
+		INSERT INTO product_attributes(product_id, key, value)
+		SELECT v.pid, v.k, v.val FROM (
+		  SELECT 'nes-001' AS pid, 'platform' AS k, 'NES' AS val UNION ALL
+		  SELECT 'nes-001', 'video_region', 'NTSC-U' UNION ALL
+		  SELECT 'nes-001', 'manufacturer', 'Nintendo' UNION ALL
+		  SELECT 'nes-001', 'year', '1985' UNION ALL
+		  SELECT 'snes-001', 'platform', 'SNES' UNION ALL
+		  SELECT 'snes-001', 'video_region', 'NTSC-U' UNION ALL
+		  SELECT 'snes-001', 'manufacturer', 'Nintendo' UNION ALL
+		  SELECT 'snes-001', 'tested_working', 'yes' UNION ALL
+		  SELECT 'gbc-001', 'platform', 'Game Boy Color' UNION ALL
+		  SELECT 'gbc-001', 'manufacturer', 'Nintendo' UNION ALL
+		  SELECT 'radio-zenith-500', 'manufacturer', 'Zenith' UNION ALL
+		  SELECT 'radio-zenith-500', 'tested_working', 'yes' UNION ALL
+		  SELECT 'radio-001', 'manufacturer', 'Philco' UNION ALL
+		  SELECT 'radio-001', 'year', '1939'
+		) v
+		WHERE EXISTS (SELECT 1 FROM products p WHERE p.id = v.pid)
+		ON CONFLICT(product_id, key) DO NOTHING
+	`)
+	return tx.Commit()
+}
+
+// seedPriceHistory records the current list price of every product that has
+// no BASE history yet, dated from the product's creation.
+func seedPriceHistory(db *sqlx.DB) error {
+	_, err := db.Exec(`
+	  INSERT INTO product_prices(product_id, kind, price, starts_at)
+	  SELECT p.id, 'BASE', p.price, COALESCE(p.created_at, CURRENT_TIMESTAMP)
+	  FROM products p
+	  WHERE NOT EXISTS (SELECT 1 FROM product_prices pp WHERE pp.product_id = p.id AND pp.kind = 'BASE')
+	`)
+	return err
+}
+
+// seedShipping installs default methods, zones and rates when no shipping
+// method exists yet, so delivery keeps working on upgraded databases.
+func seedShipping(db *sqlx.DB) error {
+	var n int
+	if err := db.Get(&n, `SELECT COUNT(*) FROM shipping_methods`); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	log.Println("[seed] inserting default shipping rates")
+
+	tx := db.MustBegin()
+	defer func() { _ = tx.Rollback() }()
+	stmts := []string{
+		`INSERT INTO shipping_methods(code,name,free_over,sort) VALUES
+		  ('STANDARD','Standard shipping (3-7 business days)',150,1),
+		  ('EXPRESS','Express shipping (1-2 business days)',NULL,2)`,
+		`INSERT INTO shipping_zones(origin_region,dest_prefix,zone) VALUES
+		  ('','',3),
+		  ('20742','2',1), ('20742','1',2), ('20742','0',2),
+		  ('10001','1',1), ('10001','0',1), ('10001','2',2)`,
+		`INSERT INTO shipping_rates(method,zone,max_weight_kg,price) VALUES
+		  ('STANDARD',1,1,5.99),  ('STANDARD',1,5,8.99),   ('STANDARD',1,20,14.99),
+		  ('STANDARD',2,1,7.99),  ('STANDARD',2,5,11.99),  ('STANDARD',2,20,19.99),
+		  ('STANDARD',3,1,9.99),  ('STANDARD',3,5,14.99),  ('STANDARD',3,20,24.99),
+		  ('EXPRESS',1,1,14.99),  ('EXPRESS',1,5,19.99),   ('EXPRESS',1,20,34.99),
+		  ('EXPRESS',2,1,19.99),  ('EXPRESS',2,5,27.99),   ('EXPRESS',2,20,44.99),
+		  ('EXPRESS',3,1,24.99),  ('EXPRESS',3,5,34.99),   ('EXPRESS',3,20,59.99)`,
+		`UPDATE products SET weight_kg=0.5, length_cm=20, width_cm=15, height_cm=8 WHERE id='gbc-001' AND weight_kg=0`,
+		`UPDATE products SET weight_kg=2.5, length_cm=40, width_cm=30, height_cm=12 WHERE id IN ('nes-001','snes-001') AND weight_kg=0`,
+		`UPDATE products SET weight_kg=4, length_cm=35, width_cm=25, height_cm=20 WHERE id='radio-001' AND weight_kg=0`,
+		`UPDATE products SET weight_kg=0.6, length_cm=20, width_cm=12, height_cm=8 WHERE id='radio-zenith-500' AND weight_kg=0`,
+	}
+	for _, q := range stmts {
+		if _, err := tx.Exec(q); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// seedPickupHours opens the demo stores for pickup Monday to Saturday when
+// no hours are configured yet.
+func seedPickupHours(db *sqlx.DB) error {
+	var n int
+	if err := db.Get(&n, `SELECT COUNT(*) FROM pickup_hours`); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	log.Println("[seed] inserting default pickup hours")
+	_, err := db.Exec(`INSERT INTO pickup_hours(region_code,weekday,opens,closes) VALUES
+	  ('20742',1,'10:00','18:00'), ('20742',2,'10:00','18:00'), ('20742',3,'10:00','18:00'),
+	  ('20742',4,'10:00','18:00'), ('20742',5,'10:00','18:00'), ('20742',6,'10:00','16:00'),
+	  ('10001',1,'11:00','19:00'), ('10001',2,'11:00','19:00'), ('10001',3,'11:00','19:00'),
+	  ('10001',4,'11:00','19:00'), ('10001',5,'11:00','19:00'), ('10001',6,'11:00','17:00')`)
+	return err
+}
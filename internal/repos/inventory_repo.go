@@ -53,7 +53,11 @@ func (r *InventoryRepo) Qty(productID, region string) (int, error) {
 // Decrement atomically subtracts "by" units if enough stock exists.
 // Returns an error if there isn't sufficient stock.
 func (r *InventoryRepo) Decrement(productID, region string, by int) error {
-	res, err := r.db.Exec(`
+	return decrementStock(r.db, productID, region, by)
+}
+
+func decrementStock(ex sqlx.Execer, productID, region string, by int) error {
+	res, err := ex.Exec(`
 		UPDATE inventory
 		SET qty = qty - ?
 		WHERE product_id = ? AND region_code = ? AND qty >= ?
@@ -68,6 +72,20 @@ func (r *InventoryRepo) Decrement(productID, region string, by int) error {
 	return nil
 }
 
+// Increment puts stock back, e.g. for refunded or canceled order lines.
+func (r *InventoryRepo) Increment(productID, region string, by int) error {
+	return incrementStock(r.db, productID, region, by)
+}
+
+func incrementStock(ex sqlx.Execer, productID, region string, by int) error {
+	_, err := ex.Exec(`
+		INSERT INTO inventory(product_id, region_code, qty)
+		VALUES (?, ?, ?)
+		ON CONFLICT(product_id, region_code) DO UPDATE SET qty = qty + excluded.qty
+	`, productID, region, by)
+	return err
+}
+
 // UpsertQty sets qty for (productID, region) creating the row if needed.
 func (r *InventoryRepo) UpsertQty(productID, region string, qty int) error {
 	_, err := r.db.Exec(`
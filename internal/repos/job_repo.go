@@ -0,0 +1,30 @@
+package repos
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// JobRepo tracks when background jobs last completed.
+type JobRepo struct{ db *sqlx.DB }
+
+func NewJobRepo(db *sqlx.DB) *JobRepo { return &JobRepo{db: db} }
+
+// LastRun returns the last completed run time ("" if the job never ran).
+func (r *JobRepo) LastRun(name string) (string, error) {
+	var ts string
+	err := r.db.Get(&ts, `SELECT last_run_at FROM job_runs WHERE name = ?`, name)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return ts, err
+}
+
+func (r *JobRepo) SetLastRun(name, ts string) error {
+	_, err := r.db.Exec(`
+	  INSERT INTO job_runs(name, last_run_at) VALUES(?, ?)
+	  ON CONFLICT(name) DO UPDATE SET last_run_at = excluded.last_run_at
+	`, name, ts)
+	return err
+}
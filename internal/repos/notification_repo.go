@@ -0,0 +1,51 @@
+package repos
+
+import "github.com/jmoiron/sqlx"
+
+type NotificationRepo struct{ db *sqlx.DB }
+
+func NewNotificationRepo(db *sqlx.DB) *NotificationRepo { return &NotificationRepo{db: db} }
+
+type Notification struct {
+	ID        int64  `db:"id"`
+	Kind      string `db:"kind"`
+	Message   string `db:"message"`
+	Link      string `db:"link"`
+	Read      bool   `db:"is_read"`
+	CreatedAt string `db:"created_at"`
+}
+
+func (r *NotificationRepo) Create(userID, kind, message, link string) error {
+	_, err := r.db.Exec(`
+	  INSERT INTO notifications(user_id, kind, message, link, created_at)
+	  VALUES(?, ?, ?, NULLIF(?,''), CURRENT_TIMESTAMP)
+	`, userID, kind, message, link)
+	return err
+}
+
+// ListByUser returns the user's most recent notifications, unread first.
+func (r *NotificationRepo) ListByUser(userID string, limit int) ([]Notification, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var out []Notification
+	err := r.db.Select(&out, `
+	  SELECT id, kind, message, COALESCE(link,'') AS link, (read_at IS NOT NULL) AS is_read, created_at
+	  FROM notifications
+	  WHERE user_id = ?
+	  ORDER BY (read_at IS NOT NULL), datetime(created_at) DESC, id DESC
+	  LIMIT ?
+	`, userID, limit)
+	return out, err
+}
+
+func (r *NotificationRepo) UnreadCount(userID string) (int, error) {
+	var n int
+	err := r.db.Get(&n, `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL`, userID)
+	return n, err
+}
+
+func (r *NotificationRepo) MarkAllRead(userID string) error {
+	_, err := r.db.Exec(`UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = ? AND read_at IS NULL`, userID)
+	return err
+}
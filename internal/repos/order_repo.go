@@ -1,6 +1,13 @@
 package repos
 
-import "github.com/jmoiron/sqlx"
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"retrobytes/internal/domain"
+)
 
 type OrderRepo struct{ db *sqlx.DB }
 
@@ -9,10 +16,13 @@ func NewOrderRepo(db *sqlx.DB) *OrderRepo { return &OrderRepo{db: db} }
 // ---------- Admin list summary ----------
 type OrderSummary struct {
 	ID            string  `db:"id"`
+	OrderNo       string  `db:"order_no"`
 	SessionID     string  `db:"session_id"`
 	CustomerName  string  `db:"customer_name"`
 	CustomerEmail string  `db:"customer_email"`
 	Total         float64 `db:"total"`
+	Tax           float64 `db:"tax"`
+	Refunded      float64 `db:"refunded"`
 	Status        string  `db:"status"`
 	CreatedAt     string  `db:"created_at"`
 }
@@ -20,18 +30,29 @@ type OrderSummary struct {
 // ---------- Order detail (used by /order/:id) ----------
 type OrderRow struct {
 	ID          string  `db:"id"`
+	OrderNo     string  `db:"order_no"` // RB-2026-000123
 	SessionID   string  `db:"session_id"`
 	UserID      string  `db:"user_id"`
 	Region      string  `db:"region_code"`
 	Fulfillment string  `db:"fulfillment"`
+	ShipMethod  string  `db:"shipping_method"` // method code, PICKUP or ""
+	ShipZIP     string  `db:"ship_zip"`
+	ShippedAt   string  `db:"shipped_at"` // first marked SHIPPED; "" before
 	Customer    string  `db:"customer_name"`
 	Email       string  `db:"customer_email"`
 	Total       float64 `db:"total"`
 	Status      string  `db:"status"`
 	CreatedAt   string  `db:"created_at"`
+	PickupFrom  string  `db:"pickup_from"` // chosen pickup slot, store-local time
+	PickupUntil string  `db:"pickup_until"`
+	PickupCode  string  `db:"pickup_code"` // shown at the counter
+	ReadyAt     string  `db:"ready_at"`
+	CollectedAt string  `db:"collected_at"`
+	CollectNote string  `db:"collect_note"` // how the collector's ID was checked
 }
 
 type OrderItemRow struct {
+	ProductID string  `db:"product_id"`
 	Title     string  `db:"title"`
 	Condition string  `db:"condition"`
 	Qty       int     `db:"qty"`
@@ -39,34 +60,266 @@ type OrderItemRow struct {
 	Subtotal  float64 `db:"subtotal"`
 }
 
+// Number is what customers and staff call the order; orders created
+// without a number fall back to the ID.
+func (o OrderRow) Number() string {
+	if o.OrderNo != "" {
+		return o.OrderNo
+	}
+	return o.ID
+}
+
+// PickupWindow renders the chosen pickup slot ("" if none).
+func (o OrderRow) PickupWindow() string { return PickupWindow(o.PickupFrom, o.PickupUntil) }
+
+// PickupOpen reports whether the order still waits to be collected.
+func (o OrderRow) PickupOpen() bool {
+	return o.PickupCode != "" && (o.Status == "PLACED" || o.Status == "RESERVED" || o.Status == "READY_FOR_PICKUP")
+}
+
+func (o OrderSummary) Number() string {
+	if o.OrderNo != "" {
+		return o.OrderNo
+	}
+	return o.ID
+}
+
+// ConditionLabel renders the grade snapshotted when the order was placed.
+func (r OrderItemRow) ConditionLabel() string { return domain.ConditionLabel(r.Condition) }
+
 // ---------- Methods your service needs ----------
 
-// Create inserts a new order header.
+// FormatOrderNo renders the n-th order number of a year.
+func FormatOrderNo(year, n int) string { return fmt.Sprintf("RB-%d-%06d", year, n) }
+
+func nextOrderNo(q sqlx.Queryer, year int) (string, error) {
+	var n int
+	if err := sqlx.Get(q, &n, `
+	  INSERT INTO order_number_seq(year, last) VALUES(?, 1)
+	  ON CONFLICT(year) DO UPDATE SET last = last + 1
+	  RETURNING last
+	`, year); err != nil {
+		return "", err
+	}
+	return FormatOrderNo(year, n), nil
+}
+
+// NextOrderNo reserves the next order number for now's year. A number whose
+// order is never created is simply skipped.
+func (r *OrderRepo) NextOrderNo(now time.Time) (string, error) {
+	return nextOrderNo(r.db, now.UTC().Year())
+}
+
+// Create inserts a new order header under the next order number.
 func (r *OrderRepo) Create(orderID, sessionID, region, fulfillment, name, email string, total float64) error {
-	_, err := r.db.Exec(`
+	orderNo, err := r.NextOrderNo(time.Now())
+	if err != nil {
+		return err
+	}
+	return insertOrder(r.db, NewOrder{ID: orderID, OrderNo: orderNo, SessionID: sessionID, Region: region,
+		Fulfillment: fulfillment, Customer: name, Email: email, Total: total})
+}
+
+// NewOrder is everything written when an order is placed.
+type NewOrder struct {
+	ID          string
+	OrderNo     string // reserved with NextOrderNo
+	SessionID   string
+	Region      string
+	Fulfillment string
+	Customer    string
+	Email       string
+	Total       float64
+	Items       []OrderItemRow // ProductID, Qty, Price and Condition
+	ShipTo      *Address
+	BillTo      *Address
+	Adjustments []Adjustment
+	// ShipMethod and ShipZIP are stored when ShipMethod is set
+	ShipMethod string
+	ShipZIP    string
+	// PickupFrom, PickupUntil and PickupCode are the chosen pickup slot
+	PickupFrom  string
+	PickupUntil string
+	PickupCode  string
+	Promo       *PromoRedemption
+	// Payment is the card authorization taken for the order
+	Payment *NewPayment
+}
+
+// NewPayment is an authorization to record with a new order.
+type NewPayment struct {
+	Provider string
+	Ref      string
+	Amount   float64
+}
+
+// Place writes a new order in one transaction: the stock it takes from
+// the region, the header, lines, addresses and adjustments, the promotion
+// redemption (re-checked against its limits), the pickup slot, the payment
+// and mails (the confirmation). Either all of it is stored or none.
+func (r *OrderRepo) Place(o NewOrder, mails ...OutboxEmail) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, it := range o.Items {
+		if err := decrementStock(tx, it.ProductID, o.Region, it.Qty); err != nil {
+			return err
+		}
+	}
+	if err := insertOrder(tx, o); err != nil {
+		return err
+	}
+	for _, it := range o.Items {
+		if err := insertOrderItem(tx, o.ID, it.ProductID, it.Qty, it.Price, it.Condition); err != nil {
+			return err
+		}
+	}
+	if o.ShipTo != nil {
+		if err := insertOrderAddress(tx, o.ID, "SHIPPING", *o.ShipTo); err != nil {
+			return err
+		}
+	}
+	if o.BillTo != nil {
+		if err := insertOrderAddress(tx, o.ID, "BILLING", *o.BillTo); err != nil {
+			return err
+		}
+	}
+	for _, a := range o.Adjustments {
+		if err := insertAdjustment(tx, o.ID, a); err != nil {
+			return err
+		}
+	}
+	if o.Promo != nil {
+		if err := redeemPromotion(tx, *o.Promo, o.ID, o.SessionID, o.Email); err != nil {
+			return err
+		}
+	}
+	if o.ShipMethod != "" {
+		if _, err := tx.Exec(`UPDATE orders SET shipping_method = ?, ship_zip = NULLIF(?, '') WHERE id = ?`, o.ShipMethod, o.ShipZIP, o.ID); err != nil {
+			return err
+		}
+	}
+	if o.PickupCode != "" {
+		if _, err := tx.Exec(`UPDATE orders SET pickup_from = ?, pickup_until = ?, pickup_code = ? WHERE id = ?`,
+			o.PickupFrom, o.PickupUntil, o.PickupCode, o.ID); err != nil {
+			return err
+		}
+	}
+	if o.Payment != nil {
+		if _, err := insertPayment(tx, o.ID, o.Payment.Provider, o.Payment.Ref, o.Payment.Amount); err != nil {
+			return err
+		}
+	}
+	if err := enqueueEmails(tx, mails); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertOrder(ex sqlx.Execer, o NewOrder) error {
+	_, err := ex.Exec(`
 	  INSERT INTO orders
-	    (id, session_id, region_code, fulfillment, customer_name, customer_email, total, status, created_at)
+	    (id, order_no, session_id, region_code, fulfillment, customer_name, customer_email, total, status, created_at)
 	  VALUES
-	    (?,  ?,         ?,           ?,           ?,             ?,              ?,     'PLACED', CURRENT_TIMESTAMP)
-	`, orderID, sessionID, region, fulfillment, name, email, total)
+	    (?,  ?,        ?,          ?,           ?,           ?,             ?,              ?,     'PLACED', CURRENT_TIMESTAMP)
+	`, o.ID, o.OrderNo, o.SessionID, o.Region, o.Fulfillment, o.Customer, o.Email, o.Total)
 	return err
 }
 
+// insertOrderAddress stores the SHIPPING or BILLING address of an order.
+func insertOrderAddress(ex sqlx.Execer, orderID, kind string, a Address) error {
+	_, err := ex.Exec(`
+	  INSERT INTO order_addresses(order_id, kind, name, line1, line2, city, state, zip, phone)
+	  VALUES(?, ?, ?, ?, NULLIF(?,''), ?, ?, ?, NULLIF(?,''))
+	`, orderID, kind, a.Name, a.Line1, a.Line2, a.City, a.State, a.ZIP, a.Phone)
+	return err
+}
+
+// Addresses returns an order's shipping and billing addresses (nil when
+// not recorded).
+func (r *OrderRepo) Addresses(orderID string) (ship, bill *Address, err error) {
+	var rows []struct {
+		Kind string `db:"kind"`
+		Address
+	}
+	if err := r.db.Select(&rows, `
+	  SELECT kind, '' AS id, name, line1, COALESCE(line2,'') AS line2, city, state, zip, COALESCE(phone,'') AS phone, 0 AS is_default
+	  FROM order_addresses WHERE order_id = ?
+	`, orderID); err != nil {
+		return nil, nil, err
+	}
+	for i := range rows {
+		if rows[i].Kind == "SHIPPING" {
+			ship = &rows[i].Address
+		} else {
+			bill = &rows[i].Address
+		}
+	}
+	return ship, bill, nil
+}
+
 // InsertItem inserts a single line item.
 func (r *OrderRepo) InsertItem(orderID, productID string, qty int, price float64, condition string) error {
-	_, err := r.db.Exec(`
+	return insertOrderItem(r.db, orderID, productID, qty, price, condition)
+}
+
+func insertOrderItem(ex sqlx.Execer, orderID, productID string, qty int, price float64, condition string) error {
+	_, err := ex.Exec(`
 	  INSERT INTO order_items(order_id, product_id, qty, price, condition)
 	  VALUES(?, ?, ?, ?, ?)
 	`, orderID, productID, qty, price, condition)
 	return err
 }
 
+// Adjustment is an order line that is not an item, e.g. a discount.
+type Adjustment struct {
+	Kind   string  `db:"kind"` // DISCOUNT | TAX | SHIPPING
+	Code   string  `db:"code"`
+	Label  string  `db:"label"`
+	Amount float64 `db:"amount"`
+	Base   float64 `db:"base"` // taxable amount for TAX lines
+}
+
+// Signed renders the amount for receipts, e.g. "−$5.00".
+func (a Adjustment) Signed() string {
+	if a.Amount < 0 {
+		return fmt.Sprintf("−$%.2f", -a.Amount)
+	}
+	return fmt.Sprintf("$%.2f", a.Amount)
+}
+
+// insertAdjustment stores a non-item line on an order.
+func insertAdjustment(ex sqlx.Execer, orderID string, a Adjustment) error {
+	_, err := ex.Exec(`
+	  INSERT INTO order_adjustments(order_id, kind, code, label, amount, base)
+	  VALUES(?, ?, NULLIF(?,''), ?, ?, NULLIF(?,0))
+	`, orderID, a.Kind, a.Code, a.Label, a.Amount, a.Base)
+	return err
+}
+
+// Adjustments lists an order's non-item lines in the order they were added.
+func (r *OrderRepo) Adjustments(orderID string) ([]Adjustment, error) {
+	var out []Adjustment
+	err := r.db.Select(&out, `
+	  SELECT kind, COALESCE(code,'') AS code, label, amount, COALESCE(base,0) AS base
+	  FROM order_adjustments WHERE order_id = ? ORDER BY id
+	`, orderID)
+	return out, err
+}
+
 // ---------- Used by order page/admin ----------
 
 func (r *OrderRepo) Get(orderID string) (OrderRow, []OrderItemRow, error) {
 	var o OrderRow
 	if err := r.db.Get(&o, `
-		SELECT o.id, o.session_id, COALESCE(s.user_id,'') AS user_id, o.region_code, o.fulfillment, o.customer_name, o.customer_email, o.total, o.status, o.created_at
+		SELECT o.id, COALESCE(o.order_no,'') AS order_no, o.session_id, COALESCE(s.user_id,'') AS user_id, o.region_code, o.fulfillment,
+		       COALESCE(o.shipping_method,'') AS shipping_method, COALESCE(o.ship_zip,'') AS ship_zip,
+		       COALESCE(o.shipped_at,'') AS shipped_at, o.customer_name, o.customer_email, o.total, o.status, o.created_at,
+		       COALESCE(o.pickup_from,'') AS pickup_from, COALESCE(o.pickup_until,'') AS pickup_until,
+		       COALESCE(o.pickup_code,'') AS pickup_code, COALESCE(o.ready_at,'') AS ready_at,
+		       COALESCE(o.collected_at,'') AS collected_at, COALESCE(o.collect_note,'') AS collect_note
 		FROM orders o
 		LEFT JOIN sessions s ON s.id = o.session_id
 		WHERE o.id = ?
@@ -76,7 +329,7 @@ func (r *OrderRepo) Get(orderID string) (OrderRow, []OrderItemRow, error) {
 
 	var items []OrderItemRow
 	if err := r.db.Select(&items, `
-		SELECT p.title, oi.condition, oi.qty, oi.price, (oi.qty * oi.price) AS subtotal
+		SELECT oi.product_id, p.title, oi.condition, oi.qty, oi.price, (oi.qty * oi.price) AS subtotal
 		FROM order_items oi
 		JOIN products p ON p.id = oi.product_id
 		WHERE oi.order_id = ?
@@ -94,7 +347,9 @@ func (r *OrderRepo) ListLatest(limit int) ([]OrderSummary, error) {
 	}
 	var out []OrderSummary
 	err := r.db.Select(&out, `
-		SELECT id, session_id, customer_name, customer_email, total, status, created_at
+		SELECT id, COALESCE(order_no,'') AS order_no, session_id, customer_name, customer_email, total, status, created_at,
+		       (SELECT COALESCE(SUM(amount),0) FROM order_adjustments a WHERE a.order_id = orders.id AND a.kind = 'TAX') AS tax,
+		       (SELECT COALESCE(SUM(amount),0) FROM refunds r WHERE r.order_id = orders.id) AS refunded
 		FROM orders
 		ORDER BY datetime(created_at) DESC
 		LIMIT ?
@@ -106,7 +361,7 @@ func (r *OrderRepo) ListLatest(limit int) ([]OrderSummary, error) {
 func (r *OrderRepo) ListByUser(userID string) ([]OrderSummary, error) {
 	var out []OrderSummary
 	err := r.db.Select(&out, `
-		SELECT o.id, o.session_id, o.customer_name, o.customer_email, o.total, o.status, o.created_at
+		SELECT o.id, COALESCE(o.order_no,'') AS order_no, o.session_id, o.customer_name, o.customer_email, o.total, o.status, o.created_at
 		FROM orders o
 		JOIN sessions s ON s.id = o.session_id
 		WHERE s.user_id = ?
@@ -119,7 +374,7 @@ func (r *OrderRepo) ListByUser(userID string) ([]OrderSummary, error) {
 func (r *OrderRepo) ListBySession(sessionID string) ([]OrderSummary, error) {
 	var out []OrderSummary
 	err := r.db.Select(&out, `
-		SELECT id, session_id, customer_name, customer_email, total, status, created_at
+		SELECT id, COALESCE(order_no,'') AS order_no, session_id, customer_name, customer_email, total, status, created_at
 		FROM orders
 		WHERE session_id = ?
 		ORDER BY datetime(created_at) DESC
@@ -127,7 +382,166 @@ func (r *OrderRepo) ListBySession(sessionID string) ([]OrderSummary, error) {
 	return out, err
 }
 
-func (r *OrderRepo) UpdateStatus(id, status string) error {
-	_, err := r.db.Exec(`UPDATE orders SET status = ? WHERE id = ?`, status, id)
+// TransitionStatus moves an order from one status to another, with h in
+// its history and mails (the status notice) queued in the same
+// transaction. It reports false without writing anything when the order
+// was no longer in status from.
+func (r *OrderRepo) TransitionStatus(id, from, to string, h StatusChange, mails ...OutboxEmail) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, err := tx.Exec(`UPDATE orders SET status = ? WHERE id = ? AND status = ?`, to, id, from)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return false, err
+	}
+	h.Status = to
+	if err := insertHistory(tx, id, h); err != nil {
+		return false, err
+	}
+	if err := enqueueEmails(tx, mails); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// StatusChange is one entry of an order's status history.
+type StatusChange struct {
+	Status    string `db:"status"`
+	Actor     string `db:"actor"` // CUSTOMER | ADMIN | SYSTEM
+	UserID    string `db:"user_id"`
+	Note      string `db:"note"`
+	CreatedAt string `db:"created_at"`
+}
+
+// AddHistory records a status change.
+func (r *OrderRepo) AddHistory(orderID string, h StatusChange) error {
+	return insertHistory(r.db, orderID, h)
+}
+
+func insertHistory(ex sqlx.Execer, orderID string, h StatusChange) error {
+	_, err := ex.Exec(`
+	  INSERT INTO order_status_history(order_id, status, actor, user_id, note)
+	  VALUES(?, ?, ?, NULLIF(?,''), NULLIF(?,''))
+	`, orderID, h.Status, h.Actor, h.UserID, h.Note)
 	return err
 }
+
+// History lists an order's status changes, oldest first.
+func (r *OrderRepo) History(orderID string) ([]StatusChange, error) {
+	var out []StatusChange
+	err := r.db.Select(&out, `
+	  SELECT status, actor, COALESCE(user_id,'') AS user_id, COALESCE(note,'') AS note, created_at
+	  FROM order_status_history WHERE order_id = ? ORDER BY id
+	`, orderID)
+	return out, err
+}
+
+// Cancel cancels an order that is in one of the from statuses: the units go
+// back into its region's stock, h is added to the history and mails (the
+// notice) are queued, all in one transaction. ok is false, and nothing is
+// changed, when the order is in another status.
+func (r *OrderRepo) Cancel(id string, from []string, h StatusChange, mails ...OutboxEmail) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	q, args, err := sqlx.In(`UPDATE orders SET status = 'CANCELED' WHERE id = ? AND status IN (?)`, id, from)
+	if err != nil {
+		return false, err
+	}
+	res, err := tx.Exec(q, args...)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return false, err
+	}
+	var items []struct {
+		ProductID string `db:"product_id"`
+		Region    string `db:"region_code"`
+		Qty       int    `db:"qty"`
+	}
+	if err := tx.Select(&items, `
+	  SELECT i.product_id, o.region_code, i.qty
+	  FROM order_items i JOIN orders o ON o.id = i.order_id
+	  WHERE i.order_id = ?
+	`, id); err != nil {
+		return false, err
+	}
+	for _, it := range items {
+		if err := incrementStock(tx, it.ProductID, it.Region, it.Qty); err != nil {
+			return false, err
+		}
+	}
+	if err := insertHistory(tx, id, h); err != nil {
+		return false, err
+	}
+	if err := enqueueEmails(tx, mails); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// UpdateStatus sets the status unconditionally, without history (seeding
+// and tests; staff changes go through TransitionStatus). mails are queued
+// in the same transaction.
+func (r *OrderRepo) UpdateStatus(id, status string, mails ...OutboxEmail) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`
+		UPDATE orders
+		SET status = ?, shipped_at = CASE WHEN ? = 'SHIPPED' THEN COALESCE(shipped_at, CURRENT_TIMESTAMP) ELSE shipped_at END
+		WHERE id = ?
+	`, status, status, id); err != nil {
+		return err
+	}
+	if err := enqueueEmails(tx, mails); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// InvoiceNo returns the order's invoice number and issue time (0 and "" if
+// no invoice was issued yet).
+func (r *OrderRepo) InvoiceNo(orderID string) (int, string, error) {
+	var row struct {
+		No int    `db:"invoice_no"`
+		At string `db:"invoiced_at"`
+	}
+	err := r.db.Get(&row, `SELECT COALESCE(invoice_no,0) AS invoice_no, COALESCE(invoiced_at,'') AS invoiced_at FROM orders WHERE id = ?`, orderID)
+	return row.No, row.At, err
+}
+
+// issueInvoice is the SET clause that gives an order the next invoice
+// number unless it already has one. Status updates that bill the order
+// (first shipment, pickup hand-over) include it so the number is issued
+// in the same statement.
+const issueInvoice = `invoice_no = COALESCE(invoice_no, (SELECT COALESCE(MAX(invoice_no),0) + 1 FROM orders)),
+	  invoiced_at = COALESCE(invoiced_at, CURRENT_TIMESTAMP)`
+
+// AssignInvoiceNo gives the order the next invoice number unless it already
+// has one or was canceled, and returns the order's number. The single
+// UPDATE keeps the sequence gap-free under concurrent requests; the unique
+// index backs it up.
+func (r *OrderRepo) AssignInvoiceNo(orderID string) (int, string, error) {
+	if _, err := r.db.Exec(`UPDATE orders SET `+issueInvoice+` WHERE id = ? AND status <> 'CANCELED'`, orderID); err != nil {
+		return 0, "", err
+	}
+	return r.InvoiceNo(orderID)
+}
+
+// IDByNumber finds an order by its number (sql.ErrNoRows if none).
+func (r *OrderRepo) IDByNumber(orderNo string) (string, error) {
+	var id string
+	err := r.db.Get(&id, `SELECT id FROM orders WHERE order_no = ?`, orderNo)
+	return id, err
+}
@@ -0,0 +1,128 @@
+package repos
+
+import "github.com/jmoiron/sqlx"
+
+// OutboxEmail is a transactional email waiting for (or done with) delivery.
+type OutboxEmail struct {
+	ID            int64  `db:"id"`
+	Kind          string `db:"kind"`
+	To            string `db:"to_addr"`
+	Subject       string `db:"subject"`
+	Text          string `db:"text_body"`
+	HTML          string `db:"html_body"`
+	Status        string `db:"status"` // PENDING, SENT or FAILED
+	Attempts      int    `db:"attempts"`
+	NextAttemptAt string `db:"next_attempt_at"`
+	LastError     string `db:"last_error"`
+	CreatedAt     string `db:"created_at"`
+	SentAt        string `db:"sent_at"`
+}
+
+// enqueueEmails queues mails on ex, which is the transaction of the change
+// that triggered them whenever there is one: the email goes out if and only
+// if that change commits.
+func enqueueEmails(ex sqlx.Execer, mails []OutboxEmail) error {
+	for _, m := range mails {
+		if _, err := ex.Exec(`
+		  INSERT INTO email_outbox(kind, to_addr, subject, text_body, html_body)
+		  VALUES(?, ?, ?, ?, ?)
+		`, m.Kind, m.To, m.Subject, m.Text, m.HTML); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type OutboxRepo struct{ db *sqlx.DB }
+
+func NewOutboxRepo(db *sqlx.DB) *OutboxRepo { return &OutboxRepo{db: db} }
+
+// Enqueue queues a mail that is not tied to any other write.
+func (r *OutboxRepo) Enqueue(m OutboxEmail) error {
+	return enqueueEmails(r.db, []OutboxEmail{m})
+}
+
+const outboxColumns = `id, kind, to_addr, subject, text_body, html_body, status, attempts,
+	  next_attempt_at, COALESCE(last_error,'') AS last_error, created_at, COALESCE(sent_at,'') AS sent_at`
+
+// Due returns pending mails whose next attempt is at or before now, oldest
+// first.
+func (r *OutboxRepo) Due(now string, limit int) ([]OutboxEmail, error) {
+	var out []OutboxEmail
+	err := r.db.Select(&out, `
+	  SELECT `+outboxColumns+`
+	  FROM email_outbox
+	  WHERE status = 'PENDING' AND next_attempt_at <= ?
+	  ORDER BY id
+	  LIMIT ?
+	`, now, limit)
+	return out, err
+}
+
+func (r *OutboxRepo) MarkSent(id int64, at string) error {
+	_, err := r.db.Exec(`
+	  UPDATE email_outbox SET status = 'SENT', attempts = attempts + 1, sent_at = ?, last_error = NULL
+	  WHERE id = ? AND status = 'PENDING'
+	`, at, id)
+	return err
+}
+
+// Reschedule records a failed attempt and when to try again.
+func (r *OutboxRepo) Reschedule(id int64, next, lastErr string) error {
+	_, err := r.db.Exec(`
+	  UPDATE email_outbox SET attempts = attempts + 1, next_attempt_at = ?, last_error = ?
+	  WHERE id = ? AND status = 'PENDING'
+	`, next, lastErr, id)
+	return err
+}
+
+// MarkFailed records the last failed attempt and gives up on the mail.
+func (r *OutboxRepo) MarkFailed(id int64, lastErr string) error {
+	_, err := r.db.Exec(`
+	  UPDATE email_outbox SET status = 'FAILED', attempts = attempts + 1, last_error = ?
+	  WHERE id = ? AND status = 'PENDING'
+	`, lastErr, id)
+	return err
+}
+
+// Failed lists undeliverable mails, newest first.
+func (r *OutboxRepo) Failed(limit int) ([]OutboxEmail, error) {
+	var out []OutboxEmail
+	err := r.db.Select(&out, `
+	  SELECT `+outboxColumns+`
+	  FROM email_outbox
+	  WHERE status = 'FAILED'
+	  ORDER BY id DESC
+	  LIMIT ?
+	`, limit)
+	return out, err
+}
+
+// Retry puts a failed mail back in the queue with a fresh set of attempts.
+func (r *OutboxRepo) Retry(id int64) (bool, error) {
+	res, err := r.db.Exec(`
+	  UPDATE email_outbox SET status = 'PENDING', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP
+	  WHERE id = ? AND status = 'FAILED'
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Counts returns the number of mails per status.
+func (r *OutboxRepo) Counts() (map[string]int, error) {
+	var rows []struct {
+		Status string `db:"status"`
+		N      int    `db:"n"`
+	}
+	if err := r.db.Select(&rows, `SELECT status, COUNT(*) AS n FROM email_outbox GROUP BY status`); err != nil {
+		return nil, err
+	}
+	out := map[string]int{}
+	for _, row := range rows {
+		out[row.Status] = row.N
+	}
+	return out, nil
+}
@@ -0,0 +1,101 @@
+package repos
+
+import (
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Payment is a card authorization held for an order.
+type Payment struct {
+	ID        string  `db:"id"`
+	OrderID   string  `db:"order_id"`
+	Provider  string  `db:"provider"`
+	Ref       string  `db:"ref"`
+	Status    string  `db:"status"` // AUTHORIZED | CAPTURED | VOIDED | REFUNDED
+	Amount    float64 `db:"amount"`
+	Captured  float64 `db:"captured"`
+	Refunded  float64 `db:"refunded"`
+	Pending   string  `db:"pending"` // gateway call in flight: CAPTURE | VOID | REFUND
+	CreatedAt string  `db:"created_at"`
+	UpdatedAt string  `db:"updated_at"`
+}
+
+type PaymentRepo struct{ db *sqlx.DB }
+
+func NewPaymentRepo(db *sqlx.DB) *PaymentRepo { return &PaymentRepo{db: db} }
+
+// insertPayment records a fresh authorization.
+func insertPayment(ex sqlx.Execer, orderID, provider, ref string, amount float64) (string, error) {
+	id := uuid.NewString()
+	_, err := ex.Exec(`
+	  INSERT INTO payments(id, order_id, provider, ref, status, amount)
+	  VALUES(?, ?, ?, ?, 'AUTHORIZED', ?)
+	`, id, orderID, provider, ref, amount)
+	return id, err
+}
+
+// ForOrder returns the order's latest payment.
+func (r *PaymentRepo) ForOrder(orderID string) (Payment, error) {
+	var p Payment
+	err := r.db.Get(&p, `
+	  SELECT id, order_id, provider, ref, status, amount, captured, refunded,
+	         COALESCE(pending,'') AS pending, created_at, updated_at
+	  FROM payments WHERE order_id = ?
+	  ORDER BY created_at DESC, rowid DESC LIMIT 1
+	`, orderID)
+	return p, err
+}
+
+// HeldOnCanceled lists orders that were canceled while their card
+// authorization is still held, i.e. whose void did not go through.
+func (r *PaymentRepo) HeldOnCanceled(limit int) ([]string, error) {
+	var ids []string
+	err := r.db.Select(&ids, `
+	  SELECT p.order_id FROM payments p JOIN orders o ON o.id = p.order_id
+	  WHERE o.status = 'CANCELED' AND p.status = 'AUTHORIZED'
+	  ORDER BY p.created_at LIMIT ?
+	`, limit)
+	return ids, err
+}
+
+// paymentClaimTimeout is how long a claim stays exclusive. A claim older
+// than that was left by a call that never finished and may be taken over.
+const paymentClaimTimeout = "-10 minutes"
+
+// Claim marks a gateway call (CAPTURE, VOID or REFUND) as in flight before
+// it is made. It only succeeds while the row is still as the caller read it
+// (p) and no other call holds it, so two concurrent captures or refunds
+// cannot both reach the gateway.
+func (r *PaymentRepo) Claim(p Payment, action string) (bool, error) {
+	res, err := r.db.Exec(`
+	  UPDATE payments SET pending = ?, updated_at = CURRENT_TIMESTAMP
+	  WHERE id = ? AND status = ? AND refunded = ?
+	    AND (pending IS NULL OR updated_at < datetime('now', ?))
+	`, action, p.ID, p.Status, p.Refunded, paymentClaimTimeout)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Unclaim drops a claim after the gateway refused the call.
+func (r *PaymentRepo) Unclaim(p Payment, action string) error {
+	_, err := r.db.Exec(`UPDATE payments SET pending = NULL WHERE id = ? AND pending = ?`, p.ID, action)
+	return err
+}
+
+// Update stores a payment's new status and running totals once the
+// claimed gateway call went through, and clears the claim. It only applies
+// while the row is still as the caller read it (p).
+func (r *PaymentRepo) Update(p Payment, status string, captured, refunded float64) (bool, error) {
+	res, err := r.db.Exec(`
+	  UPDATE payments SET status = ?, captured = ?, refunded = ?, pending = NULL, updated_at = CURRENT_TIMESTAMP
+	  WHERE id = ? AND status = ? AND refunded = ?
+	`, status, captured, refunded, p.ID, p.Status, p.Refunded)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
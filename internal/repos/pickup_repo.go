@@ -0,0 +1,164 @@
+package repos
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PickupSlotFormat is how pickup slots are stored: store-local wall time.
+const PickupSlotFormat = "2006-01-02 15:04"
+
+// PickupWindow renders a stored slot as "Tue 20 Oct 2026, 10:00–11:00".
+func PickupWindow(from, until string) string {
+	start, err := time.Parse(PickupSlotFormat, from)
+	if err != nil {
+		return from
+	}
+	end, err := time.Parse(PickupSlotFormat, until)
+	if err != nil {
+		return start.Format("Mon 2 Jan 2006, 15:04")
+	}
+	return start.Format("Mon 2 Jan 2006, 15:04") + "–" + end.Format("15:04")
+}
+
+// PickupHours is when a store hands out orders on one weekday.
+type PickupHours struct {
+	Region  string `db:"region_code"`
+	Weekday int    `db:"weekday"` // 0 = Sunday
+	Opens   string `db:"opens"`   // HH:MM
+	Closes  string `db:"closes"`
+}
+
+func (h PickupHours) Day() string { return time.Weekday(h.Weekday).String() }
+
+// PickupOrder is a line of the staff pickup queue.
+type PickupOrder struct {
+	ID          string `db:"id"`
+	OrderNo     string `db:"order_no"`
+	Customer    string `db:"customer_name"`
+	Region      string `db:"region_code"`
+	Status      string `db:"status"`
+	PickupFrom  string `db:"pickup_from"`
+	PickupUntil string `db:"pickup_until"`
+	PickupCode  string `db:"pickup_code"`
+	ReadyAt     string `db:"ready_at"`
+}
+
+func (o PickupOrder) Number() string {
+	if o.OrderNo != "" {
+		return o.OrderNo
+	}
+	return o.ID
+}
+
+func (o PickupOrder) Window() string { return PickupWindow(o.PickupFrom, o.PickupUntil) }
+
+type PickupRepo struct{ db *sqlx.DB }
+
+func NewPickupRepo(db *sqlx.DB) *PickupRepo { return &PickupRepo{db: db} }
+
+// Hours lists a store's opening hours by weekday.
+func (r *PickupRepo) Hours(region string) ([]PickupHours, error) {
+	var out []PickupHours
+	err := r.db.Select(&out, `SELECT region_code, weekday, opens, closes FROM pickup_hours WHERE region_code = ? ORDER BY weekday`, region)
+	return out, err
+}
+
+// AllHours lists the hours of every store.
+func (r *PickupRepo) AllHours() ([]PickupHours, error) {
+	var out []PickupHours
+	err := r.db.Select(&out, `SELECT region_code, weekday, opens, closes FROM pickup_hours ORDER BY region_code, weekday`)
+	return out, err
+}
+
+// SaveHours sets a store's hours for one weekday.
+func (r *PickupRepo) SaveHours(h PickupHours) error {
+	_, err := r.db.Exec(`
+	  INSERT INTO pickup_hours(region_code, weekday, opens, closes) VALUES(?, ?, ?, ?)
+	  ON CONFLICT(region_code, weekday) DO UPDATE SET opens = excluded.opens, closes = excluded.closes
+	`, h.Region, h.Weekday, h.Opens, h.Closes)
+	return err
+}
+
+// DeleteHours closes a store for pickup on one weekday.
+func (r *PickupRepo) DeleteHours(region string, weekday int) error {
+	_, err := r.db.Exec(`DELETE FROM pickup_hours WHERE region_code = ? AND weekday = ?`, region, weekday)
+	return err
+}
+
+// IDByCode finds an order by its pickup code (sql.ErrNoRows if none).
+func (r *PickupRepo) IDByCode(code string) (string, error) {
+	var id string
+	err := r.db.Get(&id, `SELECT id FROM orders WHERE pickup_code = ?`, code)
+	return id, err
+}
+
+const pickupOrderCols = `id, COALESCE(order_no,'') AS order_no, customer_name, region_code, status,
+	COALESCE(pickup_from,'') AS pickup_from, COALESCE(pickup_until,'') AS pickup_until,
+	COALESCE(pickup_code,'') AS pickup_code, COALESCE(ready_at,'') AS ready_at`
+
+// Queue lists pickup orders that have not been collected or canceled, by
+// slot.
+func (r *PickupRepo) Queue(limit int) ([]PickupOrder, error) {
+	var out []PickupOrder
+	err := r.db.Select(&out, `
+	  SELECT `+pickupOrderCols+` FROM orders
+	  WHERE fulfillment = 'pickup' AND status IN ('PLACED','RESERVED','READY_FOR_PICKUP')
+	  ORDER BY COALESCE(pickup_from, created_at), created_at
+	  LIMIT ?
+	`, limit)
+	return out, err
+}
+
+// Waiting lists the orders ready for pickup, oldest first.
+func (r *PickupRepo) Waiting() ([]PickupOrder, error) {
+	var out []PickupOrder
+	err := r.db.Select(&out, `SELECT `+pickupOrderCols+` FROM orders WHERE status = 'READY_FOR_PICKUP' ORDER BY ready_at`)
+	return out, err
+}
+
+// MarkReady moves a pickup order that is still being prepared to
+// READY_FOR_PICKUP, recording h in its history and queueing mails (the
+// customer's notice) with it. It reports false when the order was not
+// waiting for preparation.
+func (r *PickupRepo) MarkReady(orderID string, h StatusChange, mails ...OutboxEmail) (bool, error) {
+	return r.transition(orderID, `
+	  UPDATE orders SET status = 'READY_FOR_PICKUP', ready_at = CURRENT_TIMESTAMP
+	  WHERE id = ? AND fulfillment = 'pickup' AND status IN ('PLACED','RESERVED')
+	`, h, mails)
+}
+
+// MarkCollected records that a ready order was handed over and how the
+// collector's ID was checked, issues its invoice number and adds h to its
+// history.
+func (r *PickupRepo) MarkCollected(orderID, note string, h StatusChange) (bool, error) {
+	return r.transition(orderID, `
+	  UPDATE orders SET status = 'COLLECTED', collected_at = CURRENT_TIMESTAMP, collect_note = ?, `+issueInvoice+`
+	  WHERE id = ? AND status = 'READY_FOR_PICKUP'
+	`, h, nil, note)
+}
+
+// transition runs a guarded status UPDATE (orderID is its last argument)
+// and, if it matched, adds h to the history and queues mails.
+func (r *PickupRepo) transition(orderID, query string, h StatusChange, mails []OutboxEmail, args ...any) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, err := tx.Exec(query, append(args, orderID)...)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return false, err
+	}
+	if err := insertHistory(tx, orderID, h); err != nil {
+		return false, err
+	}
+	if err := enqueueEmails(tx, mails); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,127 @@
+package repos
+
+import (
+	"github.com/jmoiron/sqlx"
+)
+
+type PriceRepo struct{ db *sqlx.DB }
+
+func NewPriceRepo(db *sqlx.DB) *PriceRepo { return &PriceRepo{db: db} }
+
+// PriceEntry is one row of a product's price history.
+type PriceEntry struct {
+	ID        int64   `db:"id"`
+	ProductID string  `db:"product_id"`
+	Kind      string  `db:"kind"` // BASE | SALE
+	Price     float64 `db:"price"`
+	StartsAt  string  `db:"starts_at"`
+	EndsAt    string  `db:"ends_at"`
+	CreatedAt string  `db:"created_at"`
+	CreatedBy string  `db:"created_by"`
+}
+
+// Sale is the sale price in effect for a product.
+type Sale struct {
+	ProductID string  `db:"product_id"`
+	Price     float64 `db:"price"`
+	EndsAt    string  `db:"ends_at"`
+}
+
+// History lists a product's base price changes and sales, newest first.
+func (r *PriceRepo) History(productID string) ([]PriceEntry, error) {
+	var out []PriceEntry
+	err := r.db.Select(&out, `
+	  SELECT id, product_id, kind, price, starts_at, COALESCE(ends_at,'') AS ends_at,
+	         created_at, COALESCE(created_by,'') AS created_by
+	  FROM product_prices
+	  WHERE product_id = ?
+	  ORDER BY starts_at DESC, id DESC
+	`, productID)
+	return out, err
+}
+
+// SetBase changes a product's list price and records the change.
+func (r *PriceRepo) SetBase(productID string, price float64, at, by string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`UPDATE products SET price = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, price, productID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+	  INSERT INTO product_prices(product_id, kind, price, starts_at, created_at, created_by)
+	  VALUES(?, 'BASE', ?, ?, ?, ?)
+	`, productID, price, at, at, by); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaleOverlaps reports whether a sale already covers part of [starts, ends).
+func (r *PriceRepo) SaleOverlaps(productID, starts, ends string) (bool, error) {
+	var n int
+	err := r.db.Get(&n, `
+	  SELECT COUNT(*) FROM product_prices
+	  WHERE product_id = ? AND kind = 'SALE' AND starts_at < ? AND ends_at > ?
+	`, productID, ends, starts)
+	return n > 0, err
+}
+
+func (r *PriceRepo) AddSale(productID string, price float64, starts, ends, at, by string) error {
+	_, err := r.db.Exec(`
+	  INSERT INTO product_prices(product_id, kind, price, starts_at, ends_at, created_at, created_by)
+	  VALUES(?, 'SALE', ?, ?, ?, ?, ?)
+	`, productID, price, starts, ends, at, by)
+	return err
+}
+
+// EndSale stops a sale at `at`: a running sale is cut short, a future one
+// is removed. It returns false when there was nothing left to end.
+func (r *PriceRepo) EndSale(productID string, saleID int64, at string) (bool, error) {
+	res, err := r.db.Exec(`
+	  DELETE FROM product_prices
+	  WHERE id = ? AND product_id = ? AND kind = 'SALE' AND starts_at > ?
+	`, saleID, productID, at)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return true, nil
+	}
+	res, err = r.db.Exec(`
+	  UPDATE product_prices SET ends_at = ?
+	  WHERE id = ? AND product_id = ? AND kind = 'SALE' AND ends_at > ?
+	`, at, saleID, productID, at)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ActiveSales returns the running sale for each of ids at time `at`.
+func (r *PriceRepo) ActiveSales(ids []string, at string) (map[string]Sale, error) {
+	out := map[string]Sale{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	q, args, err := sqlx.In(`
+	  SELECT product_id, MIN(price) AS price, MAX(ends_at) AS ends_at
+	  FROM product_prices
+	  WHERE kind = 'SALE' AND product_id IN (?) AND starts_at <= ? AND ends_at > ?
+	  GROUP BY product_id
+	`, ids, at, at)
+	if err != nil {
+		return nil, err
+	}
+	var rows []Sale
+	if err := r.db.Select(&rows, r.db.Rebind(q), args...); err != nil {
+		return nil, err
+	}
+	for _, s := range rows {
+		out[s.ProductID] = s
+	}
+	return out, nil
+}
@@ -1,6 +1,8 @@
 ﻿package repos
 
 import (
+"strings"
+
 "retrobytes/internal/domain"
 "github.com/jmoiron/sqlx"
 )
@@ -15,7 +17,7 @@ err := r.db.Select(&out, `
     id, category_id, title, description, condition, price, images_json, active,
     created_at, COALESCE(updated_at,'') AS updated_at
   FROM products
-  WHERE category_id = ? AND active = 1
+  WHERE category_id = ? AND active = 1 AND parent_id IS NULL
   ORDER BY created_at DESC
   LIMIT ? OFFSET ?
 `, catID, limit, offset)
@@ -34,15 +36,28 @@ err := r.db.Get(&p, `
 return p, err
 }
 
-func (r *ProductRepo) Search(q, catID, cond string, limit, offset int) ([]domain.Product, error) {
-where := `active = 1`
+// Search lists active top-level products. cond is a minimum grade
+// (domain.Grades) and matches that grade or better; sort is "" (newest),
+// "rating", "price_asc" or "price_desc". attrs filters on structured
+// attributes (key -> exact value); a variant matching an attribute counts
+// for its parent listing.
+func (r *ProductRepo) Search(q, catID, cond string, attrs map[string]string, sort string, limit, offset int) ([]domain.Product, error) {
+where := `active = 1 AND parent_id IS NULL`
 args := []any{}
 if q != "" {
 where += ` AND (LOWER(title) LIKE ? OR LOWER(description) LIKE ?)`
 args = append(args, "%"+q+"%", "%"+q+"%")
 }
 if catID != "" { where += ` AND category_id = ?`; args = append(args, catID) }
-if cond != "" { where += ` AND condition = ?`; args = append(args, cond) }
+if grades := domain.GradesAtLeast(cond); len(grades) > 0 {
+where += ` AND condition IN (?` + strings.Repeat(`,?`, len(grades)-1) + `)`
+for _, g := range grades { args = append(args, g) }
+}
+for k, v := range attrs {
+where += ` AND EXISTS (SELECT 1 FROM product_attributes pa WHERE pa.key = ? AND pa.value = ?
+    AND (pa.product_id = products.id OR pa.product_id IN (SELECT id FROM products c WHERE c.parent_id = products.id)))`
+args = append(args, k, v)
+}
 
 sql := `
   SELECT
@@ -50,7 +65,7 @@ sql := `
     created_at, COALESCE(updated_at,'') AS updated_at
   FROM products
   WHERE ` + where + `
-  ORDER BY created_at DESC
+  ORDER BY ` + searchOrder(sort) + `
   LIMIT ? OFFSET ?`
 args = append(args, limit, offset)
 
@@ -58,3 +73,135 @@ var out []domain.Product
 err := r.db.Select(&out, sql, args...)
 return out, err
 }
+
+// searchOrder maps a whitelisted sort key to an ORDER BY clause.
+// "rating" ranks by approved-review average, then review count.
+func searchOrder(sort string) string {
+switch sort {
+case "rating":
+return `(SELECT AVG(rating) FROM reviews rv WHERE rv.product_id = products.id AND rv.status = 'APPROVED') DESC NULLS LAST,
+    (SELECT COUNT(*) FROM reviews rv WHERE rv.product_id = products.id AND rv.status = 'APPROVED') DESC,
+    created_at DESC`
+case "price_asc":
+return `price ASC, created_at DESC`
+case "price_desc":
+return `price DESC, created_at DESC`
+}
+return `created_at DESC`
+}
+
+// ListedBetween returns active top-level products put on sale in (from, to]
+// (created listed, or listed later), oldest first.
+func (r *ProductRepo) ListedBetween(from, to string) ([]domain.Product, error) {
+var out []domain.Product
+err := r.db.Select(&out, `
+  SELECT
+    id, category_id, title, description, condition, price, images_json, active,
+    created_at, COALESCE(updated_at,'') AS updated_at
+  FROM products
+  WHERE active = 1 AND parent_id IS NULL
+    AND datetime(COALESCE(listed_at, created_at)) > datetime(?)
+    AND datetime(COALESCE(listed_at, created_at)) <= datetime(?)
+  ORDER BY COALESCE(listed_at, created_at)
+`, from, to)
+return out, err
+}
+
+// ListAll returns every product, active or not (admin catalog view).
+func (r *ProductRepo) ListAll() ([]domain.Product, error) {
+var out []domain.Product
+err := r.db.Select(&out, `
+  SELECT
+    id, category_id, title, description, condition, price, COALESCE(images_json,'') AS images_json, active,
+    created_at, COALESCE(updated_at,'') AS updated_at,
+    COALESCE(parent_id,'') AS parent_id, COALESCE(variant_label,'') AS variant_label
+  FROM products
+  ORDER BY COALESCE(parent_id, id), parent_id IS NOT NULL, title
+`)
+return out, err
+}
+
+// SetImages replaces the gallery (images_json) of a product.
+func (r *ProductRepo) SetImages(id, imagesJSON string) error {
+_, err := r.db.Exec(`UPDATE products SET images_json = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, imagesJSON, id)
+return err
+}
+
+// Variants returns the child products (variants) of a listing.
+func (r *ProductRepo) Variants(parentID string) ([]domain.Product, error) {
+var out []domain.Product
+err := r.db.Select(&out, `
+  SELECT
+    id, category_id, title, description, condition, price, COALESCE(images_json,'') AS images_json, active,
+    created_at, COALESCE(updated_at,'') AS updated_at,
+    COALESCE(parent_id,'') AS parent_id, COALESCE(variant_label,'') AS variant_label
+  FROM products
+  WHERE parent_id = ?
+  ORDER BY created_at, variant_label
+`, parentID)
+return out, err
+}
+
+// ParentID returns the listing a variant belongs to ("" for top-level products).
+func (r *ProductRepo) ParentID(id string) (string, error) {
+var parent string
+err := r.db.Get(&parent, `SELECT COALESCE(parent_id,'') FROM products WHERE id = ?`, id)
+return parent, err
+}
+
+// CreateVariant adds a child product that inherits the parent's category,
+// description, condition and gallery, and opens its price history.
+func (r *ProductRepo) CreateVariant(parent domain.Product, id, label string, price float64) error {
+tx, err := r.db.Beginx()
+if err != nil {
+return err
+}
+defer func() { _ = tx.Rollback() }()
+if _, err := tx.Exec(`
+  INSERT INTO products(id, category_id, title, description, condition, price, images_json, active, created_at, parent_id, variant_label)
+  VALUES(?, ?, ?, ?, ?, ?, ?, 1, CURRENT_TIMESTAMP, ?, ?)
+`, id, parent.CategoryID, parent.Title+" ("+label+")", parent.Description, parent.Condition, price, parent.ImagesJSON, parent.ID, label); err != nil {
+return err
+}
+if _, err := tx.Exec(`INSERT INTO product_prices(product_id, kind, price, starts_at) VALUES(?, 'BASE', ?, CURRENT_TIMESTAMP)`, id, price); err != nil {
+return err
+}
+return tx.Commit()
+}
+
+// SetActive lists or unlists a product. Listing an unlisted product
+// records when it went on sale.
+func (r *ProductRepo) SetActive(id string, active bool) error {
+_, err := r.db.Exec(`
+  UPDATE products
+  SET listed_at = CASE WHEN ? AND active = 0 THEN CURRENT_TIMESTAMP ELSE listed_at END,
+      active = ?, updated_at = CURRENT_TIMESTAMP
+  WHERE id = ?
+`, active, active, id)
+return err
+}
+
+// ConditionDetails returns a product's condition notes and condition photos JSON.
+func (r *ProductRepo) ConditionDetails(id string) (notes, imagesJSON string, err error) {
+var row struct {
+Notes  string `db:"notes"`
+Images string `db:"images"`
+}
+err = r.db.Get(&row, `
+  SELECT COALESCE(condition_notes,'') AS notes, COALESCE(condition_images_json,'') AS images
+  FROM products WHERE id = ?
+`, id)
+return row.Notes, row.Images, err
+}
+
+// SetCondition updates a product's grade and condition notes.
+func (r *ProductRepo) SetCondition(id, grade, notes string) error {
+_, err := r.db.Exec(`UPDATE products SET condition = ?, condition_notes = NULLIF(?,''), updated_at = CURRENT_TIMESTAMP WHERE id = ?`, grade, notes, id)
+return err
+}
+
+// SetConditionImages replaces a product's condition photos JSON.
+func (r *ProductRepo) SetConditionImages(id, imagesJSON string) error {
+_, err := r.db.Exec(`UPDATE products SET condition_images_json = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, imagesJSON, id)
+return err
+}
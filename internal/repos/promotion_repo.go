@@ -0,0 +1,229 @@
+package repos
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrRedemptionLimit and ErrCustomerRedemptionLimit mean recording a
+// redemption would take the promotion past its total or per-customer limit.
+var (
+	ErrRedemptionLimit         = errors.New("promotion redemption limit reached")
+	ErrCustomerRedemptionLimit = errors.New("promotion per-customer limit reached")
+)
+
+type PromotionRepo struct{ db *sqlx.DB }
+
+func NewPromotionRepo(db *sqlx.DB) *PromotionRepo { return &PromotionRepo{db: db} }
+
+// Promotion is a coupon code. Zero MaxUses/MaxPerCustomer mean unlimited,
+// empty StartsAt/EndsAt an open window.
+type Promotion struct {
+	ID             string  `db:"id"`
+	Code           string  `db:"code"`
+	Description    string  `db:"description"`
+	Kind           string  `db:"kind"` // PERCENT | FIXED
+	Amount         float64 `db:"amount"`
+	MinSubtotal    float64 `db:"min_subtotal"`
+	Scope          string  `db:"scope"` // ALL | CATEGORY | PRODUCT
+	ScopeID        string  `db:"scope_id"`
+	MaxUses        int     `db:"max_uses"`
+	MaxPerCustomer int     `db:"max_uses_per_customer"`
+	StartsAt       string  `db:"starts_at"`
+	EndsAt         string  `db:"ends_at"`
+	Active         bool    `db:"active"`
+	CreatedAt      string  `db:"created_at"`
+
+	// Report columns, filled by List
+	Uses       int     `db:"uses"`
+	Discounted float64 `db:"discounted"`
+}
+
+// Value renders the discount, e.g. "10% off" or "$5.00 off".
+func (p Promotion) Value() string {
+	if p.Kind == "PERCENT" {
+		return strconv.FormatFloat(p.Amount, 'f', -1, 64) + "% off"
+	}
+	return fmt.Sprintf("$%.2f off", p.Amount)
+}
+
+// StartsInput and EndsInput format the window for datetime-local inputs.
+func (p Promotion) StartsInput() string { return dateTimeInput(p.StartsAt) }
+func (p Promotion) EndsInput() string   { return dateTimeInput(p.EndsAt) }
+
+func dateTimeInput(ts string) string {
+	if len(ts) < 16 {
+		return ""
+	}
+	return ts[:10] + "T" + ts[11:16]
+}
+
+const promotionCols = `
+	  p.id, p.code, p.description, p.kind, p.amount, p.min_subtotal, p.scope, COALESCE(p.scope_id,'') AS scope_id,
+	  COALESCE(p.max_uses,0) AS max_uses, COALESCE(p.max_uses_per_customer,0) AS max_uses_per_customer,
+	  COALESCE(p.starts_at,'') AS starts_at, COALESCE(p.ends_at,'') AS ends_at, p.active, p.created_at`
+
+// List returns all promotions with redemption counts and totals. Here and
+// in the limit checks below, redemptions on canceled orders do not count.
+func (r *PromotionRepo) List() ([]Promotion, error) {
+	var out []Promotion
+	err := r.db.Select(&out, `
+	  SELECT `+promotionCols+`,
+	         COUNT(pr.id) AS uses, COALESCE(SUM(pr.amount),0) AS discounted
+	  FROM promotions p
+	  LEFT JOIN promotion_redemptions pr ON pr.promotion_id = p.id
+	    AND pr.order_id NOT IN (SELECT id FROM orders WHERE status = 'CANCELED')
+	  GROUP BY p.id
+	  ORDER BY p.active DESC, p.created_at DESC
+	`)
+	return out, err
+}
+
+func (r *PromotionRepo) Get(id string) (Promotion, error) {
+	var p Promotion
+	err := r.db.Get(&p, `SELECT `+promotionCols+`, 0 AS uses, 0 AS discounted FROM promotions p WHERE p.id = ?`, id)
+	return p, err
+}
+
+// ByCode finds a promotion by code, case-insensitively. ok is false when
+// no such code exists.
+func (r *PromotionRepo) ByCode(code string) (p Promotion, ok bool, err error) {
+	err = r.db.Get(&p, `SELECT `+promotionCols+`, 0 AS uses, 0 AS discounted FROM promotions p WHERE p.code = ?`, code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, false, nil
+	}
+	return p, err == nil, err
+}
+
+func (r *PromotionRepo) Create(p Promotion) error {
+	_, err := r.db.Exec(`
+	  INSERT INTO promotions(id, code, description, kind, amount, min_subtotal, scope, scope_id,
+	                         max_uses, max_uses_per_customer, starts_at, ends_at, active)
+	  VALUES(?, ?, ?, ?, ?, ?, ?, NULLIF(?,''), NULLIF(?,0), NULLIF(?,0), NULLIF(?,''), NULLIF(?,''), ?)
+	`, p.ID, p.Code, p.Description, p.Kind, p.Amount, p.MinSubtotal, p.Scope, p.ScopeID,
+		p.MaxUses, p.MaxPerCustomer, p.StartsAt, p.EndsAt, p.Active)
+	return err
+}
+
+func (r *PromotionRepo) Update(p Promotion) error {
+	_, err := r.db.Exec(`
+	  UPDATE promotions SET code = ?, description = ?, kind = ?, amount = ?, min_subtotal = ?, scope = ?,
+	         scope_id = NULLIF(?,''), max_uses = NULLIF(?,0), max_uses_per_customer = NULLIF(?,0),
+	         starts_at = NULLIF(?,''), ends_at = NULLIF(?,''), active = ?
+	  WHERE id = ?
+	`, p.Code, p.Description, p.Kind, p.Amount, p.MinSubtotal, p.Scope, p.ScopeID,
+		p.MaxUses, p.MaxPerCustomer, p.StartsAt, p.EndsAt, p.Active, p.ID)
+	return err
+}
+
+func (r *PromotionRepo) SetActive(id string, active bool) error {
+	_, err := r.db.Exec(`UPDATE promotions SET active = ? WHERE id = ?`, active, id)
+	return err
+}
+
+// Delete removes a promotion that was never redeemed; it reports false if
+// the promotion has redemptions (deactivate it instead) or does not exist.
+func (r *PromotionRepo) Delete(id string) (bool, error) {
+	res, err := r.db.Exec(`
+	  DELETE FROM promotions
+	  WHERE id = ? AND NOT EXISTS (SELECT 1 FROM promotion_redemptions WHERE promotion_id = ?)
+	`, id, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// liveRedemption keeps redemptions whose order was not canceled.
+const liveRedemption = `order_id NOT IN (SELECT id FROM orders WHERE status = 'CANCELED')`
+
+const usesQuery = `SELECT COUNT(*) FROM promotion_redemptions WHERE promotion_id = ? AND ` + liveRedemption
+
+// Uses counts redemptions of a promotion.
+func (r *PromotionRepo) Uses(id string) (int, error) {
+	var n int
+	err := r.db.Get(&n, usesQuery, id)
+	return n, err
+}
+
+const customerUsesQuery = `
+	  SELECT COUNT(*) FROM promotion_redemptions
+	  WHERE promotion_id = ? AND ` + liveRedemption + ` AND (
+	    user_id = (SELECT user_id FROM sessions WHERE id = ?)
+	    OR (? <> '' AND LOWER(customer_email) = LOWER(?))
+	  )`
+
+// CustomerUses counts redemptions by the account behind sessionID or by
+// the given email address.
+func (r *PromotionRepo) CustomerUses(id, sessionID, email string) (int, error) {
+	var n int
+	err := r.db.Get(&n, customerUsesQuery, id, sessionID, email, email)
+	return n, err
+}
+
+// PromoRedemption is a promotion applied to a new order, with the limits
+// to enforce when it is recorded (0 = unlimited).
+type PromoRedemption struct {
+	PromotionID    string
+	Amount         float64
+	MaxUses        int
+	MaxPerCustomer int
+}
+
+// redeemPromotion re-counts the uses on q and records the redemption. Run
+// inside the order's transaction, which already holds the write lock, the
+// count and the insert cannot interleave with another checkout.
+func redeemPromotion(q sqlx.Ext, p PromoRedemption, orderID, sessionID, email string) error {
+	if p.MaxUses > 0 {
+		var n int
+		if err := sqlx.Get(q, &n, usesQuery, p.PromotionID); err != nil {
+			return err
+		}
+		if n >= p.MaxUses {
+			return ErrRedemptionLimit
+		}
+	}
+	if p.MaxPerCustomer > 0 {
+		var n int
+		if err := sqlx.Get(q, &n, customerUsesQuery, p.PromotionID, sessionID, email, email); err != nil {
+			return err
+		}
+		if n >= p.MaxPerCustomer {
+			return ErrCustomerRedemptionLimit
+		}
+	}
+	_, err := q.Exec(`
+	  INSERT INTO promotion_redemptions(promotion_id, order_id, user_id, customer_email, amount)
+	  VALUES(?, ?, (SELECT user_id FROM sessions WHERE id = ?), ?, ?)
+	`, p.PromotionID, orderID, sessionID, email, p.Amount)
+	return err
+}
+
+// Redemption is one use of a promotion, for the admin report.
+type Redemption struct {
+	OrderID     string  `db:"order_id"`
+	UserID      string  `db:"user_id"`
+	Email       string  `db:"customer_email"`
+	Amount      float64 `db:"amount"`
+	OrderTotal  float64 `db:"order_total"`
+	OrderStatus string  `db:"order_status"`
+	CreatedAt   string  `db:"created_at"`
+}
+
+func (r *PromotionRepo) Redemptions(id string) ([]Redemption, error) {
+	var out []Redemption
+	err := r.db.Select(&out, `
+	  SELECT pr.order_id, COALESCE(pr.user_id,'') AS user_id, pr.customer_email, pr.amount,
+	         o.total AS order_total, o.status AS order_status, pr.created_at
+	  FROM promotion_redemptions pr
+	  JOIN orders o ON o.id = pr.order_id
+	  WHERE pr.promotion_id = ?
+	  ORDER BY pr.created_at DESC, pr.id DESC
+	`, id)
+	return out, err
+}
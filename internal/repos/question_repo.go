@@ -0,0 +1,115 @@
+package repos
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type QuestionRepo struct{ db *sqlx.DB }
+
+func NewQuestionRepo(db *sqlx.DB) *QuestionRepo { return &QuestionRepo{db: db} }
+
+// Question is a shopper question about a listing. Email and ProductTitle
+// are filled for the admin queue and answer notifications.
+type Question struct {
+	ID           string `db:"id"`
+	ProductID    string `db:"product_id"`
+	UserID       string `db:"user_id"`
+	Question     string `db:"question"`
+	Answer       string `db:"answer"`
+	Status       string `db:"status"`
+	CreatedAt    string `db:"created_at"`
+	AnsweredAt   string `db:"answered_at"`
+	Author       string `db:"author"`
+	Email        string `db:"email"`
+	ProductTitle string `db:"product_title"`
+}
+
+const questionCols = `
+	  q.id, q.product_id, q.user_id, q.question, COALESCE(q.answer,'') AS answer, q.status,
+	  q.created_at, COALESCE(q.answered_at,'') AS answered_at,
+	  COALESCE(u.name,'Customer') AS author, COALESCE(u.email,'') AS email,
+	  COALESCE(p.title, q.product_id) AS product_title
+	  FROM product_questions q
+	  LEFT JOIN users u    ON u.id = q.user_id
+	  LEFT JOIN products p ON p.id = q.product_id`
+
+func (r *QuestionRepo) Create(productID, userID, question string) (string, error) {
+	id := uuid.NewString()
+	_, err := r.db.Exec(`
+	  INSERT INTO product_questions(id, product_id, user_id, question, status, created_at)
+	  VALUES(?, ?, ?, ?, 'OPEN', CURRENT_TIMESTAMP)
+	`, id, productID, userID, question)
+	return id, err
+}
+
+func (r *QuestionRepo) Get(id string) (Question, error) {
+	var q Question
+	err := r.db.Get(&q, `SELECT `+questionCols+` WHERE q.id = ?`, id)
+	return q, err
+}
+
+// CountRecent counts questions the user asked in the last `hours` hours.
+func (r *QuestionRepo) CountRecent(userID string, hours int) (int, error) {
+	var n int
+	err := r.db.Get(&n, `
+	  SELECT COUNT(*) FROM product_questions
+	  WHERE user_id = ? AND datetime(created_at) >= datetime('now', ?)
+	`, userID, fmt.Sprintf("-%d hours", hours))
+	return n, err
+}
+
+// Answered lists a listing's answered questions, newest answers first.
+func (r *QuestionRepo) Answered(productID string, limit int) ([]Question, error) {
+	var out []Question
+	err := r.db.Select(&out, `SELECT `+questionCols+`
+	  WHERE q.product_id = ? AND q.status = 'ANSWERED'
+	  ORDER BY q.answered_at DESC
+	  LIMIT ?`, productID, limit)
+	return out, err
+}
+
+// ByUserForProduct lists the user's open questions on a listing.
+func (r *QuestionRepo) ByUserForProduct(userID, productID string) ([]Question, error) {
+	var out []Question
+	err := r.db.Select(&out, `SELECT `+questionCols+`
+	  WHERE q.user_id = ? AND q.product_id = ? AND q.status = 'OPEN'
+	  ORDER BY q.created_at DESC`, userID, productID)
+	return out, err
+}
+
+// ByStatus lists questions for the admin queue, oldest first.
+func (r *QuestionRepo) ByStatus(status string, limit int) ([]Question, error) {
+	var out []Question
+	err := r.db.Select(&out, `SELECT `+questionCols+`
+	  WHERE q.status = ?
+	  ORDER BY q.created_at ASC
+	  LIMIT ?`, status, limit)
+	return out, err
+}
+
+// SetAnswer stores (or edits) an answer and publishes the question.
+func (r *QuestionRepo) SetAnswer(id, answer, adminID string) (bool, error) {
+	res, err := r.db.Exec(`
+	  UPDATE product_questions
+	  SET answer = ?, status = 'ANSWERED', answered_at = CURRENT_TIMESTAMP, answered_by = ?
+	  WHERE id = ?
+	`, answer, adminID, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Hide removes a question from the queue and the product page.
+func (r *QuestionRepo) Hide(id string) (bool, error) {
+	res, err := r.db.Exec(`UPDATE product_questions SET status = 'HIDDEN' WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
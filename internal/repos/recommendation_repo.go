@@ -0,0 +1,100 @@
+package repos
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"retrobytes/internal/domain"
+)
+
+type RecommendationRepo struct{ db *sqlx.DB }
+
+func NewRecommendationRepo(db *sqlx.DB) *RecommendationRepo { return &RecommendationRepo{db: db} }
+
+// Rebuild recomputes co-purchase pairs from non-canceled orders and keeps
+// the top `perItem` related listings for each listing. Variants count as
+// their parent listing. Returns the number of stored pairs.
+func (r *RecommendationRepo) Rebuild(perItem int, computedAt string) (int64, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM product_recommendations`); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`
+	  WITH lines AS (
+	    SELECT DISTINCT oi.order_id, COALESCE(p.parent_id, p.id) AS pid
+	    FROM order_items oi
+	    JOIN orders o   ON o.id = oi.order_id
+	    JOIN products p ON p.id = oi.product_id
+	    WHERE o.status <> 'CANCELED'
+	  ),
+	  pairs AS (
+	    SELECT a.pid AS product_id, b.pid AS related_id, COUNT(*) AS score
+	    FROM lines a JOIN lines b ON a.order_id = b.order_id AND a.pid <> b.pid
+	    GROUP BY a.pid, b.pid
+	  ),
+	  ranked AS (
+	    SELECT product_id, related_id, score,
+	           ROW_NUMBER() OVER (PARTITION BY product_id ORDER BY score DESC, related_id) AS rnk
+	    FROM pairs
+	  )
+	  INSERT INTO product_recommendations(product_id, related_id, score, rank, computed_at)
+	  SELECT product_id, related_id, score, rnk, ? FROM ranked WHERE rnk <= ?
+	`, computedAt, perItem)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, tx.Commit()
+}
+
+// Related returns active listings co-purchased with any of productIDs, best
+// first, excluding productIDs themselves.
+func (r *RecommendationRepo) Related(productIDs []string, limit int) ([]domain.Product, error) {
+	if len(productIDs) == 0 {
+		return nil, nil
+	}
+	q, args, err := sqlx.In(`
+	  SELECT p.id, p.category_id, p.title, p.description, p.condition, p.price, p.images_json, p.active,
+	         p.created_at, COALESCE(p.updated_at,'') AS updated_at
+	  FROM product_recommendations rc
+	  JOIN products p ON p.id = rc.related_id
+	  WHERE rc.product_id IN (?) AND rc.related_id NOT IN (?) AND p.active = 1
+	  GROUP BY p.id
+	  ORDER BY SUM(rc.score) DESC, MIN(rc.rank), p.id
+	  LIMIT ?
+	`, productIDs, productIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+	var out []domain.Product
+	err = r.db.Select(&out, r.db.Rebind(q), args...)
+	return out, err
+}
+
+// SameCategory returns active listings from the categories of productIDs,
+// newest first, skipping exclude (the fallback when co-purchase data is
+// sparse).
+func (r *RecommendationRepo) SameCategory(productIDs, exclude []string, limit int) ([]domain.Product, error) {
+	if len(productIDs) == 0 {
+		return nil, nil
+	}
+	q, args, err := sqlx.In(`
+	  SELECT id, category_id, title, description, condition, price, images_json, active,
+	         created_at, COALESCE(updated_at,'') AS updated_at
+	  FROM products
+	  WHERE active = 1 AND parent_id IS NULL AND id NOT IN (?)
+	    AND category_id IN (SELECT category_id FROM products WHERE id IN (?))
+	  ORDER BY created_at DESC, id
+	  LIMIT ?
+	`, exclude, productIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+	var out []domain.Product
+	err = r.db.Select(&out, r.db.Rebind(q), args...)
+	return out, err
+}
@@ -0,0 +1,157 @@
+package repos
+
+import (
+	"errors"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrRefundsChanged means another refund was recorded on the order since
+// the caller summed it up.
+var ErrRefundsChanged = errors.New("order refunds changed")
+
+// Refund is money given back on an order.
+type Refund struct {
+	ID        string  `db:"id"`
+	OrderID   string  `db:"order_id"`
+	PaymentID string  `db:"payment_id"` // "" for offline refunds
+	Ref       string  `db:"ref"`
+	Amount    float64 `db:"amount"`
+	Shipping  float64 `db:"shipping"` // part of Amount that refunds shipping
+	Reason    string  `db:"reason"`
+	Note      string  `db:"note"`
+	Restocked bool    `db:"restocked"`
+	AdminID   string  `db:"admin_id"`
+	// Pending is set while the gateway has not confirmed the payout
+	Pending   bool   `db:"pending"`
+	CreatedAt string `db:"created_at"`
+	Items     []RefundItem
+}
+
+// RefundItem is the refunded part of one order line.
+type RefundItem struct {
+	RefundID  string  `db:"refund_id"`
+	ProductID string  `db:"product_id"`
+	Title     string  `db:"title"`
+	Qty       int     `db:"qty"`
+	Amount    float64 `db:"amount"`
+	Tax       float64 `db:"tax"` // part of Amount that is sales tax
+}
+
+type RefundRepo struct{ db *sqlx.DB }
+
+func NewRefundRepo(db *sqlx.DB) *RefundRepo { return &RefundRepo{db: db} }
+
+// Reserve stores a pending refund with its lines, under f.ID when the
+// caller chose one. refundedBefore is what the caller saw refunded on the
+// order; if other refunds came in since, nothing is stored and
+// ErrRefundsChanged is returned. The check and the insert share a
+// transaction, so two refunds cannot both spend what is left.
+func (r *RefundRepo) Reserve(f Refund, refundedBefore float64) (string, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if f.ID == "" {
+		f.ID = uuid.NewString()
+	}
+	if _, err := tx.Exec(`
+	  INSERT INTO refunds(id, order_id, payment_id, ref, amount, shipping, reason, note, restocked, admin_id, pending)
+	  VALUES(?, ?, NULLIF(?,''), NULL, ?, ?, ?, NULLIF(?,''), ?, NULLIF(?,''), 1)
+	`, f.ID, f.OrderID, f.PaymentID, f.Amount, f.Shipping, f.Reason, f.Note, f.Restocked, f.AdminID); err != nil {
+		return "", err
+	}
+	// counted after the insert, which holds the write lock
+	var refunded float64
+	if err := tx.Get(&refunded, `SELECT COALESCE(SUM(amount),0) FROM refunds WHERE order_id = ? AND id <> ?`, f.OrderID, f.ID); err != nil {
+		return "", err
+	}
+	if math.Abs(refunded-refundedBefore) >= 0.005 {
+		return "", ErrRefundsChanged
+	}
+	for _, it := range f.Items {
+		if _, err := tx.Exec(`
+		  INSERT INTO refund_items(refund_id, order_id, product_id, qty, amount, tax) VALUES(?, ?, ?, ?, ?, ?)
+		`, f.ID, f.OrderID, it.ProductID, it.Qty, it.Amount, it.Tax); err != nil {
+			return "", err
+		}
+	}
+	return f.ID, tx.Commit()
+}
+
+// Complete records a reserved refund as paid out under the gateway's ref
+// ("" for offline refunds) and, for restocked refunds, puts the units back
+// in region's stock in the same transaction.
+func (r *RefundRepo) Complete(f Refund, region string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, err := tx.Exec(`UPDATE refunds SET pending = 0, ref = NULLIF(?,'') WHERE id = ? AND pending = 1`, f.Ref, f.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return err // already completed
+	}
+	if f.Restocked {
+		for _, it := range f.Items {
+			if err := incrementStock(tx, it.ProductID, region, it.Qty); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// Release drops a reserved refund the gateway refused.
+func (r *RefundRepo) Release(id string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`DELETE FROM refund_items WHERE refund_id = ? AND refund_id IN (SELECT id FROM refunds WHERE pending = 1)`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM refunds WHERE id = ? AND pending = 1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ForOrder lists an order's refunds, oldest first, with their lines.
+func (r *RefundRepo) ForOrder(orderID string) ([]Refund, error) {
+	var out []Refund
+	if err := r.db.Select(&out, `
+	  SELECT id, order_id, COALESCE(payment_id,'') AS payment_id, COALESCE(ref,'') AS ref, amount, shipping,
+	         reason, COALESCE(note,'') AS note, restocked, COALESCE(admin_id,'') AS admin_id, pending, created_at
+	  FROM refunds WHERE order_id = ?
+	  ORDER BY created_at, rowid
+	`, orderID); err != nil || len(out) == 0 {
+		return out, err
+	}
+	var items []RefundItem
+	if err := r.db.Select(&items, `
+	  SELECT ri.refund_id, ri.product_id, p.title, ri.qty, ri.amount, ri.tax
+	  FROM refund_items ri
+	  JOIN products p ON p.id = ri.product_id
+	  WHERE ri.order_id = ?
+	  ORDER BY p.title
+	`, orderID); err != nil {
+		return nil, err
+	}
+	byID := map[string]int{}
+	for i, f := range out {
+		byID[f.ID] = i
+	}
+	for _, it := range items {
+		i := byID[it.RefundID]
+		out[i].Items = append(out[i].Items, it)
+	}
+	return out, nil
+}
@@ -0,0 +1,193 @@
+package repos
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Return is a customer's request to send order lines back (an RMA).
+type Return struct {
+	ID          string `db:"id"`
+	OrderID     string `db:"order_id"`
+	Status      string `db:"status"` // REQUESTED | APPROVED | REJECTED | RECEIVED | INSPECTED | REFUNDED
+	Reason      string `db:"reason"`
+	Details     string `db:"details"`
+	PhotosJSON  string `db:"photos_json"`
+	AdminNote   string `db:"admin_note"`
+	Disposition string `db:"disposition"` // RESTOCK | WRITE_OFF once refunded
+	RefundID    string `db:"refund_id"`
+	CreatedAt   string `db:"created_at"`
+	UpdatedAt   string `db:"updated_at"`
+	Customer    string `db:"customer_name"` // filled by List
+	Items       []ReturnItem
+}
+
+// Photos lists the stored photo file names.
+func (r Return) Photos() []string {
+	var out []string
+	_ = json.Unmarshal([]byte(r.PhotosJSON), &out)
+	return out
+}
+
+// Open reports whether staff still have to act on the return.
+func (r Return) Open() bool { return r.Status != "REJECTED" && r.Status != "REFUNDED" }
+
+// ReturnItem is the returned quantity of one order line.
+type ReturnItem struct {
+	ReturnID  string `db:"return_id"`
+	ProductID string `db:"product_id"`
+	Title     string `db:"title"`
+	Qty       int    `db:"qty"`
+}
+
+type ReturnRepo struct{ db *sqlx.DB }
+
+func NewReturnRepo(db *sqlx.DB) *ReturnRepo { return &ReturnRepo{db: db} }
+
+const returnCols = `rt.id, rt.order_id, rt.status, rt.reason, COALESCE(rt.details,'') AS details,
+	COALESCE(rt.photos_json,'') AS photos_json, COALESCE(rt.admin_note,'') AS admin_note,
+	COALESCE(rt.disposition,'') AS disposition, COALESCE(rt.refund_id,'') AS refund_id,
+	rt.created_at, rt.updated_at`
+
+// Create stores a new REQUESTED return with its lines.
+func (r *ReturnRepo) Create(ret Return) (string, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if ret.ID == "" {
+		ret.ID = uuid.NewString()
+	}
+	if _, err := tx.Exec(`
+	  INSERT INTO returns(id, order_id, reason, details, photos_json)
+	  VALUES(?, ?, ?, NULLIF(?,''), NULLIF(?,''))
+	`, ret.ID, ret.OrderID, ret.Reason, ret.Details, ret.PhotosJSON); err != nil {
+		return "", err
+	}
+	for _, it := range ret.Items {
+		if _, err := tx.Exec(`INSERT INTO return_items(return_id, order_id, product_id, qty) VALUES(?, ?, ?, ?)`,
+			ret.ID, ret.OrderID, it.ProductID, it.Qty); err != nil {
+			return "", err
+		}
+	}
+	return ret.ID, tx.Commit()
+}
+
+// Get returns one return with its lines.
+func (r *ReturnRepo) Get(id string) (Return, error) {
+	var ret Return
+	if err := r.db.Get(&ret, `SELECT `+returnCols+`, '' AS customer_name FROM returns rt WHERE rt.id = ?`, id); err != nil {
+		return ret, err
+	}
+	out := []Return{ret}
+	err := r.fillItems(out)
+	return out[0], err
+}
+
+// ForOrder lists an order's returns, newest first.
+func (r *ReturnRepo) ForOrder(orderID string) ([]Return, error) {
+	var out []Return
+	if err := r.db.Select(&out, `
+	  SELECT `+returnCols+`, '' AS customer_name FROM returns rt
+	  WHERE rt.order_id = ? ORDER BY rt.created_at DESC, rt.rowid DESC
+	`, orderID); err != nil {
+		return nil, err
+	}
+	return out, r.fillItems(out)
+}
+
+// List returns the staff queue: returns in the given status ("" = still
+// open), oldest first.
+func (r *ReturnRepo) List(status string, limit int) ([]Return, error) {
+	var out []Return
+	if err := r.db.Select(&out, `
+	  SELECT `+returnCols+`, o.customer_name FROM returns rt
+	  JOIN orders o ON o.id = rt.order_id
+	  WHERE (? = '' AND rt.status NOT IN ('REJECTED','REFUNDED')) OR rt.status = ?
+	  ORDER BY rt.created_at, rt.rowid
+	  LIMIT ?
+	`, status, status, limit); err != nil {
+		return nil, err
+	}
+	return out, r.fillItems(out)
+}
+
+func (r *ReturnRepo) fillItems(rets []Return) error {
+	if len(rets) == 0 {
+		return nil
+	}
+	ids := make([]string, len(rets))
+	byID := map[string]int{}
+	for i, ret := range rets {
+		ids[i] = ret.ID
+		byID[ret.ID] = i
+	}
+	query, args, err := sqlx.In(`
+	  SELECT ri.return_id, ri.product_id, p.title, ri.qty
+	  FROM return_items ri JOIN products p ON p.id = ri.product_id
+	  WHERE ri.return_id IN (?) ORDER BY p.title
+	`, ids)
+	if err != nil {
+		return err
+	}
+	var items []ReturnItem
+	if err := r.db.Select(&items, r.db.Rebind(query), args...); err != nil {
+		return err
+	}
+	for _, it := range items {
+		i := byID[it.ReturnID]
+		rets[i].Items = append(rets[i].Items, it)
+	}
+	return nil
+}
+
+// ReturnedQty sums the units per product already in returns that were not
+// rejected, plus units refunded outside a return (a refund made for a
+// return is counted once, through the return).
+func (r *ReturnRepo) ReturnedQty(orderID string) (map[string]int, error) {
+	var rows []ReturnItem
+	if err := r.db.Select(&rows, `
+	  SELECT product_id, SUM(qty) AS qty FROM (
+	    SELECT ri.product_id, ri.qty
+	    FROM return_items ri JOIN returns rt ON rt.id = ri.return_id
+	    WHERE ri.order_id = ? AND rt.status <> 'REJECTED'
+	    UNION ALL
+	    SELECT fi.product_id, fi.qty
+	    FROM refund_items fi
+	    WHERE fi.order_id = ? AND NOT EXISTS (SELECT 1 FROM returns rt WHERE rt.refund_id = fi.refund_id)
+	  ) GROUP BY product_id
+	`, orderID, orderID); err != nil {
+		return nil, err
+	}
+	out := map[string]int{}
+	for _, row := range rows {
+		out[row.ProductID] = row.Qty
+	}
+	return out, nil
+}
+
+// Transition moves a return from one status to the next, optionally
+// replacing the staff note. It reports false when the return was no longer
+// in status from.
+func (r *ReturnRepo) Transition(id, from, to, note string) (bool, error) {
+	res, err := r.db.Exec(`
+	  UPDATE returns SET status = ?, admin_note = COALESCE(NULLIF(?,''), admin_note), updated_at = CURRENT_TIMESTAMP
+	  WHERE id = ? AND status = ?
+	`, to, note, id, from)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Resolve records how a refunded return was disposed of.
+func (r *ReturnRepo) Resolve(id, disposition, refundID string) error {
+	_, err := r.db.Exec(`
+	  UPDATE returns SET disposition = ?, refund_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, disposition, refundID, id)
+	return err
+}
@@ -0,0 +1,150 @@
+package repos
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"retrobytes/internal/domain"
+)
+
+type ReviewRepo struct{ db *sqlx.DB }
+
+func NewReviewRepo(db *sqlx.DB) *ReviewRepo { return &ReviewRepo{db: db} }
+
+// Review is a customer review; ProductTitle and Email are filled for the
+// admin queue, Author for the product page.
+type Review struct {
+	ID           string `db:"id"`
+	ProductID    string `db:"product_id"`
+	UserID       string `db:"user_id"`
+	Rating       int    `db:"rating"`
+	Title        string `db:"title"`
+	Body         string `db:"body"`
+	Status       string `db:"status"`
+	CreatedAt    string `db:"created_at"`
+	Author       string `db:"author"`
+	ProductTitle string `db:"product_title"`
+}
+
+func (r *ReviewRepo) Create(productID, userID string, rating int, title, body string) (string, error) {
+	id := uuid.NewString()
+	_, err := r.db.Exec(`
+	  INSERT INTO reviews(id, product_id, user_id, rating, title, body, status, created_at)
+	  VALUES(?, ?, ?, ?, ?, ?, 'PENDING', CURRENT_TIMESTAMP)
+	`, id, productID, userID, rating, title, body)
+	return id, err
+}
+
+// HasPurchased reports whether the user has a non-canceled order containing
+// the listing or one of its variants. Orders belong to users through their
+// session, as in OrderRepo.ListByUser.
+func (r *ReviewRepo) HasPurchased(userID, productID string) (bool, error) {
+	var n int
+	err := r.db.Get(&n, `
+	  SELECT COUNT(*)
+	  FROM order_items oi
+	  JOIN orders o   ON o.id = oi.order_id
+	  JOIN sessions s ON s.id = o.session_id
+	  WHERE s.user_id = ? AND o.status <> 'CANCELED'
+	    AND (oi.product_id = ? OR oi.product_id IN (SELECT id FROM products WHERE parent_id = ?))
+	`, userID, productID, productID)
+	return n > 0, err
+}
+
+// Exists reports whether the user already reviewed the listing (any status).
+func (r *ReviewRepo) Exists(userID, productID string) (bool, error) {
+	var n int
+	err := r.db.Get(&n, `SELECT COUNT(*) FROM reviews WHERE user_id = ? AND product_id = ?`, userID, productID)
+	return n > 0, err
+}
+
+// CountRecent counts reviews the user submitted in the last `hours` hours.
+func (r *ReviewRepo) CountRecent(userID string, hours int) (int, error) {
+	var n int
+	err := r.db.Get(&n, `
+	  SELECT COUNT(*) FROM reviews
+	  WHERE user_id = ? AND datetime(created_at) >= datetime('now', ?)
+	`, userID, fmt.Sprintf("-%d hours", hours))
+	return n, err
+}
+
+// SameBodyExists catches copy-pasted reviews across listings.
+func (r *ReviewRepo) SameBodyExists(userID, body string) (bool, error) {
+	var n int
+	err := r.db.Get(&n, `SELECT COUNT(*) FROM reviews WHERE user_id = ? AND LOWER(body) = LOWER(?)`, userID, body)
+	return n > 0, err
+}
+
+// Approved lists a listing's approved reviews, newest first.
+func (r *ReviewRepo) Approved(productID string, limit int) ([]Review, error) {
+	var out []Review
+	err := r.db.Select(&out, `
+	  SELECT rv.id, rv.product_id, rv.user_id, rv.rating, rv.title, rv.body, rv.status, rv.created_at,
+	         COALESCE(u.name, 'Customer') AS author, '' AS product_title
+	  FROM reviews rv LEFT JOIN users u ON u.id = rv.user_id
+	  WHERE rv.product_id = ? AND rv.status = 'APPROVED'
+	  ORDER BY rv.created_at DESC
+	  LIMIT ?
+	`, productID, limit)
+	return out, err
+}
+
+// Summaries returns approved-review ratings for the given listings
+// (missing = no reviews).
+func (r *ReviewRepo) Summaries(productIDs []string) (map[string]domain.Rating, error) {
+	out := make(map[string]domain.Rating, len(productIDs))
+	if len(productIDs) == 0 {
+		return out, nil
+	}
+	q, args, err := sqlx.In(`
+	  SELECT product_id, AVG(rating) AS average, COUNT(*) AS count
+	  FROM reviews
+	  WHERE status = 'APPROVED' AND product_id IN (?)
+	  GROUP BY product_id
+	`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		ProductID string `db:"product_id"`
+		domain.Rating
+	}
+	if err := r.db.Select(&rows, r.db.Rebind(q), args...); err != nil {
+		return nil, err
+	}
+	for _, s := range rows {
+		out[s.ProductID] = s.Rating
+	}
+	return out, nil
+}
+
+// ByStatus lists reviews for the moderation queue, oldest first.
+func (r *ReviewRepo) ByStatus(status string, limit int) ([]Review, error) {
+	var out []Review
+	err := r.db.Select(&out, `
+	  SELECT rv.id, rv.product_id, rv.user_id, rv.rating, rv.title, rv.body, rv.status, rv.created_at,
+	         COALESCE(u.email, rv.user_id) AS author, COALESCE(p.title, rv.product_id) AS product_title
+	  FROM reviews rv
+	  LEFT JOIN users u    ON u.id = rv.user_id
+	  LEFT JOIN products p ON p.id = rv.product_id
+	  WHERE rv.status = ?
+	  ORDER BY rv.created_at ASC
+	  LIMIT ?
+	`, status, limit)
+	return out, err
+}
+
+// SetStatus records a moderation decision; false if the review is unknown.
+func (r *ReviewRepo) SetStatus(id, status, adminID string) (bool, error) {
+	res, err := r.db.Exec(`
+	  UPDATE reviews SET status = ?, moderated_at = CURRENT_TIMESTAMP, moderated_by = ?
+	  WHERE id = ?
+	`, status, adminID, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
@@ -0,0 +1,102 @@
+package repos
+
+import (
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type SavedSearchRepo struct{ db *sqlx.DB }
+
+func NewSavedSearchRepo(db *sqlx.DB) *SavedSearchRepo { return &SavedSearchRepo{db: db} }
+
+type SavedSearch struct {
+	ID         string `db:"id"`
+	UserID     string `db:"user_id"`
+	Email      string `db:"email"`
+	Query      string `db:"query"`
+	CategoryID string `db:"category_id"`
+	Condition  string `db:"condition"`
+	CreatedAt  string `db:"created_at"`
+}
+
+// Create stores a saved search unless the user already has an identical one.
+func (r *SavedSearchRepo) Create(userID, q, catID, cond string) (string, error) {
+	var id string
+	err := r.db.Get(&id, `
+	  SELECT id FROM saved_searches
+	  WHERE user_id = ? AND query = ? AND COALESCE(category_id,'') = ? AND COALESCE(condition,'') = ?
+	`, userID, q, catID, cond)
+	if err == nil {
+		return id, nil
+	}
+	id = uuid.NewString()
+	_, err = r.db.Exec(`
+	  INSERT INTO saved_searches(id, user_id, query, category_id, condition, created_at)
+	  VALUES(?, ?, ?, NULLIF(?,''), NULLIF(?,''), CURRENT_TIMESTAMP)
+	`, id, userID, q, catID, cond)
+	return id, err
+}
+
+func (r *SavedSearchRepo) CountByUser(userID string) (int, error) {
+	var n int
+	err := r.db.Get(&n, `SELECT COUNT(*) FROM saved_searches WHERE user_id = ?`, userID)
+	return n, err
+}
+
+func (r *SavedSearchRepo) ListByUser(userID string) ([]SavedSearch, error) {
+	var out []SavedSearch
+	err := r.db.Select(&out, `
+	  SELECT s.id, s.user_id, u.email, s.query, COALESCE(s.category_id,'') AS category_id,
+	         COALESCE(s.condition,'') AS condition, s.created_at
+	  FROM saved_searches s JOIN users u ON u.id = s.user_id
+	  WHERE s.user_id = ?
+	  ORDER BY s.created_at DESC
+	`, userID)
+	return out, err
+}
+
+// ListAll returns every saved search with its owner's email (used by the alert job).
+func (r *SavedSearchRepo) ListAll() ([]SavedSearch, error) {
+	var out []SavedSearch
+	err := r.db.Select(&out, `
+	  SELECT s.id, s.user_id, u.email, s.query, COALESCE(s.category_id,'') AS category_id,
+	         COALESCE(s.condition,'') AS condition, s.created_at
+	  FROM saved_searches s JOIN users u ON u.id = s.user_id
+	`)
+	return out, err
+}
+
+// Delete removes a saved search owned by userID, with its alert history.
+// Another user's search is left alone.
+func (r *SavedSearchRepo) Delete(id, userID string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`
+	  DELETE FROM saved_search_matches
+	  WHERE saved_search_id = (SELECT id FROM saved_searches WHERE id = ? AND user_id = ?)
+	`, id, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM saved_searches WHERE id = ? AND user_id = ?`, id, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkMatched records that a product was alerted for a saved search.
+// It returns false if the pair was already recorded, so alerts fire once.
+func (r *SavedSearchRepo) MarkMatched(savedSearchID, productID string) (bool, error) {
+	res, err := r.db.Exec(`
+	  INSERT INTO saved_search_matches(saved_search_id, product_id, created_at)
+	  VALUES(?, ?, CURRENT_TIMESTAMP)
+	  ON CONFLICT(saved_search_id, product_id) DO NOTHING
+	`, savedSearchID, productID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
@@ -0,0 +1,164 @@
+package repos
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type SearchLogRepo struct{ db *sqlx.DB }
+
+func NewSearchLogRepo(db *sqlx.DB) *SearchLogRepo { return &SearchLogRepo{db: db} }
+
+// SearchEvent is a single recorded search.
+type SearchEvent struct {
+	Query       string
+	CategoryID  string
+	Condition   string
+	ResultCount int
+	SessionID   string
+	UserID      string
+}
+
+// Insert stores a search and returns its id (used to attribute clicks).
+func (r *SearchLogRepo) Insert(e SearchEvent) (int64, error) {
+	res, err := r.db.Exec(`
+	  INSERT INTO search_events(query, category_id, condition, result_count, session_id, user_id, created_at)
+	  VALUES(?, NULLIF(?,''), NULLIF(?,''), ?, NULLIF(?,''), NULLIF(?,''), CURRENT_TIMESTAMP)
+	`, e.Query, e.CategoryID, e.Condition, e.ResultCount, e.SessionID, e.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// InsertClick records that a product was opened from a search result page.
+// Unknown search ids are ignored; repeated clicks count once.
+func (r *SearchLogRepo) InsertClick(searchID int64, productID string) error {
+	_, err := r.db.Exec(`
+	  INSERT INTO search_clicks(search_id, product_id, created_at)
+	  SELECT id, ?, CURRENT_TIMESTAMP FROM search_events WHERE id = ?
+	  ON CONFLICT(search_id, product_id) DO NOTHING
+	`, productID, searchID)
+	return err
+}
+
+// QueryStat aggregates searches for one normalized query.
+type QueryStat struct {
+	Query      string  `db:"query"`
+	Searches   int     `db:"searches"`
+	AvgResults float64 `db:"avg_results"`
+	Clicks     int     `db:"clicks"`
+	Clicked    int     `db:"clicked"` // searches with at least one click
+	LastSeen   string  `db:"last_seen"`
+	ClickRate  float64 `db:"-"`
+}
+
+// TopQueries returns the most frequent queries in the last `days` days.
+func (r *SearchLogRepo) TopQueries(days, limit int) ([]QueryStat, error) {
+	var out []QueryStat
+	err := r.db.Select(&out, `
+	  SELECT e.query,
+	         COUNT(*)            AS searches,
+	         AVG(e.result_count) AS avg_results,
+	         SUM((SELECT COUNT(*) FROM search_clicks c WHERE c.search_id = e.id)) AS clicks,
+	         SUM(EXISTS (SELECT 1 FROM search_clicks c WHERE c.search_id = e.id)) AS clicked,
+	         MAX(e.created_at)   AS last_seen
+	  FROM search_events e
+	  WHERE datetime(e.created_at) >= datetime('now', ?)
+	  GROUP BY e.query
+	  ORDER BY searches DESC, last_seen DESC
+	  LIMIT ?
+	`, sinceModifier(days), limit)
+	return out, err
+}
+
+// ZeroResultQueries returns queries that found nothing in the last `days` days.
+func (r *SearchLogRepo) ZeroResultQueries(days, limit int) ([]QueryStat, error) {
+	var out []QueryStat
+	err := r.db.Select(&out, `
+	  SELECT query,
+	         COUNT(*)        AS searches,
+	         0               AS avg_results,
+	         0               AS clicks,
+	         0               AS clicked,
+	         MAX(created_at) AS last_seen
+	  FROM search_events
+	  WHERE result_count = 0 AND datetime(created_at) >= datetime('now', ?)
+	  GROUP BY query
+	  ORDER BY searches DESC, last_seen DESC
+	  LIMIT ?
+	`, sinceModifier(days), limit)
+	return out, err
+}
+
+// ClickedProduct is a product reached from search results.
+type ClickedProduct struct {
+	ProductID string `db:"product_id"`
+	Title     string `db:"title"`
+	Clicks    int    `db:"clicks"`
+}
+
+// TopClickedProducts returns products most often opened from search.
+func (r *SearchLogRepo) TopClickedProducts(days, limit int) ([]ClickedProduct, error) {
+	var out []ClickedProduct
+	err := r.db.Select(&out, `
+	  SELECT c.product_id, COALESCE(p.title, c.product_id) AS title, COUNT(*) AS clicks
+	  FROM search_clicks c
+	  LEFT JOIN products p ON p.id = c.product_id
+	  WHERE datetime(c.created_at) >= datetime('now', ?)
+	  GROUP BY c.product_id
+	  ORDER BY clicks DESC
+	  LIMIT ?
+	`, sinceModifier(days), limit)
+	return out, err
+}
+
+// Totals returns overall search and click counts for the window; clicked
+// counts the searches that led to at least one click.
+func (r *SearchLogRepo) Totals(days int) (searches, zero, clicks, clicked int, err error) {
+	mod := sinceModifier(days)
+	if err = r.db.Get(&searches, `SELECT COUNT(*) FROM search_events WHERE datetime(created_at) >= datetime('now', ?)`, mod); err != nil {
+		return
+	}
+	if err = r.db.Get(&zero, `SELECT COUNT(*) FROM search_events WHERE result_count = 0 AND datetime(created_at) >= datetime('now', ?)`, mod); err != nil {
+		return
+	}
+	if err = r.db.Get(&clicks, `SELECT COUNT(*) FROM search_clicks WHERE datetime(created_at) >= datetime('now', ?)`, mod); err != nil {
+		return
+	}
+	err = r.db.Get(&clicked, `
+	  SELECT COUNT(*) FROM search_events e
+	  WHERE datetime(e.created_at) >= datetime('now', ?)
+	    AND EXISTS (SELECT 1 FROM search_clicks c WHERE c.search_id = e.id)
+	`, mod)
+	return
+}
+
+// PurgeOlderThan deletes searches (and their clicks) older than `days` days.
+func (r *SearchLogRepo) PurgeOlderThan(days int) (int64, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	mod := sinceModifier(days)
+	if _, err := tx.Exec(`
+	  DELETE FROM search_clicks
+	  WHERE search_id IN (SELECT id FROM search_events WHERE datetime(created_at) < datetime('now', ?))
+	`, mod); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`DELETE FROM search_events WHERE datetime(created_at) < datetime('now', ?)`, mod)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, tx.Commit()
+}
+
+// sinceModifier builds a SQLite datetime modifier such as "-30 days".
+func sinceModifier(days int) string {
+	return fmt.Sprintf("-%d days", days)
+}
@@ -0,0 +1,136 @@
+package repos
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"retrobytes/internal/domain"
+)
+
+// Shipment is one parcel sent for an order.
+type Shipment struct {
+	ID         string `db:"id"`
+	OrderID    string `db:"order_id"`
+	Carrier    string `db:"carrier"` // see domain.Carriers
+	TrackingNo string `db:"tracking_no"`
+	ShippedOn  string `db:"shipped_on"` // YYYY-MM-DD
+	AdminID    string `db:"admin_id"`
+	CreatedAt  string `db:"created_at"`
+	Items      []ShipmentItem
+}
+
+func (s Shipment) CarrierLabel() string { return domain.CarrierLabel(s.Carrier) }
+
+// TrackingURL links to the carrier's tracking page ("" if it has none).
+func (s Shipment) TrackingURL() string { return domain.TrackingURL(s.Carrier, s.TrackingNo) }
+
+// ShipmentItem is the quantity of one order line in a shipment.
+type ShipmentItem struct {
+	ShipmentID string `db:"shipment_id"`
+	ProductID  string `db:"product_id"`
+	Title      string `db:"title"`
+	Qty        int    `db:"qty"`
+}
+
+type ShipmentRepo struct{ db *sqlx.DB }
+
+func NewShipmentRepo(db *sqlx.DB) *ShipmentRepo { return &ShipmentRepo{db: db} }
+
+// Create stores a shipment and moves the order to status in one
+// transaction, issuing the order's invoice number on its first shipment,
+// with the status history entry and mails (the customer's
+// tracking notice). shippedBefore is the number of the order's units the
+// caller saw as already shipped; Create reports false without writing
+// anything when another shipment was recorded in the meantime or the order
+// is no longer open for shipping (canceled, say).
+func (r *ShipmentRepo) Create(s Shipment, shippedBefore int, status string, mails ...OutboxEmail) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, err := tx.Exec(`
+	  UPDATE orders SET status = ?, shipped_at = COALESCE(shipped_at, CURRENT_TIMESTAMP), `+issueInvoice+`
+	  WHERE id = ? AND status IN ('PLACED','RESERVED','PARTIALLY_SHIPPED')
+	`, status, s.OrderID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return false, err
+	}
+	var shipped int
+	if err := tx.Get(&shipped, `SELECT COALESCE(SUM(qty),0) FROM shipment_items WHERE order_id = ?`, s.OrderID); err != nil {
+		return false, err
+	}
+	if shipped != shippedBefore {
+		return false, nil
+	}
+	if _, err := tx.Exec(`
+	  INSERT INTO shipments(id, order_id, carrier, tracking_no, shipped_on, admin_id)
+	  VALUES(?, ?, ?, ?, ?, NULLIF(?,''))
+	`, s.ID, s.OrderID, s.Carrier, s.TrackingNo, s.ShippedOn, s.AdminID); err != nil {
+		return false, err
+	}
+	for _, it := range s.Items {
+		if _, err := tx.Exec(`INSERT INTO shipment_items(shipment_id, order_id, product_id, qty) VALUES(?, ?, ?, ?)`,
+			s.ID, s.OrderID, it.ProductID, it.Qty); err != nil {
+			return false, err
+		}
+	}
+	if _, err := tx.Exec(`
+	  INSERT INTO order_status_history(order_id, status, actor, user_id, note)
+	  VALUES(?, ?, 'ADMIN', NULLIF(?,''), ?)
+	`, s.OrderID, status, s.AdminID, domain.CarrierLabel(s.Carrier)+" "+s.TrackingNo); err != nil {
+		return false, err
+	}
+	if err := enqueueEmails(tx, mails); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ForOrder lists an order's shipments with their lines, oldest first.
+func (r *ShipmentRepo) ForOrder(orderID string) ([]Shipment, error) {
+	var out []Shipment
+	if err := r.db.Select(&out, `
+	  SELECT id, order_id, carrier, tracking_no, shipped_on, COALESCE(admin_id,'') AS admin_id, created_at
+	  FROM shipments WHERE order_id = ? ORDER BY shipped_on, created_at, rowid
+	`, orderID); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+	var items []ShipmentItem
+	if err := r.db.Select(&items, `
+	  SELECT si.shipment_id, si.product_id, p.title, si.qty
+	  FROM shipment_items si JOIN products p ON p.id = si.product_id
+	  WHERE si.order_id = ? ORDER BY p.title
+	`, orderID); err != nil {
+		return nil, err
+	}
+	byID := map[string]int{}
+	for i, s := range out {
+		byID[s.ID] = i
+	}
+	for _, it := range items {
+		i := byID[it.ShipmentID]
+		out[i].Items = append(out[i].Items, it)
+	}
+	return out, nil
+}
+
+// ShippedQty sums the units per product already shipped for an order.
+func (r *ShipmentRepo) ShippedQty(orderID string) (map[string]int, error) {
+	var rows []ShipmentItem
+	if err := r.db.Select(&rows, `
+	  SELECT product_id, SUM(qty) AS qty FROM shipment_items WHERE order_id = ? GROUP BY product_id
+	`, orderID); err != nil {
+		return nil, err
+	}
+	out := map[string]int{}
+	for _, row := range rows {
+		out[row.ProductID] = row.Qty
+	}
+	return out, nil
+}
@@ -0,0 +1,183 @@
+package repos
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ShippingRepo struct{ db *sqlx.DB }
+
+func NewShippingRepo(db *sqlx.DB) *ShippingRepo { return &ShippingRepo{db: db} }
+
+// ShippingMethod is a delivery service offered at checkout. FreeOver is the
+// order amount from which it ships free (nil = never).
+type ShippingMethod struct {
+	Code     string   `db:"code"`
+	Name     string   `db:"name"`
+	FreeOver *float64 `db:"free_over"`
+	Active   bool     `db:"active"`
+	Sort     int      `db:"sort"`
+}
+
+// FreeOverInput is the threshold as a form value ("" when none).
+func (m ShippingMethod) FreeOverInput() string {
+	if m.FreeOver == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", *m.FreeOver)
+}
+
+// ShippingZone maps an origin region and destination ZIP prefix to a zone.
+type ShippingZone struct {
+	Origin     string `db:"origin_region"`
+	DestPrefix string `db:"dest_prefix"`
+	Zone       int    `db:"zone"`
+}
+
+// ShippingRate is the price of a method in a zone for parcels up to
+// MaxWeight kilograms.
+type ShippingRate struct {
+	Method    string  `db:"method"`
+	Zone      int     `db:"zone"`
+	MaxWeight float64 `db:"max_weight_kg"`
+	Price     float64 `db:"price"`
+}
+
+// Parcel is the packed size of one unit of a product.
+type Parcel struct {
+	ProductID string  `db:"id"`
+	WeightKg  float64 `db:"weight_kg"`
+	LengthCm  float64 `db:"length_cm"`
+	WidthCm   float64 `db:"width_cm"`
+	HeightCm  float64 `db:"height_cm"`
+}
+
+// Methods lists shipping methods; activeOnly hides disabled ones.
+func (r *ShippingRepo) Methods(activeOnly bool) ([]ShippingMethod, error) {
+	var out []ShippingMethod
+	err := r.db.Select(&out, `
+	  SELECT code, name, free_over, active, sort FROM shipping_methods
+	  WHERE active = 1 OR ? = 0
+	  ORDER BY sort, code
+	`, activeOnly)
+	return out, err
+}
+
+// SaveMethod creates or updates a method.
+func (r *ShippingRepo) SaveMethod(m ShippingMethod) error {
+	_, err := r.db.Exec(`
+	  INSERT INTO shipping_methods(code, name, free_over, active, sort) VALUES(?, ?, ?, ?, ?)
+	  ON CONFLICT(code) DO UPDATE SET name = excluded.name, free_over = excluded.free_over,
+	    active = excluded.active, sort = excluded.sort
+	`, m.Code, m.Name, m.FreeOver, m.Active, m.Sort)
+	return err
+}
+
+// Zone returns the zone for a shipment from origin to zip: an exact origin
+// beats the catch-all origin, then the longest destination prefix wins.
+func (r *ShippingRepo) Zone(origin, zip string) (zone int, ok bool, err error) {
+	err = r.db.Get(&zone, `
+	  SELECT zone FROM shipping_zones
+	  WHERE origin_region IN (?, '') AND substr(?, 1, length(dest_prefix)) = dest_prefix
+	  ORDER BY origin_region = ? DESC, length(dest_prefix) DESC
+	  LIMIT 1
+	`, origin, zip, origin)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return zone, err == nil, err
+}
+
+func (r *ShippingRepo) Zones() ([]ShippingZone, error) {
+	var out []ShippingZone
+	err := r.db.Select(&out, `SELECT origin_region, dest_prefix, zone FROM shipping_zones ORDER BY origin_region, dest_prefix`)
+	return out, err
+}
+
+func (r *ShippingRepo) SaveZone(z ShippingZone) error {
+	_, err := r.db.Exec(`
+	  INSERT INTO shipping_zones(origin_region, dest_prefix, zone) VALUES(?, ?, ?)
+	  ON CONFLICT(origin_region, dest_prefix) DO UPDATE SET zone = excluded.zone
+	`, z.Origin, z.DestPrefix, z.Zone)
+	return err
+}
+
+func (r *ShippingRepo) DeleteZone(origin, destPrefix string) error {
+	_, err := r.db.Exec(`DELETE FROM shipping_zones WHERE origin_region = ? AND dest_prefix = ?`, origin, destPrefix)
+	return err
+}
+
+// Rate returns the cheapest bracket of method in zone that fits weightKg.
+func (r *ShippingRepo) Rate(method string, zone int, weightKg float64) (price float64, ok bool, err error) {
+	err = r.db.Get(&price, `
+	  SELECT price FROM shipping_rates
+	  WHERE method = ? AND zone = ? AND max_weight_kg >= ?
+	  ORDER BY max_weight_kg
+	  LIMIT 1
+	`, method, zone, weightKg)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return price, err == nil, err
+}
+
+func (r *ShippingRepo) Rates() ([]ShippingRate, error) {
+	var out []ShippingRate
+	err := r.db.Select(&out, `SELECT method, zone, max_weight_kg, price FROM shipping_rates ORDER BY method, zone, max_weight_kg`)
+	return out, err
+}
+
+func (r *ShippingRepo) SaveRate(rt ShippingRate) error {
+	_, err := r.db.Exec(`
+	  INSERT INTO shipping_rates(method, zone, max_weight_kg, price) VALUES(?, ?, ?, ?)
+	  ON CONFLICT(method, zone, max_weight_kg) DO UPDATE SET price = excluded.price
+	`, rt.Method, rt.Zone, rt.MaxWeight, rt.Price)
+	return err
+}
+
+func (r *ShippingRepo) DeleteRate(method string, zone int, maxWeight float64) error {
+	_, err := r.db.Exec(`DELETE FROM shipping_rates WHERE method = ? AND zone = ? AND max_weight_kg = ?`, method, zone, maxWeight)
+	return err
+}
+
+// Parcels returns parcel sizes keyed by product id. A variant without its
+// own size uses its parent's.
+func (r *ShippingRepo) Parcels(ids []string) (map[string]Parcel, error) {
+	out := map[string]Parcel{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	q, args, err := sqlx.In(`
+	  SELECT p.id,
+	         CASE WHEN p.weight_kg > 0 OR par.id IS NULL THEN p.weight_kg ELSE par.weight_kg END AS weight_kg,
+	         CASE WHEN p.weight_kg > 0 OR par.id IS NULL THEN p.length_cm ELSE par.length_cm END AS length_cm,
+	         CASE WHEN p.weight_kg > 0 OR par.id IS NULL THEN p.width_cm  ELSE par.width_cm  END AS width_cm,
+	         CASE WHEN p.weight_kg > 0 OR par.id IS NULL THEN p.height_cm ELSE par.height_cm END AS height_cm
+	  FROM products p
+	  LEFT JOIN products par ON par.id = p.parent_id
+	  WHERE p.id IN (?)
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	var rows []Parcel
+	if err := r.db.Select(&rows, r.db.Rebind(q), args...); err != nil {
+		return nil, err
+	}
+	for _, p := range rows {
+		out[p.ProductID] = p
+	}
+	return out, nil
+}
+
+// SetParcel stores the packed size of a product.
+func (r *ShippingRepo) SetParcel(p Parcel) error {
+	_, err := r.db.Exec(`
+	  UPDATE products SET weight_kg = ?, length_cm = ?, width_cm = ?, height_cm = ?, updated_at = CURRENT_TIMESTAMP
+	  WHERE id = ?
+	`, p.WeightKg, p.LengthCm, p.WidthCm, p.HeightCm, p.ProductID)
+	return err
+}
@@ -0,0 +1,139 @@
+package repos
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type TaxRepo struct{ db *sqlx.DB }
+
+func NewTaxRepo(db *sqlx.DB) *TaxRepo { return &TaxRepo{db: db} }
+
+// TaxRate applies to ZIP codes starting with ZIPPrefix. Rate is a percentage.
+type TaxRate struct {
+	ZIPPrefix string  `db:"zip_prefix"`
+	Name      string  `db:"name"`
+	Rate      float64 `db:"rate"`
+	UpdatedAt string  `db:"updated_at"`
+
+	Exempt []string `db:"-"` // exempt category ids
+}
+
+// IsExempt reports whether categoryID is exempt under this rate.
+func (t TaxRate) IsExempt(categoryID string) bool {
+	for _, id := range t.Exempt {
+		if id == categoryID {
+			return true
+		}
+	}
+	return false
+}
+
+// Rates lists all rates with their exempt categories.
+func (r *TaxRepo) Rates() ([]TaxRate, error) {
+	var out []TaxRate
+	if err := r.db.Select(&out, `SELECT zip_prefix, name, rate, updated_at FROM tax_rates ORDER BY zip_prefix`); err != nil {
+		return nil, err
+	}
+	var ex []struct {
+		Prefix   string `db:"zip_prefix"`
+		Category string `db:"category_id"`
+	}
+	if err := r.db.Select(&ex, `SELECT zip_prefix, category_id FROM tax_exempt_categories ORDER BY category_id`); err != nil {
+		return nil, err
+	}
+	for i := range out {
+		for _, e := range ex {
+			if e.Prefix == out[i].ZIPPrefix {
+				out[i].Exempt = append(out[i].Exempt, e.Category)
+			}
+		}
+	}
+	return out, nil
+}
+
+// ForZIP returns the most specific rate covering zip; ok is false when no
+// rate applies.
+func (r *TaxRepo) ForZIP(zip string) (rate TaxRate, ok bool, err error) {
+	err = r.db.Get(&rate, `
+	  SELECT zip_prefix, name, rate, updated_at FROM tax_rates
+	  WHERE substr(?, 1, length(zip_prefix)) = zip_prefix
+	  ORDER BY length(zip_prefix) DESC
+	  LIMIT 1
+	`, zip)
+	if errors.Is(err, sql.ErrNoRows) {
+		return rate, false, nil
+	}
+	if err != nil {
+		return rate, false, err
+	}
+	err = r.db.Select(&rate.Exempt, `SELECT category_id FROM tax_exempt_categories WHERE zip_prefix = ?`, rate.ZIPPrefix)
+	return rate, err == nil, err
+}
+
+// Save creates or updates a rate.
+func (r *TaxRepo) Save(prefix, name string, rate float64) error {
+	_, err := r.db.Exec(`
+	  INSERT INTO tax_rates(zip_prefix, name, rate, updated_at) VALUES(?, ?, ?, CURRENT_TIMESTAMP)
+	  ON CONFLICT(zip_prefix) DO UPDATE SET name = excluded.name, rate = excluded.rate, updated_at = excluded.updated_at
+	`, prefix, name, rate)
+	return err
+}
+
+func (r *TaxRepo) Delete(prefix string) error {
+	_, err := r.db.Exec(`DELETE FROM tax_rates WHERE zip_prefix = ?`, prefix)
+	return err
+}
+
+// SetExemptions replaces the exempt categories of a rate.
+func (r *TaxRepo) SetExemptions(prefix string, categoryIDs []string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`DELETE FROM tax_exempt_categories WHERE zip_prefix = ?`, prefix); err != nil {
+		return err
+	}
+	for _, id := range categoryIDs {
+		if _, err := tx.Exec(`INSERT INTO tax_exempt_categories(zip_prefix, category_id) VALUES(?, ?)`, prefix, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// TaxReportRow sums tax collected per jurisdiction.
+type TaxReportRow struct {
+	Code    string  `db:"code"`
+	Label   string  `db:"label"`
+	Orders  int     `db:"orders"`
+	Taxable float64 `db:"taxable"`
+	Tax     float64 `db:"tax"`
+}
+
+// Report sums TAX lines of non-canceled orders placed in [from, to), less
+// the tax given back by refunds paid out on them. Refunded taxable amounts
+// follow from the refunded tax at the order's rate.
+func (r *TaxRepo) Report(from, to string) ([]TaxReportRow, error) {
+	var out []TaxReportRow
+	err := r.db.Select(&out, `
+	  SELECT code, MAX(label) AS label, COUNT(DISTINCT order_id) AS orders,
+	         ROUND(SUM(base - CASE WHEN amount > 0 THEN refunded * base / amount ELSE 0 END), 2) AS taxable,
+	         ROUND(SUM(amount - refunded), 2) AS tax
+	  FROM (
+	    SELECT COALESCE(a.code,'') AS code, a.label, a.order_id, COALESCE(a.base,0) AS base, a.amount,
+	           (SELECT COALESCE(SUM(ri.tax),0) FROM refund_items ri
+	            JOIN refunds f ON f.id = ri.refund_id
+	            WHERE ri.order_id = a.order_id AND f.pending = 0) AS refunded
+	    FROM order_adjustments a
+	    JOIN orders o ON o.id = a.order_id
+	    WHERE a.kind = 'TAX' AND o.status <> 'CANCELED' AND o.created_at >= ? AND o.created_at < ?
+	  )
+	  GROUP BY code
+	  ORDER BY code
+	`, from, to)
+	return out, err
+}
@@ -102,6 +102,33 @@ func (r *UserRepo) DeleteUserCascade(userID string) error {
 		}
 	}
 
+	// Saved searches and notifications belong to the account
+	if _, err := tx.Exec(`DELETE FROM saved_search_matches WHERE saved_search_id IN (SELECT id FROM saved_searches WHERE user_id=?)`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM saved_searches WHERE user_id=?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_addresses WHERE user_id=?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM notifications WHERE user_id=?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM reviews WHERE user_id=?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM product_questions WHERE user_id=?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM recent_views WHERE owner_key=?`, "u:"+userID); err != nil {
+		return err
+	}
+	// Redemptions stay with their (canceled) orders for reporting
+	if _, err := tx.Exec(`UPDATE promotion_redemptions SET user_id=NULL WHERE user_id=?`, userID); err != nil {
+		return err
+	}
+
 	// Finally delete user
 	if _, err := tx.Exec(`DELETE FROM users WHERE id=?`, userID); err != nil {
 		return err
@@ -109,3 +136,66 @@ func (r *UserRepo) DeleteUserCascade(userID string) error {
 
 	return tx.Commit()
 }
+
+// CreatePasswordReset stores a reset token (by hash). mails (the reset link)
+// are queued in the same transaction.
+func (r *UserRepo) CreatePasswordReset(userID, tokenHash, expiresAt string, mails ...OutboxEmail) error {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`INSERT INTO password_resets(token_hash,user_id,expires_at) VALUES(?,?,?)`, tokenHash, userID, expiresAt); err != nil {
+		return err
+	}
+	if err := enqueueEmails(tx, mails); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PasswordResetUser returns the account of an unused, unexpired token ("" if none).
+func (r *UserRepo) PasswordResetUser(tokenHash, now string) (string, error) {
+	var ids []string
+	err := r.DB.Select(&ids, `SELECT user_id FROM password_resets WHERE token_hash=? AND used_at IS NULL AND expires_at > ?`, tokenHash, now)
+	if err != nil || len(ids) == 0 {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// UsePasswordReset consumes the token, sets the new password hash, retires
+// the account's other tokens and signs the account out of every session.
+// ok is false when the token is unknown, used or expired.
+func (r *UserRepo) UsePasswordReset(tokenHash, now, passwordHash string) (bool, error) {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	var ids []string
+	if err := tx.Select(&ids, `SELECT user_id FROM password_resets WHERE token_hash=? AND used_at IS NULL AND expires_at > ?`, tokenHash, now); err != nil {
+		return false, err
+	}
+	if len(ids) == 0 {
+		return false, nil
+	}
+	userID := ids[0]
+	res, err := tx.Exec(`UPDATE password_resets SET used_at=? WHERE token_hash=? AND used_at IS NULL`, now, tokenHash)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return false, err
+	}
+	if _, err := tx.Exec(`UPDATE password_resets SET used_at=? WHERE user_id=? AND used_at IS NULL`, now, userID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`UPDATE users SET password_hash=? WHERE id=?`, passwordHash, userID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`UPDATE sessions SET user_id=NULL WHERE user_id=?`, userID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,105 @@
+package repos
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"retrobytes/internal/domain"
+)
+
+type ViewRepo struct{ db *sqlx.DB }
+
+func NewViewRepo(db *sqlx.DB) *ViewRepo { return &ViewRepo{db: db} }
+
+// Record counts a product view and moves it to the front of each owner's
+// recently-viewed list, keeping at most `keep` entries per owner. viewedAt
+// carries sub-second precision so quick successive views stay in order.
+func (r *ViewRepo) Record(productID string, owners []string, keep int, viewedAt string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`
+	  INSERT INTO product_view_counts(product_id, views, last_viewed_at)
+	  VALUES(?, 1, datetime('now'))
+	  ON CONFLICT(product_id) DO UPDATE SET views = views + 1, last_viewed_at = excluded.last_viewed_at
+	`, productID); err != nil {
+		return err
+	}
+	for _, o := range owners {
+		if _, err := tx.Exec(`
+		  INSERT INTO recent_views(owner_key, product_id, viewed_at)
+		  VALUES(?, ?, ?)
+		  ON CONFLICT(owner_key, product_id) DO UPDATE SET viewed_at = excluded.viewed_at
+		`, o, productID, viewedAt); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+		  DELETE FROM recent_views
+		  WHERE owner_key = ? AND product_id NOT IN (
+		    SELECT product_id FROM recent_views WHERE owner_key = ? ORDER BY viewed_at DESC LIMIT ?
+		  )
+		`, o, o, keep); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Recent returns the owners' recently viewed active listings, newest first.
+func (r *ViewRepo) Recent(owners []string, exclude string, limit int) ([]domain.Product, error) {
+	if len(owners) == 0 {
+		return nil, nil
+	}
+	q, args, err := sqlx.In(`
+	  SELECT p.id, p.category_id, p.title, p.description, p.condition, p.price, p.images_json, p.active,
+	         p.created_at, COALESCE(p.updated_at,'') AS updated_at
+	  FROM recent_views rv
+	  JOIN products p ON p.id = rv.product_id
+	  WHERE rv.owner_key IN (?) AND rv.product_id <> ? AND p.active = 1
+	  GROUP BY p.id
+	  ORDER BY MAX(rv.viewed_at) DESC
+	  LIMIT ?
+	`, owners, exclude, limit)
+	if err != nil {
+		return nil, err
+	}
+	var out []domain.Product
+	err = r.db.Select(&out, r.db.Rebind(q), args...)
+	return out, err
+}
+
+// ViewStat compares a listing's views with what it sold.
+type ViewStat struct {
+	ProductID  string  `db:"product_id"`
+	Title      string  `db:"title"`
+	Views      int     `db:"views"`
+	LastViewed string  `db:"last_viewed_at"`
+	Orders     int     `db:"orders"`
+	UnitsSold  int     `db:"units_sold"`
+	Conversion float64 `db:"-"` // orders per 100 views
+}
+
+// Stats lists view counts next to order_items sales (excluding canceled
+// orders), most viewed first. Variant sales count toward their listing.
+func (r *ViewRepo) Stats(limit int) ([]ViewStat, error) {
+	var out []ViewStat
+	err := r.db.Select(&out, `
+	  SELECT p.id AS product_id, p.title,
+	         COALESCE(vc.views, 0) AS views,
+	         COALESCE(vc.last_viewed_at, '') AS last_viewed_at,
+	         (SELECT COUNT(DISTINCT oi.order_id) FROM order_items oi JOIN orders o ON o.id = oi.order_id
+	            JOIN products x ON x.id = oi.product_id
+	           WHERE COALESCE(x.parent_id, x.id) = p.id AND o.status <> 'CANCELED') AS orders,
+	         (SELECT COALESCE(SUM(oi.qty), 0) FROM order_items oi JOIN orders o ON o.id = oi.order_id
+	            JOIN products x ON x.id = oi.product_id
+	           WHERE COALESCE(x.parent_id, x.id) = p.id AND o.status <> 'CANCELED') AS units_sold
+	  FROM products p
+	  LEFT JOIN product_view_counts vc ON vc.product_id = p.id
+	  WHERE p.parent_id IS NULL
+	  ORDER BY views DESC, units_sold DESC, p.title
+	  LIMIT ?
+	`, limit)
+	return out, err
+}
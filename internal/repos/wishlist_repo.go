@@ -3,6 +3,8 @@ package repos
 import (
 	"time"
 
+	"retrobytes/internal/domain"
+
 	"github.com/jmoiron/sqlx"
 )
 
@@ -43,12 +45,18 @@ type WishlistRow struct {
 	Condition string  `db:"condition"`
 	Price     float64 `db:"price"`
 	Active    bool    `db:"active"`
+	Images    string  `db:"images_json"`
 }
 
+// ThumbURL is the first gallery image of the saved product ("" if none).
+func (w WishlistRow) ThumbURL() string { return domain.ThumbURL(w.Images) }
+
+func (w WishlistRow) ConditionLabel() string { return domain.ConditionLabel(w.Condition) }
+
 func (r *WishlistRepo) List(wishlistID string) ([]WishlistRow, error) {
 	var out []WishlistRow
 	err := r.db.Select(&out, `
-	  SELECT p.id AS product_id, p.title, p.condition, p.price, p.active
+	  SELECT p.id AS product_id, p.title, p.condition, p.price, p.active, COALESCE(p.images_json,'') AS images_json
 	  FROM wishlist_items wi
 	  JOIN products p ON p.id = wi.product_id
 	  WHERE wi.wishlist_id = ?
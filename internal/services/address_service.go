@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+
+	"retrobytes/internal/repos"
+)
+
+// MaxAddresses caps how many addresses one account can keep.
+const MaxAddresses = 10
+
+var ErrTooManyAddresses = errors.New("address book is full")
+
+// AddressService manages a user's saved shipping addresses.
+type AddressService struct {
+	Addrs *repos.AddressRepo
+}
+
+func NewAddressService(addrs *repos.AddressRepo) *AddressService {
+	return &AddressService{Addrs: addrs}
+}
+
+func (s *AddressService) List(userID string) ([]repos.Address, error) { return s.Addrs.List(userID) }
+
+func (s *AddressService) Get(userID, id string) (repos.Address, error) {
+	return s.Addrs.Get(userID, id)
+}
+
+// Add saves a, unless the user already has the same address.
+func (s *AddressService) Add(userID string, a repos.Address) (string, error) {
+	list, err := s.Addrs.List(userID)
+	if err != nil {
+		return "", err
+	}
+	for _, old := range list {
+		if old.SameAs(a) {
+			return old.ID, nil
+		}
+	}
+	if len(list) >= MaxAddresses {
+		return "", ErrTooManyAddresses
+	}
+	return s.Addrs.Add(userID, a)
+}
+
+func (s *AddressService) Delete(userID, id string) error { return s.Addrs.Delete(userID, id) }
+
+func (s *AddressService) SetDefault(userID, id string) error { return s.Addrs.SetDefault(userID, id) }
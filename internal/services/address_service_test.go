@@ -0,0 +1,104 @@
+package services_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestAddressBook(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book := services.NewAddressService(repos.NewAddressRepo(db))
+	home := repos.Address{Name: "Ann", Line1: "1 Main St", City: "New York", State: "NY", ZIP: "10001"}
+	work := repos.Address{Name: "Ann", Line1: "8400 Baltimore Ave", City: "College Park", State: "MD", ZIP: "20742"}
+
+	homeID, err := book.Add("u-admin", home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := book.Add("u-admin", home); err != nil || again != homeID {
+		t.Fatalf("duplicate address saved twice: %q vs %q (%v)", again, homeID, err)
+	}
+	workID, err := book.Add("u-admin", work)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := book.List("u-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != homeID || !list[0].IsDefault || list[1].IsDefault {
+		t.Fatalf("first address should be the default: %+v", list)
+	}
+
+	if err := book.SetDefault("u-admin", "nope"); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ = book.List("u-admin"); !list[0].IsDefault {
+		t.Fatal("unknown id cleared the default")
+	}
+	if err := book.Delete("u-admin", homeID); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ = book.List("u-admin"); len(list) != 1 || list[0].ID != workID || !list[0].IsDefault {
+		t.Fatalf("remaining address should become the default: %+v", list)
+	}
+	if _, err := book.Get("u-other", workID); err == nil {
+		t.Fatal("address readable by another user")
+	}
+
+	for i := len(list); i < services.MaxAddresses; i++ {
+		a := home
+		a.Line1 = fmt.Sprintf("%d Main St", i+2)
+		if _, err := book.Add("u-admin", a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := book.Add("u-admin", repos.Address{Name: "Ann", Line1: "99 Elm", City: "Albany", State: "NY", ZIP: "12207"}); !errors.Is(err, services.ErrTooManyAddresses) {
+		t.Fatalf("11th address: err = %v", err)
+	}
+}
+
+func TestOrderStoresAddresses(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	carts := repos.NewCartRepo(db)
+	orders := repos.NewOrderRepo(db)
+	cart := services.NewCartService(carts, prods)
+	svc := services.NewOrderService(carts, repos.NewInventoryRepo(db), orders, prods)
+	svc.Tax = services.NewTaxService(repos.NewTaxRepo(db), prods)
+
+	ship := repos.Address{Name: "Ann", Line1: "1 Main St", Line2: "Apt 4", City: "New York", State: "NY", ZIP: "10001", Phone: "212-555-0100"}
+	bill := repos.Address{Name: "Ann", Line1: "8400 Baltimore Ave", City: "College Park", State: "MD", ZIP: "20742"}
+	if err := cart.Add("sid-a1", "gbc-001", 1); err != nil {
+		t.Fatal(err)
+	}
+	oid, _, _, err := svc.Place("sid-a1", "20742", "delivery", services.Contact{Name: "Ann", Email: "a@retrobytes.test", ShipTo: &ship, BillTo: &bill})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotShip, gotBill, err := orders.Addresses(oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotShip == nil || !gotShip.SameAs(ship) || gotBill == nil || !gotBill.SameAs(bill) {
+		t.Fatalf("addresses = %+v / %+v", gotShip, gotBill)
+	}
+	// tax follows the delivery address, not the stock region
+	adj, _ := orders.Adjustments(oid)
+	if len(adj) != 1 || adj[0].Code != "100" {
+		t.Fatalf("tax charged for %+v, want the 100 (NYC) rate", adj)
+	}
+	if o, _, _ := orders.Get(oid); o.ShipZIP != "" {
+		t.Fatalf("ship_zip recorded without a shipping service: %q", o.ShipZIP)
+	}
+}
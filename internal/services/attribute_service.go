@@ -0,0 +1,207 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"retrobytes/internal/domain"
+	"retrobytes/internal/repos"
+)
+
+var reVariantSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// AttributeService manages per-category attribute schemas, product
+// attribute values and product variants.
+type AttributeService struct {
+	Attrs *repos.AttributeRepo
+	Prods *repos.ProductRepo
+}
+
+func NewAttributeService(attrs *repos.AttributeRepo, prods *repos.ProductRepo) *AttributeService {
+	return &AttributeService{Attrs: attrs, Prods: prods}
+}
+
+// Schema returns a category's attribute definitions.
+func (s *AttributeService) Schema(categoryID string) ([]domain.AttributeDef, error) {
+	return s.Attrs.Defs(categoryID)
+}
+
+// Filterable returns the attributes shoppers can filter a category by.
+func (s *AttributeService) Filterable(categoryID string) ([]domain.AttributeDef, error) {
+	defs, err := s.Attrs.Defs(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	out := defs[:0]
+	for _, d := range defs {
+		if d.Filterable {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// ForProduct returns the product's attributes in schema order. With
+// includeEmpty, unset attributes are included (for the admin form).
+func (s *AttributeService) ForProduct(p domain.Product, includeEmpty bool) ([]domain.AttributeValue, error) {
+	defs, err := s.Attrs.Defs(p.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := s.Attrs.Values(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.AttributeValue, 0, len(defs))
+	for _, d := range defs {
+		v := vals[d.Key]
+		if v == "" && !includeEmpty {
+			continue
+		}
+		out = append(out, domain.AttributeValue{AttributeDef: d, Value: v})
+	}
+	return out, nil
+}
+
+// NormalizeValue validates a raw value against its definition. Empty input
+// means "not set" and is always accepted.
+func NormalizeValue(d domain.AttributeDef, raw string) (string, error) {
+	v := strings.TrimSpace(raw)
+	if v == "" {
+		return "", nil
+	}
+	switch d.Type {
+	case "number":
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 100000 {
+			return "", fmt.Errorf("%s must be a whole number", d.Label)
+		}
+		return strconv.Itoa(n), nil
+	case "bool":
+		switch strings.ToLower(v) {
+		case "yes", "true", "on", "1":
+			return "yes", nil
+		case "no", "false", "off", "0":
+			return "no", nil
+		}
+		return "", fmt.Errorf("%s must be yes or no", d.Label)
+	case "enum":
+		for _, o := range d.Options() {
+			if strings.EqualFold(o, v) {
+				return o, nil
+			}
+		}
+		return "", fmt.Errorf("%s must be one of %s", d.Label, strings.Join(d.Options(), ", "))
+	default:
+		if len(v) > 80 || strings.ContainsAny(v, "\r\n") {
+			return "", fmt.Errorf("%s must be a single line of at most 80 characters", d.Label)
+		}
+		return v, nil
+	}
+}
+
+// SaveValues validates and stores a product's attributes. Keys not in the
+// category schema are ignored.
+func (s *AttributeService) SaveValues(p domain.Product, raw map[string]string) error {
+	defs, err := s.Attrs.Defs(p.CategoryID)
+	if err != nil {
+		return err
+	}
+	clean := make(map[string]string, len(defs))
+	for _, d := range defs {
+		v, err := NormalizeValue(d, raw[d.Key])
+		if err != nil {
+			return err
+		}
+		clean[d.Key] = v
+	}
+	return s.Attrs.SetValues(p.ID, clean)
+}
+
+// ParseFilters turns ?attr.<key>=<value> pairs into validated filters for
+// the category's filterable attributes; anything else is dropped.
+func (s *AttributeService) ParseFilters(categoryID string, raw map[string]string) (map[string]string, error) {
+	out := map[string]string{}
+	if categoryID == "" || len(raw) == 0 {
+		return out, nil
+	}
+	defs, err := s.Filterable(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range defs {
+		v, err := NormalizeValue(d, raw[d.Key])
+		if err != nil {
+			return nil, err
+		}
+		if v != "" {
+			out[d.Key] = v
+		}
+	}
+	return out, nil
+}
+
+// SaveDef creates or updates an attribute definition.
+func (s *AttributeService) SaveDef(d domain.AttributeDef, options []string) error {
+	switch d.Type {
+	case "text", "number", "bool":
+		d.OptionsJSON = ""
+	case "enum":
+		clean := make([]string, 0, len(options))
+		for _, o := range options {
+			if o = strings.TrimSpace(o); o != "" && len(o) <= 40 {
+				clean = append(clean, o)
+			}
+		}
+		if len(clean) == 0 {
+			return errors.New("an enum attribute needs at least one option")
+		}
+		b, _ := json.Marshal(clean)
+		d.OptionsJSON = string(b)
+	default:
+		return errors.New("unknown attribute type")
+	}
+	return s.Attrs.UpsertDef(d)
+}
+
+func (s *AttributeService) DeleteDef(categoryID, key string) error {
+	return s.Attrs.DeleteDef(categoryID, key)
+}
+
+// AddVariant creates a variant of a listing with its own price; stock is
+// managed per variant through the normal inventory table.
+func (s *AttributeService) AddVariant(parentID, label string, price float64) (string, error) {
+	parent, err := s.Prods.Get(parentID)
+	if err != nil {
+		return "", err
+	}
+	if pp, err := s.Prods.ParentID(parentID); err != nil || pp != "" {
+		return "", errors.New("variants cannot have variants")
+	}
+	slug := strings.Trim(reVariantSlug.ReplaceAllString(strings.ToLower(label), "-"), "-")
+	if slug == "" {
+		slug = strconv.FormatInt(time.Now().Unix(), 36)
+	}
+	id := parent.ID + "-" + slug
+	if len(id) > 64 {
+		id = id[:64]
+	}
+	return id, s.Prods.CreateVariant(parent, id, label, price)
+}
+
+// SetVariantActive lists or unlists a variant of parentID.
+func (s *AttributeService) SetVariantActive(parentID, variantID string, active bool) error {
+	pp, err := s.Prods.ParentID(variantID)
+	if err != nil {
+		return err
+	}
+	if pp != parentID {
+		return errors.New("not a variant of this product")
+	}
+	return s.Prods.SetActive(variantID, active)
+}
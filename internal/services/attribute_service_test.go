@@ -0,0 +1,70 @@
+package services_test
+
+import (
+	"testing"
+
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestAttributeFiltersAndVariants(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	attrs := services.NewAttributeService(repos.NewAttributeRepo(db), prods)
+	catalog := services.NewCatalogService(repos.NewCategoryRepo(db), prods)
+
+	// Values are normalized against the schema; unknown enum values are rejected
+	f, err := attrs.ParseFilters("retro-consoles", map[string]string{"video_region": "ntsc-u", "bogus": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f["video_region"] != "NTSC-U" || len(f) != 1 {
+		t.Fatalf("unexpected filters %v", f)
+	}
+	if _, err := attrs.ParseFilters("retro-consoles", map[string]string{"video_region": "SECAM"}); err == nil {
+		t.Fatal("expected invalid enum value to be rejected")
+	}
+
+	got, err := catalog.Search("", "retro-consoles", "", map[string]string{"platform": "SNES"}, "", 1, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != "snes-001" {
+		t.Fatalf("want only snes-001, got %+v", got)
+	}
+
+	// A variant has its own price, is hidden from listings and matches via its parent
+	vid, err := attrs.AddVariant("nes-001", "Limited Gray", 249)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vid != "nes-001-limited-gray" {
+		t.Fatalf("unexpected variant id %q", vid)
+	}
+	if _, err := attrs.AddVariant(vid, "Nested", 1); err == nil {
+		t.Fatal("variants of variants must be rejected")
+	}
+	vs, err := catalog.Variants("nes-001")
+	if err != nil || len(vs) != 1 || vs[0].Price != 249 || vs[0].VariantLabel != "Limited Gray" {
+		t.Fatalf("unexpected variants %+v (%v)", vs, err)
+	}
+	got, _ = catalog.Search("nes", "", "", nil, "", 1, 20)
+	for _, p := range got {
+		if p.ID == vid {
+			t.Fatal("variants must not be listed on their own")
+		}
+	}
+
+	if err := attrs.SetVariantActive("snes-001", vid, false); err == nil {
+		t.Fatal("toggling another product's variant must fail")
+	}
+	if err := attrs.SetVariantActive("nes-001", vid, false); err != nil {
+		t.Fatal(err)
+	}
+	if vs, _ := catalog.Variants("nes-001"); len(vs) != 0 {
+		t.Fatalf("inactive variant still offered: %+v", vs)
+	}
+}
@@ -1,9 +1,15 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"errors"
+	"time"
 
 	"retrobytes/internal/domain"
+	"retrobytes/internal/mail"
 	"retrobytes/internal/repos"
 
 	"golang.org/x/crypto/bcrypt"
@@ -11,8 +17,17 @@ import (
 
 var ErrBadCreds = errors.New("invalid email or password")
 
+// ErrResetInvalid means a password reset token is unknown, used or expired.
+var ErrResetInvalid = errors.New("this reset link is invalid or has expired")
+
+// PasswordResetTTL is how long a reset link stays valid.
+const PasswordResetTTL = time.Hour
+
 type AuthService struct {
 	Users *repos.UserRepo
+	// Mail queues the reset link with the token; without it password reset
+	// is unavailable
+	Mail *MailService
 }
 
 func (s *AuthService) Login(sid, email, password string) (*domain.User, error) {
@@ -36,3 +51,60 @@ func (s *AuthService) Logout(sid string) error {
 func (s *AuthService) CurrentUser(sid string) (*domain.User, error) {
 	return s.Users.SessionUser(sid)
 }
+
+// RequestPasswordReset emails a reset link to the account behind email. An
+// unknown address is not an error, so the form does not reveal which
+// addresses have accounts.
+func (s *AuthService) RequestPasswordReset(email string, now time.Time) error {
+	if s.Mail == nil {
+		return errors.New("password reset needs a mail service")
+	}
+	u, err := s.Users.ByEmail(email)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(buf)
+	m, err := mail.PasswordResetMessage(u.Email, mail.PasswordReset{
+		Name: u.Name, Link: s.Mail.Link("/password/reset?token=" + token), ValidMinutes: int(PasswordResetTTL / time.Minute),
+	})
+	if err != nil {
+		return err
+	}
+	expires := now.Add(PasswordResetTTL).UTC().Format(outboxTimeFormat)
+	return s.Users.CreatePasswordReset(u.ID, resetTokenHash(token), expires, outboxEmail(mail.KindPasswordReset, m))
+}
+
+// ResetTokenValid reports whether token can still be used.
+func (s *AuthService) ResetTokenValid(token string, now time.Time) (bool, error) {
+	userID, err := s.Users.PasswordResetUser(resetTokenHash(token), now.UTC().Format(outboxTimeFormat))
+	return userID != "", err
+}
+
+// ResetPassword sets a new password with a reset token. The token is used
+// up and the account is signed out everywhere.
+func (s *AuthService) ResetPassword(token, password string, now time.Time) error {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err != nil {
+		return err
+	}
+	ok, err := s.Users.UsePasswordReset(resetTokenHash(token), now.UTC().Format(outboxTimeFormat), string(h))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrResetInvalid
+	}
+	return nil
+}
+
+func resetTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
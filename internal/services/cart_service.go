@@ -1,7 +1,12 @@
 package services
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"time"
+
+	"retrobytes/internal/domain"
 	"retrobytes/internal/repos"
 )
 
@@ -9,6 +14,10 @@ type CartService struct {
 	Repo  *repos.CartRepo
 	Carts *repos.CartRepo
 	Prods *repos.ProductRepo
+	// Pricing, when set, snapshots the sale price running at add time
+	Pricing *PricingService
+	// Promos, when set, evaluates the cart's promotion code
+	Promos *PromotionService
 }
 
 func NewCartService(carts *repos.CartRepo, prods *repos.ProductRepo) *CartService {
@@ -57,12 +66,59 @@ func (s *CartService) Add(sessionID, productID string, qty int) error {
 			return fmt.Errorf("cannot add more of this item (cart limit)")
 		}
 	}
-	return s.Carts.UpsertItem(cartID, productID, finalQty, p.Price)
+	price := p.Price
+	if s.Pricing != nil {
+		if price, err = s.Pricing.Effective(p, time.Now()); err != nil {
+			return err
+		}
+	}
+	return s.Carts.UpsertItem(cartID, productID, finalQty, price)
 }
 
 type CartView struct {
 	Items []repos.CartItemRow
-	Total float64
+	Total float64 // at current prices
+	// Changes lists lines whose price moved since they were added; the
+	// shopper must accept them before the order can be placed.
+	Changes []PriceChange
+
+	PromoCode  string        // code entered on the cart
+	Promo      *AppliedPromo // set while the code applies
+	PromoError string        // PromoErrorCode when it no longer applies
+}
+
+// Due is the item total after the promotion discount.
+func (v CartView) Due() float64 {
+	if v.Promo == nil {
+		return v.Total
+	}
+	return v.Total - v.Promo.Discount
+}
+
+// Lines returns the cart at current prices for the promotion and tax
+// engines.
+func (v CartView) Lines() []CartLine { return cartLines(v.Items) }
+
+// PriceChange is the per-line diff shown at checkout.
+type PriceChange struct {
+	ProductID string
+	Title     string
+	Was       float64
+	Now       float64
+}
+
+// PricesDigest fingerprints the price changes shown to the shopper, so
+// accepting them only goes through while they are still the ones shown.
+func (v CartView) PricesDigest() string { return pricesDigest(v.Items) }
+
+func pricesDigest(items []repos.CartItemRow) string {
+	h := sha256.New()
+	for _, it := range items {
+		if it.PriceChanged() {
+			fmt.Fprintf(h, "%s=%.2f\n", it.ProductID, it.CurrentPrice)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
 }
 
 func (s *CartService) View(sessionID string) (CartView, error) {
@@ -70,11 +126,123 @@ func (s *CartService) View(sessionID string) (CartView, error) {
 	if err != nil {
 		return CartView{}, err
 	}
-	items, total, err := s.Carts.View(cartID)
+	items, _, err := s.Carts.View(cartID)
 	if err != nil {
 		return CartView{}, err
 	}
-	return CartView{Items: items, Total: total}, nil
+	if err := s.currentPrices(items); err != nil {
+		return CartView{}, err
+	}
+	cv := CartView{Items: items}
+	for i, it := range items {
+		items[i].Subtotal = it.CurrentPrice * float64(it.Qty)
+		cv.Total += items[i].Subtotal
+		if it.PriceChanged() {
+			cv.Changes = append(cv.Changes, PriceChange{ProductID: it.ProductID, Title: it.Title, Was: it.PriceAtAdd, Now: it.CurrentPrice})
+		}
+	}
+	if s.Promos != nil {
+		if cv.PromoCode, err = s.Carts.PromoCode(cartID); err != nil {
+			return CartView{}, err
+		}
+		if cv.PromoCode != "" && len(items) > 0 {
+			applied, err := s.Promos.Evaluate(cv.PromoCode, cartLines(items), sessionID, "", time.Now())
+			if code := PromoErrorCode(err); code != "" {
+				cv.PromoError = code
+			} else if err != nil {
+				return CartView{}, err
+			} else {
+				cv.Promo = &applied
+			}
+		}
+	}
+	return cv, nil
+}
+
+// ApplyPromo checks code against the cart and remembers it if it applies.
+func (s *CartService) ApplyPromo(sessionID, code string) error {
+	cartID, err := s.Carts.EnsureCart(sessionID)
+	if err != nil {
+		return err
+	}
+	if s.Promos == nil {
+		return ErrPromoNotFound
+	}
+	items, _, err := s.Carts.View(cartID)
+	if err != nil {
+		return err
+	}
+	if err := s.currentPrices(items); err != nil {
+		return err
+	}
+	applied, err := s.Promos.Evaluate(code, cartLines(items), sessionID, "", time.Now())
+	if err != nil {
+		return err
+	}
+	return s.Carts.SetPromoCode(cartID, applied.Code)
+}
+
+// RemovePromo clears the cart's promotion code.
+func (s *CartService) RemovePromo(sessionID string) error {
+	cartID, err := s.Carts.EnsureCart(sessionID)
+	if err != nil {
+		return err
+	}
+	return s.Carts.SetPromoCode(cartID, "")
+}
+
+func cartLines(items []repos.CartItemRow) []CartLine {
+	out := make([]CartLine, len(items))
+	for i, it := range items {
+		out[i] = CartLine{ProductID: it.ProductID, Qty: it.Qty, Price: it.CurrentPrice}
+	}
+	return out
+}
+
+// ConfirmPrices accepts the current prices for every line in the cart.
+// digest is the PricesDigest of the changes the shopper saw; if prices
+// moved again since, nothing is accepted and ErrPricesChanged is returned.
+func (s *CartService) ConfirmPrices(sessionID, digest string) error {
+	cartID, err := s.Carts.EnsureCart(sessionID)
+	if err != nil {
+		return err
+	}
+	items, _, err := s.Carts.View(cartID)
+	if err != nil {
+		return err
+	}
+	if err := s.currentPrices(items); err != nil {
+		return err
+	}
+	if digest != pricesDigest(items) {
+		return ErrPricesChanged
+	}
+	for _, it := range items {
+		if it.PriceChanged() {
+			if err := s.Carts.SetPrice(cartID, it.ProductID, it.CurrentPrice); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// currentPrices applies running sales to the lines' catalog prices.
+func (s *CartService) currentPrices(items []repos.CartItemRow) error {
+	if s.Pricing == nil || len(items) == 0 {
+		return nil
+	}
+	prods := make([]domain.Product, len(items))
+	for i, it := range items {
+		prods[i] = domain.Product{ID: it.ProductID, Price: it.CurrentPrice}
+	}
+	if err := s.Pricing.Apply(prods, time.Now()); err != nil {
+		return err
+	}
+	for i := range items {
+		items[i].CurrentPrice = prods[i].Price
+	}
+	return nil
 }
 
 func (s *CartService) MergeOnLogin(userID, sid string) error {
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"time"
+
 	"retrobytes/internal/domain"
 	"retrobytes/internal/repos"
 )
@@ -8,6 +10,10 @@ import (
 type CatalogService struct {
 	Cats  *repos.CategoryRepo
 	Prods *repos.ProductRepo
+	// Reviews, when set, fills Product.Rating on listings
+	Reviews *repos.ReviewRepo
+	// Pricing, when set, applies running sales to everything returned
+	Pricing *PricingService
 }
 
 func NewCatalogService(cats *repos.CategoryRepo, prods *repos.ProductRepo) *CatalogService {
@@ -26,14 +32,22 @@ func (s *CatalogService) ListProductsByCategory(catID string, page, pageSize int
 		pageSize = 12
 	}
 	offset := (page - 1) * pageSize
-	return s.Prods.ListByCategory(catID, pageSize, offset)
+	return s.withRatings(s.withSales(s.Prods.ListByCategory(catID, pageSize, offset)))
 }
 
 func (s *CatalogService) GetProduct(id string) (domain.Product, error) {
-	return s.Prods.Get(id)
+	p, err := s.Prods.Get(id)
+	if err != nil {
+		return p, err
+	}
+	list, err := s.withSales([]domain.Product{p}, nil)
+	if err != nil {
+		return p, err
+	}
+	return list[0], nil
 }
 
-func (s *CatalogService) Search(q, category, condition string, page, pageSize int) ([]domain.Product, error) {
+func (s *CatalogService) Search(q, category, condition string, attrs map[string]string, sort string, page, pageSize int) ([]domain.Product, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -41,5 +55,68 @@ func (s *CatalogService) Search(q, category, condition string, page, pageSize in
 		pageSize = 12
 	}
 	offset := (page - 1) * pageSize
-	return s.Prods.Search(q, category, condition, pageSize, offset)
+	return s.withRatings(s.withSales(s.Prods.Search(q, category, condition, attrs, sort, pageSize, offset)))
+}
+
+// withSales applies sale prices running now to a product list.
+func (s *CatalogService) withSales(list []domain.Product, err error) ([]domain.Product, error) {
+	if err != nil || s.Pricing == nil {
+		return list, err
+	}
+	if err := s.Pricing.Apply(list, time.Now()); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// withRatings attaches review summaries to a product list.
+func (s *CatalogService) withRatings(list []domain.Product, err error) ([]domain.Product, error) {
+	if err != nil || s.Reviews == nil || len(list) == 0 {
+		return list, err
+	}
+	ids := make([]string, len(list))
+	for i, p := range list {
+		ids[i] = p.ID
+	}
+	ratings, err := s.Reviews.Summaries(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Rating = ratings[list[i].ID]
+	}
+	return list, nil
+}
+
+// Variants lists the active variants of a listing.
+func (s *CatalogService) Variants(id string) ([]domain.Product, error) {
+	all, err := s.Prods.Variants(id)
+	if err != nil {
+		return nil, err
+	}
+	out := all[:0]
+	for _, v := range all {
+		if v.Active {
+			out = append(out, v)
+		}
+	}
+	return s.withSales(out, nil)
+}
+
+// ParentOf returns the listing a variant belongs to ("" for listings).
+func (s *CatalogService) ParentOf(id string) (string, error) {
+	return s.Prods.ParentID(id)
+}
+
+// Condition returns the grade, notes and condition photos of a listing.
+func (s *CatalogService) Condition(p domain.Product) (domain.ConditionReport, error) {
+	notes, photos, err := s.Prods.ConditionDetails(p.ID)
+	if err != nil {
+		return domain.ConditionReport{}, err
+	}
+	g, ok := domain.GradeOf(p.Condition)
+	if !ok {
+		g = domain.Grade{Code: p.Condition, Label: p.Condition}
+	}
+	return domain.ConditionReport{Grade: g, Notes: notes, Photos: domain.ParseImages(photos)}, nil
 }
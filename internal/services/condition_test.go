@@ -0,0 +1,104 @@
+package services_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+
+	"retrobytes/internal/domain"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestSearchByMinimumGrade(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := services.NewCatalogService(repos.NewCategoryRepo(db), repos.NewProductRepo(db))
+
+	got, err := catalog.Search("", "retro-consoles", "EXCELLENT", nil, "", 1, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]bool{}
+	for _, p := range got {
+		ids[p.ID] = true
+		if !domain.MeetsGrade(p.Condition, "EXCELLENT") {
+			t.Fatalf("%s (%s) is below the minimum grade", p.ID, p.Condition)
+		}
+	}
+	if !ids["nes-001"] || !ids["snes-001"] || ids["gbc-001"] {
+		t.Fatalf("unexpected results %v", ids)
+	}
+
+	ss := repos.SavedSearch{Condition: "GOOD"}
+	if !services.Matches(ss, domain.Product{Condition: "MINT"}) || services.Matches(ss, domain.Product{Condition: "FAIR"}) {
+		t.Fatal("saved search should match its grade or better only")
+	}
+}
+
+// Databases created with the FIRST_HAND/SECOND_HAND CHECK are rebuilt on open
+func TestLegacyConditionsAreMigrated(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "legacy.db")
+	raw, err := sqlx.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = raw.Exec(`
+	CREATE TABLE categories(id TEXT PRIMARY KEY, name TEXT NOT NULL, created_at TEXT, updated_at TEXT);
+	CREATE TABLE products(
+	  id TEXT PRIMARY KEY,
+	  category_id TEXT NOT NULL REFERENCES categories(id) ON DELETE RESTRICT,
+	  title TEXT NOT NULL,
+	  description TEXT,
+	  condition TEXT NOT NULL CHECK (condition IN ('FIRST_HAND','SECOND_HAND')),
+	  price NUMERIC NOT NULL CHECK (price >= 0),
+	  images_json TEXT,
+	  active INTEGER NOT NULL DEFAULT 1,
+	  created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	  updated_at TEXT
+	);
+	INSERT INTO categories(id, name) VALUES('retro-consoles','Retro Consoles');
+	INSERT INTO products(id, category_id, title, description, condition, price, images_json) VALUES
+	  ('old-new','retro-consoles','Sealed thing','','FIRST_HAND',10,'[]'),
+	  ('old-used','retro-consoles','Used thing','','SECOND_HAND',5,'[]');
+	CREATE TABLE saved_searches(id TEXT PRIMARY KEY, user_id TEXT NOT NULL, query TEXT NOT NULL, category_id TEXT, condition TEXT, created_at TEXT);
+	INSERT INTO saved_searches(id, user_id, query, condition) VALUES
+	  ('ss-new','u-alice','famicom','FIRST_HAND'),
+	  ('ss-used','u-alice','walkman','SECOND_HAND'),
+	  ('ss-any','u-alice','radio',NULL);
+	`)
+	_ = raw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := repos.OpenDB(dsn)
+	if err != nil {
+		t.Fatalf("open legacy db: %v", err)
+	}
+	defer db.Close()
+	prods := repos.NewProductRepo(db)
+	for id, want := range map[string]string{"old-new": "NEW_SEALED", "old-used": "GOOD"} {
+		p, err := prods.Get(id)
+		if err != nil || p.Condition != want {
+			t.Fatalf("%s: got %q (%v), want %s", id, p.Condition, err, want)
+		}
+	}
+	// saved searches keep their filter on the new scale
+	for id, want := range map[string]string{"ss-new": "NEW_SEALED", "ss-used": "GOOD", "ss-any": ""} {
+		var cond string
+		if err := db.Get(&cond, `SELECT COALESCE(condition,'') FROM saved_searches WHERE id = ?`, id); err != nil || cond != want {
+			t.Fatalf("saved search %s: got %q (%v), want %q", id, cond, err, want)
+		}
+	}
+	if err := prods.SetCondition("old-used", "FOR_PARTS", "No power"); err != nil {
+		t.Fatalf("new grades should be accepted after migration: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE products SET condition = 'SECOND_HAND' WHERE id = 'old-new'`); err == nil {
+		t.Fatal("legacy values should be rejected after migration")
+	}
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"retrobytes/internal/pdf"
+	"retrobytes/internal/repos"
+)
+
+// ErrNoDocument means the order was canceled before an invoice was issued,
+// so there is nothing to invoice or pack.
+var ErrNoDocument = errors.New("no documents for a canceled order")
+
+// ErrNoInvoice means the order has no invoice number yet; it gets one when
+// it first ships or is collected, or when an admin issues it.
+var ErrNoInvoice = errors.New("no invoice issued yet")
+
+// DocumentService renders invoices and packing slips as PDF.
+type DocumentService struct {
+	Orders *repos.OrderRepo
+}
+
+func NewDocumentService(orders *repos.OrderRepo) *DocumentService {
+	return &DocumentService{Orders: orders}
+}
+
+// InvoiceNumber formats a stored invoice number for display.
+func InvoiceNumber(n int) string { return fmt.Sprintf("INV-%06d", n) }
+
+const (
+	docLeft  = 50.0
+	docRight = pdf.PageWidth - 50
+	docLast  = pdf.PageHeight - 60 // lowest baseline before a page break
+)
+
+type orderDoc struct {
+	o     repos.OrderRow
+	items []repos.OrderItemRow
+	adj   []repos.Adjustment
+	ship  *repos.Address
+	bill  *repos.Address
+}
+
+func (s *DocumentService) load(orderID string) (orderDoc, error) {
+	var d orderDoc
+	var err error
+	if d.o, d.items, err = s.Orders.Get(orderID); err != nil {
+		return d, err
+	}
+	if d.adj, err = s.Orders.Adjustments(orderID); err != nil {
+		return d, err
+	}
+	d.ship, d.bill, err = s.Orders.Addresses(orderID)
+	return d, err
+}
+
+// Issue gives the order the next invoice number ahead of shipping or
+// collection and returns it; an order that has one keeps it.
+func (s *DocumentService) Issue(orderID string) (string, error) {
+	no, _, err := s.Orders.AssignInvoiceNo(orderID)
+	if err != nil {
+		return "", err
+	}
+	if no == 0 {
+		return "", ErrNoDocument
+	}
+	return InvoiceNumber(no), nil
+}
+
+// Invoice renders the order's invoice under the number it was issued. It
+// returns the PDF and a download file name, or ErrNoInvoice (ErrNoDocument
+// once canceled) if no number was issued.
+func (s *DocumentService) Invoice(orderID string) ([]byte, string, error) {
+	d, err := s.load(orderID)
+	if err != nil {
+		return nil, "", err
+	}
+	no, at, err := s.Orders.InvoiceNo(orderID)
+	if err != nil {
+		return nil, "", err
+	}
+	if no == 0 {
+		if d.o.Status == "CANCELED" {
+			return nil, "", ErrNoDocument
+		}
+		return nil, "", ErrNoInvoice
+	}
+	number := InvoiceNumber(no)
+
+	doc := pdf.New("Invoice " + number)
+	y := docHeader(doc, "INVOICE", []string{
+		"Invoice no. " + number,
+		"Invoice date: " + docDay(at),
+		"Order: " + d.o.Number(),
+		"Order date: " + docDay(d.o.CreatedAt),
+	})
+
+	billTo := addressLines(d.bill)
+	if billTo == nil {
+		billTo = []string{d.o.Customer}
+	}
+	billTo = append(billTo, d.o.Email)
+	y = docColumns(doc, y, "Bill to", billTo, "Ship to", shipToLines(d))
+
+	cols := []docCol{{"Item", docLeft, false}, {"Condition", 300, false}, {"Qty", 410, true}, {"Unit price", 485, true}, {"Amount", docRight, true}}
+	y = docTableHeader(doc, y, cols)
+	subtotal := 0.0
+	for _, it := range d.items {
+		if y > docLast {
+			doc.AddPage()
+			y = docTableHeader(doc, 60, cols)
+		}
+		doc.Text(docLeft, y, pdf.Regular, 10, pdf.Fit(it.Title, pdf.Regular, 10, 240))
+		doc.Text(300, y, pdf.Regular, 10, it.ConditionLabel())
+		doc.TextRight(410, y, pdf.Regular, 10, fmt.Sprint(it.Qty))
+		doc.TextRight(485, y, pdf.Regular, 10, docMoney(it.Price))
+		doc.TextRight(docRight, y, pdf.Regular, 10, docMoney(it.Subtotal))
+		subtotal += it.Subtotal
+		y += 16
+	}
+	doc.Line(docLeft, y-10, docRight, y-10)
+	y += 4
+
+	totals := [][2]string{{"Subtotal", docMoney(subtotal)}}
+	for _, a := range d.adj {
+		totals = append(totals, [2]string{a.Label, docMoney(a.Amount)})
+	}
+	for _, t := range totals {
+		if y > docLast {
+			doc.AddPage()
+			y = 60
+		}
+		doc.TextRight(485, y, pdf.Regular, 10, pdf.Fit(t[0], pdf.Regular, 10, 300))
+		doc.TextRight(docRight, y, pdf.Regular, 10, t[1])
+		y += 16
+	}
+	doc.TextRight(485, y+4, pdf.Bold, 12, "Total")
+	doc.TextRight(docRight, y+4, pdf.Bold, 12, docMoney(d.o.Total))
+
+	doc.Text(docLeft, pdf.PageHeight-40, pdf.Regular, 8, "Thank you for shopping at RetroBytes. Prices include the condition grade shown for each item.")
+	return doc.Bytes(), strings.ToLower(number) + ".pdf", nil
+}
+
+// PackingSlip renders the pick list that goes into the parcel: items and
+// grades, no prices.
+func (s *DocumentService) PackingSlip(orderID string) ([]byte, string, error) {
+	d, err := s.load(orderID)
+	if err != nil {
+		return nil, "", err
+	}
+	if d.o.Status == "CANCELED" {
+		return nil, "", ErrNoDocument
+	}
+
+	doc := pdf.New("Packing slip " + d.o.Number())
+	method := d.o.ShipMethod
+	if method == "" {
+		method = strings.ToUpper(d.o.Fulfillment)
+	}
+	y := docHeader(doc, "PACKING SLIP", []string{
+		"Order: " + d.o.Number(),
+		"Order date: " + docDay(d.o.CreatedAt),
+		"Shipping: " + method,
+	})
+	y = docColumns(doc, y, "Ship to", shipToLines(d), "Customer", []string{d.o.Customer, d.o.Email})
+
+	cols := []docCol{{"", docLeft, false}, {"Item", docLeft + 20, false}, {"SKU", 300, false}, {"Condition", 400, false}, {"Qty", docRight, true}}
+	y = docTableHeader(doc, y, cols)
+	units := 0
+	for _, it := range d.items {
+		if y > docLast {
+			doc.AddPage()
+			y = docTableHeader(doc, 60, cols)
+		}
+		doc.Rect(docLeft, y-9, 10, 10)
+		doc.Text(docLeft+20, y, pdf.Regular, 10, pdf.Fit(it.Title, pdf.Regular, 10, 220))
+		doc.Text(300, y, pdf.Regular, 10, pdf.Fit(it.ProductID, pdf.Regular, 10, 95))
+		doc.Text(400, y, pdf.Regular, 10, it.ConditionLabel())
+		doc.TextRight(docRight, y, pdf.Bold, 10, fmt.Sprint(it.Qty))
+		units += it.Qty
+		y += 18
+	}
+	doc.Line(docLeft, y-12, docRight, y-12)
+	doc.TextRight(docRight, y+2, pdf.Bold, 10, fmt.Sprintf("%d item(s)", units))
+
+	doc.Text(docLeft, pdf.PageHeight-40, pdf.Regular, 8, "Questions about your order? Reply to your order confirmation email. Returns can be requested from the order page.")
+	return doc.Bytes(), "packing-slip-" + strings.ToLower(d.o.Number()) + ".pdf", nil
+}
+
+type docCol struct {
+	title string
+	x     float64
+	right bool // x is the right edge
+}
+
+// docHeader draws the shop name, the document title and the reference
+// lines, and returns the next baseline.
+func docHeader(doc *pdf.Doc, title string, refs []string) float64 {
+	doc.AddPage()
+	doc.Text(docLeft, 70, pdf.Bold, 22, "RetroBytes")
+	doc.Text(docLeft, 86, pdf.Regular, 9, "Retro consoles, games & collectibles")
+	doc.TextRight(docRight, 70, pdf.Bold, 16, title)
+	y := 90.0
+	for _, r := range refs {
+		doc.TextRight(docRight, y, pdf.Regular, 9, r)
+		y += 13
+	}
+	return y + 20
+}
+
+// docColumns draws two labelled blocks side by side.
+func docColumns(doc *pdf.Doc, y float64, leftTitle string, left []string, rightTitle string, right []string) float64 {
+	doc.Text(docLeft, y, pdf.Bold, 10, leftTitle)
+	doc.Text(310, y, pdf.Bold, 10, rightTitle)
+	end := y
+	for i, l := range left {
+		doc.Text(docLeft, y+14*float64(i+1), pdf.Regular, 10, pdf.Fit(l, pdf.Regular, 10, 240))
+		end = max(end, y+14*float64(i+1))
+	}
+	for i, l := range right {
+		doc.Text(310, y+14*float64(i+1), pdf.Regular, 10, pdf.Fit(l, pdf.Regular, 10, 250))
+		end = max(end, y+14*float64(i+1))
+	}
+	return end + 36
+}
+
+func docTableHeader(doc *pdf.Doc, y float64, cols []docCol) float64 {
+	for _, c := range cols {
+		if c.right {
+			doc.TextRight(c.x, y, pdf.Bold, 10, c.title)
+		} else {
+			doc.Text(c.x, y, pdf.Bold, 10, c.title)
+		}
+	}
+	doc.Line(docLeft, y+5, docRight, y+5)
+	return y + 22
+}
+
+func shipToLines(d orderDoc) []string {
+	if d.o.Fulfillment == "pickup" {
+		return []string{"Store pickup", "Region " + d.o.Region}
+	}
+	if lines := addressLines(d.ship); lines != nil {
+		return lines
+	}
+	return []string{d.o.Customer, "ZIP " + d.o.ShipZIP}
+}
+
+func addressLines(a *repos.Address) []string {
+	if a == nil {
+		return nil
+	}
+	lines := []string{a.Name, a.Line1}
+	if a.Line2 != "" {
+		lines = append(lines, a.Line2)
+	}
+	lines = append(lines, strings.TrimSpace(a.City+", "+a.State+" "+a.ZIP))
+	if a.Phone != "" {
+		lines = append(lines, a.Phone)
+	}
+	return lines
+}
+
+func docMoney(v float64) string {
+	if v < 0 {
+		return fmt.Sprintf("-$%.2f", -v)
+	}
+	return fmt.Sprintf("$%.2f", v)
+}
+
+// docDay trims a stored timestamp to its date.
+func docDay(ts string) string {
+	if len(ts) >= 10 {
+		return ts[:10]
+	}
+	return ts
+}
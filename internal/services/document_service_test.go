@@ -0,0 +1,91 @@
+package services_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestInvoiceNumbersAndContent(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	carts := repos.NewCartRepo(db)
+	orders := repos.NewOrderRepo(db)
+	cart := services.NewCartService(carts, prods)
+	svc := services.NewOrderService(carts, repos.NewInventoryRepo(db), orders, prods)
+	svc.Tax = services.NewTaxService(repos.NewTaxRepo(db), prods)
+	docs := services.NewDocumentService(orders)
+
+	place := func(sid string) string {
+		if err := cart.Add(sid, "gbc-001", 2); err != nil {
+			t.Fatal(err)
+		}
+		oid, _, _, err := svc.Place(sid, "20742", "pickup", services.Contact{Name: "Ann (Collector)", Email: "ann@retrobytes.test"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return oid
+	}
+	first, second, canceled := place("sid-d1"), place("sid-d2"), place("sid-d3")
+
+	// downloading does not issue a number
+	if _, _, err := docs.Invoice(second); !errors.Is(err, services.ErrNoInvoice) {
+		t.Fatalf("invoice before issue: err = %v", err)
+	}
+	if no, _, _ := orders.InvoiceNo(second); no != 0 {
+		t.Fatal("download issued an invoice number")
+	}
+	// numbers follow issue order, not placement order
+	if n, err := docs.Issue(second); err != nil || n != "INV-000001" {
+		t.Fatalf("issue second: %q, %v", n, err)
+	}
+	if n, _ := docs.Issue(first); n != "INV-000002" {
+		t.Fatalf("issue first: %q", n)
+	}
+	if n, _ := docs.Issue(second); n != "INV-000001" {
+		t.Fatalf("re-issue renumbered the invoice: %q", n)
+	}
+	inv2, name2, err := docs.Invoice(second)
+	if err != nil || name2 != "inv-000001.pdf" {
+		t.Fatalf("second: %q, %v", name2, err)
+	}
+	if no, at, _ := orders.InvoiceNo(second); no != 1 || at == "" {
+		t.Fatalf("stored invoice = %d %q", no, at)
+	}
+
+	if !bytes.HasPrefix(inv2, []byte("%PDF-1.4")) || !bytes.HasSuffix(inv2, []byte("%%EOF\n")) {
+		t.Fatal("not a PDF")
+	}
+	for _, want := range []string{"INV-000001", "Game Boy Color", "Good", "Ann \\(Collector\\)", "Sales tax", "$275.58"} {
+		if !bytes.Contains(inv2, []byte(want)) {
+			t.Errorf("invoice lacks %q", want)
+		}
+	}
+
+	slip, _, err := docs.PackingSlip(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(slip, []byte("PACKING SLIP")) || !bytes.Contains(slip, []byte("gbc-001")) || bytes.Contains(slip, []byte("$")) {
+		t.Fatal("packing slip should list the items without prices")
+	}
+
+	if _, err := svc.Cancel(canceled, repos.StatusChange{Actor: "CUSTOMER"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := docs.Invoice(canceled); !errors.Is(err, services.ErrNoDocument) {
+		t.Fatalf("invoice for canceled order: err = %v", err)
+	}
+	if _, err := docs.Issue(canceled); !errors.Is(err, services.ErrNoDocument) {
+		t.Fatalf("issue for canceled order: err = %v", err)
+	}
+	if no, _, _ := orders.InvoiceNo(canceled); no != 0 {
+		t.Fatal("canceled order used up an invoice number")
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"retrobytes/internal/mail"
+	"retrobytes/internal/repos"
+)
+
+const (
+	// MaxEmailAttempts is how many deliveries are tried before a mail is
+	// marked FAILED and left for staff to retry.
+	MaxEmailAttempts = 6
+	// emailRetryBase is the wait after the first failure; it doubles with
+	// every further attempt (1, 2, 4, 8, 16 minutes).
+	emailRetryBase = time.Minute
+	// emailBatch caps the mails handled by one Deliver run.
+	emailBatch = 50
+)
+
+const outboxTimeFormat = "2006-01-02 15:04:05"
+
+// MailService queues transactional email in the outbox and delivers it
+// through the configured transport.
+type MailService struct {
+	Outbox *repos.OutboxRepo
+	Mailer mail.Mailer
+	// BaseURL prefixes links in emails, e.g. "https://shop.example"
+	BaseURL string
+}
+
+func NewMailService(outbox *repos.OutboxRepo, m mail.Mailer, baseURL string) *MailService {
+	if m == nil {
+		m = mail.LogMailer{}
+	}
+	return &MailService{Outbox: outbox, Mailer: m, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Link turns a site path into an absolute URL for an email.
+func (s *MailService) Link(path string) string { return s.BaseURL + path }
+
+// Send queues m as a standalone notification. It satisfies mail.Mailer, so
+// notification emails go through the outbox as well.
+func (s *MailService) Send(m mail.Message) error {
+	return s.Outbox.Enqueue(outboxEmail("notification", m))
+}
+
+func outboxEmail(kind string, m mail.Message) repos.OutboxEmail {
+	return repos.OutboxEmail{Kind: kind, To: m.To, Subject: m.Subject, Text: m.Text, HTML: m.HTML}
+}
+
+// StatusEmail renders the notice for an order moving to status, ready to
+// be queued with the status change.
+func (s *MailService) StatusEmail(o repos.OrderRow, status string) (repos.OutboxEmail, error) {
+	m, err := mail.OrderStatusMessage(o.Email, mail.OrderStatus{
+		Name: o.Customer, OrderNo: o.Number(), Status: status, Link: s.Link("/order/" + o.ID),
+	})
+	if err != nil {
+		return repos.OutboxEmail{}, err
+	}
+	return outboxEmail(mail.KindOrderStatus, m), nil
+}
+
+// Deliver sends the mails that are due at now. A failed send is retried
+// with exponential backoff until MaxEmailAttempts; then the mail is marked
+// FAILED. Only outbox errors are returned.
+func (s *MailService) Deliver(now time.Time) (sent, failed int, err error) {
+	due, err := s.Outbox.Due(now.UTC().Format(outboxTimeFormat), emailBatch)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, e := range due {
+		sendErr := s.Mailer.Send(mail.Message{To: e.To, Subject: e.Subject, Text: e.Text, HTML: e.HTML})
+		if sendErr == nil {
+			if err := s.Outbox.MarkSent(e.ID, now.UTC().Format(outboxTimeFormat)); err != nil {
+				return sent, failed, err
+			}
+			sent++
+			continue
+		}
+		msg := sendErr.Error()
+		if len(msg) > 500 {
+			msg = msg[:500]
+		}
+		attempts := e.Attempts + 1
+		if attempts >= MaxEmailAttempts {
+			log.Printf("[mail] giving up on %d to %s after %d attempts: %v", e.ID, e.To, attempts, sendErr)
+			if err := s.Outbox.MarkFailed(e.ID, msg); err != nil {
+				return sent, failed, err
+			}
+			failed++
+			continue
+		}
+		next := now.Add(emailRetryBase << (attempts - 1))
+		if err := s.Outbox.Reschedule(e.ID, next.UTC().Format(outboxTimeFormat), msg); err != nil {
+			return sent, failed, err
+		}
+	}
+	return sent, failed, nil
+}
+
+// Failed lists mails that ran out of attempts.
+func (s *MailService) Failed() ([]repos.OutboxEmail, error) { return s.Outbox.Failed(100) }
+
+// Retry re-queues a failed mail; false if it was not FAILED.
+func (s *MailService) Retry(id int64) (bool, error) { return s.Outbox.Retry(id) }
+
+func (s *MailService) Counts() (map[string]int, error) { return s.Outbox.Counts() }
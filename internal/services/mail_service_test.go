@@ -0,0 +1,147 @@
+package services_test
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"retrobytes/internal/mail"
+	"retrobytes/internal/payments"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+// flakyMailer fails every send while down is set.
+type flakyMailer struct {
+	captureMailer
+	down bool
+}
+
+func (m *flakyMailer) Send(msg mail.Message) error {
+	if m.down {
+		return errors.New("421 service not available")
+	}
+	return m.captureMailer.Send(msg)
+}
+
+func TestOutboxConfirmationAndBackoff(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer := &flakyMailer{down: true}
+	outbox := repos.NewOutboxRepo(db)
+	mailSvc := services.NewMailService(outbox, mailer, "http://shop.test/")
+	carts := repos.NewCartRepo(db)
+	prods := repos.NewProductRepo(db)
+	svc := services.NewOrderService(carts, repos.NewInventoryRepo(db), repos.NewOrderRepo(db), prods)
+	svc.Payments = services.NewPaymentService(payments.NewFake(), repos.NewPaymentRepo(db))
+	svc.Mail = mailSvc
+
+	if err := services.NewCartService(carts, prods).Add("sid-m1", "gbc-001", 1); err != nil {
+		t.Fatal(err)
+	}
+	contact := services.Contact{Name: "Ann", Email: "ann@retrobytes.test", PaymentToken: payments.TokenDeclined}
+	if _, _, _, err := svc.Place("sid-m1", "20742", "pickup", contact); err == nil {
+		t.Fatal("declined card placed the order")
+	}
+	if n, _ := mailSvc.Counts(); n["PENDING"] != 0 {
+		t.Fatalf("confirmation queued for an order that was never created: %v", n)
+	}
+	contact.PaymentToken = payments.TokenApproved
+	oid, _, _, err := svc.Place("sid-m1", "20742", "pickup", contact)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every failure pushes the next attempt out: 1, 2, 4, 8, 16 minutes
+	now := time.Now()
+	wait := time.Minute
+	for attempt := 1; attempt < services.MaxEmailAttempts; attempt++ {
+		if sent, failed, err := mailSvc.Deliver(now); err != nil || sent != 0 || failed != 0 {
+			t.Fatalf("attempt %d: sent=%d failed=%d err=%v", attempt, sent, failed, err)
+		}
+		if sent, _, _ := mailSvc.Deliver(now.Add(wait - time.Second)); sent != 0 {
+			t.Fatalf("attempt %d retried before its backoff", attempt)
+		}
+		now = now.Add(wait)
+		wait *= 2
+	}
+	if _, failed, err := mailSvc.Deliver(now); err != nil || failed != 1 {
+		t.Fatalf("last attempt: failed=%d err=%v", failed, err)
+	}
+	bad, err := mailSvc.Failed()
+	if err != nil || len(bad) != 1 || bad[0].Kind != mail.KindOrderConfirmation || bad[0].Attempts != services.MaxEmailAttempts || bad[0].LastError == "" {
+		t.Fatalf("failed = %+v, %v", bad, err)
+	}
+
+	mailer.down = false
+	if ok, err := mailSvc.Retry(bad[0].ID); err != nil || !ok {
+		t.Fatalf("retry: %v %v", ok, err)
+	}
+	if ok, _ := mailSvc.Retry(bad[0].ID); ok {
+		t.Fatal("retried a mail that is no longer FAILED")
+	}
+	if sent, _, err := mailSvc.Deliver(time.Now().Add(time.Minute)); err != nil || sent != 1 {
+		t.Fatalf("after retry: sent=%d err=%v", sent, err)
+	}
+	m := mailer.sent[0]
+	o, _, _ := repos.NewOrderRepo(db).Get(oid)
+	if m.To != "ann@retrobytes.test" || o.OrderNo == "" || !strings.Contains(m.Subject, o.OrderNo) ||
+		!strings.Contains(m.Text, "Game Boy Color") || !strings.Contains(m.HTML, "http://shop.test/order/"+oid) {
+		t.Fatalf("confirmation = %+v", m)
+	}
+	if n, _ := mailSvc.Counts(); n["SENT"] != 1 || n["PENDING"] != 0 || n["FAILED"] != 0 {
+		t.Fatalf("counts = %v", n)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer := &captureMailer{}
+	mailSvc := services.NewMailService(repos.NewOutboxRepo(db), mailer, "http://shop.test")
+	users := repos.NewUserRepo(db)
+	auth := &services.AuthService{Users: users, Mail: mailSvc}
+	now := time.Now()
+
+	if err := auth.RequestPasswordReset("nobody@retrobytes.test", now); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.RequestPasswordReset("Alice@RetroBytes.test", now); err != nil {
+		t.Fatal(err)
+	}
+	if sent, _, _ := mailSvc.Deliver(now); sent != 1 || mailer.sent[0].To != "alice@retrobytes.test" {
+		t.Fatalf("sent %d: %+v", sent, mailer.sent)
+	}
+	token := regexp.MustCompile(`token=([0-9a-f]{64})`).FindStringSubmatch(mailer.sent[0].Text)
+	if token == nil {
+		t.Fatalf("no reset link in %q", mailer.sent[0].Text)
+	}
+
+	if err := users.BindSession("sid-alice", "u-alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.ResetPassword(token[1], "N3w-pass!", now.Add(services.PasswordResetTTL+time.Minute)); !errors.Is(err, services.ErrResetInvalid) {
+		t.Fatalf("expired token: err = %v", err)
+	}
+	if err := auth.ResetPassword(token[1], "N3w-pass!", now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := auth.CurrentUser("sid-alice"); u != nil {
+		t.Fatal("old session still signed in after the reset")
+	}
+	if _, err := auth.Login("sid-2", "alice@retrobytes.test", "N3w-pass!"); err != nil {
+		t.Fatalf("login with the new password: %v", err)
+	}
+	if ok, _ := auth.ResetTokenValid(token[1], now); ok {
+		t.Fatal("token still valid after use")
+	}
+	if err := auth.ResetPassword(token[1], "0ther-pass!", now.Add(time.Minute)); !errors.Is(err, services.ErrResetInvalid) {
+		t.Fatalf("reused token: err = %v", err)
+	}
+}
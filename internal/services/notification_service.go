@@ -0,0 +1,51 @@
+package services
+
+import (
+	"retrobytes/internal/mail"
+	"retrobytes/internal/repos"
+)
+
+// NotificationService fans a user-facing event out to the on-site
+// notification list and to the configured mailer.
+type NotificationService struct {
+	Repo   *repos.NotificationRepo
+	Mailer mail.Mailer
+}
+
+func NewNotificationService(r *repos.NotificationRepo, m mail.Mailer) *NotificationService {
+	if m == nil {
+		m = mail.LogMailer{}
+	}
+	return &NotificationService{Repo: r, Mailer: m}
+}
+
+// Notify records an on-site notification and emails the user. A mail failure
+// is returned but does not undo the on-site notification. Guests (no userID)
+// only get the email.
+func (s *NotificationService) Notify(userID, email, kind, subject, message, link string) error {
+	if userID != "" {
+		if err := s.Repo.Create(userID, kind, message, link); err != nil {
+			return err
+		}
+	}
+	if email == "" {
+		return nil
+	}
+	text := message
+	if link != "" {
+		text += "\n\n" + link
+	}
+	return s.Mailer.Send(mail.Message{To: email, Subject: subject, Text: text})
+}
+
+func (s *NotificationService) List(userID string) ([]repos.Notification, error) {
+	return s.Repo.ListByUser(userID, 50)
+}
+
+func (s *NotificationService) UnreadCount(userID string) (int, error) {
+	return s.Repo.UnreadCount(userID)
+}
+
+func (s *NotificationService) MarkAllRead(userID string) error {
+	return s.Repo.MarkAllRead(userID)
+}
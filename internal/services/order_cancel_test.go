@@ -0,0 +1,126 @@
+package services_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"retrobytes/internal/mail"
+	"retrobytes/internal/payments"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestCustomerCancel(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	carts := repos.NewCartRepo(db)
+	inv := repos.NewInventoryRepo(db)
+	orders := repos.NewOrderRepo(db)
+	cart := services.NewCartService(carts, prods)
+	svc := services.NewOrderService(carts, inv, orders, prods)
+	pay := services.NewPaymentService(payments.NewFake(), repos.NewPaymentRepo(db))
+	outbox := repos.NewOutboxRepo(db)
+	svc.Payments, svc.Mail = pay, services.NewMailService(outbox, &captureMailer{}, "http://shop.test")
+	customer := repos.StatusChange{Actor: "CUSTOMER", Note: "Canceled by the customer"}
+
+	place := func(sid string) string {
+		if err := cart.Add(sid, "gbc-001", 2); err != nil {
+			t.Fatal(err)
+		}
+		contact := services.Contact{Name: "Ann", Email: "a@retrobytes.test", PaymentToken: payments.TokenApproved}
+		oid, _, _, err := svc.Place(sid, "20742", "pickup", contact)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return oid
+	}
+
+	before, _ := inv.Qty("gbc-001", "20742")
+	oid := place("sid-c1")
+	if o, err := svc.Cancel(oid, customer); err != nil || o.Status != "CANCELED" {
+		t.Fatalf("cancel: %+v, %v", o, err)
+	}
+	if qty, _ := inv.Qty("gbc-001", "20742"); qty != before {
+		t.Fatalf("stock not restored: %d -> %d", before, qty)
+	}
+	if p, _, _ := pay.ForOrder(oid); p.Status != "VOIDED" {
+		t.Fatalf("payment = %s, want VOIDED", p.Status)
+	}
+	hist, err := orders.History(oid)
+	if err != nil || len(hist) != 1 || hist[0].Actor != "CUSTOMER" || hist[0].Status != "CANCELED" {
+		t.Fatalf("history = %+v, %v", hist, err)
+	}
+	o, _, _ := orders.Get(oid)
+	due, err := outbox.Due(time.Now().Add(time.Minute).UTC().Format("2006-01-02 15:04:05"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notice repos.OutboxEmail
+	for _, m := range due {
+		if m.Kind == mail.KindOrderStatus {
+			notice = m
+		}
+	}
+	if !strings.Contains(notice.Subject, o.Number()) || !strings.Contains(notice.Text, "http://shop.test/order/"+oid) {
+		t.Fatalf("cancel notice = %q\n%s", notice.Subject, notice.Text)
+	}
+
+	// a cancel whose void did not go through is finished by a retry,
+	// without restocking twice
+	retry := place("sid-c4")
+	if _, err := db.Exec(`UPDATE orders SET status = 'CANCELED' WHERE id = ?`, retry); err != nil {
+		t.Fatal(err)
+	}
+	stock, _ := inv.Qty("gbc-001", "20742")
+	if _, err := svc.Cancel(retry, customer); err != nil {
+		t.Fatal(err)
+	}
+	if p, _, _ := pay.ForOrder(retry); p.Status != "VOIDED" {
+		t.Fatalf("retried cancel left the payment %s", p.Status)
+	}
+	if qty, _ := inv.Qty("gbc-001", "20742"); qty != stock {
+		t.Fatalf("retried cancel restocked: %d -> %d", stock, qty)
+	}
+
+	// Once the card has been charged the order is past the point of cancel.
+	captured := place("sid-c2")
+	if _, err := pay.Capture(captured); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Cancel(captured, customer); !errors.Is(err, services.ErrNotCancelable) {
+		t.Fatalf("cancel after capture: err = %v", err)
+	}
+
+	shipped := place("sid-c3")
+	if err := orders.UpdateStatus(shipped, "SHIPPED"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Cancel(shipped, customer); !errors.Is(err, services.ErrNotCancelable) {
+		t.Fatalf("cancel after shipping: err = %v", err)
+	}
+
+	// staff can still cancel a picked order; it is restocked all the same
+	picked := place("sid-c5")
+	if err := orders.UpdateStatus(picked, "RESERVED"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Cancel(picked, customer); !errors.Is(err, services.ErrNotCancelable) {
+		t.Fatalf("customer cancel of a picked order: err = %v", err)
+	}
+	stock, _ = inv.Qty("gbc-001", "20742")
+	if _, err := svc.Cancel(picked, repos.StatusChange{Actor: "ADMIN", UserID: "u-admin"}); err != nil {
+		t.Fatal(err)
+	}
+	if qty, _ := inv.Qty("gbc-001", "20742"); qty != stock+2 {
+		t.Fatalf("admin cancel restock: %d -> %d", stock, qty)
+	}
+	hist, _ = orders.History(picked)
+	if len(hist) != 1 || hist[0].Actor != "ADMIN" || hist[0].UserID != "u-admin" {
+		t.Fatalf("admin cancel history = %+v", hist)
+	}
+}
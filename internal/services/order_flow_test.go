@@ -25,8 +25,9 @@ func memdbAll(t *testing.T) *sqlx.DB {
 	CREATE TABLE carts(id TEXT PRIMARY KEY, session_id TEXT UNIQUE NOT NULL, updated_at TEXT);
 	CREATE TABLE cart_items(cart_id TEXT, product_id TEXT, qty INTEGER, price_at_add NUMERIC,
 	  created_at TEXT, updated_at TEXT, PRIMARY KEY(cart_id, product_id));
-	CREATE TABLE orders(id TEXT PRIMARY KEY, session_id TEXT, region_code TEXT, fulfillment TEXT,
+	CREATE TABLE orders(id TEXT PRIMARY KEY, order_no TEXT UNIQUE, session_id TEXT, region_code TEXT, fulfillment TEXT,
 	  customer_name TEXT, customer_email TEXT, total NUMERIC, status TEXT, created_at TEXT);
+	CREATE TABLE order_number_seq(year INTEGER PRIMARY KEY, last INTEGER NOT NULL);
 	CREATE TABLE order_items(order_id TEXT, product_id TEXT, qty INTEGER, price NUMERIC, condition TEXT,
 	  PRIMARY KEY(order_id, product_id));
 
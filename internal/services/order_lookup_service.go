@@ -0,0 +1,89 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"retrobytes/internal/mail"
+	"retrobytes/internal/repos"
+)
+
+// OrderLinkTTL is how long an emailed order link works.
+const OrderLinkTTL = 24 * time.Hour
+
+// OrderLookupService lets guests get back to an order without their
+// session: given the order number and email they receive a signed,
+// expiring link to the order page.
+type OrderLookupService struct {
+	Orders *repos.OrderRepo
+	Mail   *MailService
+	Secret []byte
+}
+
+func NewOrderLookupService(orders *repos.OrderRepo, m *MailService, secret []byte) *OrderLookupService {
+	return &OrderLookupService{Orders: orders, Mail: m, Secret: secret}
+}
+
+// Token signs access to orderID until expires: "<unix expiry>.<hmac>".
+func (s *OrderLookupService) Token(orderID string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + s.sign(orderID, exp)
+}
+
+// Verify checks a token for orderID and returns when it expires.
+func (s *OrderLookupService) Verify(orderID, token string, now time.Time) (time.Time, bool) {
+	exp, sig, ok := strings.Cut(token, ".")
+	if !ok || len(s.Secret) == 0 {
+		return time.Time{}, false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	expires := time.Unix(unix, 0)
+	if !now.Before(expires) || !hmac.Equal([]byte(sig), []byte(s.sign(orderID, exp))) {
+		return time.Time{}, false
+	}
+	return expires, true
+}
+
+func (s *OrderLookupService) sign(orderID, exp string) string {
+	m := hmac.New(sha256.New, s.Secret)
+	fmt.Fprintf(m, "order-link\x00%s\x00%s", orderID, exp)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// Request emails a link to the order if orderNo and email belong together.
+// A mismatch is not an error, so the form does not confirm which orders
+// or addresses exist.
+func (s *OrderLookupService) Request(orderNo, email string, now time.Time) error {
+	id, err := s.Orders.IDByNumber(orderNo)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	o, _, err := s.Orders.Get(id)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(strings.TrimSpace(o.Email), email) {
+		return nil
+	}
+	link := s.Mail.Link("/order/" + o.ID + "?t=" + s.Token(o.ID, now.Add(OrderLinkTTL)))
+	m, err := mail.OrderLinkMessage(o.Email, mail.OrderLink{
+		Name: o.Customer, OrderNo: o.Number(), Link: link, ValidHours: int(OrderLinkTTL / time.Hour),
+	})
+	if err != nil {
+		return err
+	}
+	return s.Mail.Outbox.Enqueue(outboxEmail(mail.KindOrderLink, m))
+}
@@ -0,0 +1,104 @@
+package services_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"retrobytes/internal/mail"
+	"retrobytes/internal/payments"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestOrderNumbersAndLookupLinks(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outbox := repos.NewOutboxRepo(db)
+	mailSvc := services.NewMailService(outbox, &captureMailer{}, "http://shop.test/")
+	carts := repos.NewCartRepo(db)
+	prods := repos.NewProductRepo(db)
+	orders := repos.NewOrderRepo(db)
+	svc := services.NewOrderService(carts, repos.NewInventoryRepo(db), orders, prods)
+	svc.Payments = services.NewPaymentService(payments.NewFake(), repos.NewPaymentRepo(db))
+	svc.Mail = mailSvc
+
+	var ids []string
+	for _, sid := range []string{"sid-l1", "sid-l2"} {
+		if err := services.NewCartService(carts, prods).Add(sid, "gbc-001", 1); err != nil {
+			t.Fatal(err)
+		}
+		contact := services.Contact{Name: "Ann", Email: "Ann@RetroBytes.test", PaymentToken: payments.TokenApproved}
+		oid, _, _, err := svc.Place(sid, "20742", "pickup", contact)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, oid)
+	}
+	first, _, _ := orders.Get(ids[0])
+	second, _, _ := orders.Get(ids[1])
+	if !regexp.MustCompile(`^RB-\d{4}-\d{6}$`).MatchString(first.OrderNo) {
+		t.Fatalf("order number %q", first.OrderNo)
+	}
+	if n1, n2 := first.OrderNo[8:], second.OrderNo[8:]; n2 <= n1 || first.OrderNo[:8] != second.OrderNo[:8] {
+		t.Fatalf("numbers not sequential: %s then %s", first.OrderNo, second.OrderNo)
+	}
+	if id, err := orders.IDByNumber(second.OrderNo); err != nil || id != ids[1] {
+		t.Fatalf("IDByNumber = %q, %v", id, err)
+	}
+
+	lookup := services.NewOrderLookupService(orders, mailSvc, []byte("test-secret"))
+	now := time.Now()
+	queued := func() int {
+		n, _ := mailSvc.Counts()
+		return n["PENDING"]
+	}
+	before := queued()
+	// wrong email or unknown number: no error, no mail
+	if err := lookup.Request(first.OrderNo, "mallory@retrobytes.test", now); err != nil {
+		t.Fatal(err)
+	}
+	if err := lookup.Request("RB-1999-000001", "ann@retrobytes.test", now); err != nil {
+		t.Fatal(err)
+	}
+	if queued() != before {
+		t.Fatal("lookup mail sent without a matching order and email")
+	}
+	if err := lookup.Request(first.OrderNo, "ann@retrobytes.test", now); err != nil {
+		t.Fatal(err)
+	}
+	due, err := outbox.Due(now.Add(time.Minute).UTC().Format("2006-01-02 15:04:05"), 10)
+	if err != nil || len(due) != before+1 {
+		t.Fatalf("due = %d, %v", len(due), err)
+	}
+	m := due[len(due)-1]
+	if m.Kind != mail.KindOrderLink || m.To != first.Email || !strings.Contains(m.Subject, first.OrderNo) {
+		t.Fatalf("lookup mail = %s %s %q", m.Kind, m.To, m.Subject)
+	}
+	link := regexp.MustCompile(`http://shop\.test/order/` + first.ID + `\?t=(\S+)`).FindStringSubmatch(m.Text)
+	if link == nil {
+		t.Fatalf("no order link in %q", m.Text)
+	}
+	token := link[1]
+
+	if exp, ok := lookup.Verify(first.ID, token, now); !ok || exp.Sub(now) > services.OrderLinkTTL {
+		t.Fatalf("fresh token rejected or lives too long: %v %v", exp, ok)
+	}
+	if _, ok := lookup.Verify(second.ID, token, now); ok {
+		t.Fatal("token opened a different order")
+	}
+	if _, ok := lookup.Verify(first.ID, token, now.Add(services.OrderLinkTTL+time.Second)); ok {
+		t.Fatal("expired token accepted")
+	}
+	exp, sig, _ := strings.Cut(token, ".")
+	if _, ok := lookup.Verify(first.ID, exp+"0."+sig, now); ok {
+		t.Fatal("token with a pushed-out expiry accepted")
+	}
+	other := services.NewOrderLookupService(orders, mailSvc, []byte("other-secret"))
+	if _, ok := other.Verify(first.ID, token, now); ok {
+		t.Fatal("token accepted under another secret")
+	}
+}
@@ -4,15 +4,40 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
+	"slices"
+	"time"
 
+	"retrobytes/internal/mail"
 	"retrobytes/internal/repos"
 
 	"github.com/google/uuid"
 )
 
+// ErrPricesChanged means a cart line no longer matches the catalog price;
+// the shopper has to review and accept the new prices first.
+var ErrPricesChanged = errors.New("prices changed since items were added to the cart")
+
+// ErrNotCancelable means the order has moved past PLACED (or its payment was
+// already taken) and can no longer be canceled by the customer.
+var ErrNotCancelable = errors.New("order can no longer be canceled")
+
 type Contact struct {
 	Name  string
 	Email string
+	// ShipZIP is the delivery destination (defaults to the region) and
+	// ShipMethod the chosen shipping method ("" = cheapest); both are
+	// ignored for pickup.
+	ShipZIP    string
+	ShipMethod string
+	// ShipTo is the delivery address (its ZIP overrides ShipZIP) and
+	// BillTo the billing address; both are stored on the order when set.
+	ShipTo *repos.Address
+	BillTo *repos.Address
+	// PaymentToken is the card token collected at checkout
+	PaymentToken string
+	// PickupSlot is the chosen pickup slot's key (see PickupSlot.Key)
+	PickupSlot string
 }
 
 type OrderService struct {
@@ -20,13 +45,32 @@ type OrderService struct {
 	Inv    *repos.InventoryRepo
 	Orders *repos.OrderRepo
 	Prods  *repos.ProductRepo
+	// Pricing resolves scheduled sales at placement time; without it the
+	// regular catalog price is charged
+	Pricing *PricingService
+	// Promos re-checks the cart's promotion code at placement
+	Promos *PromotionService
+	// Tax adds sales tax for the order's ZIP as a TAX adjustment line
+	Tax *TaxService
+	// Shipping prices delivery orders and records the chosen method; pickup
+	// is always free
+	Shipping *ShippingService
+	// Payments authorizes the card before the order is created; without it
+	// orders are placed unpaid
+	Payments *PaymentService
+	// Mail queues the order confirmation and the cancel notice together
+	// with the order changes
+	Mail *MailService
+	// Pickup checks the chosen pickup slot and issues the pickup code;
+	// without it pickup orders are placed without a slot
+	Pickup *PickupService
 }
 
 func NewOrderService(carts *repos.CartRepo, inv *repos.InventoryRepo, orders *repos.OrderRepo, prods *repos.ProductRepo) *OrderService {
 	return &OrderService{Carts: carts, Inv: inv, Orders: orders, Prods: prods}
 }
 
-func (s *OrderService) Place(sessionID, region, fulfillment string, contact Contact) (string, float64, float64, error) {
+func (s *OrderService) Place(sessionID, region, fulfillment string, contact Contact) (_ string, _ float64, _ float64, err error) {
 	if region == "" {
 		return "", 0, 0, errors.New("missing region")
 	}
@@ -47,9 +91,12 @@ func (s *OrderService) Place(sessionID, region, fulfillment string, contact Cont
 		return "", 0, 0, errors.New("cart empty")
 	}
 
-	// pre-check stock and recompute totals from trusted product data
+	// pre-check stock and recompute totals from trusted product data,
+	// priced as of placement
+	now := time.Now()
 	serverTotal := 0.0
 	clientTotal := 0.0
+	titles := make(map[string]string, len(items))
 	for i, it := range items {
 		qty, err := s.Inv.Qty(it.ProductID, region)
 		if err != nil && err != sql.ErrNoRows {
@@ -63,30 +110,260 @@ func (s *OrderService) Place(sessionID, region, fulfillment string, contact Cont
 		if err != nil {
 			return "", 0, 0, err
 		}
+		price := p.Price
+		if s.Pricing != nil {
+			if price, err = s.Pricing.Effective(p, now); err != nil {
+				return "", 0, 0, err
+			}
+		}
+		if math.Abs(price-it.Price) >= 0.005 {
+			return "", 0, 0, ErrPricesChanged
+		}
 		clientTotal += it.Price * float64(it.Qty)
-		items[i].Price = p.Price
+		items[i].Price = price
 		items[i].Condition = p.Condition
-		serverTotal += p.Price * float64(it.Qty)
+		titles[it.ProductID] = p.Title
+		serverTotal += price * float64(it.Qty)
 	}
 
-	// decrement
-	for _, it := range items {
-		if err := s.Inv.Decrement(it.ProductID, region, it.Qty); err != nil {
+	// the cart's code must still apply; the discount is recomputed from
+	// placement-time prices and stored as an adjustment line
+	lines := make([]CartLine, len(items))
+	for i, it := range items {
+		lines[i] = CartLine{ProductID: it.ProductID, Qty: it.Qty, Price: it.Price}
+	}
+	var promo *AppliedPromo
+	if s.Promos != nil {
+		code, err := s.Carts.PromoCode(cartID)
+		if err != nil {
+			return "", 0, 0, err
+		}
+		if code != "" {
+			applied, err := s.Promos.Evaluate(code, lines, sessionID, contact.Email, now)
+			if err != nil {
+				return "", 0, 0, err
+			}
+			promo = &applied
+			serverTotal -= applied.Discount
+			clientTotal -= applied.Discount
+		}
+	}
+
+	discount := 0.0
+	if promo != nil {
+		discount = promo.Discount
+	}
+
+	// delivery ships from the region's stock to the customer's ZIP; the
+	// free-shipping threshold applies to items after discounts
+	destZIP := region
+	if fulfillment == "delivery" {
+		if contact.ShipTo != nil {
+			destZIP = contact.ShipTo.ZIP
+		} else if contact.ShipZIP != "" {
+			destZIP = contact.ShipZIP
+		}
+	}
+	var ship *ShippingOption
+	if fulfillment == "delivery" && s.Shipping != nil {
+		itemsDue := 0.0
+		for _, l := range lines {
+			itemsDue += l.Price * float64(l.Qty)
+		}
+		opt, err := s.Shipping.Quote(region, destZIP, contact.ShipMethod, lines, itemsDue-discount)
+		if err != nil {
+			return "", 0, 0, err
+		}
+		ship = &opt
+		serverTotal += opt.Cost
+		clientTotal += opt.Cost
+	}
+
+	// tax follows the discount: it is charged on what the customer pays for
+	// the items, at the delivery or pickup ZIP
+	var tax TaxQuote
+	if s.Tax != nil {
+		if tax, err = s.Tax.Quote(destZIP, lines, discount); err != nil {
 			return "", 0, 0, err
 		}
+		serverTotal += tax.Amount
+		clientTotal += tax.Amount
 	}
 
-	// create order
+	serverTotal = math.Round(serverTotal*100) / 100
 	orderID := uuid.NewString()
-	if err := s.Orders.Create(orderID, sessionID, region, fulfillment, contact.Name, contact.Email, serverTotal); err != nil {
+
+	// the slot must still be offered before the card is touched
+	var slot PickupSlot
+	pickupCode := ""
+	if fulfillment == "pickup" && s.Pickup != nil {
+		if slot, err = s.Pickup.Slot(region, contact.PickupSlot, now); err != nil {
+			return "", 0, 0, err
+		}
+		if pickupCode, err = NewPickupCode(); err != nil {
+			return "", 0, 0, err
+		}
+	}
+
+	// the order only exists once the card is authorized; if anything fails
+	// after that, the hold is released again
+	authRef := ""
+	if s.Payments != nil && serverTotal > 0 {
+		if authRef, err = s.Payments.Authorize(orderID, serverTotal, contact.PaymentToken, contact.Email); err != nil {
+			return "", 0, 0, err
+		}
+		defer func() {
+			if err != nil {
+				_ = s.Payments.Release(authRef)
+			}
+		}()
+	}
+
+	// the number is reserved first so the confirmation can quote it
+	orderNo, err := s.Orders.NextOrderNo(now)
+	if err != nil {
 		return "", 0, 0, err
 	}
-	for _, it := range items {
-		if err := s.Orders.InsertItem(orderID, it.ProductID, it.Qty, it.Price, it.Condition); err != nil {
+	var mails []repos.OutboxEmail
+	if s.Mail != nil && contact.Email != "" {
+		conf := mail.OrderConfirmation{Name: contact.Name, OrderNo: orderNo, Fulfillment: fulfillment,
+			Total: serverTotal, Link: s.Mail.Link("/order/" + orderID), LookupLink: s.Mail.Link("/orders/lookup")}
+		if pickupCode != "" {
+			conf.PickupAt, conf.PickupCode = slot.Label(), pickupCode
+		}
+		for _, it := range items {
+			conf.Lines = append(conf.Lines, mail.OrderLine{Title: titles[it.ProductID], Qty: it.Qty, Price: it.Price})
+		}
+		if promo != nil {
+			conf.Adjustments = append(conf.Adjustments, mail.OrderAdjustment{Label: "Promotion " + promo.Code, Amount: -promo.Discount})
+		}
+		if ship != nil {
+			conf.Adjustments = append(conf.Adjustments, mail.OrderAdjustment{Label: ship.Label(), Amount: ship.Cost})
+		}
+		if tax.ZIPPrefix != "" {
+			conf.Adjustments = append(conf.Adjustments, mail.OrderAdjustment{Label: tax.Label(), Amount: tax.Amount})
+		}
+		m, err := mail.OrderConfirmationMessage(contact.Email, conf)
+		if err != nil {
 			return "", 0, 0, err
 		}
+		mails = append(mails, outboxEmail(mail.KindOrderConfirmation, m))
+	}
+
+	// stock, order, lines, adjustments, redemption, payment and mail are
+	// written in one transaction
+	order := repos.NewOrder{ID: orderID, OrderNo: orderNo, SessionID: sessionID, Region: region,
+		Fulfillment: fulfillment, Customer: contact.Name, Email: contact.Email, Total: serverTotal, BillTo: contact.BillTo}
+	for _, it := range items {
+		order.Items = append(order.Items, repos.OrderItemRow{ProductID: it.ProductID, Qty: it.Qty, Price: it.Price, Condition: it.Condition})
+	}
+	if fulfillment == "delivery" {
+		order.ShipTo = contact.ShipTo
+	}
+	if pickupCode != "" {
+		order.PickupFrom, order.PickupUntil, order.PickupCode = slot.Key(), slot.End.Format(repos.PickupSlotFormat), pickupCode
+	}
+	if promo != nil {
+		label := "Promotion " + promo.Code
+		if promo.Description != "" {
+			label += ": " + promo.Description
+		}
+		order.Adjustments = append(order.Adjustments, repos.Adjustment{Kind: "DISCOUNT", Code: promo.Code, Label: label, Amount: -promo.Discount})
+		order.Promo = promo.Redemption()
+	}
+	if s.Shipping != nil {
+		order.ShipMethod = "PICKUP"
+		if ship != nil {
+			order.ShipMethod, order.ShipZIP = ship.Method, destZIP
+			order.Adjustments = append(order.Adjustments, repos.Adjustment{Kind: "SHIPPING", Code: ship.Method, Label: ship.Label(), Amount: ship.Cost})
+		}
+	}
+	if tax.ZIPPrefix != "" {
+		order.Adjustments = append(order.Adjustments, repos.Adjustment{Kind: "TAX", Code: tax.ZIPPrefix, Label: tax.Label(), Amount: tax.Amount, Base: tax.Taxable})
+	}
+	if authRef != "" {
+		order.Payment = s.Payments.Authorization(authRef, serverTotal)
+	}
+	// another checkout may have taken the last use since Evaluate
+	if err = s.Orders.Place(order, mails...); err != nil {
+		switch {
+		case errors.Is(err, repos.ErrRedemptionLimit):
+			err = ErrPromoUsedUp
+		case errors.Is(err, repos.ErrCustomerRedemptionLimit):
+			err = ErrPromoCustomerLimit
+		}
+		return "", 0, 0, err
+	}
+	if promo != nil {
+		_ = s.Carts.SetPromoCode(cartID, "")
 	}
 	_ = s.Carts.Clear(cartID)
 	return orderID, serverTotal, clientTotal, nil
 
 }
+
+// cancelableBy lists the statuses an order can be canceled from, by
+// actor. Staff can still cancel once the order is picked; after shipping or
+// collection it takes a refund.
+var cancelableBy = map[string][]string{
+	"CUSTOMER": {"PLACED"},
+	"ADMIN":    {"PLACED", "RESERVED", "READY_FOR_PICKUP"},
+}
+
+// Cancel cancels an order on behalf of by.Actor (CUSTOMER or ADMIN): the
+// status change, the units going back into the region's stock, the history
+// entry and the customer's notice are stored together, then the card
+// authorization is voided. Canceling an order that is already canceled
+// finishes a void that did not go through the first time.
+func (s *OrderService) Cancel(orderID string, by repos.StatusChange) (repos.OrderRow, error) {
+	o, _, err := s.Orders.Get(orderID)
+	if err != nil {
+		return o, err
+	}
+	if o.Status == "CANCELED" {
+		return o, s.voidPayment(orderID)
+	}
+	if !slices.Contains(cancelableBy[by.Actor], o.Status) {
+		return o, ErrNotCancelable
+	}
+	if s.Payments != nil {
+		p, ok, err := s.Payments.ForOrder(orderID)
+		if err != nil {
+			return o, err
+		}
+		if ok && p.Status != "AUTHORIZED" {
+			return o, ErrNotCancelable
+		}
+	}
+	var mails []repos.OutboxEmail
+	if s.Mail != nil && o.Email != "" {
+		m, err := s.Mail.StatusEmail(o, "CANCELED")
+		if err != nil {
+			return o, err
+		}
+		mails = append(mails, m)
+	}
+	by.Status = "CANCELED"
+	ok, err := s.Orders.Cancel(orderID, cancelableBy[by.Actor], by, mails...)
+	if err != nil {
+		return o, err
+	}
+	if !ok {
+		return o, ErrNotCancelable
+	}
+	o.Status = "CANCELED"
+	return o, s.voidPayment(orderID)
+}
+
+// voidPayment releases the card hold of a canceled order, if one is left.
+func (s *OrderService) voidPayment(orderID string) error {
+	if s.Payments == nil {
+		return nil
+	}
+	p, ok, err := s.Payments.ForOrder(orderID)
+	if err != nil || !ok || p.Status != "AUTHORIZED" {
+		return err
+	}
+	_, err = s.Payments.Void(orderID)
+	return err
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"retrobytes/internal/payments"
+	"retrobytes/internal/repos"
+)
+
+// ErrPaymentState means the requested action does not fit the payment's
+// current status, e.g. capturing a voided authorization.
+var ErrPaymentState = errors.New("payment cannot be changed in its current state")
+
+// PaymentService runs card payments through the configured gateway and keeps
+// the payments table in step with it.
+type PaymentService struct {
+	Provider payments.Provider
+	Payments *repos.PaymentRepo
+}
+
+func NewPaymentService(provider payments.Provider, pays *repos.PaymentRepo) *PaymentService {
+	return &PaymentService{Provider: provider, Payments: pays}
+}
+
+// Authorize holds amount on the shopper's card for an order that is about to
+// be placed. Nothing is stored until the order is placed; gateway errors wrap
+// payments.ErrDeclined or payments.ErrTimeout where they apply.
+func (s *PaymentService) Authorize(orderID string, amount float64, token, email string) (string, error) {
+	ref, err := s.Provider.Authorize(payments.Request{OrderID: orderID, Amount: amount, Token: token, Email: email})
+	if err != nil {
+		return "", fmt.Errorf("authorize payment: %w", err)
+	}
+	return ref, nil
+}
+
+// Authorization is the payment row stored with the order an authorization
+// was taken for.
+func (s *PaymentService) Authorization(ref string, amount float64) *repos.NewPayment {
+	return &repos.NewPayment{Provider: s.Provider.Name(), Ref: ref, Amount: amount}
+}
+
+// Release voids an authorization that never became an order because
+// placement failed after the card was approved.
+func (s *PaymentService) Release(ref string) error {
+	return s.Provider.Void(ref)
+}
+
+// ForOrder returns the order's payment; ok is false for orders placed
+// without one.
+func (s *PaymentService) ForOrder(orderID string) (repos.Payment, bool, error) {
+	p, err := s.Payments.ForOrder(orderID)
+	if err == sql.ErrNoRows {
+		return p, false, nil
+	}
+	return p, err == nil, err
+}
+
+// Capture settles the full authorized amount.
+func (s *PaymentService) Capture(orderID string) (repos.Payment, error) {
+	p, err := s.Payments.ForOrder(orderID)
+	if err != nil {
+		return p, err
+	}
+	if p.Status != "AUTHORIZED" {
+		return p, ErrPaymentState
+	}
+	if err := s.claim(p, "CAPTURE"); err != nil {
+		return p, err
+	}
+	if err := s.Provider.Capture(p.Ref, p.Amount); err != nil {
+		_ = s.Payments.Unclaim(p, "CAPTURE")
+		return p, fmt.Errorf("capture payment: %w", err)
+	}
+	return s.update(p, "CAPTURED", p.Amount, 0)
+}
+
+// Void releases an uncaptured authorization.
+func (s *PaymentService) Void(orderID string) (repos.Payment, error) {
+	p, err := s.Payments.ForOrder(orderID)
+	if err != nil {
+		return p, err
+	}
+	if p.Status != "AUTHORIZED" {
+		return p, ErrPaymentState
+	}
+	if err := s.claim(p, "VOID"); err != nil {
+		return p, err
+	}
+	if err := s.Provider.Void(p.Ref); err != nil {
+		_ = s.Payments.Unclaim(p, "VOID")
+		return p, fmt.Errorf("void payment: %w", err)
+	}
+	return s.update(p, "VOIDED", 0, 0)
+}
+
+// ReleaseCanceled voids the authorizations still held on canceled orders,
+// finishing cancels whose void failed. A void that fails again is logged
+// and left for the next run; it returns how many were released and how
+// many failed.
+func (s *PaymentService) ReleaseCanceled() (released, failed int, err error) {
+	ids, err := s.Payments.HeldOnCanceled(100)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, id := range ids {
+		if _, err := s.Void(id); err != nil {
+			log.Printf("[payments] releasing the hold on order %s failed: %v", id, err)
+			failed++
+			continue
+		}
+		released++
+	}
+	return released, failed, nil
+}
+
+// Refund returns amount of a captured payment to the card. key identifies
+// the refund at the gateway, so a retry cannot pay it out twice. The
+// payment turns REFUNDED once nothing captured is left.
+func (s *PaymentService) Refund(orderID, key string, amount float64) (repos.Payment, string, error) {
+	p, err := s.Payments.ForOrder(orderID)
+	if err != nil {
+		return p, "", err
+	}
+	amount = math.Round(amount*100) / 100
+	left := math.Round((p.Captured-p.Refunded)*100) / 100
+	if p.Status != "CAPTURED" || amount <= 0 || amount > left {
+		return p, "", ErrPaymentState
+	}
+	if err := s.claim(p, "REFUND"); err != nil {
+		return p, "", err
+	}
+	ref, err := s.Provider.Refund(p.Ref, key, amount)
+	if err != nil {
+		_ = s.Payments.Unclaim(p, "REFUND")
+		return p, "", fmt.Errorf("refund payment: %w", err)
+	}
+	status := "CAPTURED"
+	if amount == left {
+		status = "REFUNDED"
+	}
+	p, err = s.update(p, status, p.Captured, math.Round((p.Refunded+amount)*100)/100)
+	return p, ref, err
+}
+
+// claim reserves the payment for one gateway call before it is made; it
+// fails with ErrPaymentState while another capture, void or refund is
+// under way.
+func (s *PaymentService) claim(p repos.Payment, action string) error {
+	ok, err := s.Payments.Claim(p, action)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrPaymentState
+	}
+	return nil
+}
+
+func (s *PaymentService) update(p repos.Payment, status string, captured, refunded float64) (repos.Payment, error) {
+	ok, err := s.Payments.Update(p, status, captured, refunded)
+	if err != nil {
+		return p, err
+	}
+	if !ok {
+		return p, ErrPaymentState
+	}
+	p.Status, p.Captured, p.Refunded = status, captured, refunded
+	return p, nil
+}
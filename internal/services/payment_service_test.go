@@ -0,0 +1,93 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"retrobytes/internal/payments"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestPlaceRequiresAuthorization(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	carts := repos.NewCartRepo(db)
+	inv := repos.NewInventoryRepo(db)
+	orders := repos.NewOrderRepo(db)
+	cart := services.NewCartService(carts, prods)
+	svc := services.NewOrderService(carts, inv, orders, prods)
+	pay := services.NewPaymentService(payments.NewFake(), repos.NewPaymentRepo(db))
+	svc.Payments = pay
+
+	if err := cart.Add("sid-p1", "gbc-001", 1); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := inv.Qty("gbc-001", "20742")
+	contact := services.Contact{Name: "Ann", Email: "a@retrobytes.test"}
+
+	for _, tc := range []struct {
+		token string
+		want  error
+	}{{payments.TokenDeclined, payments.ErrDeclined}, {payments.TokenTimeout, payments.ErrTimeout}} {
+		contact.PaymentToken = tc.token
+		if _, _, _, err := svc.Place("sid-p1", "20742", "pickup", contact); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: err = %v, want %v", tc.token, err, tc.want)
+		}
+	}
+	if qty, _ := inv.Qty("gbc-001", "20742"); qty != before {
+		t.Fatalf("failed payment took stock: %d -> %d", before, qty)
+	}
+	if cv, _ := cart.View("sid-p1"); len(cv.Items) != 1 {
+		t.Fatal("failed payment emptied the cart")
+	}
+	if ords, _ := orders.ListLatest(10); len(ords) != 0 {
+		t.Fatalf("order created without authorization: %+v", ords)
+	}
+
+	contact.PaymentToken = payments.TokenApproved
+	oid, total, _, err := svc.Place("sid-p1", "20742", "pickup", contact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok, err := pay.ForOrder(oid)
+	if err != nil || !ok || p.Status != "AUTHORIZED" || p.Amount != total || p.Ref != "fake_auth_"+oid {
+		t.Fatalf("payment = %+v (%v, %v), want AUTHORIZED for %.2f", p, ok, err, total)
+	}
+
+	if _, _, err := pay.Refund(oid, "rf-0", 10); !errors.Is(err, services.ErrPaymentState) {
+		t.Fatalf("refund before capture: err = %v", err)
+	}
+	// a capture already under way keeps a second one from the gateway
+	if ok, err := pay.Payments.Claim(p, "CAPTURE"); err != nil || !ok {
+		t.Fatalf("claim: %v, %v", ok, err)
+	}
+	if _, err := pay.Capture(oid); !errors.Is(err, services.ErrPaymentState) {
+		t.Fatalf("capture while claimed: err = %v", err)
+	}
+	if err := pay.Payments.Unclaim(p, "CAPTURE"); err != nil {
+		t.Fatal(err)
+	}
+	if p, err = pay.Capture(oid); err != nil || p.Status != "CAPTURED" || p.Captured != total {
+		t.Fatalf("capture: %+v, %v", p, err)
+	}
+	if _, err := pay.Void(oid); !errors.Is(err, services.ErrPaymentState) {
+		t.Fatalf("void after capture: err = %v", err)
+	}
+	var ref string
+	if p, ref, err = pay.Refund(oid, "rf-1", 29.99); err != nil || p.Status != "CAPTURED" || p.Refunded != 29.99 || p.Pending != "" {
+		t.Fatalf("partial refund: %+v, %v", p, err)
+	}
+	if ref != "fake_rf_rf-1" {
+		t.Fatalf("refund ref = %q", ref)
+	}
+	if _, _, err := pay.Refund(oid, "rf-2", total); !errors.Is(err, services.ErrPaymentState) {
+		t.Fatalf("refund above captured: err = %v", err)
+	}
+	if p, _, err = pay.Refund(oid, "rf-3", total-29.99); err != nil || p.Status != "REFUNDED" {
+		t.Fatalf("final refund: %+v, %v", p, err)
+	}
+}
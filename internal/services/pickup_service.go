@@ -0,0 +1,292 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"retrobytes/internal/mail"
+	"retrobytes/internal/repos"
+)
+
+// ErrPickupSlot means the chosen pickup time is not (or no longer) offered.
+var ErrPickupSlot = errors.New("that pickup time is no longer available")
+
+// ErrPickup wraps pickup actions that do not fit the order's state.
+var ErrPickup = errors.New("invalid pickup action")
+
+// pickupLeadTime is how long staff get to pick an order before the first
+// slot it can be collected in.
+const pickupLeadTime = 2 * time.Hour
+
+// pickupCodeAlphabet leaves out 0/O and 1/I, which are misread at the counter.
+const pickupCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// PickupSlot is a collection window in store-local time.
+type PickupSlot struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Key identifies the slot in the checkout form and on the order.
+func (s PickupSlot) Key() string { return s.Start.Format(repos.PickupSlotFormat) }
+
+func (s PickupSlot) Label() string {
+	return repos.PickupWindow(s.Key(), s.End.Format(repos.PickupSlotFormat))
+}
+
+type PickupService struct {
+	Repo   *repos.PickupRepo
+	Orders *repos.OrderRepo
+	// Payments captures the card at collection and voids it on expiry
+	Payments *PaymentService
+	// Mail queues the ready and expiry notices; nil sends none
+	Mail *MailService
+	// SlotMinutes is the length of a pickup slot, DaysAhead how far ahead
+	// slots are offered and HoldDays how long a ready order is kept
+	SlotMinutes int
+	DaysAhead   int
+	HoldDays    int
+}
+
+func NewPickupService(repo *repos.PickupRepo, orders *repos.OrderRepo, payments *PaymentService, m *MailService, slotMinutes, daysAhead, holdDays int) *PickupService {
+	if slotMinutes < 15 {
+		slotMinutes = 15
+	}
+	return &PickupService{Repo: repo, Orders: orders, Payments: payments, Mail: m,
+		SlotMinutes: slotMinutes, DaysAhead: daysAhead, HoldDays: holdDays}
+}
+
+// Slots lists the pickup slots a store offers from now on, in now's
+// location. Slots starting within the lead time are left out.
+func (s *PickupService) Slots(region string, now time.Time) ([]PickupSlot, error) {
+	hours, err := s.Repo.Hours(region)
+	if err != nil {
+		return nil, err
+	}
+	byDay := make(map[int]repos.PickupHours, len(hours))
+	for _, h := range hours {
+		byDay[h.Weekday] = h
+	}
+	earliest := now.Add(pickupLeadTime)
+	step := time.Duration(s.SlotMinutes) * time.Minute
+	var out []PickupSlot
+	for d := 0; d <= s.DaysAhead; d++ {
+		day := time.Date(now.Year(), now.Month(), now.Day()+d, 0, 0, 0, 0, now.Location())
+		h, ok := byDay[int(day.Weekday())]
+		if !ok {
+			continue
+		}
+		opens, err1 := time.Parse("15:04", h.Opens)
+		closes, err2 := time.Parse("15:04", h.Closes)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		start := day.Add(time.Duration(opens.Hour())*time.Hour + time.Duration(opens.Minute())*time.Minute)
+		end := day.Add(time.Duration(closes.Hour())*time.Hour + time.Duration(closes.Minute())*time.Minute)
+		for t := start; !t.Add(step).After(end); t = t.Add(step) {
+			if t.Before(earliest) {
+				continue
+			}
+			out = append(out, PickupSlot{Start: t, End: t.Add(step)})
+		}
+	}
+	return out, nil
+}
+
+// Slot looks up an offered slot by its key; ErrPickupSlot if it is not
+// offered at now.
+func (s *PickupService) Slot(region, key string, now time.Time) (PickupSlot, error) {
+	slots, err := s.Slots(region, now)
+	if err != nil {
+		return PickupSlot{}, err
+	}
+	for _, sl := range slots {
+		if sl.Key() == key {
+			return sl, nil
+		}
+	}
+	return PickupSlot{}, ErrPickupSlot
+}
+
+// NewPickupCode returns a random code like "K7QM-3XTB".
+func NewPickupCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	out := make([]byte, 0, 9)
+	for i, c := range b {
+		if i == 4 {
+			out = append(out, '-')
+		}
+		out = append(out, pickupCodeAlphabet[int(c)%len(pickupCodeAlphabet)])
+	}
+	return string(out), nil
+}
+
+// Queue lists the pickup orders staff still have to prepare or hand over.
+func (s *PickupService) Queue() ([]repos.PickupOrder, error) { return s.Repo.Queue(200) }
+
+// Find returns the order with a pickup code (sql.ErrNoRows if none).
+func (s *PickupService) Find(code string) (string, error) { return s.Repo.IDByCode(code) }
+
+func (s *PickupService) Hours() ([]repos.PickupHours, error) { return s.Repo.AllHours() }
+
+// SaveHours sets a store's pickup hours on one weekday.
+func (s *PickupService) SaveHours(h repos.PickupHours) error {
+	if h.Opens >= h.Closes {
+		return fmt.Errorf("%w: the store has to open before it closes", ErrPickup)
+	}
+	return s.Repo.SaveHours(h)
+}
+
+func (s *PickupService) DeleteHours(region string, weekday int) error {
+	return s.Repo.DeleteHours(region, weekday)
+}
+
+// Deadline is when an order that is ready for pickup gets canceled: HoldDays
+// after it became ready or after its slot ended, whichever is later.
+func (s *PickupService) Deadline(o repos.OrderRow) (time.Time, bool) {
+	if o.Status != "READY_FOR_PICKUP" {
+		return time.Time{}, false
+	}
+	base, err := time.Parse("2006-01-02 15:04:05", o.ReadyAt)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if until, err := time.ParseInLocation(repos.PickupSlotFormat, o.PickupUntil, time.Local); err == nil && until.After(base) {
+		base = until
+	}
+	return base.AddDate(0, 0, s.HoldDays), true
+}
+
+// Ready marks a pickup order as prepared and tells the customer.
+func (s *PickupService) Ready(orderID, adminID string) (repos.OrderRow, error) {
+	o, _, err := s.Orders.Get(orderID)
+	if err != nil {
+		return o, err
+	}
+	if o.Fulfillment != "pickup" {
+		return o, fmt.Errorf("%w: this order is delivered, not collected", ErrPickup)
+	}
+	prev := o.Status
+	o.Status, o.ReadyAt = "READY_FOR_PICKUP", time.Now().UTC().Format("2006-01-02 15:04:05")
+
+	var mails []repos.OutboxEmail
+	if s.Mail != nil && o.Email != "" {
+		until, _ := s.Deadline(o)
+		m, err := mail.PickupReadyMessage(o.Email, mail.Pickup{
+			Name: o.Customer, OrderNo: o.Number(), Store: o.Region, PickupAt: o.PickupWindow(),
+			PickupCode: o.PickupCode, HoldUntil: until.Local().Format("Mon 2 Jan 2006"), HoldDays: s.HoldDays,
+			Link: s.Mail.Link("/order/" + o.ID),
+		})
+		if err != nil {
+			return o, err
+		}
+		mails = append(mails, outboxEmail(mail.KindPickupReady, m))
+	}
+	h := repos.StatusChange{Status: o.Status, Actor: "ADMIN", UserID: adminID, Note: "Ready for pickup"}
+	ok, err := s.Repo.MarkReady(orderID, h, mails...)
+	if err != nil {
+		return o, err
+	}
+	if !ok {
+		return o, fmt.Errorf("%w: order is %s", ErrPickup, prev)
+	}
+	return o, nil
+}
+
+// Collect hands a ready order over. The code is the one the customer shows
+// and note records how their ID was checked; an authorized card payment is
+// captured.
+func (s *PickupService) Collect(orderID, adminID, code, note string) (repos.OrderRow, error) {
+	o, _, err := s.Orders.Get(orderID)
+	if err != nil {
+		return o, err
+	}
+	if o.Status != "READY_FOR_PICKUP" {
+		return o, fmt.Errorf("%w: order is %s", ErrPickup, o.Status)
+	}
+	if o.PickupCode == "" || code != o.PickupCode {
+		return o, fmt.Errorf("%w: the pickup code does not match this order", ErrPickup)
+	}
+	if s.Payments != nil {
+		p, ok, err := s.Payments.ForOrder(orderID)
+		if err != nil {
+			return o, err
+		}
+		if ok && p.Status == "AUTHORIZED" {
+			if _, err := s.Payments.Capture(orderID); err != nil {
+				return o, err
+			}
+		}
+	}
+	h := repos.StatusChange{Status: "COLLECTED", Actor: "ADMIN", UserID: adminID, Note: "Collected; ID check: " + note}
+	ok, err := s.Repo.MarkCollected(orderID, note, h)
+	if err != nil {
+		return o, err
+	}
+	if !ok {
+		return o, fmt.Errorf("%w: order was just updated by someone else", ErrPickup)
+	}
+	o.Status, o.CollectNote = "COLLECTED", note
+	return o, nil
+}
+
+// ExpireUnclaimed cancels ready orders whose hold ran out by now: the units
+// go back into the store's stock and the customer is told together with
+// the cancel, then the card authorization is voided. A void that fails is
+// logged and retried by PaymentService.ReleaseCanceled. It returns how many
+// orders were canceled.
+func (s *PickupService) ExpireUnclaimed(now time.Time) (int, error) {
+	waiting, err := s.Repo.Waiting()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, w := range waiting {
+		o, _, err := s.Orders.Get(w.ID)
+		if err != nil {
+			return n, err
+		}
+		deadline, ok := s.Deadline(o)
+		if !ok || now.Before(deadline) {
+			continue
+		}
+		var mails []repos.OutboxEmail
+		if s.Mail != nil && o.Email != "" {
+			m, err := mail.PickupExpiredMessage(o.Email, mail.Pickup{
+				Name: o.Customer, OrderNo: o.Number(), Store: o.Region, HoldDays: s.HoldDays,
+				Link: s.Mail.Link("/order/" + o.ID),
+			})
+			if err != nil {
+				return n, err
+			}
+			mails = append(mails, outboxEmail(mail.KindPickupExpired, m))
+		}
+		h := repos.StatusChange{Status: "CANCELED", Actor: "SYSTEM", Note: fmt.Sprintf("Not collected within %d days", s.HoldDays)}
+		ok, err = s.Orders.Cancel(o.ID, []string{"READY_FOR_PICKUP"}, h, mails...)
+		if err != nil {
+			return n, err
+		}
+		if !ok {
+			continue // collected in the meantime
+		}
+		n++
+		if s.Payments != nil {
+			p, ok, err := s.Payments.ForOrder(o.ID)
+			if err != nil {
+				return n, err
+			}
+			if ok && p.Status == "AUTHORIZED" {
+				if _, err := s.Payments.Void(o.ID); err != nil {
+					log.Printf("[pickup] voiding the hold on expired order %s failed: %v", o.ID, err)
+				}
+			}
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,220 @@
+package services_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"retrobytes/internal/mail"
+	"retrobytes/internal/payments"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+	"retrobytes/internal/validate"
+)
+
+func TestPickupSlots(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := services.NewPickupService(repos.NewPickupRepo(db), nil, nil, nil, 60, 7, 7)
+
+	// Saturday 19:30: the store has closed, Sunday is closed, Monday opens
+	// at 10:00
+	sat := time.Date(2026, 10, 17, 19, 30, 0, 0, time.Local)
+	slots, err := svc.Slots("20742", sat)
+	if err != nil || len(slots) == 0 {
+		t.Fatalf("slots: %v, %d", err, len(slots))
+	}
+	if got := slots[0].Key(); got != "2026-10-19 10:00" {
+		t.Fatalf("first slot = %s", got)
+	}
+	if got := slots[0].Label(); got != "Mon 19 Oct 2026, 10:00–11:00" {
+		t.Fatalf("label = %s", got)
+	}
+	last := slots[len(slots)-1]
+	if last.Start.Weekday() != time.Saturday || last.End.Format("15:04") != "16:00" {
+		t.Fatalf("last slot = %s", last.Label())
+	}
+
+	// Monday 11:30: nothing before the two-hour lead time
+	slots, _ = svc.Slots("20742", time.Date(2026, 10, 19, 11, 30, 0, 0, time.Local))
+	if got := slots[0].Key(); got != "2026-10-19 14:00" {
+		t.Fatalf("first slot with lead time = %s", got)
+	}
+	if _, err := svc.Slot("20742", "2026-10-19 12:00", time.Date(2026, 10, 19, 11, 30, 0, 0, time.Local)); !errors.Is(err, services.ErrPickupSlot) {
+		t.Fatalf("past slot: err = %v", err)
+	}
+	if slots, _ := svc.Slots("99999", sat); len(slots) != 0 {
+		t.Fatalf("store without hours offers %d slots", len(slots))
+	}
+
+	code, err := services.NewPickupCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if norm, ok := validate.PickupCode(strings.ToLower(strings.ReplaceAll(code, "-", ""))); !ok || norm != code {
+		t.Fatalf("code %s normalizes to %s (%v)", code, norm, ok)
+	}
+}
+
+func TestPickupReadyCollectAndExpiry(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	carts := repos.NewCartRepo(db)
+	orders := repos.NewOrderRepo(db)
+	inv := repos.NewInventoryRepo(db)
+	outbox := repos.NewOutboxRepo(db)
+	cart := services.NewCartService(carts, prods)
+	svc := services.NewOrderService(carts, inv, orders, prods)
+	pay := services.NewPaymentService(payments.NewFake(), repos.NewPaymentRepo(db))
+	mails := services.NewMailService(outbox, &captureMailer{}, "http://shop.test")
+	svc.Payments, svc.Mail = pay, mails
+	pickup := services.NewPickupService(repos.NewPickupRepo(db), orders, pay, mails, 60, 7, 3)
+	svc.Pickup = pickup
+
+	slots, err := pickup.Slots("20742", time.Now())
+	if err != nil || len(slots) == 0 {
+		t.Fatalf("slots: %v, %d", err, len(slots))
+	}
+	contact := services.Contact{Name: "Ann", Email: "a@retrobytes.test", PaymentToken: payments.TokenApproved, PickupSlot: "2020-01-01 10:00"}
+	place := func(sid string) string {
+		t.Helper()
+		if err := cart.Add(sid, "gbc-001", 2); err != nil {
+			t.Fatal(err)
+		}
+		oid, _, _, err := svc.Place(sid, "20742", "pickup", contact)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return oid
+	}
+	if err := cart.Add("sid-pk0", "gbc-001", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := svc.Place("sid-pk0", "20742", "pickup", contact); !errors.Is(err, services.ErrPickupSlot) {
+		t.Fatalf("unavailable slot: err = %v", err)
+	}
+	if q, _ := inv.Qty("gbc-001", "20742"); q != 8 {
+		t.Fatalf("refused order took stock: %d left", q)
+	}
+
+	contact.PickupSlot = slots[0].Key()
+	oid := place("sid-pk1")
+	o, _, err := orders.Get(oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.PickupFrom != slots[0].Key() || o.PickupWindow() != slots[0].Label() || !o.PickupOpen() {
+		t.Fatalf("pickup on order = %+v", o)
+	}
+	if _, ok := validate.PickupCode(o.PickupCode); !ok {
+		t.Fatalf("pickup code %q", o.PickupCode)
+	}
+	if id, err := pickup.Find(o.PickupCode); err != nil || id != oid {
+		t.Fatalf("find by code = %q, %v", id, err)
+	}
+	if _, err := pickup.Collect(oid, "u-admin", o.PickupCode, "driver's license"); !errors.Is(err, services.ErrPickup) {
+		t.Fatalf("collect before ready: err = %v", err)
+	}
+
+	if _, err := pickup.Ready(oid, "u-admin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pickup.Ready(oid, "u-admin"); !errors.Is(err, services.ErrPickup) {
+		t.Fatalf("second ready: err = %v", err)
+	}
+	due, err := outbox.Due(time.Now().Add(time.Minute).UTC().Format("2006-01-02 15:04:05"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conf, ready repos.OutboxEmail
+	for _, m := range due {
+		switch m.Kind {
+		case mail.KindOrderConfirmation:
+			conf = m
+		case mail.KindPickupReady:
+			ready = m
+		}
+	}
+	if !strings.Contains(conf.Text, o.PickupCode) || !strings.Contains(conf.Text, slots[0].Label()) {
+		t.Fatalf("confirmation does not quote the pickup:\n%s", conf.Text)
+	}
+	if !strings.Contains(ready.Subject, "ready for pickup") || !strings.Contains(ready.Text, o.PickupCode) {
+		t.Fatalf("ready notice = %q\n%s", ready.Subject, ready.Text)
+	}
+
+	if _, err := pickup.Collect(oid, "u-admin", "AAAA-BBBB", "driver's license"); !errors.Is(err, services.ErrPickup) {
+		t.Fatalf("wrong code: err = %v", err)
+	}
+	if _, err := pickup.Collect(oid, "u-admin", o.PickupCode, "driver's license, name matches"); err != nil {
+		t.Fatal(err)
+	}
+	if o, _, _ := orders.Get(oid); o.Status != "COLLECTED" || o.CollectedAt == "" || o.CollectNote != "driver's license, name matches" || o.PickupOpen() {
+		t.Fatalf("after collect: %+v", o)
+	}
+	if p, _, _ := pay.ForOrder(oid); p.Status != "CAPTURED" {
+		t.Fatalf("collect left the payment %s", p.Status)
+	}
+	if no, _, _ := orders.InvoiceNo(oid); no == 0 {
+		t.Fatal("collect issued no invoice")
+	}
+
+	// an order nobody picks up goes back on the shelf after the hold
+	stale := place("sid-pk2")
+	if _, err := pickup.Ready(stale, "u-admin"); err != nil {
+		t.Fatal(err)
+	}
+	if q, _ := inv.Qty("gbc-001", "20742"); q != 4 {
+		t.Fatalf("stock before expiry = %d", q)
+	}
+	if n, err := pickup.ExpireUnclaimed(time.Now()); err != nil || n != 0 {
+		t.Fatalf("fresh order expired: %d, %v", n, err)
+	}
+	if _, err := db.Exec(`UPDATE orders SET ready_at = datetime('now', '-10 days'), pickup_until = '2020-01-01 11:00' WHERE id = ?`, stale); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := pickup.ExpireUnclaimed(time.Now()); err != nil || n != 1 {
+		t.Fatalf("expired %d, %v", n, err)
+	}
+	if o, _, _ := orders.Get(stale); o.Status != "CANCELED" {
+		t.Fatalf("stale order is %s", o.Status)
+	}
+	if q, _ := inv.Qty("gbc-001", "20742"); q != 6 {
+		t.Fatalf("stock after expiry = %d", q)
+	}
+	if p, _, _ := pay.ForOrder(stale); p.Status != "VOIDED" {
+		t.Fatalf("expiry left the payment %s", p.Status)
+	}
+	hist, _ := orders.History(stale)
+	if len(hist) == 0 || hist[len(hist)-1].Actor != "SYSTEM" || hist[len(hist)-1].Note != "Not collected within 3 days" {
+		t.Fatalf("history = %+v", hist)
+	}
+	if n, _ := pickup.ExpireUnclaimed(time.Now()); n != 0 {
+		t.Fatalf("expired twice: %d", n)
+	}
+
+	// holds whose void failed when the order was canceled are released by
+	// the payment job; one that fails again does not stop the others
+	stuck, held := place("sid-pk3"), place("sid-pk4")
+	if _, err := db.Exec(`UPDATE orders SET status = 'CANCELED' WHERE id IN (?, ?)`, stuck, held); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`UPDATE payments SET ref = 'gone' WHERE order_id = ?`, stuck); err != nil {
+		t.Fatal(err)
+	}
+	released, failed, err := pay.ReleaseCanceled()
+	if err != nil || released != 1 || failed != 1 {
+		t.Fatalf("release = %d, %d, %v", released, failed, err)
+	}
+	if p, _, _ := pay.ForOrder(held); p.Status != "VOIDED" {
+		t.Fatalf("hold on a canceled order left %s", p.Status)
+	}
+	if p, _, _ := pay.ForOrder(stuck); p.Status != "AUTHORIZED" {
+		t.Fatalf("failed release left %s", p.Status)
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"retrobytes/internal/domain"
+	"retrobytes/internal/repos"
+)
+
+const priceTimeFormat = "2006-01-02 15:04:05"
+
+var (
+	ErrSaleWindow   = errors.New("a sale must end after it starts and after now")
+	ErrSaleOverlap  = errors.New("another sale is already scheduled in that window")
+	ErrSaleNotBelow = errors.New("the sale price must be below the regular price")
+	ErrSaleNotFound = errors.New("sale not found or already over")
+)
+
+// PricingService keeps the price history and resolves scheduled sales into
+// the price a shopper pays at a given moment.
+type PricingService struct {
+	Prices *repos.PriceRepo
+	Prods  *repos.ProductRepo
+}
+
+func NewPricingService(prices *repos.PriceRepo, prods *repos.ProductRepo) *PricingService {
+	return &PricingService{Prices: prices, Prods: prods}
+}
+
+// Apply replaces Price with the running sale price (keeping the regular
+// price in ListPrice) for each product in list.
+func (s *PricingService) Apply(list []domain.Product, at time.Time) error {
+	if len(list) == 0 {
+		return nil
+	}
+	ids := make([]string, len(list))
+	for i, p := range list {
+		ids[i] = p.ID
+	}
+	sales, err := s.Prices.ActiveSales(ids, at.UTC().Format(priceTimeFormat))
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		if sale, ok := sales[list[i].ID]; ok && sale.Price < list[i].Price {
+			list[i].ListPrice = list[i].Price
+			list[i].Price = sale.Price
+			list[i].SaleEnds = sale.EndsAt
+		}
+	}
+	return nil
+}
+
+// Effective returns the price of p at time at.
+func (s *PricingService) Effective(p domain.Product, at time.Time) (float64, error) {
+	list := []domain.Product{p}
+	if err := s.Apply(list, at); err != nil {
+		return 0, err
+	}
+	return list[0].Price, nil
+}
+
+func (s *PricingService) History(productID string) ([]repos.PriceEntry, error) {
+	return s.Prices.History(productID)
+}
+
+// SetBase changes the regular price of a product.
+func (s *PricingService) SetBase(productID string, price float64, by string, now time.Time) error {
+	return s.Prices.SetBase(productID, price, now.UTC().Format(priceTimeFormat), by)
+}
+
+// ScheduleSale adds a sale price for [starts, ends). Sales may not overlap
+// and must undercut the regular price.
+func (s *PricingService) ScheduleSale(productID string, price float64, starts, ends time.Time, by string, now time.Time) error {
+	if !ends.After(starts) || !ends.After(now) {
+		return ErrSaleWindow
+	}
+	p, err := s.Prods.Get(productID)
+	if err != nil {
+		return err
+	}
+	if price >= p.Price {
+		return ErrSaleNotBelow
+	}
+	from, to := starts.UTC().Format(priceTimeFormat), ends.UTC().Format(priceTimeFormat)
+	overlap, err := s.Prices.SaleOverlaps(productID, from, to)
+	if err != nil {
+		return err
+	}
+	if overlap {
+		return ErrSaleOverlap
+	}
+	return s.Prices.AddSale(productID, price, from, to, now.UTC().Format(priceTimeFormat), by)
+}
+
+// EndSale cancels a scheduled sale or ends a running one now.
+func (s *PricingService) EndSale(productID string, saleID int64, now time.Time) error {
+	ok, err := s.Prices.EndSale(productID, saleID, now.UTC().Format(priceTimeFormat))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSaleNotFound
+	}
+	return nil
+}
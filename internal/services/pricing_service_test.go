@@ -0,0 +1,162 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestScheduledSalePricing(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	pricing := services.NewPricingService(repos.NewPriceRepo(db), prods)
+	base, err := prods.Get("nes-001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	sale := base.Price - 10
+	if err := pricing.ScheduleSale("nes-001", sale, now.Add(-time.Hour), now.Add(time.Hour), "u-admin", now); err != nil {
+		t.Fatal(err)
+	}
+	if err := pricing.ScheduleSale("nes-001", sale, now.Add(30*time.Minute), now.Add(2*time.Hour), "u-admin", now); !errors.Is(err, services.ErrSaleOverlap) {
+		t.Fatalf("overlapping sale: err = %v", err)
+	}
+	if err := pricing.ScheduleSale("nes-001", base.Price, now.Add(2*time.Hour), now.Add(3*time.Hour), "u-admin", now); !errors.Is(err, services.ErrSaleNotBelow) {
+		t.Fatalf("sale at list price: err = %v", err)
+	}
+
+	for at, want := range map[time.Time]float64{
+		now:                          sale,
+		now.Add(-2 * time.Hour):      base.Price,
+		now.Add(time.Hour):           base.Price, // end is exclusive
+		now.Add(59 * time.Minute):    sale,
+		now.Add(-time.Hour):          sale, // start is inclusive
+		now.Add(-61 * time.Minute):   base.Price,
+		now.Add(24 * 60 * time.Hour): base.Price,
+	} {
+		got, err := pricing.Effective(base, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("price at %s = %.2f, want %.2f", at.Format(time.RFC3339), got, want)
+		}
+	}
+
+	// Catalog listings show was/now while the sale runs
+	catalog := services.NewCatalogService(repos.NewCategoryRepo(db), prods)
+	catalog.Pricing = pricing
+	p, err := catalog.GetProduct("nes-001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.OnSale() || p.Price != sale || p.ListPrice != base.Price {
+		t.Fatalf("product = price %.2f list %.2f, want sale %.2f from %.2f", p.Price, p.ListPrice, sale, base.Price)
+	}
+
+	// Base changes are recorded next to the sale
+	if err := pricing.SetBase("nes-001", base.Price+5, "u-admin", now); err != nil {
+		t.Fatal(err)
+	}
+	history, err := pricing.History("nes-001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[string]int{}
+	for _, h := range history {
+		kinds[h.Kind]++
+	}
+	if kinds["BASE"] != 2 || kinds["SALE"] != 1 {
+		t.Fatalf("history kinds = %v, want 2 BASE and 1 SALE", kinds)
+	}
+
+	// Ending a running sale restores the regular price; BASE rows can't be ended
+	for _, h := range history {
+		if h.Kind == "BASE" {
+			if err := pricing.EndSale("nes-001", h.ID, now); !errors.Is(err, services.ErrSaleNotFound) {
+				t.Fatalf("ending a BASE row: err = %v", err)
+			}
+		} else {
+			if err := pricing.EndSale("nes-001", h.ID, now); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if p, _ = catalog.GetProduct("nes-001"); p.OnSale() {
+		t.Fatal("sale still applied after ending it")
+	}
+}
+
+func TestOrderUsesSalePriceAtPlacement(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	pricing := services.NewPricingService(repos.NewPriceRepo(db), prods)
+	carts := repos.NewCartRepo(db)
+	inv := repos.NewInventoryRepo(db)
+	orders := repos.NewOrderRepo(db)
+
+	// Added to the cart at the regular price, then a sale starts
+	cart := services.NewCartService(carts, prods)
+	cart.Pricing = pricing
+	if err := cart.Add("sid-sale", "gbc-001", 1); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := prods.Get("gbc-001")
+	const salePrice = 99.99
+	now := time.Now()
+	if err := pricing.ScheduleSale("gbc-001", salePrice, now.Add(-time.Minute), now.Add(time.Hour), "u-admin", now); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := services.NewOrderService(carts, inv, orders, prods)
+	svc.Pricing = pricing
+	var region string
+	if err := db.Get(&region, `SELECT region_code FROM inventory WHERE product_id = 'gbc-001' AND qty > 0 LIMIT 1`); err != nil {
+		t.Fatal(err)
+	}
+	contact := services.Contact{Name: "Test", Email: "t@retrobytes.test"}
+
+	// The price moved since the item was added: checkout shows the diff and
+	// the order waits for the shopper to accept it
+	if _, _, _, err := svc.Place("sid-sale", region, "pickup", contact); !errors.Is(err, services.ErrPricesChanged) {
+		t.Fatalf("place with stale cart price: err = %v", err)
+	}
+	cv, err := cart.View("sid-sale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cv.Changes) != 1 || cv.Changes[0].Was != p.Price || cv.Changes[0].Now != salePrice || cv.Total != salePrice {
+		t.Fatalf("cart view = %+v, want one change %.2f -> %.2f", cv, p.Price, salePrice)
+	}
+	if err := cart.ConfirmPrices("sid-sale", "stale"); !errors.Is(err, services.ErrPricesChanged) {
+		t.Fatalf("confirm prices the shopper did not see: err = %v", err)
+	}
+	if cv2, _ := cart.View("sid-sale"); len(cv2.Changes) != 1 {
+		t.Fatalf("refused confirmation accepted prices: %+v", cv2.Changes)
+	}
+	if err := cart.ConfirmPrices("sid-sale", cv.PricesDigest()); err != nil {
+		t.Fatal(err)
+	}
+	if cv, _ = cart.View("sid-sale"); len(cv.Changes) != 0 {
+		t.Fatalf("changes after confirming: %+v", cv.Changes)
+	}
+
+	_, total, _, err := svc.Place("sid-sale", region, "pickup", contact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != salePrice {
+		t.Fatalf("total = %.2f, want sale price %.2f", total, salePrice)
+	}
+}
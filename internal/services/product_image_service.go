@@ -0,0 +1,187 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"retrobytes/internal/domain"
+	"retrobytes/internal/imaging"
+	"retrobytes/internal/repos"
+
+	"github.com/google/uuid"
+)
+
+// MaxImageBytes bounds a single uploaded image (the server also caps bodies at 1 MiB).
+const MaxImageBytes = 1 << 20
+
+// MaxImagesPerProduct bounds the gallery size.
+const MaxImagesPerProduct = 12
+
+var (
+	ErrImageType       = imaging.ErrFormat
+	ErrImageDimensions = imaging.ErrDimensions
+	ErrImageTooLarge   = errors.New("image is too large")
+	ErrImageLimit      = errors.New("too many images for this product")
+	ErrImageNotFound   = errors.New("image not found")
+)
+
+// ProductImageService manages product galleries: files live under
+// MediaDir/products/<id>/ and order/alt text live in products.images_json.
+// The condition-photo service works the same way on condition_images_json.
+type ProductImageService struct {
+	Prods    *repos.ProductRepo
+	MediaDir string
+
+	condition bool
+}
+
+func NewProductImageService(prods *repos.ProductRepo, mediaDir string) *ProductImageService {
+	return &ProductImageService{Prods: prods, MediaDir: mediaDir}
+}
+
+// NewConditionPhotoService manages the close-up photos shown with a
+// listing's condition grade.
+func NewConditionPhotoService(prods *repos.ProductRepo, mediaDir string) *ProductImageService {
+	return &ProductImageService{Prods: prods, MediaDir: mediaDir, condition: true}
+}
+
+func (s *ProductImageService) List(productID string) (domain.Product, []domain.ProductImage, error) {
+	p, err := s.Prods.Get(productID)
+	if err != nil {
+		return domain.Product{}, nil, err
+	}
+	if s.condition {
+		_, raw, err := s.Prods.ConditionDetails(productID)
+		if err != nil {
+			return domain.Product{}, nil, err
+		}
+		return p, domain.ParseImages(raw), nil
+	}
+	return p, domain.ParseImages(p.ImagesJSON), nil
+}
+
+func (s *ProductImageService) save(productID string, imgs []domain.ProductImage) error {
+	if s.condition {
+		return s.Prods.SetConditionImages(productID, domain.EncodeImages(imgs))
+	}
+	return s.Prods.SetImages(productID, domain.EncodeImages(imgs))
+}
+
+// Add sanitizes an uploaded image (see imaging.Process), writes the original
+// and its resized variants, and appends it to the gallery.
+func (s *ProductImageService) Add(productID string, data []byte, alt string) (domain.ProductImage, error) {
+	p, imgs, err := s.List(productID)
+	if err != nil {
+		return domain.ProductImage{}, err
+	}
+	if len(imgs) >= MaxImagesPerProduct {
+		return domain.ProductImage{}, ErrImageLimit
+	}
+	if len(data) > MaxImageBytes {
+		return domain.ProductImage{}, ErrImageTooLarge
+	}
+	res, err := imaging.Process(data)
+	if err != nil {
+		return domain.ProductImage{}, err
+	}
+
+	dir := filepath.Join("products", p.ID)
+	if s.condition {
+		dir = filepath.Join(dir, "condition")
+	}
+	rel := filepath.ToSlash(filepath.Join(dir, uuid.NewString()+res.Ext))
+	full := filepath.Join(s.MediaDir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return domain.ProductImage{}, err
+	}
+	if err := os.WriteFile(full, res.Original, 0o644); err != nil {
+		return domain.ProductImage{}, err
+	}
+	for _, v := range res.Variants {
+		vp := filepath.Join(s.MediaDir, filepath.FromSlash(imaging.VariantPath(rel, v.Width)))
+		if err := os.WriteFile(vp, v.Data, 0o644); err != nil {
+			s.removeFiles(rel)
+			return domain.ProductImage{}, err
+		}
+	}
+
+	img := domain.ProductImage{Path: rel, Alt: alt}
+	imgs = append(imgs, img)
+	if err := s.save(p.ID, imgs); err != nil {
+		s.removeFiles(rel)
+		return domain.ProductImage{}, err
+	}
+	return img, nil
+}
+
+// removeFiles deletes an image and any generated variants.
+func (s *ProductImageService) removeFiles(rel string) {
+	_ = os.Remove(filepath.Join(s.MediaDir, filepath.FromSlash(rel)))
+	for _, w := range imaging.Widths {
+		_ = os.Remove(filepath.Join(s.MediaDir, filepath.FromSlash(imaging.VariantPath(rel, w))))
+	}
+}
+
+// Remove drops an image from the gallery and deletes its file.
+func (s *ProductImageService) Remove(productID, path string) error {
+	_, imgs, err := s.List(productID)
+	if err != nil {
+		return err
+	}
+	i := indexOfImage(imgs, path)
+	if i < 0 {
+		return ErrImageNotFound
+	}
+	imgs = append(imgs[:i], imgs[i+1:]...)
+	if err := s.save(productID, imgs); err != nil {
+		return err
+	}
+	// Only delete files that live in this product's own media folder.
+	if strings.HasPrefix(path, "products/"+productID+"/") {
+		s.removeFiles(path)
+	}
+	return nil
+}
+
+// Move shifts an image up (delta<0) or down (delta>0) in display order.
+func (s *ProductImageService) Move(productID, path string, delta int) error {
+	_, imgs, err := s.List(productID)
+	if err != nil {
+		return err
+	}
+	i := indexOfImage(imgs, path)
+	if i < 0 {
+		return ErrImageNotFound
+	}
+	j := i + delta
+	if j < 0 || j >= len(imgs) {
+		return nil
+	}
+	imgs[i], imgs[j] = imgs[j], imgs[i]
+	return s.save(productID, imgs)
+}
+
+// SetAlt updates the alt text of one image.
+func (s *ProductImageService) SetAlt(productID, path, alt string) error {
+	_, imgs, err := s.List(productID)
+	if err != nil {
+		return err
+	}
+	i := indexOfImage(imgs, path)
+	if i < 0 {
+		return ErrImageNotFound
+	}
+	imgs[i].Alt = alt
+	return s.save(productID, imgs)
+}
+
+func indexOfImage(imgs []domain.ProductImage, path string) int {
+	for i, im := range imgs {
+		if im.Path == path {
+			return i
+		}
+	}
+	return -1
+}
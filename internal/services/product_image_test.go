@@ -0,0 +1,151 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"retrobytes/internal/domain"
+	"retrobytes/internal/imaging"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestProductImages_AddReorderRemove(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	dir := t.TempDir()
+	svc := services.NewProductImageService(prods, dir)
+
+	// Legacy images_json (bare paths) is parsed
+	_, imgs, err := svc.List("gbc-001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imgs) != 1 || imgs[0].Path != "products/gbc-001/main.jpg" {
+		t.Fatalf("legacy images_json not parsed: %+v", imgs)
+	}
+
+	// Non-images are rejected by content, not name
+	if _, err := svc.Add("gbc-001", []byte("GIF89a not really"), ""); err != services.ErrImageType {
+		t.Fatalf("want ErrImageType, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 400, 300)))
+	added, err := svc.Add("gbc-001", buf.Bytes(), "Back of the console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(added.Path))); err != nil {
+		t.Fatalf("uploaded file missing: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(imaging.VariantPath(added.Path, 320)))); err != nil {
+		t.Fatalf("320px variant missing: %v", err)
+	}
+
+	// Move the new image to the front; it becomes the thumbnail
+	if err := svc.Move("gbc-001", added.Path, -1); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := prods.Get("gbc-001")
+	if p.ThumbURL() != "/media/"+added.Path {
+		t.Fatalf("want new cover, got %s", p.ThumbURL())
+	}
+	if got := p.Images(); len(got) != 2 || got[0].Alt != "Back of the console" || got[1].Alt != p.Title {
+		t.Fatalf("unexpected gallery: %+v", got)
+	}
+
+	if err := svc.Remove("gbc-001", added.Path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(added.Path))); !os.IsNotExist(err) {
+		t.Fatalf("file should be deleted, stat err=%v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(imaging.VariantPath(added.Path, 320)))); !os.IsNotExist(err) {
+		t.Fatalf("variant should be deleted, stat err=%v", err)
+	}
+	p, _ = prods.Get("gbc-001")
+	if imgs := domain.ParseImages(p.ImagesJSON); len(imgs) != 1 {
+		t.Fatalf("want 1 image after delete, got %+v", imgs)
+	}
+}
+
+// jpegWithExif builds a w x h JPEG carrying an EXIF block with the given
+// orientation and a fake GPS marker string.
+func jpegWithExif(t *testing.T, w, h int, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	// TIFF header + IFD0 with one Orientation entry
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01")
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	payload = append(payload, []byte("GPSLatitude 38.98N")...)
+	seg := []byte{0xFF, 0xE1}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(payload)+2))
+	seg = append(seg, payload...)
+
+	src := buf.Bytes()
+	out := append([]byte{}, src[:2]...)
+	out = append(out, seg...)
+	return append(out, src[2:]...)
+}
+
+func TestProductImages_StripExifAndLimits(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	svc := services.NewProductImageService(repos.NewProductRepo(db), dir)
+
+	// Too small to be a useful product photo
+	var small bytes.Buffer
+	_ = png.Encode(&small, image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	if _, err := svc.Add("nes-001", small.Bytes(), ""); !errors.Is(err, services.ErrImageDimensions) {
+		t.Fatalf("want ErrImageDimensions, got %v", err)
+	}
+
+	// Portrait phone photo stored sideways with orientation=6 (rotate 90 CW)
+	raw := jpegWithExif(t, 1000, 600, 6)
+	if !bytes.Contains(raw, []byte("GPSLatitude")) {
+		t.Fatal("fixture should carry metadata")
+	}
+	img, err := svc.Add("nes-001", raw, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(img.Path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(stored, []byte("Exif")) || bytes.Contains(stored, []byte("GPSLatitude")) {
+		t.Fatal("metadata was not stripped")
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(stored))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 600 || cfg.Height != 1000 {
+		t.Fatalf("orientation not applied: got %dx%d", cfg.Width, cfg.Height)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(imaging.VariantPath(img.Path, 320)))); err != nil {
+		t.Fatalf("320px variant missing: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(imaging.VariantPath(img.Path, 800)))); !os.IsNotExist(err) {
+		t.Fatal("no 800px variant should be generated for a 600px-wide image")
+	}
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"retrobytes/internal/repos"
+
+	"github.com/google/uuid"
+)
+
+// Reasons a code is refused at the cart or at placement.
+var (
+	ErrPromoNotFound      = errors.New("that code is not valid")
+	ErrPromoExpired       = errors.New("that code is not active right now")
+	ErrPromoMinSubtotal   = errors.New("your order does not reach the minimum for that code")
+	ErrPromoNotApplicable = errors.New("that code does not apply to the items in your cart")
+	ErrPromoUsedUp        = errors.New("that code has been fully redeemed")
+	ErrPromoCustomerLimit = errors.New("you have already used that code")
+)
+
+// ErrPromoConfig wraps invalid promotion settings entered by an admin.
+var ErrPromoConfig = errors.New("invalid promotion")
+
+// ErrPromoInUse is returned when deleting a promotion that was redeemed.
+var ErrPromoInUse = errors.New("promotion has redemptions; deactivate it instead")
+
+// PromoErrorCode maps a refusal to the short code used in redirects and
+// templates ("" for other errors).
+func PromoErrorCode(err error) string {
+	for code, e := range map[string]error{
+		"not_found": ErrPromoNotFound, "expired": ErrPromoExpired, "min_subtotal": ErrPromoMinSubtotal,
+		"not_applicable": ErrPromoNotApplicable, "used_up": ErrPromoUsedUp, "customer_limit": ErrPromoCustomerLimit,
+	} {
+		if errors.Is(err, e) {
+			return code
+		}
+	}
+	return ""
+}
+
+// CartLine is a priced cart line as seen by the promotions and tax engines.
+type CartLine struct {
+	ProductID string
+	Qty       int
+	Price     float64 // current unit price
+}
+
+// AppliedPromo is a promotion evaluated against a cart.
+type AppliedPromo struct {
+	PromotionID string
+	Code        string
+	Description string
+	Discount    float64
+	// MaxUses and MaxPerCustomer are re-checked when the order is placed
+	MaxUses        int
+	MaxPerCustomer int
+}
+
+// PromotionService validates coupon codes, computes discounts and keeps
+// redemption records.
+type PromotionService struct {
+	Promos *repos.PromotionRepo
+	Prods  *repos.ProductRepo
+}
+
+func NewPromotionService(promos *repos.PromotionRepo, prods *repos.ProductRepo) *PromotionService {
+	return &PromotionService{Promos: promos, Prods: prods}
+}
+
+// Evaluate checks code against the cart lines for the customer behind
+// sessionID (and email, once known at placement) and returns the discount.
+func (s *PromotionService) Evaluate(code string, lines []CartLine, sessionID, email string, now time.Time) (AppliedPromo, error) {
+	p, ok, err := s.Promos.ByCode(strings.TrimSpace(code))
+	if err != nil {
+		return AppliedPromo{}, err
+	}
+	if !ok || !p.Active {
+		return AppliedPromo{}, ErrPromoNotFound
+	}
+	ts := now.UTC().Format(priceTimeFormat)
+	if (p.StartsAt != "" && ts < p.StartsAt) || (p.EndsAt != "" && ts >= p.EndsAt) {
+		return AppliedPromo{}, ErrPromoExpired
+	}
+
+	eligible := 0.0
+	for _, l := range lines {
+		in, err := s.inScope(p, l.ProductID)
+		if err != nil {
+			return AppliedPromo{}, err
+		}
+		if in {
+			eligible += l.Price * float64(l.Qty)
+		}
+	}
+	if eligible == 0 {
+		return AppliedPromo{}, ErrPromoNotApplicable
+	}
+	if eligible < p.MinSubtotal {
+		return AppliedPromo{}, fmt.Errorf("%w (spend at least $%.2f)", ErrPromoMinSubtotal, p.MinSubtotal)
+	}
+
+	if p.MaxUses > 0 {
+		n, err := s.Promos.Uses(p.ID)
+		if err != nil {
+			return AppliedPromo{}, err
+		}
+		if n >= p.MaxUses {
+			return AppliedPromo{}, ErrPromoUsedUp
+		}
+	}
+	if p.MaxPerCustomer > 0 {
+		n, err := s.Promos.CustomerUses(p.ID, sessionID, email)
+		if err != nil {
+			return AppliedPromo{}, err
+		}
+		if n >= p.MaxPerCustomer {
+			return AppliedPromo{}, ErrPromoCustomerLimit
+		}
+	}
+
+	discount := math.Min(p.Amount, eligible)
+	if p.Kind == "PERCENT" {
+		discount = math.Round(eligible*p.Amount) / 100
+	}
+	return AppliedPromo{PromotionID: p.ID, Code: p.Code, Description: p.Description, Discount: discount,
+		MaxUses: p.MaxUses, MaxPerCustomer: p.MaxPerCustomer}, nil
+}
+
+// inScope reports whether a product is covered; variants count as their
+// listing for PRODUCT scope.
+func (s *PromotionService) inScope(p repos.Promotion, productID string) (bool, error) {
+	switch p.Scope {
+	case "PRODUCT":
+		if productID == p.ScopeID {
+			return true, nil
+		}
+		parent, err := s.Prods.ParentID(productID)
+		return parent != "" && parent == p.ScopeID, err
+	case "CATEGORY":
+		prod, err := s.Prods.Get(productID)
+		return prod.CategoryID == p.ScopeID, err
+	}
+	return true, nil
+}
+
+// Redemption is what gets recorded when the promotion is placed on an order.
+func (a AppliedPromo) Redemption() *repos.PromoRedemption {
+	return &repos.PromoRedemption{PromotionID: a.PromotionID, Amount: a.Discount, MaxUses: a.MaxUses, MaxPerCustomer: a.MaxPerCustomer}
+}
+
+func (s *PromotionService) List() ([]repos.Promotion, error) { return s.Promos.List() }
+
+func (s *PromotionService) Get(id string) (repos.Promotion, error) { return s.Promos.Get(id) }
+
+func (s *PromotionService) Redemptions(id string) ([]repos.Redemption, error) {
+	return s.Promos.Redemptions(id)
+}
+
+// Save creates (empty ID) or updates a promotion after checking its rules.
+func (s *PromotionService) Save(p repos.Promotion) (string, error) {
+	if err := checkPromotion(p); err != nil {
+		return "", err
+	}
+	if err := s.checkScope(p); err != nil {
+		return "", err
+	}
+	if existing, ok, err := s.Promos.ByCode(p.Code); err != nil {
+		return "", err
+	} else if ok && existing.ID != p.ID {
+		return "", fmt.Errorf("%w: code %s is already in use", ErrPromoConfig, p.Code)
+	}
+	if p.ID == "" {
+		p.ID = uuid.NewString()
+		return p.ID, s.Promos.Create(p)
+	}
+	return p.ID, s.Promos.Update(p)
+}
+
+func (s *PromotionService) checkScope(p repos.Promotion) error {
+	if p.Scope != "PRODUCT" {
+		return nil
+	}
+	if _, err := s.Prods.Get(p.ScopeID); err != nil {
+		return fmt.Errorf("%w: unknown product %s", ErrPromoConfig, p.ScopeID)
+	}
+	return nil
+}
+
+func checkPromotion(p repos.Promotion) error {
+	switch {
+	case p.Kind != "PERCENT" && p.Kind != "FIXED":
+		return fmt.Errorf("%w: unknown discount type", ErrPromoConfig)
+	case p.Amount <= 0:
+		return fmt.Errorf("%w: amount must be positive", ErrPromoConfig)
+	case p.Kind == "PERCENT" && p.Amount > 100:
+		return fmt.Errorf("%w: a percentage cannot exceed 100", ErrPromoConfig)
+	case p.Scope != "ALL" && p.Scope != "CATEGORY" && p.Scope != "PRODUCT":
+		return fmt.Errorf("%w: unknown scope", ErrPromoConfig)
+	case p.Scope != "ALL" && p.ScopeID == "":
+		return fmt.Errorf("%w: pick the category or product the code applies to", ErrPromoConfig)
+	case p.StartsAt != "" && p.EndsAt != "" && p.EndsAt <= p.StartsAt:
+		return fmt.Errorf("%w: the code must end after it starts", ErrPromoConfig)
+	case p.MaxUses < 0 || p.MaxPerCustomer < 0:
+		return fmt.Errorf("%w: usage limits cannot be negative", ErrPromoConfig)
+	}
+	return nil
+}
+
+func (s *PromotionService) SetActive(id string, active bool) error {
+	return s.Promos.SetActive(id, active)
+}
+
+func (s *PromotionService) Delete(id string) error {
+	ok, err := s.Promos.Delete(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrPromoInUse
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestPromotionRules(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	promos := services.NewPromotionService(repos.NewPromotionRepo(db), repos.NewProductRepo(db))
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	save := func(p repos.Promotion) {
+		t.Helper()
+		p.Active = true
+		if _, err := promos.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save(repos.Promotion{Code: "TENOFF", Kind: "PERCENT", Amount: 10, Scope: "ALL"})
+	save(repos.Promotion{Code: "RADIO20", Kind: "FIXED", Amount: 20, Scope: "CATEGORY", ScopeID: "vintage-radios", MinSubtotal: 300})
+	save(repos.Promotion{Code: "NESONLY", Kind: "FIXED", Amount: 500, Scope: "PRODUCT", ScopeID: "nes-001"})
+	save(repos.Promotion{Code: "MAYDAY", Kind: "PERCENT", Amount: 50, Scope: "ALL", StartsAt: "2026-05-01 00:00:00", EndsAt: "2026-05-02 00:00:00"})
+
+	if _, err := promos.Save(repos.Promotion{Code: "HUGE", Kind: "PERCENT", Amount: 150, Scope: "ALL"}); !errors.Is(err, services.ErrPromoConfig) {
+		t.Fatalf("150%% off accepted: %v", err)
+	}
+	if _, err := promos.Save(repos.Promotion{Code: "tenoff", Kind: "FIXED", Amount: 1, Scope: "ALL"}); !errors.Is(err, services.ErrPromoConfig) {
+		t.Fatalf("duplicate code accepted: %v", err)
+	}
+
+	gbc := services.CartLine{ProductID: "gbc-001", Qty: 1, Price: 129.99}
+	radio := services.CartLine{ProductID: "radio-001", Qty: 1, Price: 349.50}
+	nes := services.CartLine{ProductID: "nes-001", Qty: 2, Price: 199}
+
+	for _, tc := range []struct {
+		code  string
+		lines []services.CartLine
+		at    time.Time
+		want  float64
+		err   error
+	}{
+		{"tenoff", []services.CartLine{gbc}, now, 13.00, nil}, // codes are case-insensitive; rounded to cents
+		{"RADIO20", []services.CartLine{gbc, radio}, now, 20, nil},
+		{"RADIO20", []services.CartLine{gbc}, now, 0, services.ErrPromoNotApplicable},
+		{"RADIO20", []services.CartLine{{ProductID: "radio-001", Qty: 1, Price: 250}}, now, 0, services.ErrPromoMinSubtotal},
+		{"NESONLY", []services.CartLine{nes, gbc}, now, 398, nil}, // fixed amount capped at the eligible lines
+		{"MAYDAY", []services.CartLine{gbc}, now, 65.00, nil},
+		{"MAYDAY", []services.CartLine{gbc}, now.Add(24 * time.Hour), 0, services.ErrPromoExpired},
+		{"NOPE", []services.CartLine{gbc}, now, 0, services.ErrPromoNotFound},
+	} {
+		got, err := promos.Evaluate(tc.code, tc.lines, "sid-x", "", tc.at)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: err = %v, want %v", tc.code, err, tc.err)
+			continue
+		}
+		if err == nil && got.Discount != tc.want {
+			t.Errorf("%s: discount = %.2f, want %.2f", tc.code, got.Discount, tc.want)
+		}
+	}
+}
+
+func TestPromotionRedeemedAtPlacement(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	carts := repos.NewCartRepo(db)
+	orders := repos.NewOrderRepo(db)
+	promos := services.NewPromotionService(repos.NewPromotionRepo(db), prods)
+	cart := services.NewCartService(carts, prods)
+	cart.Promos = promos
+	svc := services.NewOrderService(carts, repos.NewInventoryRepo(db), orders, prods)
+	svc.Promos = promos
+
+	id, err := promos.Save(repos.Promotion{Code: "ONCE", Kind: "FIXED", Amount: 10, Scope: "ALL", MaxUses: 3, MaxPerCustomer: 1, Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	place := func(sid, email string) (string, error) {
+		t.Helper()
+		if err := cart.Add(sid, "gbc-001", 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := cart.ApplyPromo(sid, "once"); err != nil {
+			return "", err
+		}
+		oid, _, _, err := svc.Place(sid, "20742", "pickup", services.Contact{Name: "Test", Email: email})
+		return oid, err
+	}
+
+	oid, err := place("sid-p1", "a@retrobytes.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, _, err := orders.Get(oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Total != 119.99 {
+		t.Fatalf("order total = %.2f, want 119.99", o.Total)
+	}
+	adj, err := orders.Adjustments(oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(adj) != 1 || adj[0].Kind != "DISCOUNT" || adj[0].Amount != -10 || adj[0].Code != "ONCE" {
+		t.Fatalf("adjustments = %+v", adj)
+	}
+	if cv, _ := cart.View("sid-p1"); cv.PromoCode != "" {
+		t.Fatalf("promo code left on the emptied cart: %q", cv.PromoCode)
+	}
+
+	// Same customer (by email) again: refused at placement, not at the cart
+	if err := cart.Add("sid-p2", "gbc-001", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := cart.ApplyPromo("sid-p2", "ONCE"); err != nil {
+		t.Fatal(err)
+	}
+	_, _, _, err = svc.Place("sid-p2", "20742", "pickup", services.Contact{Name: "Test", Email: "A@retrobytes.test"})
+	if !errors.Is(err, services.ErrPromoCustomerLimit) {
+		t.Fatalf("second use by same email: err = %v", err)
+	}
+
+	if _, err := place("sid-p3", "b@retrobytes.test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := place("sid-p4", "c@retrobytes.test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := place("sid-p5", "d@retrobytes.test"); !errors.Is(err, services.ErrPromoUsedUp) {
+		t.Fatalf("fourth use of a 3-use code: err = %v", err)
+	}
+
+	reds, err := promos.Redemptions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reds) != 3 {
+		t.Fatalf("redemptions = %d, want 3", len(reds))
+	}
+
+	// a checkout that evaluated the code before the last use was taken is
+	// refused when it is recorded, and nothing of the order is kept
+	inv := repos.NewInventoryRepo(db)
+	before, _ := inv.Qty("gbc-001", "20742")
+	err = orders.Place(repos.NewOrder{ID: "o-late", OrderNo: "RB-2026-999999", SessionID: "sid-p6", Region: "20742",
+		Fulfillment: "pickup", Customer: "Test", Email: "e@retrobytes.test", Total: 119.99,
+		Items: []repos.OrderItemRow{{ProductID: "gbc-001", Qty: 1, Price: 129.99, Condition: "GOOD"}},
+		Promo: &repos.PromoRedemption{PromotionID: id, Amount: 10, MaxUses: 3}})
+	if !errors.Is(err, repos.ErrRedemptionLimit) {
+		t.Fatalf("late redemption: err = %v", err)
+	}
+	if _, _, err := orders.Get("o-late"); err == nil {
+		t.Fatal("order kept after the redemption was refused")
+	}
+	if after, _ := inv.Qty("gbc-001", "20742"); after != before {
+		t.Fatalf("refused order took stock: %d -> %d", before, after)
+	}
+	if err := promos.Delete(id); !errors.Is(err, services.ErrPromoInUse) {
+		t.Fatalf("delete redeemed promotion: err = %v", err)
+	}
+
+	// a canceled order gives its use back, overall and to the customer
+	if _, err := db.Exec(`UPDATE orders SET status = 'CANCELED' WHERE id = ?`, oid); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := repos.NewPromotionRepo(db).Uses(id); err != nil || n != 2 {
+		t.Fatalf("uses after cancel = %d, %v", n, err)
+	}
+	if _, err := place("sid-p7", "a@retrobytes.test"); err != nil {
+		t.Fatalf("reuse after cancel: %v", err)
+	}
+	list, err := repos.NewPromotionRepo(db).List()
+	if err != nil || len(list) != 1 || list[0].Uses != 3 || list[0].Discounted != 30 {
+		t.Fatalf("report = %+v, %v", list, err)
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"log"
+
+	"retrobytes/internal/repos"
+)
+
+// MaxQuestionsPerDay limits how many questions one account can ask in 24h.
+const MaxQuestionsPerDay = 10
+
+var (
+	ErrQuestionRateLimited = errors.New("too many questions today, please try again tomorrow")
+	ErrQuestionNotFound    = errors.New("question not found")
+)
+
+// QuestionService handles product Q&A: shoppers ask, staff answer, and the
+// asker is notified on-site and by mail.
+type QuestionService struct {
+	Questions *repos.QuestionRepo
+	Prods     *repos.ProductRepo
+	Notify    *NotificationService
+}
+
+func NewQuestionService(questions *repos.QuestionRepo, prods *repos.ProductRepo, notify *NotificationService) *QuestionService {
+	return &QuestionService{Questions: questions, Prods: prods, Notify: notify}
+}
+
+// Ask stores a question (already validated with validate.Question) for the
+// listing a product or variant belongs to.
+func (s *QuestionService) Ask(userID, productID, question string) (string, error) {
+	pid := productID
+	if parent, err := s.Prods.ParentID(productID); err != nil {
+		return "", err
+	} else if parent != "" {
+		pid = parent
+	}
+	n, err := s.Questions.CountRecent(userID, 24)
+	if err != nil {
+		return "", err
+	}
+	if n >= MaxQuestionsPerDay {
+		return "", ErrQuestionRateLimited
+	}
+	return s.Questions.Create(pid, userID, question)
+}
+
+// ForProduct returns a listing's answered questions.
+func (s *QuestionService) ForProduct(productID string) ([]repos.Question, error) {
+	return s.Questions.Answered(productID, 50)
+}
+
+// Mine returns the user's unanswered questions on a listing.
+func (s *QuestionService) Mine(userID, productID string) ([]repos.Question, error) {
+	return s.Questions.ByUserForProduct(userID, productID)
+}
+
+// Queue lists questions by status (OPEN by default).
+func (s *QuestionService) Queue(status string) ([]repos.Question, error) {
+	switch status {
+	case "ANSWERED", "HIDDEN":
+	default:
+		status = "OPEN"
+	}
+	return s.Questions.ByStatus(status, 100)
+}
+
+// Answer publishes an answer and notifies the asker. A notification failure
+// is logged; the answer stays published.
+func (s *QuestionService) Answer(id, answer, adminID string) error {
+	ok, err := s.Questions.SetAnswer(id, answer, adminID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrQuestionNotFound
+	}
+	q, err := s.Questions.Get(id)
+	if err != nil {
+		return err
+	}
+	if s.Notify != nil {
+		msg := "Your question about " + q.ProductTitle + " has been answered: " + answer
+		if err := s.Notify.Notify(q.UserID, q.Email, "question_answered",
+			"Your question about "+q.ProductTitle+" was answered", msg, "/product/"+q.ProductID+"#questions"); err != nil {
+			log.Printf("[questions] notify %s: %v", q.UserID, err)
+		}
+	}
+	return nil
+}
+
+// Hide removes a question from the queue without answering it.
+func (s *QuestionService) Hide(id string) error {
+	ok, err := s.Questions.Hide(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrQuestionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package services_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestQuestionAnswerNotifiesAsker(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer := &captureMailer{}
+	notifRepo := repos.NewNotificationRepo(db)
+	notify := services.NewNotificationService(notifRepo, mailer)
+	svc := services.NewQuestionService(repos.NewQuestionRepo(db), repos.NewProductRepo(db), notify)
+
+	id, err := svc.Ask("u-alice", "radio-zenith-500", "Does it have the original back panel?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qs, _ := svc.ForProduct("radio-zenith-500"); len(qs) != 0 {
+		t.Fatal("unanswered questions must not be published")
+	}
+	if open, _ := svc.Queue(""); len(open) != 1 || open[0].ID != id {
+		t.Fatalf("question missing from the open queue: %+v", open)
+	}
+
+	if err := svc.Answer(id, "Yes, the original back panel is included.", "u-admin"); err != nil {
+		t.Fatal(err)
+	}
+	qs, _ := svc.ForProduct("radio-zenith-500")
+	if len(qs) != 1 || !strings.Contains(qs[0].Answer, "back panel") {
+		t.Fatalf("answered question not published: %+v", qs)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].To != "alice@retrobytes.test" {
+		t.Fatalf("asker not emailed: %+v", mailer.sent)
+	}
+	if n, _ := notifRepo.UnreadCount("u-alice"); n != 1 {
+		t.Fatalf("want 1 on-site notification, got %d", n)
+	}
+
+	if err := svc.Answer("missing", "x", "u-admin"); !errors.Is(err, services.ErrQuestionNotFound) {
+		t.Fatalf("want ErrQuestionNotFound, got %v", err)
+	}
+	for i := 0; i < services.MaxQuestionsPerDay-1; i++ {
+		if _, err := svc.Ask("u-alice", "nes-001", "Is the cartridge slot clean?"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := svc.Ask("u-alice", "nes-001", "One more question please"); !errors.Is(err, services.ErrQuestionRateLimited) {
+		t.Fatalf("want ErrQuestionRateLimited, got %v", err)
+	}
+}
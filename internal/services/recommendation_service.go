@@ -0,0 +1,86 @@
+package services
+
+import (
+	"time"
+
+	"retrobytes/internal/domain"
+	"retrobytes/internal/repos"
+)
+
+// RecommendationsPerItem is how many related listings are stored per listing.
+const RecommendationsPerItem = 8
+
+const recommendationJob = "recommendations"
+
+// RecommendationService computes "customers also bought" from order history
+// and serves it with a same-category fallback.
+type RecommendationService struct {
+	Recs  *repos.RecommendationRepo
+	Prods *repos.ProductRepo
+	Jobs  *repos.JobRepo
+	// Pricing, when set, shows sale prices on suggestions
+	Pricing *PricingService
+}
+
+func NewRecommendationService(recs *repos.RecommendationRepo, prods *repos.ProductRepo, jobs *repos.JobRepo) *RecommendationService {
+	return &RecommendationService{Recs: recs, Prods: prods, Jobs: jobs}
+}
+
+// Rebuild recomputes the co-purchase table; run periodically from main.
+func (s *RecommendationService) Rebuild(now time.Time) (int64, error) {
+	ts := now.UTC().Format("2006-01-02 15:04:05")
+	n, err := s.Recs.Rebuild(RecommendationsPerItem, ts)
+	if err != nil {
+		return 0, err
+	}
+	return n, s.Jobs.SetLastRun(recommendationJob, ts)
+}
+
+// AlsoBought returns up to limit listings bought together with productID.
+func (s *RecommendationService) AlsoBought(productID string, limit int) ([]domain.Product, error) {
+	return s.forItems([]string{productID}, limit)
+}
+
+// ForCart suggests listings for the items in a cart (variants count as
+// their listing).
+func (s *RecommendationService) ForCart(items []repos.CartItemRow, limit int) ([]domain.Product, error) {
+	ids := make([]string, 0, len(items))
+	for _, it := range items {
+		id := it.ProductID
+		if parent, err := s.Prods.ParentID(id); err == nil && parent != "" {
+			id = parent
+		}
+		ids = append(ids, id)
+	}
+	return s.forItems(ids, limit)
+}
+
+// forItems fills up co-purchase results with same-category items.
+func (s *RecommendationService) forItems(ids []string, limit int) ([]domain.Product, error) {
+	if len(ids) == 0 || limit <= 0 {
+		return nil, nil
+	}
+	out, err := s.Recs.Related(ids, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) >= limit {
+		return s.withSales(out)
+	}
+	exclude := append([]string{}, ids...)
+	for _, p := range out {
+		exclude = append(exclude, p.ID)
+	}
+	more, err := s.Recs.SameCategory(ids, exclude, limit-len(out))
+	if err != nil {
+		return nil, err
+	}
+	return s.withSales(append(out, more...))
+}
+
+func (s *RecommendationService) withSales(list []domain.Product) ([]domain.Product, error) {
+	if s.Pricing == nil {
+		return list, nil
+	}
+	return list, s.Pricing.Apply(list, time.Now())
+}
@@ -0,0 +1,70 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestCoPurchaseRecommendations(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders := repos.NewOrderRepo(db)
+	place := func(id, status string, products ...string) {
+		if err := orders.Create(id, "sid-"+id, "20742", "pickup", "Test", "t@retrobytes.test", 1); err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range products {
+			if err := orders.InsertItem(id, p, 1, 1, "GOOD"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if status != "" {
+			if err := orders.UpdateStatus(id, status); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	place("o1", "", "nes-001", "gbc-001")
+	place("o2", "", "nes-001", "gbc-001", "radio-001")
+	place("o3", "CANCELED", "nes-001", "radio-zenith-500")
+
+	svc := services.NewRecommendationService(repos.NewRecommendationRepo(db), repos.NewProductRepo(db), repos.NewJobRepo(db))
+	if _, err := svc.Rebuild(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := svc.AlsoBought("nes-001", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "gbc-001" || got[1].ID != "radio-001" {
+		t.Fatalf("want [gbc-001 radio-001], got %+v", got)
+	}
+
+	// Sparse data is topped up from the same category, never with the item itself
+	got, _ = svc.AlsoBought("snes-001", 3)
+	if len(got) == 0 {
+		t.Fatal("expected same-category fallback for an item with no orders")
+	}
+	for _, p := range got {
+		if p.ID == "snes-001" || p.CategoryID != "retro-consoles" {
+			t.Fatalf("unexpected fallback item %+v", p)
+		}
+	}
+
+	// Cart suggestions skip items already in the cart
+	recs, _ := svc.ForCart([]repos.CartItemRow{{ProductID: "nes-001"}, {ProductID: "gbc-001"}}, 4)
+	for _, p := range recs {
+		if p.ID == "nes-001" || p.ID == "gbc-001" {
+			t.Fatalf("cart item suggested back: %s", p.ID)
+		}
+	}
+	if len(recs) == 0 || recs[0].ID != "radio-001" {
+		t.Fatalf("want radio-001 first, got %+v", recs)
+	}
+}
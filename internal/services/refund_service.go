@@ -0,0 +1,277 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+
+	"retrobytes/internal/repos"
+)
+
+// ErrRefund wraps refund requests that cannot be granted as asked.
+var ErrRefund = errors.New("invalid refund")
+
+// RefundReason is a reason code an admin picks when refunding.
+type RefundReason struct {
+	Code  string
+	Label string
+}
+
+// RefundReasons lists the accepted reason codes in display order.
+var RefundReasons = []RefundReason{
+	{"DAMAGED", "Arrived damaged"},
+	{"NOT_AS_DESCRIBED", "Not as described"},
+	{"MISSING_ITEM", "Item missing from parcel"},
+	{"LOST_IN_TRANSIT", "Lost in transit"},
+	{"CUSTOMER_REQUEST", "Customer request"},
+	{"GOODWILL", "Goodwill gesture"},
+	{"RETURNED", "Item returned"},
+	{"OTHER", "Other (see note)"},
+}
+
+// RefundReasonLabel returns the display label for a reason code.
+func RefundReasonLabel(code string) string {
+	for _, r := range RefundReasons {
+		if r.Code == code {
+			return r.Label
+		}
+	}
+	return code
+}
+
+// RefundLine is an order line with what has been refunded on it so far.
+type RefundLine struct {
+	ProductID string
+	Title     string
+	Qty       int
+	Refunded  int
+	Price     float64
+	// Unit is what refunding one unit gives back: the price plus its share
+	// of the order's discounts and tax. UnitTax is the tax part of it.
+	Unit    float64
+	UnitTax float64
+}
+
+// Refundable is the quantity that can still be refunded.
+func (l RefundLine) Refundable() int { return l.Qty - l.Refunded }
+
+// RefundSummary is an order's refund position.
+type RefundSummary struct {
+	Total            float64 // what the order charged
+	Refunded         float64
+	Shipping         float64 // shipping charged
+	ShippingRefunded bool
+	Tax              float64 // sales tax charged
+	TaxRefunded      float64
+	Lines            []RefundLine
+	Refunds          []repos.Refund
+}
+
+// Net is what the customer has paid after refunds.
+func (s RefundSummary) Net() float64 { return math.Round((s.Total-s.Refunded)*100) / 100 }
+
+// RefundRequest describes one refund. Qty maps product ids to units; Full
+// refunds everything not yet refunded, shipping included.
+type RefundRequest struct {
+	Qty      map[string]int
+	Shipping bool
+	Full     bool
+	Reason   string
+	Note     string
+	Restock  bool
+}
+
+// RefundService gives money back on orders through the payment gateway and
+// keeps refunds itemized against order lines.
+type RefundService struct {
+	Orders   *repos.OrderRepo
+	Refunds  *repos.RefundRepo
+	Payments *PaymentService
+}
+
+func NewRefundService(orders *repos.OrderRepo, refunds *repos.RefundRepo, pays *PaymentService) *RefundService {
+	return &RefundService{Orders: orders, Refunds: refunds, Payments: pays}
+}
+
+// Summary returns what an order charged and what has been refunded on it.
+func (s *RefundService) Summary(orderID string) (RefundSummary, error) {
+	o, items, err := s.Orders.Get(orderID)
+	if err != nil {
+		return RefundSummary{}, err
+	}
+	adj, err := s.Orders.Adjustments(orderID)
+	if err != nil {
+		return RefundSummary{}, err
+	}
+	refunds, err := s.Refunds.ForOrder(orderID)
+	if err != nil {
+		return RefundSummary{}, err
+	}
+	sum := RefundSummary{Total: o.Total, Refunds: refunds}
+
+	// discounts and tax are spread over the items in proportion to their
+	// value; shipping is refunded on its own
+	itemsTotal, extras := 0.0, 0.0
+	for _, it := range items {
+		itemsTotal += it.Subtotal
+	}
+	for _, a := range adj {
+		switch a.Kind {
+		case "SHIPPING":
+			sum.Shipping += a.Amount
+		case "TAX":
+			sum.Tax += a.Amount
+			extras += a.Amount
+		default:
+			extras += a.Amount
+		}
+	}
+	factor, taxFactor := 1.0, 0.0
+	if itemsTotal > 0 {
+		factor = (itemsTotal + extras) / itemsTotal
+		taxFactor = sum.Tax / itemsTotal
+	}
+
+	refunded := map[string]int{}
+	for _, f := range refunds {
+		sum.Refunded += f.Amount
+		if f.Shipping > 0 {
+			sum.ShippingRefunded = true
+		}
+		for _, it := range f.Items {
+			refunded[it.ProductID] += it.Qty
+			sum.TaxRefunded += it.Tax
+		}
+	}
+	sum.Refunded = math.Round(sum.Refunded*100) / 100
+	sum.TaxRefunded = math.Round(sum.TaxRefunded*100) / 100
+	for _, it := range items {
+		sum.Lines = append(sum.Lines, RefundLine{
+			ProductID: it.ProductID, Title: it.Title, Qty: it.Qty, Price: it.Price,
+			Refunded: refunded[it.ProductID], Unit: math.Round(it.Price*factor*100) / 100,
+			UnitTax: it.Price * taxFactor,
+		})
+	}
+	return sum, nil
+}
+
+// Refund pays back the requested lines (and shipping) on an order, records
+// the refund against the order's lines and optionally puts the units back in
+// stock. Captured card payments are refunded through the gateway; orders
+// without a payment get an offline refund record.
+func (s *RefundService) Refund(orderID, adminID string, req RefundRequest) (repos.Refund, error) {
+	if RefundReasonLabel(req.Reason) == req.Reason {
+		return repos.Refund{}, fmt.Errorf("%w: unknown reason %q", ErrRefund, req.Reason)
+	}
+	if req.Reason == "OTHER" && req.Note == "" {
+		return repos.Refund{}, fmt.Errorf("%w: reason OTHER needs a note", ErrRefund)
+	}
+	o, _, err := s.Orders.Get(orderID)
+	if err != nil {
+		return repos.Refund{}, err
+	}
+	sum, err := s.Summary(orderID)
+	if err != nil {
+		return repos.Refund{}, err
+	}
+
+	f := repos.Refund{OrderID: orderID, Reason: req.Reason, Note: req.Note, Restocked: req.Restock, AdminID: adminID}
+	complete := true // nothing refundable left afterwards
+	for _, l := range sum.Lines {
+		qty := req.Qty[l.ProductID]
+		if req.Full {
+			qty = l.Refundable()
+		}
+		if qty < 0 || qty > l.Refundable() {
+			return repos.Refund{}, fmt.Errorf("%w: %s has %d refundable units", ErrRefund, l.Title, l.Refundable())
+		}
+		if qty < l.Refundable() {
+			complete = false
+		}
+		if qty == 0 {
+			continue
+		}
+		amount := math.Round(l.Unit*float64(qty)*100) / 100
+		tax := math.Round(l.UnitTax*float64(qty)*100) / 100
+		f.Items = append(f.Items, repos.RefundItem{ProductID: l.ProductID, Title: l.Title, Qty: qty, Amount: amount, Tax: tax})
+		f.Amount += amount
+	}
+	for id := range req.Qty {
+		if !hasLine(sum.Lines, id) {
+			return repos.Refund{}, fmt.Errorf("%w: %s is not on this order", ErrRefund, id)
+		}
+	}
+	shippingLeft := sum.Shipping > 0 && !sum.ShippingRefunded
+	switch {
+	case shippingLeft && (req.Full || req.Shipping):
+		f.Shipping = sum.Shipping
+		f.Amount += sum.Shipping
+	case shippingLeft:
+		complete = false
+	case req.Shipping && !req.Full:
+		return repos.Refund{}, fmt.Errorf("%w: no shipping left to refund", ErrRefund)
+	}
+	// the last refund takes whatever is left so rounding never strands cents
+	left := math.Round((sum.Total-sum.Refunded)*100) / 100
+	if complete {
+		f.Amount = left
+		if n := len(f.Items); n > 0 {
+			taxLeft := sum.Tax - sum.TaxRefunded
+			for _, it := range f.Items[:n-1] {
+				taxLeft -= it.Tax
+			}
+			f.Items[n-1].Tax = math.Round(taxLeft*100) / 100
+		}
+	}
+	f.Amount = math.Round(f.Amount*100) / 100
+	if f.Amount <= 0 {
+		return repos.Refund{}, fmt.Errorf("%w: nothing selected", ErrRefund)
+	}
+	if f.Amount > left {
+		return repos.Refund{}, fmt.Errorf("%w: only $%.2f left to refund", ErrRefund, left)
+	}
+
+	// the refund is reserved before the gateway is asked, so a concurrent
+	// refund sees it and the gateway can tell a retry by its id
+	var pay bool
+	if s.Payments != nil {
+		p, ok, err := s.Payments.ForOrder(orderID)
+		if err != nil {
+			return repos.Refund{}, err
+		}
+		if ok {
+			if p.Status == "AUTHORIZED" {
+				return repos.Refund{}, fmt.Errorf("%w: the payment is not captured yet; capture it or cancel the order", ErrRefund)
+			}
+			f.PaymentID, pay = p.ID, true
+		}
+	}
+	f.ID = uuid.NewString()
+	if _, err := s.Refunds.Reserve(f, sum.Refunded); err != nil {
+		if errors.Is(err, repos.ErrRefundsChanged) {
+			return repos.Refund{}, fmt.Errorf("%w: the order was refunded meanwhile; reload and try again", ErrRefund)
+		}
+		return repos.Refund{}, err
+	}
+	if pay {
+		if _, f.Ref, err = s.Payments.Refund(orderID, f.ID, f.Amount); err != nil {
+			_ = s.Refunds.Release(f.ID)
+			return repos.Refund{}, err
+		}
+	}
+	if err := s.Refunds.Complete(f, o.Region); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+func hasLine(lines []RefundLine, productID string) bool {
+	for _, l := range lines {
+		if l.ProductID == productID {
+			return true
+		}
+	}
+	return false
+}
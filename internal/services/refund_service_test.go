@@ -0,0 +1,91 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"retrobytes/internal/payments"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestRefunds(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	carts := repos.NewCartRepo(db)
+	inv := repos.NewInventoryRepo(db)
+	orders := repos.NewOrderRepo(db)
+	cart := services.NewCartService(carts, prods)
+	svc := services.NewOrderService(carts, inv, orders, prods)
+	svc.Tax = services.NewTaxService(repos.NewTaxRepo(db), prods)
+	svc.Shipping = services.NewShippingService(repos.NewShippingRepo(db))
+	pay := services.NewPaymentService(payments.NewFake(), repos.NewPaymentRepo(db))
+	svc.Payments = pay
+	refunds := services.NewRefundService(orders, repos.NewRefundRepo(db), pay)
+
+	if err := cart.Add("sid-r1", "gbc-001", 2); err != nil {
+		t.Fatal(err)
+	}
+	contact := services.Contact{Name: "Ann", Email: "a@retrobytes.test", ShipZIP: "20742", ShipMethod: "EXPRESS", PaymentToken: payments.TokenApproved}
+	oid, total, _, err := svc.Place("sid-r1", "20742", "delivery", contact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := services.RefundRequest{Qty: map[string]int{"gbc-001": 1}, Reason: "DAMAGED", Restock: true}
+	if _, err := refunds.Refund(oid, "u-admin", one); !errors.Is(err, services.ErrRefund) {
+		t.Fatalf("refund before capture: err = %v", err)
+	}
+	if _, err := pay.Capture(oid); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := refunds.Refund(oid, "u-admin", services.RefundRequest{Qty: one.Qty, Reason: "BORED"}); !errors.Is(err, services.ErrRefund) {
+		t.Fatalf("unknown reason: err = %v", err)
+	}
+
+	stock, _ := inv.Qty("gbc-001", "20742")
+	f, err := refunds.Refund(oid, "u-admin", one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the unit carries its 6% Maryland tax back with it
+	if f.Amount != 137.79 || len(f.Items) != 1 || f.Items[0].Qty != 1 || f.Shipping != 0 {
+		t.Fatalf("line refund = %+v", f)
+	}
+	if qty, _ := inv.Qty("gbc-001", "20742"); qty != stock+1 {
+		t.Fatalf("restock: %d -> %d", stock, qty)
+	}
+	if f.Ref != "fake_rf_"+f.ID {
+		t.Fatalf("refund ref = %q for %s", f.Ref, f.ID)
+	}
+	// a refund worked out before this one was recorded is turned away
+	stale := repos.Refund{OrderID: oid, Amount: 137.79, Reason: "DAMAGED"}
+	if _, err := refunds.Refunds.Reserve(stale, 0); !errors.Is(err, repos.ErrRefundsChanged) {
+		t.Fatalf("stale refund: err = %v", err)
+	}
+	if _, err := refunds.Refund(oid, "u-admin", services.RefundRequest{Qty: map[string]int{"gbc-001": 2}, Reason: "DAMAGED"}); !errors.Is(err, services.ErrRefund) {
+		t.Fatalf("refunding more units than left: err = %v", err)
+	}
+
+	if _, err := refunds.Refund(oid, "u-admin", services.RefundRequest{Full: true, Reason: "LOST_IN_TRANSIT"}); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := refunds.Summary(oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Refunded != total || sum.Net() != 0 || !sum.ShippingRefunded || sum.Lines[0].Refundable() != 0 {
+		t.Fatalf("after full refund: %+v (total %.2f)", sum, total)
+	}
+	if p, _, _ := pay.ForOrder(oid); p.Status != "REFUNDED" || p.Refunded != total {
+		t.Fatalf("payment = %+v", p)
+	}
+	if qty, _ := inv.Qty("gbc-001", "20742"); qty != stock+1 {
+		t.Fatal("full refund restocked without being asked to")
+	}
+	if _, err := refunds.Refund(oid, "u-admin", services.RefundRequest{Full: true, Reason: "GOODWILL"}); !errors.Is(err, services.ErrRefund) {
+		t.Fatalf("refund of a fully refunded order: err = %v", err)
+	}
+}
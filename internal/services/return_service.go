@@ -0,0 +1,414 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"retrobytes/internal/domain"
+	"retrobytes/internal/imaging"
+	"retrobytes/internal/repos"
+
+	"github.com/google/uuid"
+)
+
+// ErrReturn wraps return requests and staff actions that are not allowed.
+var ErrReturn = errors.New("return not possible")
+
+// MaxReturnPhotos bounds the photos attached to one return request, and
+// MaxReturnPhotoBytes their combined size: the whole form has to fit in
+// the server's 1 MiB request body, so the photos share one budget.
+const (
+	MaxReturnPhotos     = 3
+	MaxReturnPhotoBytes = 900 << 10
+)
+
+// ReturnReason is a reason code a customer picks when requesting a return.
+type ReturnReason struct {
+	Code  string
+	Label string
+}
+
+// ReturnReasons lists the accepted reason codes in display order.
+var ReturnReasons = []ReturnReason{
+	{"DEAD_ON_ARRIVAL", "Arrived dead / does not power on"},
+	{"FAULTY", "Works but has a fault"},
+	{"DAMAGED_IN_TRANSIT", "Damaged in transit"},
+	{"NOT_AS_DESCRIBED", "Not as described"},
+	{"WRONG_ITEM", "Wrong item sent"},
+	{"CHANGED_MIND", "No longer wanted"},
+}
+
+// ReturnReasonLabel returns the display label for a reason code.
+func ReturnReasonLabel(code string) string {
+	for _, r := range ReturnReasons {
+		if r.Code == code {
+			return r.Label
+		}
+	}
+	return code
+}
+
+// ReturnLine is an order line and how many of its units can still be
+// returned, and until when.
+type ReturnLine struct {
+	ProductID  string
+	Title      string
+	Qty        int
+	Returnable int
+	Until      time.Time // zero when nothing can be returned
+}
+
+// returnSteps maps a staff action to the statuses it applies to and the
+// status it leads to. Refunding (Resolve) is the last step after INSPECTED.
+var returnSteps = map[string]struct {
+	from []string
+	to   string
+}{
+	"approve": {[]string{"REQUESTED"}, "APPROVED"},
+	"reject":  {[]string{"REQUESTED", "RECEIVED", "INSPECTED"}, "REJECTED"},
+	"receive": {[]string{"APPROVED"}, "RECEIVED"},
+	"inspect": {[]string{"RECEIVED"}, "INSPECTED"},
+}
+
+var returnPhotoRe = regexp.MustCompile(`^[0-9a-f-]{36}\.(jpg|png)$`)
+
+// ReturnService runs customer returns (RMAs): shipped order lines can be
+// requested back within the return window, and staff approve, receive,
+// inspect and finally refund them, putting working units back on sale as
+// second-hand stock or writing them off. Photos are stored under
+// MediaDir/returns/<id>/ and are not public.
+type ReturnService struct {
+	Returns   *repos.ReturnRepo
+	Orders    *repos.OrderRepo
+	Shipments *repos.ShipmentRepo
+	Prods     *repos.ProductRepo
+	Inv       *repos.InventoryRepo
+	Refunds   *RefundService
+	MediaDir  string
+	// WindowDays is how long after shipping a return may be requested,
+	// counted per shipment
+	WindowDays int
+}
+
+func NewReturnService(returns *repos.ReturnRepo, orders *repos.OrderRepo, shipments *repos.ShipmentRepo, prods *repos.ProductRepo, inv *repos.InventoryRepo, refunds *RefundService, mediaDir string, windowDays int) *ReturnService {
+	return &ReturnService{Returns: returns, Orders: orders, Shipments: shipments, Prods: prods, Inv: inv, Refunds: refunds, MediaDir: mediaDir, WindowDays: windowDays}
+}
+
+// delivery is a set of units the customer received at one time, and the
+// last moment a return can be requested for them.
+type delivery struct {
+	until time.Time
+	qty   map[string]int
+}
+
+// deliveries lists what of the order the customer has: one delivery per
+// shipment, or the whole order once collected (or shipped before shipments
+// were recorded).
+func (s *ReturnService) deliveries(o repos.OrderRow, items []repos.OrderItemRow) ([]delivery, error) {
+	var at string
+	switch o.Status {
+	case "SHIPPED", "PARTIALLY_SHIPPED":
+		shipments, err := s.Shipments.ForOrder(o.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(shipments) > 0 {
+			out := make([]delivery, 0, len(shipments))
+			for _, sh := range shipments {
+				day, err := time.Parse("2006-01-02", sh.ShippedOn)
+				if err != nil {
+					return nil, err
+				}
+				// the whole shipping day counts
+				d := delivery{until: day.AddDate(0, 0, s.WindowDays+1).Add(-time.Second), qty: map[string]int{}}
+				for _, it := range sh.Items {
+					d.qty[it.ProductID] += it.Qty
+				}
+				out = append(out, d)
+			}
+			return out, nil
+		}
+		at = o.ShippedAt
+	case "COLLECTED":
+		at = o.CollectedAt
+	default:
+		return nil, nil
+	}
+	if at == "" {
+		at = o.CreatedAt // shipped before shipping dates were recorded
+	}
+	t, err := time.Parse("2006-01-02 15:04:05", at)
+	if err != nil {
+		return nil, err
+	}
+	d := delivery{until: t.AddDate(0, 0, s.WindowDays), qty: map[string]int{}}
+	for _, it := range items {
+		d.qty[it.ProductID] += it.Qty
+	}
+	return []delivery{d}, nil
+}
+
+// Returnable lists the order's lines with the units that can still be
+// returned at now: units delivered within the return window, less those
+// already returned or refunded. The time returned is the last deadline of
+// any delivery; it fails with ErrReturn once every window has closed.
+func (s *ReturnService) Returnable(orderID string, now time.Time) ([]ReturnLine, time.Time, error) {
+	o, items, err := s.Orders.Get(orderID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	dels, err := s.deliveries(o, items)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if len(dels) == 0 {
+		return nil, time.Time{}, fmt.Errorf("%w: only shipped or collected orders can be returned", ErrReturn)
+	}
+	var deadline time.Time
+	delivered, open, until := map[string]int{}, map[string]int{}, map[string]time.Time{}
+	for _, d := range dels {
+		if d.until.After(deadline) {
+			deadline = d.until
+		}
+		for id, n := range d.qty {
+			delivered[id] += n
+			if !now.After(d.until) {
+				open[id] += n
+				if d.until.After(until[id]) {
+					until[id] = d.until
+				}
+			}
+		}
+	}
+	if now.After(deadline) {
+		return nil, deadline, fmt.Errorf("%w: the %d-day return window ended on %s", ErrReturn, s.WindowDays, deadline.Format("2006-01-02"))
+	}
+	returned, err := s.Returns.ReturnedQty(orderID)
+	if err != nil {
+		return nil, deadline, err
+	}
+	out := make([]ReturnLine, 0, len(items))
+	for _, it := range items {
+		// units already sent back are taken from the oldest deliveries
+		// first, so a closed window does not use up an open one
+		n := max(0, min(open[it.ProductID], delivered[it.ProductID]-returned[it.ProductID]))
+		l := ReturnLine{ProductID: it.ProductID, Title: it.Title, Qty: it.Qty, Returnable: n}
+		if n > 0 {
+			l.Until = until[it.ProductID]
+		}
+		out = append(out, l)
+	}
+	return out, deadline, nil
+}
+
+// Request opens a return for the given units. Photos are sanitized like
+// product images (metadata stripped) before they are stored.
+func (s *ReturnService) Request(orderID string, qty map[string]int, reason, details string, photos [][]byte, now time.Time) (string, error) {
+	if ReturnReasonLabel(reason) == reason {
+		return "", fmt.Errorf("%w: choose a reason", ErrReturn)
+	}
+	if len(photos) > MaxReturnPhotos {
+		return "", fmt.Errorf("%w: at most %d photos", ErrReturn, MaxReturnPhotos)
+	}
+	total := 0
+	for _, data := range photos {
+		total += len(data)
+	}
+	if total > MaxReturnPhotoBytes {
+		return "", fmt.Errorf("%w: photos must be under %d KB in total", ErrReturn, MaxReturnPhotoBytes>>10)
+	}
+	lines, _, err := s.Returnable(orderID, now)
+	if err != nil {
+		return "", err
+	}
+	ret := repos.Return{ID: uuid.NewString(), OrderID: orderID, Reason: reason, Details: details}
+	for _, l := range lines {
+		n := qty[l.ProductID]
+		if n < 0 || n > l.Returnable {
+			return "", fmt.Errorf("%w: %s has %d returnable units", ErrReturn, l.Title, l.Returnable)
+		}
+		if n > 0 {
+			ret.Items = append(ret.Items, repos.ReturnItem{ProductID: l.ProductID, Title: l.Title, Qty: n})
+		}
+	}
+	if len(ret.Items) == 0 {
+		return "", fmt.Errorf("%w: choose at least one item", ErrReturn)
+	}
+
+	names := []string{}
+	dir := filepath.Join(s.MediaDir, "returns", ret.ID)
+	for _, data := range photos {
+		if len(data) > MaxImageBytes {
+			return "", ErrImageTooLarge
+		}
+		res, err := imaging.Process(data)
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", err
+		}
+		name := uuid.NewString() + res.Ext
+		if err := os.WriteFile(filepath.Join(dir, name), res.Original, 0o644); err != nil {
+			return "", err
+		}
+		names = append(names, name)
+	}
+	if len(names) > 0 {
+		raw, _ := json.Marshal(names)
+		ret.PhotosJSON = string(raw)
+	}
+	id, err := s.Returns.Create(ret)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+	}
+	return id, err
+}
+
+// ForOrder lists an order's returns, newest first.
+func (s *ReturnService) ForOrder(orderID string) ([]repos.Return, error) {
+	return s.Returns.ForOrder(orderID)
+}
+
+// List returns the staff queue for a status ("" = all open returns).
+func (s *ReturnService) List(status string) ([]repos.Return, error) {
+	return s.Returns.List(status, 200)
+}
+
+func (s *ReturnService) Get(id string) (repos.Return, error) {
+	return s.Returns.Get(id)
+}
+
+// Advance applies a staff action (approve, reject, receive, inspect) with an
+// optional note shown to the customer.
+func (s *ReturnService) Advance(id, action, note string) (repos.Return, error) {
+	step, ok := returnSteps[action]
+	if !ok {
+		return repos.Return{}, fmt.Errorf("%w: unknown action %q", ErrReturn, action)
+	}
+	ret, err := s.Returns.Get(id)
+	if err != nil {
+		return ret, err
+	}
+	for _, from := range step.from {
+		if ret.Status != from {
+			continue
+		}
+		moved, err := s.Returns.Transition(id, from, step.to, note)
+		if err != nil {
+			return ret, err
+		}
+		if !moved {
+			break
+		}
+		ret.Status = step.to
+		if note != "" {
+			ret.AdminNote = note
+		}
+		return ret, nil
+	}
+	return ret, fmt.Errorf("%w: cannot %s a %s return", ErrReturn, action, ret.Status)
+}
+
+// Resolve refunds an inspected return and disposes of the units: RESTOCK
+// puts them back on sale as second-hand stock in the order's region,
+// WRITE_OFF drops them.
+func (s *ReturnService) Resolve(id, adminID, disposition string) (repos.Return, repos.Refund, error) {
+	if disposition != "RESTOCK" && disposition != "WRITE_OFF" {
+		return repos.Return{}, repos.Refund{}, fmt.Errorf("%w: unknown disposition %q", ErrReturn, disposition)
+	}
+	ret, err := s.Returns.Get(id)
+	if err != nil {
+		return ret, repos.Refund{}, err
+	}
+	o, _, err := s.Orders.Get(ret.OrderID)
+	if err != nil {
+		return ret, repos.Refund{}, err
+	}
+	// claim the return first so a double submit cannot refund twice
+	moved, err := s.Returns.Transition(id, "INSPECTED", "REFUNDED", "")
+	if err != nil {
+		return ret, repos.Refund{}, err
+	}
+	if !moved {
+		return ret, repos.Refund{}, fmt.Errorf("%w: only inspected returns can be refunded", ErrReturn)
+	}
+	req := RefundRequest{Qty: map[string]int{}, Reason: "RETURNED", Note: "Return " + ret.ID}
+	for _, it := range ret.Items {
+		req.Qty[it.ProductID] = it.Qty
+	}
+	f, err := s.Refunds.Refund(ret.OrderID, adminID, req)
+	if err != nil {
+		_, _ = s.Returns.Transition(id, "REFUNDED", "INSPECTED", "")
+		return ret, f, err
+	}
+	if err := s.Returns.Resolve(id, disposition, f.ID); err != nil {
+		return ret, f, err
+	}
+	ret.Status, ret.Disposition, ret.RefundID = "REFUNDED", disposition, f.ID
+	if disposition == "RESTOCK" {
+		for _, it := range ret.Items {
+			if err := s.restockSecondHand(it.ProductID, o.Region, it.Qty); err != nil {
+				return ret, f, err
+			}
+		}
+	}
+	return ret, f, nil
+}
+
+// restockSecondHand puts returned units back on sale. Listings already
+// graded second-hand (GOOD or below) take them directly; better-graded
+// listings get a sibling "Returned" variant graded GOOD, created at the
+// same price on first use.
+func (s *ReturnService) restockSecondHand(productID, region string, qty int) error {
+	p, err := s.Prods.Get(productID)
+	if err != nil {
+		return err
+	}
+	used := domain.LegacyGrade["SECOND_HAND"]
+	if !domain.MeetsGrade(p.Condition, used) || p.Condition == used {
+		return s.Inv.Increment(productID, region, qty)
+	}
+	parentID, err := s.Prods.ParentID(productID)
+	if err != nil {
+		return err
+	}
+	if parentID == "" {
+		parentID = productID
+	}
+	usedID := productID + "-returned"
+	if _, err := s.Prods.Get(usedID); errors.Is(err, sql.ErrNoRows) {
+		parent, err := s.Prods.Get(parentID)
+		if err != nil {
+			return err
+		}
+		if err := s.Prods.CreateVariant(parent, usedID, "Returned, "+domain.ConditionLabel(used), p.Price); err != nil {
+			return err
+		}
+		if err := s.Prods.SetCondition(usedID, used, "Customer return, inspected and working."); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	return s.Inv.Increment(usedID, region, qty)
+}
+
+// PhotoPath returns the file behind one of a return's photos.
+func (s *ReturnService) PhotoPath(ret repos.Return, name string) (string, bool) {
+	if !returnPhotoRe.MatchString(name) {
+		return "", false
+	}
+	for _, p := range ret.Photos() {
+		if p == name {
+			return filepath.Join(s.MediaDir, "returns", ret.ID, name), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,202 @@
+package services_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"retrobytes/internal/payments"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestReturnWorkflow(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	carts := repos.NewCartRepo(db)
+	inv := repos.NewInventoryRepo(db)
+	orders := repos.NewOrderRepo(db)
+	cart := services.NewCartService(carts, prods)
+	svc := services.NewOrderService(carts, inv, orders, prods)
+	pay := services.NewPaymentService(payments.NewFake(), repos.NewPaymentRepo(db))
+	svc.Payments = pay
+	refunds := services.NewRefundService(orders, repos.NewRefundRepo(db), pay)
+	returns := services.NewReturnService(repos.NewReturnRepo(db), orders, repos.NewShipmentRepo(db), prods, inv, refunds, t.TempDir(), 30)
+
+	for id, qty := range map[string]int{"gbc-001": 2, "snes-001": 1} {
+		if err := cart.Add("sid-rma", id, qty); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oid, _, _, err := svc.Place("sid-rma", "20742", "pickup", services.Contact{Name: "Ann", Email: "a@retrobytes.test", PaymentToken: payments.TokenApproved})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	if _, _, err := returns.Returnable(oid, now); !errors.Is(err, services.ErrReturn) {
+		t.Fatalf("return before shipping: err = %v", err)
+	}
+	if _, err := pay.Capture(oid); err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.UpdateStatus(oid, "SHIPPED"); err != nil {
+		t.Fatal(err)
+	}
+
+	photo := jpegWithExif(t, 200, 150, 1)
+	big := make([]byte, services.MaxReturnPhotoBytes/2+1)
+	if _, err := returns.Request(oid, map[string]int{"gbc-001": 1}, "FAULTY", "", [][]byte{big, big}, now); !errors.Is(err, services.ErrReturn) {
+		t.Fatalf("photos over the combined limit: err = %v", err)
+	}
+	gbc, err := returns.Request(oid, map[string]int{"gbc-001": 1}, "DEAD_ON_ARRIVAL", "No picture on screen", [][]byte{photo}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := returns.Get(gbc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ret.Photos()) != 1 {
+		t.Fatalf("photos = %v", ret.Photos())
+	}
+	if path, ok := returns.PhotoPath(ret, ret.Photos()[0]); !ok {
+		t.Fatal("stored photo not found")
+	} else if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := returns.PhotoPath(ret, "../../secret.jpg"); ok {
+		t.Fatal("photo path escapes the return")
+	}
+	if _, err := returns.Request(oid, map[string]int{"gbc-001": 2}, "FAULTY", "", nil, now); !errors.Is(err, services.ErrReturn) {
+		t.Fatalf("returning more units than left: err = %v", err)
+	}
+	snes, err := returns.Request(oid, map[string]int{"snes-001": 1}, "CHANGED_MIND", "", nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := returns.Advance(gbc, "receive", ""); !errors.Is(err, services.ErrReturn) {
+		t.Fatalf("receive before approval: err = %v", err)
+	}
+	if _, _, err := returns.Resolve(gbc, "u-admin", "RESTOCK"); !errors.Is(err, services.ErrReturn) {
+		t.Fatalf("refund before inspection: err = %v", err)
+	}
+	for _, id := range []string{gbc, snes} {
+		for _, step := range []string{"approve", "receive", "inspect"} {
+			if _, err := returns.Advance(id, step, ""); err != nil {
+				t.Fatalf("%s: %v", step, err)
+			}
+		}
+	}
+
+	// a GOOD listing takes the unit back as is
+	gbcStock, _ := inv.Qty("gbc-001", "20742")
+	ret, f, err := returns.Resolve(gbc, "u-admin", "RESTOCK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Status != "REFUNDED" || f.Amount != 129.99 || f.Reason != "RETURNED" || f.Restocked {
+		t.Fatalf("resolved %+v with refund %+v", ret, f)
+	}
+	if qty, _ := inv.Qty("gbc-001", "20742"); qty != gbcStock+1 {
+		t.Fatalf("gbc stock %d -> %d", gbcStock, qty)
+	}
+	if _, _, err := returns.Resolve(gbc, "u-admin", "RESTOCK"); !errors.Is(err, services.ErrReturn) {
+		t.Fatalf("second refund: err = %v", err)
+	}
+
+	// an Excellent console comes back as a second-hand variant
+	snesStock, _ := inv.Qty("snes-001", "20742")
+	if _, _, err := returns.Resolve(snes, "u-admin", "RESTOCK"); err != nil {
+		t.Fatal(err)
+	}
+	if qty, _ := inv.Qty("snes-001", "20742"); qty != snesStock {
+		t.Fatal("used unit restocked as new")
+	}
+	used, err := prods.Get("snes-001-returned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qty, _ := inv.Qty(used.ID, "20742"); used.Condition != "GOOD" || qty != 1 {
+		t.Fatalf("returned variant %+v with %d in stock", used, qty)
+	}
+	if sum, _ := refunds.Summary(oid); sum.Refunded != 328.99 {
+		t.Fatalf("refunded = %.2f", sum.Refunded)
+	}
+
+	// the window is counted from shipping
+	if _, _, err := returns.Returnable(oid, now.AddDate(0, 0, 31)); !errors.Is(err, services.ErrReturn) {
+		t.Fatalf("return after the window: err = %v", err)
+	}
+}
+
+func TestReturnWindowPerShipment(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	carts := repos.NewCartRepo(db)
+	inv := repos.NewInventoryRepo(db)
+	orders := repos.NewOrderRepo(db)
+	shipments := repos.NewShipmentRepo(db)
+	cart := services.NewCartService(carts, prods)
+	svc := services.NewOrderService(carts, inv, orders, prods)
+	pay := services.NewPaymentService(payments.NewFake(), repos.NewPaymentRepo(db))
+	svc.Payments = pay
+	refunds := services.NewRefundService(orders, repos.NewRefundRepo(db), pay)
+	returns := services.NewReturnService(repos.NewReturnRepo(db), orders, shipments, prods, inv, refunds, t.TempDir(), 30)
+
+	if err := cart.Add("sid-rw", "gbc-001", 3); err != nil {
+		t.Fatal(err)
+	}
+	oid, _, _, err := svc.Place("sid-rw", "20742", "delivery", services.Contact{Name: "Ann", Email: "a@retrobytes.test", PaymentToken: payments.TokenApproved})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pay.Capture(oid); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	ship := func(id string, on time.Time) {
+		t.Helper()
+		sh := repos.Shipment{ID: id, OrderID: oid, Carrier: "UPS", TrackingNo: "1Z" + id, ShippedOn: on.Format("2006-01-02"),
+			Items: []repos.ShipmentItem{{ProductID: "gbc-001", Qty: 1}}}
+		shipped, _ := shipments.ForOrder(oid) // one unit per parcel
+		if ok, err := shipments.Create(sh, len(shipped), "PARTIALLY_SHIPPED"); err != nil || !ok {
+			t.Fatalf("ship %s: %v, %v", id, ok, err)
+		}
+	}
+
+	// the first parcel went out long ago: its window has closed
+	ship("a", now.AddDate(0, 0, -40))
+	if _, _, err := returns.Returnable(oid, now); !errors.Is(err, services.ErrReturn) {
+		t.Fatalf("return after the first parcel's window: err = %v", err)
+	}
+
+	// a parcel shipped today opens a window for its own unit only, even
+	// though the order is not fully shipped
+	ship("b", now)
+	lines, _, err := returns.Returnable(oid, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines[0].Returnable != 1 || lines[0].Until.Before(now.AddDate(0, 0, 29)) {
+		t.Fatalf("returnable = %+v", lines)
+	}
+
+	// units refunded without a return cannot be sent back as well
+	if _, err := refunds.Refund(oid, "u-admin", services.RefundRequest{Qty: map[string]int{"gbc-001": 2}, Reason: "DAMAGED"}); err != nil {
+		t.Fatal(err)
+	}
+	if lines, _, _ = returns.Returnable(oid, now); lines[0].Returnable != 0 {
+		t.Fatalf("returnable after refunding both shipped units = %+v", lines)
+	}
+	if _, err := returns.Request(oid, map[string]int{"gbc-001": 1}, "FAULTY", "", nil, now); !errors.Is(err, services.ErrReturn) {
+		t.Fatalf("return of a refunded unit: err = %v", err)
+	}
+}
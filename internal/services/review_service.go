@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+
+	"retrobytes/internal/domain"
+	"retrobytes/internal/repos"
+)
+
+// MaxReviewsPerDay limits how many reviews one account can submit in 24h.
+const MaxReviewsPerDay = 5
+
+var (
+	ErrReviewNotPurchased = errors.New("only customers who bought this item can review it")
+	ErrReviewDuplicate    = errors.New("you have already reviewed this item")
+	ErrReviewRateLimited  = errors.New("too many reviews today, please try again tomorrow")
+	ErrReviewNotFound     = errors.New("review not found")
+)
+
+// ReviewService handles verified-buyer reviews and their moderation.
+type ReviewService struct {
+	Reviews *repos.ReviewRepo
+	Prods   *repos.ProductRepo
+}
+
+func NewReviewService(reviews *repos.ReviewRepo, prods *repos.ProductRepo) *ReviewService {
+	return &ReviewService{Reviews: reviews, Prods: prods}
+}
+
+// listing resolves a variant to the listing its reviews belong to.
+func (s *ReviewService) listing(productID string) (string, error) {
+	parent, err := s.Prods.ParentID(productID)
+	if err != nil {
+		return "", err
+	}
+	if parent != "" {
+		return parent, nil
+	}
+	return productID, nil
+}
+
+// CanReview reports whether the user bought the item and has not reviewed it yet.
+func (s *ReviewService) CanReview(userID, productID string) (bool, error) {
+	if userID == "" {
+		return false, nil
+	}
+	pid, err := s.listing(productID)
+	if err != nil {
+		return false, err
+	}
+	bought, err := s.Reviews.HasPurchased(userID, pid)
+	if err != nil || !bought {
+		return false, err
+	}
+	done, err := s.Reviews.Exists(userID, pid)
+	return !done, err
+}
+
+// Submit stores a review in the moderation queue. Rating, title and body are
+// expected to be validated already (see validate.Rating/ReviewTitle/ReviewBody).
+func (s *ReviewService) Submit(userID, productID string, rating int, title, body string) (string, error) {
+	pid, err := s.listing(productID)
+	if err != nil {
+		return "", err
+	}
+	bought, err := s.Reviews.HasPurchased(userID, pid)
+	if err != nil {
+		return "", err
+	}
+	if !bought {
+		return "", ErrReviewNotPurchased
+	}
+	if done, err := s.Reviews.Exists(userID, pid); err != nil {
+		return "", err
+	} else if done {
+		return "", ErrReviewDuplicate
+	}
+	if n, err := s.Reviews.CountRecent(userID, 24); err != nil {
+		return "", err
+	} else if n >= MaxReviewsPerDay {
+		return "", ErrReviewRateLimited
+	}
+	// The same text pasted onto several listings is treated as a duplicate
+	if same, err := s.Reviews.SameBodyExists(userID, body); err != nil {
+		return "", err
+	} else if same {
+		return "", ErrReviewDuplicate
+	}
+	return s.Reviews.Create(pid, userID, rating, title, body)
+}
+
+// ForProduct returns a listing's rating summary and approved reviews.
+func (s *ReviewService) ForProduct(productID string) (domain.Rating, []repos.Review, error) {
+	sums, err := s.Reviews.Summaries([]string{productID})
+	if err != nil {
+		return domain.Rating{}, nil, err
+	}
+	list, err := s.Reviews.Approved(productID, 50)
+	return sums[productID], list, err
+}
+
+// Queue lists reviews in a moderation state (PENDING by default).
+func (s *ReviewService) Queue(status string) ([]repos.Review, error) {
+	switch status {
+	case "APPROVED", "REJECTED":
+	default:
+		status = "PENDING"
+	}
+	return s.Reviews.ByStatus(status, 100)
+}
+
+// Moderate approves or rejects a review.
+func (s *ReviewService) Moderate(id string, approve bool, adminID string) error {
+	status := "REJECTED"
+	if approve {
+		status = "APPROVED"
+	}
+	ok, err := s.Reviews.SetStatus(id, status, adminID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrReviewNotFound
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestReviewsVerifiedBuyersAndModeration(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	reviews := repos.NewReviewRepo(db)
+	svc := services.NewReviewService(reviews, prods)
+
+	// Alice bought a Game Boy Color; Bob bought nothing
+	users := repos.NewUserRepo(db)
+	orders := repos.NewOrderRepo(db)
+	if err := users.BindSession("sid-alice", "u-alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.Create("o-1", "sid-alice", "20742", "pickup", "Alice", "alice@retrobytes.test", 129.99); err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.InsertItem("o-1", "gbc-001", 1, 129.99, "GOOD"); err != nil {
+		t.Fatal(err)
+	}
+
+	body := "Works perfectly, screen has no dead pixels and the shell is clean."
+	if _, err := svc.Submit("u-bob", "gbc-001", 5, "Great", body); !errors.Is(err, services.ErrReviewNotPurchased) {
+		t.Fatalf("want ErrReviewNotPurchased, got %v", err)
+	}
+	if ok, _ := svc.CanReview("u-alice", "gbc-001"); !ok {
+		t.Fatal("alice should be able to review gbc-001")
+	}
+	id, err := svc.Submit("u-alice", "gbc-001", 4, "Great handheld", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Submit("u-alice", "gbc-001", 5, "Again", body+" Again!"); !errors.Is(err, services.ErrReviewDuplicate) {
+		t.Fatalf("want ErrReviewDuplicate, got %v", err)
+	}
+
+	// Pending reviews are not public
+	rating, list, err := svc.ForProduct("gbc-001")
+	if err != nil || rating.Count != 0 || len(list) != 0 {
+		t.Fatalf("pending review leaked: %+v %+v %v", rating, list, err)
+	}
+	if err := svc.Moderate(id, true, "u-admin"); err != nil {
+		t.Fatal(err)
+	}
+	rating, list, _ = svc.ForProduct("gbc-001")
+	if rating.Count != 1 || rating.Average != 4 || len(list) != 1 || list[0].Author != "Alice" {
+		t.Fatalf("unexpected summary %+v %+v", rating, list)
+	}
+	if err := svc.Moderate("nope", true, "u-admin"); !errors.Is(err, services.ErrReviewNotFound) {
+		t.Fatalf("want ErrReviewNotFound, got %v", err)
+	}
+
+	// Rated listings sort first and carry their rating
+	catalog := services.NewCatalogService(repos.NewCategoryRepo(db), prods)
+	catalog.Reviews = reviews
+	got, err := catalog.Search("", "retro-consoles", "", nil, "rating", 1, 20)
+	if err != nil || len(got) == 0 {
+		t.Fatalf("search: %v", err)
+	}
+	if got[0].ID != "gbc-001" || got[0].Rating.Count != 1 {
+		t.Fatalf("want gbc-001 first with its rating, got %+v", got[0])
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"retrobytes/internal/domain"
+	"retrobytes/internal/repos"
+)
+
+// MaxSavedSearches caps how many alerts one account can keep.
+const MaxSavedSearches = 20
+
+const savedSearchJob = "saved_search_alerts"
+
+var ErrTooManySavedSearches = errors.New("saved search limit reached")
+
+type SavedSearchService struct {
+	Saved  *repos.SavedSearchRepo
+	Prods  *repos.ProductRepo
+	Jobs   *repos.JobRepo
+	Notify *NotificationService
+}
+
+func NewSavedSearchService(saved *repos.SavedSearchRepo, prods *repos.ProductRepo, jobs *repos.JobRepo, notify *NotificationService) *SavedSearchService {
+	return &SavedSearchService{Saved: saved, Prods: prods, Jobs: jobs, Notify: notify}
+}
+
+func (s *SavedSearchService) Save(userID, q, category, condition string) (string, error) {
+	n, err := s.Saved.CountByUser(userID)
+	if err != nil {
+		return "", err
+	}
+	if n >= MaxSavedSearches {
+		return "", ErrTooManySavedSearches
+	}
+	return s.Saved.Create(userID, NormalizeQuery(q), category, condition)
+}
+
+func (s *SavedSearchService) List(userID string) ([]repos.SavedSearch, error) {
+	return s.Saved.ListByUser(userID)
+}
+
+func (s *SavedSearchService) Delete(userID, id string) error {
+	return s.Saved.Delete(id, userID)
+}
+
+// Matches applies the same rules as ProductRepo.Search to a single product.
+func Matches(ss repos.SavedSearch, p domain.Product) bool {
+	if ss.CategoryID != "" && ss.CategoryID != p.CategoryID {
+		return false
+	}
+	if !domain.MeetsGrade(p.Condition, ss.Condition) {
+		return false
+	}
+	if ss.Query == "" {
+		return true
+	}
+	q := strings.ToLower(ss.Query)
+	return strings.Contains(strings.ToLower(p.Title), q) || strings.Contains(strings.ToLower(p.Description), q)
+}
+
+// RunAlerts checks products listed since the previous run against every
+// saved search and notifies owners of new matches. The first run only
+// records a starting point so existing stock does not trigger alerts.
+func (s *SavedSearchService) RunAlerts(now time.Time) (int, error) {
+	to := now.UTC().Format("2006-01-02 15:04:05")
+	from, err := s.Jobs.LastRun(savedSearchJob)
+	if err != nil {
+		return 0, err
+	}
+	if from == "" {
+		return 0, s.Jobs.SetLastRun(savedSearchJob, to)
+	}
+
+	products, err := s.Prods.ListedBetween(from, to)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	if len(products) > 0 {
+		searches, err := s.Saved.ListAll()
+		if err != nil {
+			return 0, err
+		}
+		for _, ss := range searches {
+			for _, p := range products {
+				if !Matches(ss, p) {
+					continue
+				}
+				fresh, err := s.Saved.MarkMatched(ss.ID, p.ID)
+				if err != nil {
+					return sent, err
+				}
+				if !fresh {
+					continue
+				}
+				msg := fmt.Sprintf("New arrival for \"%s\": %s ($%.2f)", ss.Query, p.Title, p.Price)
+				if err := s.Notify.Notify(ss.UserID, ss.Email, "saved_search", "New arrival: "+p.Title, msg, "/product/"+p.ID); err != nil {
+					log.Printf("[alerts] notify %s about %s failed: %v", ss.UserID, p.ID, err)
+					continue
+				}
+				sent++
+			}
+		}
+	}
+	return sent, s.Jobs.SetLastRun(savedSearchJob, to)
+}
@@ -0,0 +1,130 @@
+package services_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"retrobytes/internal/mail"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+type captureMailer struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (m *captureMailer) Send(msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestSavedSearchAlerts_NewArrivalsOnly(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer := &captureMailer{}
+	notify := services.NewNotificationService(repos.NewNotificationRepo(db), mailer)
+	svc := services.NewSavedSearchService(repos.NewSavedSearchRepo(db), repos.NewProductRepo(db), repos.NewJobRepo(db), notify)
+
+	if _, err := svc.Save("u-alice", "Zenith", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Save("u-bob", "famicom", "retro-consoles", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// First run only sets the watermark; the seeded Zenith radio must not alert.
+	t0 := time.Now().UTC()
+	if n, err := svc.RunAlerts(t0); err != nil || n != 0 {
+		t.Fatalf("first run: n=%d err=%v", n, err)
+	}
+
+	_, err = db.Exec(`INSERT INTO products(id,category_id,title,description,condition,price,images_json,active,created_at)
+	  VALUES('zenith-trans-oceanic','vintage-radios','Zenith Trans-Oceanic','Shortwave portable','GOOD',149.00,'[]',1,?)`,
+		t0.Add(30*time.Minute).Format("2006-01-02 15:04:05"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := svc.RunAlerts(t0.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || len(mailer.sent) != 1 || mailer.sent[0].To != "alice@retrobytes.test" {
+		t.Fatalf("want one alert to alice, got n=%d sent=%+v", n, mailer.sent)
+	}
+	if c, _ := notify.UnreadCount("u-alice"); c != 1 {
+		t.Fatalf("want 1 unread notification, got %d", c)
+	}
+	if c, _ := notify.UnreadCount("u-bob"); c != 0 {
+		t.Fatalf("bob's famicom search should not match, got %d", c)
+	}
+
+	// A later run does not re-alert the same product.
+	if n, _ := svc.RunAlerts(t0.Add(2 * time.Hour)); n != 0 {
+		t.Fatalf("duplicate alert sent: %d", n)
+	}
+
+	// Another user cannot delete alice's search or its alert history.
+	searches, err := svc.List("u-alice")
+	if err != nil || len(searches) != 1 {
+		t.Fatalf("alice's searches: %+v, %v", searches, err)
+	}
+	if err := svc.Delete("u-bob", searches[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	var matches int
+	if err := db.Get(&matches, `SELECT COUNT(*) FROM saved_search_matches WHERE saved_search_id = ?`, searches[0].ID); err != nil || matches != 1 {
+		t.Fatalf("matches after bob's delete = %d, %v", matches, err)
+	}
+	if left, _ := svc.List("u-alice"); len(left) != 1 {
+		t.Fatal("bob deleted alice's search")
+	}
+}
+
+func TestListedBetween_ListingTimeAndNoVariants(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	long := time.Now().UTC().AddDate(0, -1, 0).Format("2006-01-02 15:04:05")
+	if _, err := db.Exec(`INSERT INTO products(id,category_id,title,description,condition,price,images_json,active,created_at)
+	  VALUES('famicom-001','retro-consoles','Famicom','Drafted last month','GOOD',99.00,'[]',0,?)`, long); err != nil {
+		t.Fatal(err)
+	}
+	from := time.Now().UTC().Add(-time.Minute).Format("2006-01-02 15:04:05")
+	if err := prods.SetActive("famicom-001", true); err != nil {
+		t.Fatal(err)
+	}
+	famicom, err := prods.Get("famicom-001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prods.CreateVariant(famicom, "famicom-001-boxed", "Boxed", 129.00); err != nil {
+		t.Fatal(err)
+	}
+	to := time.Now().UTC().Add(time.Minute).Format("2006-01-02 15:04:05")
+
+	listed, err := prods.ListedBetween(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the seeded demo catalog was created just now too; only the Famicom
+	// lines matter here
+	var ids []string
+	for _, p := range listed {
+		if strings.HasPrefix(p.ID, "famicom-") {
+			ids = append(ids, p.ID)
+		}
+	}
+	if len(ids) != 1 || ids[0] != "famicom-001" {
+		t.Fatalf("listed famicoms = %v, want the product put on sale now and not its variant", ids)
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"strings"
+
+	"retrobytes/internal/repos"
+)
+
+// SearchAnalyticsService records storefront searches and summarizes them for admins.
+type SearchAnalyticsService struct {
+	Log *repos.SearchLogRepo
+}
+
+func NewSearchAnalyticsService(l *repos.SearchLogRepo) *SearchAnalyticsService {
+	return &SearchAnalyticsService{Log: l}
+}
+
+// NormalizeQuery lowercases and collapses whitespace so "  NES  console" and
+// "nes console" aggregate together.
+func NormalizeQuery(q string) string {
+	return strings.Join(strings.Fields(strings.ToLower(q)), " ")
+}
+
+// Record stores one search and returns its id for click attribution.
+func (s *SearchAnalyticsService) Record(q, category, condition string, results int, sessionID, userID string) (int64, error) {
+	return s.Log.Insert(repos.SearchEvent{
+		Query:       NormalizeQuery(q),
+		CategoryID:  category,
+		Condition:   condition,
+		ResultCount: results,
+		SessionID:   sessionID,
+		UserID:      userID,
+	})
+}
+
+// RecordClick attributes a product view to the search that linked to it.
+func (s *SearchAnalyticsService) RecordClick(searchID int64, productID string) error {
+	if searchID <= 0 {
+		return nil
+	}
+	return s.Log.InsertClick(searchID, productID)
+}
+
+type SearchInsights struct {
+	Days        int
+	Searches    int
+	ZeroResults int
+	Clicks      int
+	Clicked     int     // searches that led to at least one product view
+	ClickRate   float64 // percent of searches that led to a product view
+	TopQueries  []repos.QueryStat
+	ZeroQueries []repos.QueryStat
+	TopProducts []repos.ClickedProduct
+}
+
+// Insights summarizes the last `days` days of search activity.
+func (s *SearchAnalyticsService) Insights(days int) (SearchInsights, error) {
+	if days <= 0 {
+		days = 30
+	}
+	out := SearchInsights{Days: days}
+	var err error
+	if out.Searches, out.ZeroResults, out.Clicks, out.Clicked, err = s.Log.Totals(days); err != nil {
+		return out, err
+	}
+	if out.TopQueries, err = s.Log.TopQueries(days, 25); err != nil {
+		return out, err
+	}
+	if out.ZeroQueries, err = s.Log.ZeroResultQueries(days, 25); err != nil {
+		return out, err
+	}
+	if out.TopProducts, err = s.Log.TopClickedProducts(days, 10); err != nil {
+		return out, err
+	}
+	for i, q := range out.TopQueries {
+		if q.Searches > 0 {
+			out.TopQueries[i].ClickRate = 100 * float64(q.Clicked) / float64(q.Searches)
+		}
+	}
+	if out.Searches > 0 {
+		out.ClickRate = 100 * float64(out.Clicked) / float64(out.Searches)
+	}
+	return out, nil
+}
+
+// Purge drops search history older than the retention window.
+func (s *SearchAnalyticsService) Purge(retentionDays int) (int64, error) {
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+	return s.Log.PurgeOlderThan(retentionDays)
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"retrobytes/internal/domain"
+	"retrobytes/internal/mail"
+	"retrobytes/internal/repos"
+)
+
+// ErrShipment wraps shipments that cannot be recorded as asked.
+var ErrShipment = errors.New("invalid shipment")
+
+// ShipmentLine is an order line with how many units have gone out.
+type ShipmentLine struct {
+	ProductID string
+	Title     string
+	Qty       int
+	Shipped   int
+}
+
+// Unshipped is the quantity still waiting to be sent.
+func (l ShipmentLine) Unshipped() int { return l.Qty - l.Shipped }
+
+// ShipmentRequest describes one parcel. Qty maps product ids to units.
+type ShipmentRequest struct {
+	Carrier    string
+	TrackingNo string
+	ShippedOn  time.Time
+	Qty        map[string]int
+}
+
+type ShipmentService struct {
+	Shipments *repos.ShipmentRepo
+	Orders    *repos.OrderRepo
+	// Payments settles the card with the first shipment
+	Payments *PaymentService
+	// Mail queues the tracking notice; nil sends none
+	Mail *MailService
+}
+
+func NewShipmentService(shipments *repos.ShipmentRepo, orders *repos.OrderRepo, payments *PaymentService, m *MailService) *ShipmentService {
+	return &ShipmentService{Shipments: shipments, Orders: orders, Payments: payments, Mail: m}
+}
+
+// Shippable reports whether an order in status can still get shipments.
+func Shippable(o repos.OrderRow) bool {
+	return o.Fulfillment != "pickup" && (o.Status == "PLACED" || o.Status == "RESERVED" || o.Status == "PARTIALLY_SHIPPED")
+}
+
+// Lines lists the order's lines with their shipped quantities.
+func (s *ShipmentService) Lines(orderID string) ([]ShipmentLine, error) {
+	_, items, err := s.Orders.Get(orderID)
+	if err != nil {
+		return nil, err
+	}
+	shipped, err := s.Shipments.ShippedQty(orderID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ShipmentLine, 0, len(items))
+	for _, it := range items {
+		out = append(out, ShipmentLine{ProductID: it.ProductID, Title: it.Title, Qty: it.Qty, Shipped: shipped[it.ProductID]})
+	}
+	return out, nil
+}
+
+// ForOrder lists the order's shipments, oldest first.
+func (s *ShipmentService) ForOrder(orderID string) ([]repos.Shipment, error) {
+	return s.Shipments.ForOrder(orderID)
+}
+
+// Ship records a parcel for an order. The order becomes PARTIALLY_SHIPPED
+// while units are left to send and SHIPPED with the last of them; the first
+// shipment captures an authorized card payment.
+func (s *ShipmentService) Ship(orderID, adminID string, req ShipmentRequest) (repos.Shipment, error) {
+	o, _, err := s.Orders.Get(orderID)
+	if err != nil {
+		return repos.Shipment{}, err
+	}
+	if o.Fulfillment == "pickup" {
+		return repos.Shipment{}, fmt.Errorf("%w: pickup orders are collected in store", ErrShipment)
+	}
+	if !Shippable(o) {
+		return repos.Shipment{}, fmt.Errorf("%w: order is %s", ErrShipment, o.Status)
+	}
+	carrier, ok := domain.CarrierOf(req.Carrier)
+	if !ok {
+		return repos.Shipment{}, fmt.Errorf("%w: unknown carrier %q", ErrShipment, req.Carrier)
+	}
+	lines, err := s.Lines(orderID)
+	if err != nil {
+		return repos.Shipment{}, err
+	}
+	sh := repos.Shipment{
+		ID: uuid.NewString(), OrderID: orderID, Carrier: carrier.Code, TrackingNo: req.TrackingNo,
+		ShippedOn: req.ShippedOn.Format("2006-01-02"), AdminID: adminID,
+	}
+	var mailLines []mail.OrderLine
+	shippedBefore, left := 0, 0
+	for _, l := range lines {
+		n := req.Qty[l.ProductID]
+		if n < 0 || n > l.Unshipped() {
+			return repos.Shipment{}, fmt.Errorf("%w: %s has %d unit(s) left to ship", ErrShipment, l.Title, l.Unshipped())
+		}
+		shippedBefore += l.Shipped
+		left += l.Unshipped() - n
+		if n > 0 {
+			sh.Items = append(sh.Items, repos.ShipmentItem{ProductID: l.ProductID, Title: l.Title, Qty: n})
+			mailLines = append(mailLines, mail.OrderLine{Title: l.Title, Qty: n})
+		}
+	}
+	if len(sh.Items) == 0 {
+		return repos.Shipment{}, fmt.Errorf("%w: choose at least one unit to ship", ErrShipment)
+	}
+	status := "SHIPPED"
+	if left > 0 {
+		status = "PARTIALLY_SHIPPED"
+	}
+
+	var mails []repos.OutboxEmail
+	if s.Mail != nil && o.Email != "" {
+		m, err := mail.ShipmentMessage(o.Email, mail.Shipment{
+			Name: o.Customer, OrderNo: o.Number(), Carrier: carrier.Label, TrackingNo: sh.TrackingNo,
+			TrackingURL: sh.TrackingURL(), ShippedOn: sh.ShippedOn, Lines: mailLines, Partial: left > 0,
+			Link: s.Mail.Link("/order/" + o.ID),
+		})
+		if err != nil {
+			return repos.Shipment{}, err
+		}
+		mails = append(mails, outboxEmail(mail.KindShipment, m))
+	}
+
+	created, err := s.Shipments.Create(sh, shippedBefore, status, mails...)
+	if err != nil {
+		return repos.Shipment{}, err
+	}
+	if !created {
+		return repos.Shipment{}, fmt.Errorf("%w: the order changed while the shipment was recorded; check the quantities and try again", ErrShipment)
+	}
+	// the card is only charged for a shipment that is on record
+	if s.Payments != nil {
+		p, ok, err := s.Payments.ForOrder(orderID)
+		if err != nil {
+			return sh, err
+		}
+		if ok && p.Status == "AUTHORIZED" {
+			if _, err := s.Payments.Capture(orderID); err != nil {
+				return sh, fmt.Errorf("shipment recorded, capture failed: %w", err)
+			}
+		}
+	}
+	return sh, nil
+}
@@ -0,0 +1,140 @@
+package services_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"retrobytes/internal/mail"
+	"retrobytes/internal/payments"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestPartialShipments(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	carts := repos.NewCartRepo(db)
+	orders := repos.NewOrderRepo(db)
+	outbox := repos.NewOutboxRepo(db)
+	cart := services.NewCartService(carts, prods)
+	svc := services.NewOrderService(carts, repos.NewInventoryRepo(db), orders, prods)
+	pay := services.NewPaymentService(payments.NewFake(), repos.NewPaymentRepo(db))
+	svc.Payments = pay
+	shipmentRepo := repos.NewShipmentRepo(db)
+	shipments := services.NewShipmentService(shipmentRepo, orders, pay, services.NewMailService(outbox, &captureMailer{}, "http://shop.test"))
+
+	contact := services.Contact{Name: "Ann", Email: "a@retrobytes.test", PaymentToken: payments.TokenApproved}
+	if err := cart.Add("sid-sh0", "gbc-001", 1); err != nil {
+		t.Fatal(err)
+	}
+	pickup, _, _, err := svc.Place("sid-sh0", "20742", "pickup", contact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now()
+	if _, err := shipments.Ship(pickup, "u-admin", services.ShipmentRequest{Carrier: "USPS", TrackingNo: "9400111", ShippedOn: today, Qty: map[string]int{"gbc-001": 1}}); !errors.Is(err, services.ErrShipment) {
+		t.Fatalf("pickup shipment: err = %v", err)
+	}
+
+	if err := cart.Add("sid-sh1", "gbc-001", 3); err != nil {
+		t.Fatal(err)
+	}
+	oid, _, _, err := svc.Place("sid-sh1", "20742", "delivery", contact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, req := range map[string]services.ShipmentRequest{
+		"too many":        {Carrier: "UPS", TrackingNo: "1Z999", Qty: map[string]int{"gbc-001": 4}},
+		"nothing":         {Carrier: "UPS", TrackingNo: "1Z999", Qty: map[string]int{}},
+		"unknown carrier": {Carrier: "PONY", TrackingNo: "1Z999", Qty: map[string]int{"gbc-001": 1}},
+	} {
+		req.ShippedOn = today
+		if _, err := shipments.Ship(oid, "u-admin", req); !errors.Is(err, services.ErrShipment) {
+			t.Fatalf("%s: err = %v", name, err)
+		}
+	}
+	if o, _, _ := orders.Get(oid); o.Status != "PLACED" {
+		t.Fatalf("rejected shipments changed the status to %s", o.Status)
+	}
+
+	first, err := shipments.Ship(oid, "u-admin", services.ShipmentRequest{Carrier: "UPS", TrackingNo: "1Z999AA10123456784", ShippedOn: today, Qty: map[string]int{"gbc-001": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o, _, _ := orders.Get(oid); o.Status != "PARTIALLY_SHIPPED" || o.ShippedAt == "" {
+		t.Fatalf("after first parcel: %s shipped_at=%q", o.Status, o.ShippedAt)
+	}
+	if p, _, _ := pay.ForOrder(oid); p.Status != "CAPTURED" {
+		t.Fatalf("first shipment left the payment %s", p.Status)
+	}
+	invoiceNo, _, _ := orders.InvoiceNo(oid)
+	if invoiceNo == 0 {
+		t.Fatal("first shipment issued no invoice")
+	}
+	due, err := outbox.Due(today.Add(time.Minute).UTC().Format("2006-01-02 15:04:05"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notice repos.OutboxEmail
+	for _, m := range due {
+		if m.Kind == mail.KindShipment {
+			notice = m
+		}
+	}
+	if !strings.HasPrefix(notice.Subject, "Part of your order") || !strings.Contains(notice.Text, "https://www.ups.com/track?tracknum=1Z999AA10123456784") || !strings.Contains(notice.Text, "1 x Game Boy") {
+		t.Fatalf("tracking notice = %q\n%s", notice.Subject, notice.Text)
+	}
+
+	// a second admin working from the same page must not over-ship
+	stale := repos.Shipment{ID: "stale", OrderID: oid, Carrier: "UPS", TrackingNo: "1Z000", ShippedOn: first.ShippedOn,
+		Items: []repos.ShipmentItem{{ProductID: "gbc-001", Qty: 2}}}
+	if ok, err := shipmentRepo.Create(stale, 0, "SHIPPED"); err != nil || ok {
+		t.Fatalf("stale shipment: ok=%v err=%v", ok, err)
+	}
+
+	// nor ship an order canceled since the page was loaded
+	if err := cart.Add("sid-sh2", "gbc-001", 1); err != nil {
+		t.Fatal(err)
+	}
+	canceled, _, _, err := svc.Place("sid-sh2", "20742", "delivery", contact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Cancel(canceled, repos.StatusChange{Actor: "ADMIN"}); err != nil {
+		t.Fatal(err)
+	}
+	late := repos.Shipment{ID: "late", OrderID: canceled, Carrier: "UPS", TrackingNo: "1Z001", ShippedOn: first.ShippedOn,
+		Items: []repos.ShipmentItem{{ProductID: "gbc-001", Qty: 1}}}
+	if ok, err := shipmentRepo.Create(late, 0, "SHIPPED"); err != nil || ok {
+		t.Fatalf("shipment of a canceled order: ok=%v err=%v", ok, err)
+	}
+	if o, _, _ := orders.Get(canceled); o.Status != "CANCELED" {
+		t.Fatalf("canceled order moved to %s", o.Status)
+	}
+
+	if _, err := shipments.Ship(oid, "u-admin", services.ShipmentRequest{Carrier: "USPS", TrackingNo: "9400111899223", ShippedOn: today, Qty: map[string]int{"gbc-001": 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if o, _, _ := orders.Get(oid); o.Status != "SHIPPED" {
+		t.Fatalf("after last parcel: %s", o.Status)
+	}
+	if no, _, _ := orders.InvoiceNo(oid); no != invoiceNo {
+		t.Fatalf("second shipment renumbered the invoice: %d -> %d", invoiceNo, no)
+	}
+	list, err := shipments.ForOrder(oid)
+	if err != nil || len(list) != 2 || list[1].Items[0].Qty != 2 || list[1].CarrierLabel() != "USPS" {
+		t.Fatalf("shipments = %+v, %v", list, err)
+	}
+	hist, _ := orders.History(oid)
+	if len(hist) < 2 || hist[len(hist)-1].Status != "SHIPPED" || hist[len(hist)-1].Note != "USPS 9400111899223" {
+		t.Fatalf("history = %+v", hist)
+	}
+	if _, err := shipments.Ship(oid, "u-admin", services.ShipmentRequest{Carrier: "USPS", TrackingNo: "9400111899224", ShippedOn: today, Qty: map[string]int{"gbc-001": 1}}); !errors.Is(err, services.ErrShipment) {
+		t.Fatalf("shipping a shipped order: err = %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"retrobytes/internal/repos"
+)
+
+// ErrShippingUnavailable is returned when the chosen method cannot deliver
+// the cart to the destination (no zone, or heavier than every bracket).
+var ErrShippingUnavailable = errors.New("that shipping option is not available for this address")
+
+// ErrShippingConfig wraps invalid shipping settings entered by an admin.
+var ErrShippingConfig = errors.New("invalid shipping settings")
+
+var methodCodeRe = regexp.MustCompile(`^[A-Z0-9_]{2,20}$`)
+
+// dimDivisor converts cm³ to volumetric kilograms, as carriers do.
+const dimDivisor = 5000
+
+// ShippingOption is one method priced for a cart and destination.
+type ShippingOption struct {
+	Method   string
+	Name     string
+	Zone     int
+	WeightKg float64 // billable weight
+	Cost     float64
+	Free     bool    // waived by the method's free-shipping threshold
+	FreeOver float64 // threshold, 0 when the method never ships free
+}
+
+// Label is the line shown on checkout and orders.
+func (o ShippingOption) Label() string {
+	if o.Free {
+		return o.Name + " (free)"
+	}
+	return o.Name
+}
+
+// ShippingService prices delivery from the zone and rate tables.
+type ShippingService struct {
+	Ship *repos.ShippingRepo
+}
+
+func NewShippingService(ship *repos.ShippingRepo) *ShippingService {
+	return &ShippingService{Ship: ship}
+}
+
+// Weight is the billable weight of lines: per unit, the larger of actual
+// and volumetric weight.
+func (s *ShippingService) Weight(lines []CartLine) (float64, error) {
+	ids := make([]string, len(lines))
+	for i, l := range lines {
+		ids[i] = l.ProductID
+	}
+	parcels, err := s.Ship.Parcels(ids)
+	if err != nil {
+		return 0, err
+	}
+	total := 0.0
+	for _, l := range lines {
+		p := parcels[l.ProductID]
+		w := math.Max(p.WeightKg, p.LengthCm*p.WidthCm*p.HeightCm/dimDivisor)
+		total += w * float64(l.Qty)
+	}
+	return math.Round(total*1000) / 1000, nil
+}
+
+// Options prices every active method able to ship lines from origin (the
+// stock region) to zip. due is the amount checked against free-shipping
+// thresholds, i.e. items after discounts.
+func (s *ShippingService) Options(origin, zip string, lines []CartLine, due float64) ([]ShippingOption, error) {
+	zone, ok, err := s.Ship.Zone(origin, strings.TrimSpace(zip))
+	if err != nil || !ok {
+		return nil, err
+	}
+	weight, err := s.Weight(lines)
+	if err != nil {
+		return nil, err
+	}
+	methods, err := s.Ship.Methods(true)
+	if err != nil {
+		return nil, err
+	}
+	var out []ShippingOption
+	for _, m := range methods {
+		price, ok, err := s.Ship.Rate(m.Code, zone, weight)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		opt := ShippingOption{Method: m.Code, Name: m.Name, Zone: zone, WeightKg: weight, Cost: price}
+		if m.FreeOver != nil {
+			opt.FreeOver = *m.FreeOver
+			if due >= *m.FreeOver {
+				opt.Cost, opt.Free = 0, true
+			}
+		}
+		out = append(out, opt)
+	}
+	return out, nil
+}
+
+// Quote prices one method; an empty method picks the cheapest option.
+func (s *ShippingService) Quote(origin, zip, method string, lines []CartLine, due float64) (ShippingOption, error) {
+	opts, err := s.Options(origin, zip, lines, due)
+	if err != nil {
+		return ShippingOption{}, err
+	}
+	opt, ok := ChooseShipping(opts, method)
+	if !ok {
+		return ShippingOption{}, ErrShippingUnavailable
+	}
+	return opt, nil
+}
+
+// ChooseShipping picks method from opts, or the cheapest when method is "".
+func ChooseShipping(opts []ShippingOption, method string) (ShippingOption, bool) {
+	var best *ShippingOption
+	for i, o := range opts {
+		if o.Method == method {
+			return o, true
+		}
+		if method == "" && (best == nil || o.Cost < best.Cost) {
+			best = &opts[i]
+		}
+	}
+	if best == nil {
+		return ShippingOption{}, false
+	}
+	return *best, true
+}
+
+func (s *ShippingService) Methods() ([]repos.ShippingMethod, error) { return s.Ship.Methods(false) }
+func (s *ShippingService) Zones() ([]repos.ShippingZone, error)     { return s.Ship.Zones() }
+func (s *ShippingService) Rates() ([]repos.ShippingRate, error)     { return s.Ship.Rates() }
+
+// SaveMethod validates and stores a method.
+func (s *ShippingService) SaveMethod(m repos.ShippingMethod) error {
+	m.Code, m.Name = strings.ToUpper(strings.TrimSpace(m.Code)), strings.TrimSpace(m.Name)
+	switch {
+	case m.Code == "PICKUP" || !methodCodeRe.MatchString(m.Code):
+		return fmt.Errorf("%w: code must be 2-20 letters, digits or _ (PICKUP is reserved)", ErrShippingConfig)
+	case m.Name == "" || len(m.Name) > 60:
+		return fmt.Errorf("%w: name must be 1-60 characters", ErrShippingConfig)
+	case m.FreeOver != nil && *m.FreeOver < 0:
+		return fmt.Errorf("%w: free-shipping threshold cannot be negative", ErrShippingConfig)
+	}
+	return s.Ship.SaveMethod(m)
+}
+
+// SaveZone validates and stores a zone mapping.
+func (s *ShippingService) SaveZone(z repos.ShippingZone) error {
+	z.Origin, z.DestPrefix = strings.TrimSpace(z.Origin), strings.TrimSpace(z.DestPrefix)
+	switch {
+	case z.Origin != "" && !zipPrefixRe.MatchString(z.Origin):
+		return fmt.Errorf("%w: origin must be a region code or empty for any", ErrShippingConfig)
+	case z.DestPrefix != "" && !zipPrefixRe.MatchString(z.DestPrefix):
+		return fmt.Errorf("%w: destination must be a ZIP prefix or empty for everywhere", ErrShippingConfig)
+	case z.Zone < 1 || z.Zone > 99:
+		return fmt.Errorf("%w: zone must be 1-99", ErrShippingConfig)
+	}
+	return s.Ship.SaveZone(z)
+}
+
+func (s *ShippingService) DeleteZone(origin, destPrefix string) error {
+	return s.Ship.DeleteZone(origin, destPrefix)
+}
+
+// SaveRate validates and stores a weight bracket.
+func (s *ShippingService) SaveRate(rt repos.ShippingRate) error {
+	switch {
+	case rt.Zone < 1 || rt.Zone > 99:
+		return fmt.Errorf("%w: zone must be 1-99", ErrShippingConfig)
+	case rt.MaxWeight <= 0 || rt.MaxWeight > 1000:
+		return fmt.Errorf("%w: max weight must be between 0 and 1000 kg", ErrShippingConfig)
+	case rt.Price < 0:
+		return fmt.Errorf("%w: price cannot be negative", ErrShippingConfig)
+	}
+	methods, err := s.Ship.Methods(false)
+	if err != nil {
+		return err
+	}
+	for _, m := range methods {
+		if m.Code == rt.Method {
+			return s.Ship.SaveRate(rt)
+		}
+	}
+	return fmt.Errorf("%w: unknown method %q", ErrShippingConfig, rt.Method)
+}
+
+func (s *ShippingService) DeleteRate(method string, zone int, maxWeight float64) error {
+	return s.Ship.DeleteRate(method, zone, maxWeight)
+}
+
+// Parcel returns the stored size of one product (variants fall back to
+// their parent).
+func (s *ShippingService) Parcel(productID string) (repos.Parcel, error) {
+	ps, err := s.Ship.Parcels([]string{productID})
+	return ps[productID], err
+}
+
+// SetParcel validates and stores a product's packed size.
+func (s *ShippingService) SetParcel(p repos.Parcel) error {
+	for _, v := range []float64{p.WeightKg, p.LengthCm, p.WidthCm, p.HeightCm} {
+		if math.IsNaN(v) || v < 0 || v > 1000 {
+			return fmt.Errorf("%w: weight and dimensions must be between 0 and 1000", ErrShippingConfig)
+		}
+	}
+	return s.Ship.SetParcel(p)
+}
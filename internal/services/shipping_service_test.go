@@ -0,0 +1,120 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestShippingOptions(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ship := services.NewShippingService(repos.NewShippingRepo(db))
+
+	gbc := services.CartLine{ProductID: "gbc-001", Qty: 1, Price: 129.99}    // 0.5 kg, 20x15x8 cm
+	radio := services.CartLine{ProductID: "radio-001", Qty: 1, Price: 349.5} // 4 kg but 35x25x20 cm = 3.5 kg volumetric
+
+	if w, err := ship.Weight([]services.CartLine{gbc, {ProductID: "gbc-001", Qty: 2}}); err != nil || w != 1.5 {
+		t.Fatalf("weight = %v, %v; want 1.5", w, err)
+	}
+	if err := ship.SetParcel(repos.Parcel{ProductID: "gbc-001", WeightKg: 0.5, LengthCm: 40, WidthCm: 30, HeightCm: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := ship.Weight([]services.CartLine{gbc}); w != 2.4 {
+		t.Fatalf("volumetric weight = %v, want 2.4", w)
+	}
+
+	for _, tc := range []struct {
+		origin, zip, method string
+		lines               []services.CartLine
+		due                 float64
+		zone                int
+		cost                float64
+		free                bool
+	}{
+		{"20742", "20901", "STANDARD", []services.CartLine{gbc}, 129.99, 1, 8.99, false},  // 2.4 kg: 5 kg bracket
+		{"20742", "10001", "EXPRESS", []services.CartLine{gbc}, 129.99, 2, 27.99, false},  // longest prefix under the origin
+		{"20742", "94105", "STANDARD", []services.CartLine{gbc}, 129.99, 3, 14.99, false}, // catch-all destination
+		{"99999", "20742", "STANDARD", []services.CartLine{gbc}, 129.99, 3, 14.99, false}, // unknown origin uses the default zone
+		{"20742", "20742", "STANDARD", []services.CartLine{radio}, 349.5, 1, 0, true},     // over the free threshold
+		{"20742", "20742", "", []services.CartLine{gbc}, 129.99, 1, 8.99, false},          // cheapest by default
+	} {
+		got, err := ship.Quote(tc.origin, tc.zip, tc.method, tc.lines, tc.due)
+		if err != nil {
+			t.Fatalf("%s→%s %s: %v", tc.origin, tc.zip, tc.method, err)
+		}
+		if got.Zone != tc.zone || got.Cost != tc.cost || got.Free != tc.free {
+			t.Errorf("%s→%s %s: zone %d cost %.2f free %v, want %d %.2f %v", tc.origin, tc.zip, tc.method, got.Zone, got.Cost, got.Free, tc.zone, tc.cost, tc.free)
+		}
+	}
+
+	// six radios weigh 24 kg, beyond the largest bracket
+	heavy := services.CartLine{ProductID: "radio-001", Qty: 6, Price: 349.5}
+	if _, err := ship.Quote("20742", "20742", "EXPRESS", []services.CartLine{heavy}, 2097); !errors.Is(err, services.ErrShippingUnavailable) {
+		t.Fatalf("overweight parcel: err = %v", err)
+	}
+	if err := ship.SaveMethod(repos.ShippingMethod{Code: "PICKUP", Name: "Pickup"}); !errors.Is(err, services.ErrShippingConfig) {
+		t.Fatalf("reserved code accepted: %v", err)
+	}
+	if err := ship.SaveRate(repos.ShippingRate{Method: "NOPE", Zone: 1, MaxWeight: 1, Price: 1}); !errors.Is(err, services.ErrShippingConfig) {
+		t.Fatalf("rate for unknown method accepted: %v", err)
+	}
+}
+
+func TestShippingStoredOnOrder(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	carts := repos.NewCartRepo(db)
+	orders := repos.NewOrderRepo(db)
+	cart := services.NewCartService(carts, prods)
+	svc := services.NewOrderService(carts, repos.NewInventoryRepo(db), orders, prods)
+	svc.Shipping = services.NewShippingService(repos.NewShippingRepo(db))
+	contact := services.Contact{Name: "Test", Email: "s@retrobytes.test", ShipZIP: "10001", ShipMethod: "EXPRESS"}
+
+	if err := cart.Add("sid-s1", "gbc-001", 1); err != nil {
+		t.Fatal(err)
+	}
+	oid, total, _, err := svc.Place("sid-s1", "20742", "delivery", contact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 149.98 {
+		t.Fatalf("total = %.2f, want 149.98", total)
+	}
+	o, _, err := orders.Get(oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.ShipMethod != "EXPRESS" || o.ShipZIP != "10001" {
+		t.Fatalf("order shipping = %q to %q", o.ShipMethod, o.ShipZIP)
+	}
+	adj, err := orders.Adjustments(oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(adj) != 1 || adj[0].Kind != "SHIPPING" || adj[0].Code != "EXPRESS" || adj[0].Amount != 19.99 {
+		t.Fatalf("adjustments = %+v", adj)
+	}
+
+	// pickup is free whatever the method says
+	if err := cart.Add("sid-s2", "gbc-001", 1); err != nil {
+		t.Fatal(err)
+	}
+	oid, total, _, err = svc.Place("sid-s2", "20742", "pickup", contact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 129.99 {
+		t.Fatalf("pickup total = %.2f, want 129.99", total)
+	}
+	if o, _, _ := orders.Get(oid); o.ShipMethod != "PICKUP" || o.ShipZIP != "" {
+		t.Fatalf("pickup order shipping = %q to %q", o.ShipMethod, o.ShipZIP)
+	}
+}
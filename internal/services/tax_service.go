@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"retrobytes/internal/repos"
+)
+
+// ErrTaxConfig wraps invalid rate settings entered by an admin.
+var ErrTaxConfig = errors.New("invalid tax rate")
+
+var zipPrefixRe = regexp.MustCompile(`^[0-9]{1,5}$`)
+
+// TaxQuote is the sales tax owed on a cart delivered to (or picked up in)
+// one ZIP code. A zero quote with an empty ZIPPrefix means no rate applies.
+type TaxQuote struct {
+	ZIPPrefix string
+	Name      string
+	Rate      float64 // percent
+	Taxable   float64 // non-exempt amount after discounts
+	Amount    float64
+}
+
+// Label is the line shown on checkout, orders and reports.
+func (q TaxQuote) Label() string {
+	return fmt.Sprintf("Sales tax (%s %s%%)", q.Name, strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.3f", q.Rate), "0"), "."))
+}
+
+// TaxService computes sales tax from the admin-maintained rate table.
+type TaxService struct {
+	Taxes *repos.TaxRepo
+	Prods *repos.ProductRepo
+}
+
+func NewTaxService(taxes *repos.TaxRepo, prods *repos.ProductRepo) *TaxService {
+	return &TaxService{Taxes: taxes, Prods: prods}
+}
+
+// Quote computes the tax on lines for zip. An order-level discount is
+// spread over the lines in proportion to their value, so exempt items
+// carry their share of it.
+func (s *TaxService) Quote(zip string, lines []CartLine, discount float64) (TaxQuote, error) {
+	rate, ok, err := s.Taxes.ForZIP(strings.TrimSpace(zip))
+	if err != nil || !ok {
+		return TaxQuote{}, err
+	}
+	exempt := map[string]bool{}
+	for _, id := range rate.Exempt {
+		exempt[id] = true
+	}
+	subtotal, taxable := 0.0, 0.0
+	for _, l := range lines {
+		amount := l.Price * float64(l.Qty)
+		subtotal += amount
+		prod, err := s.Prods.Get(l.ProductID)
+		if err != nil {
+			return TaxQuote{}, err
+		}
+		if !exempt[prod.CategoryID] {
+			taxable += amount
+		}
+	}
+	if subtotal > 0 && discount > 0 {
+		taxable -= discount * taxable / subtotal
+	}
+	taxable = math.Max(0, math.Round(taxable*100)/100)
+	return TaxQuote{
+		ZIPPrefix: rate.ZIPPrefix,
+		Name:      rate.Name,
+		Rate:      rate.Rate,
+		Taxable:   taxable,
+		Amount:    math.Round(taxable*rate.Rate) / 100,
+	}, nil
+}
+
+func (s *TaxService) Rates() ([]repos.TaxRate, error) { return s.Taxes.Rates() }
+
+// Save validates and stores a rate and its exempt categories.
+func (s *TaxService) Save(prefix, name string, rate float64, exempt []string) error {
+	prefix, name = strings.TrimSpace(prefix), strings.TrimSpace(name)
+	switch {
+	case !zipPrefixRe.MatchString(prefix):
+		return fmt.Errorf("%w: ZIP prefix must be 1-5 digits", ErrTaxConfig)
+	case name == "" || len(name) > 60:
+		return fmt.Errorf("%w: name must be 1-60 characters", ErrTaxConfig)
+	case math.IsNaN(rate) || rate < 0 || rate > 25:
+		return fmt.Errorf("%w: rate must be between 0 and 25 percent", ErrTaxConfig)
+	}
+	if err := s.Taxes.Save(prefix, name, rate); err != nil {
+		return err
+	}
+	return s.Taxes.SetExemptions(prefix, exempt)
+}
+
+func (s *TaxService) Delete(prefix string) error { return s.Taxes.Delete(prefix) }
+
+// Report sums collected tax per rate for orders placed in [from, to).
+func (s *TaxService) Report(from, to string) ([]repos.TaxReportRow, error) {
+	return s.Taxes.Report(from, to)
+}
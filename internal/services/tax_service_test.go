@@ -0,0 +1,120 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestTaxQuote(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tax := services.NewTaxService(repos.NewTaxRepo(db), repos.NewProductRepo(db))
+	// a more specific rate for one ZIP, with consoles exempt
+	if err := tax.Save("20742", "College Park", 10, []string{"retro-consoles"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tax.Save("2x", "Bad", 5, nil); !errors.Is(err, services.ErrTaxConfig) {
+		t.Fatalf("non-digit prefix accepted: %v", err)
+	}
+	if err := tax.Save("300", "Too high", 30, nil); !errors.Is(err, services.ErrTaxConfig) {
+		t.Fatalf("30%% rate accepted: %v", err)
+	}
+
+	gbc := services.CartLine{ProductID: "gbc-001", Qty: 1, Price: 129.99}
+	radio := services.CartLine{ProductID: "radio-001", Qty: 2, Price: 100}
+
+	for _, tc := range []struct {
+		zip      string
+		lines    []services.CartLine
+		discount float64
+		prefix   string
+		taxable  float64
+		want     float64
+	}{
+		{"20740", []services.CartLine{gbc}, 0, "207", 129.99, 7.80},
+		{"20742", []services.CartLine{gbc, radio}, 0, "20742", 200, 20},      // longest prefix wins; consoles exempt
+		{"20740", []services.CartLine{radio}, 20, "207", 180, 10.80},         // tax on the discounted amount
+		{"20742", []services.CartLine{gbc, radio}, 32.999, "20742", 180, 18}, // discount shared with exempt lines
+		{"10001", []services.CartLine{radio}, 0, "100", 200, 17.75},
+		{"90210", []services.CartLine{gbc}, 0, "", 0, 0},
+	} {
+		q, err := tax.Quote(tc.zip, tc.lines, tc.discount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.ZIPPrefix != tc.prefix || q.Taxable != tc.taxable || q.Amount != tc.want {
+			t.Errorf("%s: got %s taxable %.2f tax %.2f, want %s %.2f %.2f", tc.zip, q.ZIPPrefix, q.Taxable, q.Amount, tc.prefix, tc.taxable, tc.want)
+		}
+	}
+}
+
+func TestTaxStoredOnOrderAndReported(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prods := repos.NewProductRepo(db)
+	carts := repos.NewCartRepo(db)
+	orders := repos.NewOrderRepo(db)
+	tax := services.NewTaxService(repos.NewTaxRepo(db), prods)
+	cart := services.NewCartService(carts, prods)
+	svc := services.NewOrderService(carts, repos.NewInventoryRepo(db), orders, prods)
+	svc.Tax = tax
+
+	if err := cart.Add("sid-t1", "gbc-001", 1); err != nil {
+		t.Fatal(err)
+	}
+	oid, total, _, err := svc.Place("sid-t1", "20742", "pickup", services.Contact{Name: "Test", Email: "t@retrobytes.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 137.79 {
+		t.Fatalf("total = %.2f, want 137.79", total)
+	}
+	adj, err := orders.Adjustments(oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(adj) != 1 || adj[0].Kind != "TAX" || adj[0].Code != "207" || adj[0].Amount != 7.80 || adj[0].Base != 129.99 {
+		t.Fatalf("adjustments = %+v", adj)
+	}
+
+	today := time.Now().UTC()
+	rows, err := tax.Report(today.Format("2006-01-02"), today.AddDate(0, 0, 1).Format("2006-01-02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Orders != 1 || rows[0].Tax != 7.80 || rows[0].Taxable != 129.99 {
+		t.Fatalf("report = %+v", rows)
+	}
+	latest, err := orders.ListLatest(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(latest) == 0 || latest[0].Tax != 7.80 {
+		t.Fatalf("order list tax = %+v", latest)
+	}
+
+	// tax refunded with the item no longer counts as collected
+	refunds := services.NewRefundService(orders, repos.NewRefundRepo(db), nil)
+	f, err := refunds.Refund(oid, "u-admin", services.RefundRequest{Full: true, Reason: "DAMAGED"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Items) != 1 || f.Items[0].Tax != 7.80 {
+		t.Fatalf("refund lines = %+v", f.Items)
+	}
+	rows, err = tax.Report(today.Format("2006-01-02"), today.AddDate(0, 0, 1).Format("2006-01-02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Tax != 0 || rows[0].Taxable != 0 {
+		t.Fatalf("report after refund = %+v", rows)
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"time"
+
+	"retrobytes/internal/domain"
+	"retrobytes/internal/repos"
+)
+
+// RecentlyViewedKept is how many listings are remembered per session/user.
+const RecentlyViewedKept = 12
+
+// ViewService records product page views for the "recently viewed" strip
+// and the admin views-vs-sales report.
+type ViewService struct {
+	Views *repos.ViewRepo
+	// Pricing, when set, shows sale prices in the recently viewed strip
+	Pricing *PricingService
+}
+
+func NewViewService(views *repos.ViewRepo) *ViewService {
+	return &ViewService{Views: views}
+}
+
+// owners maps a visitor to recently-viewed keys: the session always, plus
+// the account when logged in so the list follows the user across devices.
+func owners(sessionID, userID string) []string {
+	var out []string
+	if sessionID != "" {
+		out = append(out, "s:"+sessionID)
+	}
+	if userID != "" {
+		out = append(out, "u:"+userID)
+	}
+	return out
+}
+
+func (s *ViewService) Record(productID, sessionID, userID string) error {
+	ts := time.Now().UTC().Format("2006-01-02 15:04:05.000000000")
+	return s.Views.Record(productID, owners(sessionID, userID), RecentlyViewedKept, ts)
+}
+
+// Recent returns the visitor's recently viewed listings, skipping exclude.
+func (s *ViewService) Recent(sessionID, userID, exclude string, limit int) ([]domain.Product, error) {
+	list, err := s.Views.Recent(owners(sessionID, userID), exclude, limit)
+	if err != nil || s.Pricing == nil {
+		return list, err
+	}
+	return list, s.Pricing.Apply(list, time.Now())
+}
+
+// Report returns per-listing views with sales and a conversion rate.
+func (s *ViewService) Report(limit int) ([]repos.ViewStat, error) {
+	stats, err := s.Views.Stats(limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		if stats[i].Views > 0 {
+			stats[i].Conversion = float64(stats[i].Orders) * 100 / float64(stats[i].Views)
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,94 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+
+	"retrobytes/internal/domain"
+	"retrobytes/internal/repos"
+	"retrobytes/internal/services"
+)
+
+func TestRecentlyViewedAndViewReport(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := services.NewViewService(repos.NewViewRepo(db))
+
+	for _, id := range []string{"nes-001", "gbc-001", "radio-001", "nes-001"} {
+		if err := svc.Record(id, "sid-a", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recent, err := svc.Recent("sid-a", "", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(recent); got != "[nes-001 radio-001 gbc-001]" {
+		t.Fatalf("recent = %s, want newest first without duplicates", got)
+	}
+	if recent, _ = svc.Recent("sid-a", "", "nes-001", 10); ids(recent) != "[radio-001 gbc-001]" {
+		t.Fatalf("exclude current product: got %s", ids(recent))
+	}
+	if recent, _ = svc.Recent("sid-b", "", "", 10); len(recent) != 0 {
+		t.Fatalf("other session sees %s", ids(recent))
+	}
+
+	// Logged-in views follow the account to a new session
+	if err := svc.Record("gbc-001", "sid-c", "u-alice"); err != nil {
+		t.Fatal(err)
+	}
+	if recent, _ = svc.Recent("sid-d", "u-alice", "", 10); ids(recent) != "[gbc-001]" {
+		t.Fatalf("account recent = %s", ids(recent))
+	}
+
+	orders := repos.NewOrderRepo(db)
+	if err := orders.Create("o1", "sid-a", "20742", "pickup", "Test", "t@retrobytes.test", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.InsertItem("o1", "nes-001", 2, 1, "GOOD"); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := svc.Report(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range stats {
+		if s.ProductID == "nes-001" {
+			if s.Views != 2 || s.Orders != 1 || s.UnitsSold != 2 || s.Conversion != 50 {
+				t.Fatalf("nes-001 stats = %+v", s)
+			}
+			return
+		}
+	}
+	t.Fatal("nes-001 missing from report")
+}
+
+func TestRecentlyViewedIsCapped(t *testing.T) {
+	db, err := repos.OpenDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	views := repos.NewViewRepo(db)
+	for i, id := range []string{"nes-001", "gbc-001", "radio-001"} {
+		if err := views.Record(id, []string{"s:x"}, 2, fmt.Sprintf("2026-01-01 00:00:0%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var n int
+	if err := db.Get(&n, `SELECT COUNT(*) FROM recent_views WHERE owner_key = 's:x'`); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("kept %d entries, want 2", n)
+	}
+}
+
+func ids(list []domain.Product) string {
+	out := make([]string, len(list))
+	for i, p := range list {
+		out[i] = p.ID
+	}
+	return fmt.Sprint(out)
+}
@@ -2,8 +2,12 @@ package validate
 
 import (
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"retrobytes/internal/domain"
 )
 
 var (
@@ -12,7 +16,7 @@ var (
 	reEmail = regexp.MustCompile(`^[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$`)
 	reQ     = regexp.MustCompile(`^[A-Za-z0-9 _'\\-]{1,50}$`)
 	reID    = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
-	reCond  = regexp.MustCompile(`^(FIRST_HAND|SECOND_HAND)$`)
+	reCond  = regexp.MustCompile(`^(NEW_SEALED|MINT|EXCELLENT|GOOD|FAIR|FOR_PARTS)$`)
 )
 
 func Region(s string) (string, bool) {
@@ -59,12 +63,34 @@ func ID(s string) (string, bool) {
 	return s, s != "" && reID.MatchString(s)
 }
 
-// Condition validates allowed condition enums.
+// Carrier validates a carrier code (see domain.Carriers).
+func Carrier(s string) (string, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	_, ok := domain.CarrierOf(s)
+	return s, ok
+}
+
+var reTracking = regexp.MustCompile(`^[A-Z0-9-]{6,40}$`)
+
+// TrackingNo normalizes a carrier tracking number: upper case, without
+// the spaces carriers print in it.
+func TrackingNo(s string) (string, bool) {
+	s = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+	return s, reTracking.MatchString(s)
+}
+
+// Condition validates a condition grade code (see domain.Grades).
 func Condition(s string) (string, bool) {
 	s = strings.TrimSpace(s)
 	return s, s != "" && reCond.MatchString(s)
 }
 
+// ConditionNotes validates the free-text notes shown under a listing's grade.
+func ConditionNotes(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	return s, len(s) <= 1000
+}
+
 // Name validates a displayable name with a reasonable max length.
 func Name(s string) (string, bool) {
 	s = strings.TrimSpace(s)
@@ -95,3 +121,242 @@ func Password(s string) bool {
 	}
 	return hasLower && hasUpper && hasDigit && hasSymbol
 }
+
+// AltText validates image alt text: optional, single line, max 120 chars.
+func AltText(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) > 120 || strings.ContainsAny(s, "\r\n") {
+		return "", false
+	}
+	return s, true
+}
+
+var reAttrKey = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// AttrKey validates an attribute key such as "model_number".
+func AttrKey(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	return s, reAttrKey.MatchString(s)
+}
+
+// Label validates a short single-line label (attribute names, variant labels).
+func Label(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" || len(s) > 40 || strings.ContainsAny(s, "\r\n<>") {
+		return "", false
+	}
+	return s, true
+}
+
+// Price parses a non-negative money amount with an upper bound.
+func Price(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || f < 0 || f > 100000 {
+		return 0, false
+	}
+	return f, true
+}
+
+// DateTime parses a datetime-local form value ("2006-01-02T15:04") as UTC.
+func DateTime(s string) (time.Time, bool) {
+	t, err := time.Parse("2006-01-02T15:04", strings.TrimSpace(s))
+	return t, err == nil
+}
+
+// Date parses a date form value ("2006-01-02") as UTC.
+func Date(s string) (time.Time, bool) {
+	t, err := time.Parse("2006-01-02", strings.TrimSpace(s))
+	return t, err == nil
+}
+
+var rePromo = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// PromoCode normalizes a coupon code to upper case and checks its shape.
+func PromoCode(s string) (string, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	return s, rePromo.MatchString(s)
+}
+
+var reOrderNo = regexp.MustCompile(`^RB-[0-9]{4}-[0-9]{6}$`)
+
+// OrderNo normalizes a customer-facing order number (RB-2026-000123).
+func OrderNo(s string) (string, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	return s, reOrderNo.MatchString(s)
+}
+
+var reLink = regexp.MustCompile(`(?i)https?://|www\.`)
+
+// Rating parses a 1-5 star rating.
+func Rating(s string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	return n, err == nil && n >= 1 && n <= 5
+}
+
+// ReviewTitle validates a review headline: one line, 3-80 characters.
+func ReviewTitle(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < 3 || len(s) > 80 || strings.ContainsAny(s, "\r\n") {
+		return "", false
+	}
+	return s, true
+}
+
+// ReviewBody validates review text: 20-2000 characters, at most one link and
+// no long runs of a repeated character (typical spam and keyboard mashing).
+func ReviewBody(s string) (string, bool) {
+	return freeText(s, 20, 2000)
+}
+
+// Question validates a shopper question about a product (10-500 characters,
+// same spam rules as ReviewBody).
+func Question(s string) (string, bool) {
+	return freeText(s, 10, 500)
+}
+
+// Answer validates a staff answer to a product question.
+func Answer(s string) (string, bool) {
+	return freeText(s, 2, 2000)
+}
+
+func freeText(s string, min, max int) (string, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < min || len(s) > max {
+		return "", false
+	}
+	if len(reLink.FindAllStringIndex(s, -1)) > 1 {
+		return "", false
+	}
+	run, prev := 0, rune(0)
+	for _, r := range s {
+		if r == prev {
+			run++
+			if run >= 10 {
+				return "", false
+			}
+		} else {
+			run, prev = 1, r
+		}
+	}
+	return s, true
+}
+
+// zipRanges lists the first-three-digit ZIP ranges assigned to each state,
+// DC and the territories (USPS prefix table).
+var zipRanges = map[string][][2]int{
+	"AL": {{350, 369}}, "AK": {{995, 999}}, "AZ": {{850, 865}}, "AR": {{716, 729}},
+	"CA": {{900, 961}}, "CO": {{800, 816}}, "CT": {{60, 69}}, "DE": {{197, 199}},
+	"DC": {{200, 200}, {202, 205}, {569, 569}}, "FL": {{320, 349}}, "GA": {{300, 319}, {398, 399}},
+	"HI": {{967, 968}}, "ID": {{832, 838}}, "IL": {{600, 629}}, "IN": {{460, 479}},
+	"IA": {{500, 528}}, "KS": {{660, 679}}, "KY": {{400, 427}}, "LA": {{700, 714}},
+	"ME": {{39, 49}}, "MD": {{206, 219}}, "MA": {{10, 27}, {55, 55}}, "MI": {{480, 499}},
+	"MN": {{550, 567}}, "MS": {{386, 397}}, "MO": {{630, 658}}, "MT": {{590, 599}},
+	"NE": {{680, 693}}, "NV": {{889, 898}}, "NH": {{30, 38}}, "NJ": {{70, 89}},
+	"NM": {{870, 884}}, "NY": {{5, 5}, {100, 149}}, "NC": {{270, 289}}, "ND": {{580, 588}},
+	"OH": {{430, 459}}, "OK": {{730, 749}}, "OR": {{970, 979}}, "PA": {{150, 196}},
+	"RI": {{28, 29}}, "SC": {{290, 299}}, "SD": {{570, 577}}, "TN": {{370, 385}},
+	"TX": {{733, 733}, {750, 799}, {885, 885}}, "UT": {{840, 847}}, "VT": {{50, 54}, {56, 59}},
+	"VA": {{201, 201}, {220, 246}}, "WA": {{980, 994}}, "WV": {{247, 268}}, "WI": {{530, 549}},
+	"WY": {{820, 831}}, "PR": {{6, 7}, {9, 9}}, "VI": {{8, 8}}, "GU": {{969, 969}},
+}
+
+// State normalizes a two-letter state or territory code.
+func State(s string) (string, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	_, ok := zipRanges[s]
+	return s, ok
+}
+
+// ZIPInState reports whether a 5-digit ZIP belongs to state.
+func ZIPInState(zip, state string) bool {
+	if !reZIP.MatchString(zip) {
+		return false
+	}
+	prefix, _ := strconv.Atoi(zip[:3])
+	for _, r := range zipRanges[state] {
+		if prefix >= r[0] && prefix <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// AddressLine validates a street line; optional lines may be empty.
+func AddressLine(s string, required bool) (string, bool) {
+	s = strings.TrimSpace(s)
+	if (required && s == "") || len(s) > 80 || strings.ContainsAny(s, "\r\n<>") {
+		return "", false
+	}
+	return s, true
+}
+
+var rePhone = regexp.MustCompile(`^\+?[0-9 ()./-]{7,20}$`)
+
+// Phone validates an optional contact phone number.
+func Phone(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	return s, s == "" || rePhone.MatchString(s)
+}
+
+// States lists the accepted state and territory codes, sorted.
+func States() []string {
+	out := make([]string, 0, len(zipRanges))
+	for s := range zipRanges {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+var rePaymentToken = regexp.MustCompile(`^[A-Za-z0-9_-]{3,64}$`)
+
+// PaymentToken validates the opaque card token posted from checkout.
+func PaymentToken(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	return s, rePaymentToken.MatchString(s)
+}
+
+// RefundNote validates the optional staff note stored with a refund.
+func RefundNote(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	return s, len(s) <= 500
+}
+
+// ReturnDetails validates the optional description on a return request or
+// the staff note on its decisions.
+func ReturnDetails(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	return s, len(s) <= 1000
+}
+
+var rePickupCode = regexp.MustCompile(`^[A-HJ-NP-Z2-9]{4}-[A-HJ-NP-Z2-9]{4}$`)
+
+// PickupCode normalizes a pickup code as read off a phone or printout:
+// upper case, spaces dropped and the dash optional.
+func PickupCode(s string) (string, bool) {
+	s = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+	if len(s) == 8 && !strings.Contains(s, "-") {
+		s = s[:4] + "-" + s[4:]
+	}
+	return s, rePickupCode.MatchString(s)
+}
+
+// PickupNote validates the staff note on how the collector's ID was
+// checked; it is required.
+func PickupNote(s string) (string, bool) {
+	return freeText(s, 3, 200)
+}
+
+var reClock = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+// Clock validates a 24-hour HH:MM time of day.
+func Clock(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	return s, reClock.MatchString(s)
+}
+
+// Weekday validates a weekday number, 0 = Sunday.
+func Weekday(s string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	return n, err == nil && n >= 0 && n <= 6
+}